@@ -211,11 +211,29 @@ func request_ABitOfEverythingService_Create_0(ctx context.Context, marshaler run
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "nonConventionalNameValue", err)
 	}
 
-	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_ABitOfEverythingService_Create_0); err != nil {
+	if err := runtime.CheckQueryParameters(ctx, &protoReq, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.PopulateQueryParametersContext(ctx, &protoReq, req.URL.Query(), filter_ABitOfEverythingService_Create_0); err != nil {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
+	if err := runtime.PopulateQueryDefaults(ctx, &protoReq, req.URL.Query(), filter_ABitOfEverythingService_Create_0); err != nil {
+		return nil, metadata, err
+	}
 
-	msg, err := client.Create(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	if runtime.IsValidateOnly(ctx) {
+		return &ABitOfEverything{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.ABitOfEverythingService/Create", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.Create(ctx, req.(*ABitOfEverything), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -224,13 +242,29 @@ func request_ABitOfEverythingService_CreateBody_0(ctx context.Context, marshaler
 	var protoReq ABitOfEverything
 	var metadata runtime.ServerMetadata
 
-	if req.ContentLength > 0 {
-		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq); err != nil {
+	if req.Body != nil {
+		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq); err != nil && err != io.EOF {
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 		}
 	}
 
-	msg, err := client.CreateBody(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	if runtime.IsValidateOnly(ctx) {
+		return &ABitOfEverything{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.ABitOfEverythingService/CreateBody", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.CreateBody(ctx, req.(*ABitOfEverything), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -257,7 +291,23 @@ func request_ABitOfEverythingService_Lookup_0(ctx context.Context, marshaler run
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "uuid", err)
 	}
 
-	msg, err := client.Lookup(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	if runtime.IsValidateOnly(ctx) {
+		return &ABitOfEverything{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.ABitOfEverythingService/Lookup", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.Lookup(ctx, req.(*sub2.IdMessage), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -266,8 +316,8 @@ func request_ABitOfEverythingService_Update_0(ctx context.Context, marshaler run
 	var protoReq ABitOfEverything
 	var metadata runtime.ServerMetadata
 
-	if req.ContentLength > 0 {
-		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq); err != nil {
+	if req.Body != nil {
+		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq); err != nil && err != io.EOF {
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 		}
 	}
@@ -290,7 +340,23 @@ func request_ABitOfEverythingService_Update_0(ctx context.Context, marshaler run
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "uuid", err)
 	}
 
-	msg, err := client.Update(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	if runtime.IsValidateOnly(ctx) {
+		return &empty.Empty{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.ABitOfEverythingService/Update", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.Update(ctx, req.(*ABitOfEverything), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -317,7 +383,23 @@ func request_ABitOfEverythingService_Delete_0(ctx context.Context, marshaler run
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "uuid", err)
 	}
 
-	msg, err := client.Delete(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	if runtime.IsValidateOnly(ctx) {
+		return &empty.Empty{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.ABitOfEverythingService/Delete", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.Delete(ctx, req.(*sub2.IdMessage), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -348,11 +430,29 @@ func request_ABitOfEverythingService_GetQuery_0(ctx context.Context, marshaler r
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "uuid", err)
 	}
 
-	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_ABitOfEverythingService_GetQuery_0); err != nil {
+	if err := runtime.CheckQueryParameters(ctx, &protoReq, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.PopulateQueryParametersContext(ctx, &protoReq, req.URL.Query(), filter_ABitOfEverythingService_GetQuery_0); err != nil {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
+	if err := runtime.PopulateQueryDefaults(ctx, &protoReq, req.URL.Query(), filter_ABitOfEverythingService_GetQuery_0); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
 
-	msg, err := client.GetQuery(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if runtime.IsValidateOnly(ctx) {
+		return &empty.Empty{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.ABitOfEverythingService/GetQuery", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.GetQuery(ctx, req.(*ABitOfEverything), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -379,7 +479,23 @@ func request_ABitOfEverythingService_Echo_0(ctx context.Context, marshaler runti
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "value", err)
 	}
 
-	msg, err := client.Echo(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	if runtime.IsValidateOnly(ctx) {
+		return &sub.StringMessage{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.ABitOfEverythingService/Echo", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.Echo(ctx, req.(*sub.StringMessage), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -388,13 +504,29 @@ func request_ABitOfEverythingService_Echo_1(ctx context.Context, marshaler runti
 	var protoReq sub.StringMessage
 	var metadata runtime.ServerMetadata
 
-	if req.ContentLength > 0 {
+	if req.Body != nil {
 		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq.Value); err != nil {
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 		}
 	}
 
-	msg, err := client.Echo(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	if runtime.IsValidateOnly(ctx) {
+		return &sub.StringMessage{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.ABitOfEverythingService/Echo", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.Echo(ctx, req.(*sub.StringMessage), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -407,11 +539,29 @@ func request_ABitOfEverythingService_Echo_2(ctx context.Context, marshaler runti
 	var protoReq sub.StringMessage
 	var metadata runtime.ServerMetadata
 
-	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_ABitOfEverythingService_Echo_2); err != nil {
+	if err := runtime.CheckQueryParameters(ctx, &protoReq, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.PopulateQueryParametersContext(ctx, &protoReq, req.URL.Query(), filter_ABitOfEverythingService_Echo_2); err != nil {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
+	if err := runtime.PopulateQueryDefaults(ctx, &protoReq, req.URL.Query(), filter_ABitOfEverythingService_Echo_2); err != nil {
+		return nil, metadata, err
+	}
 
-	msg, err := client.Echo(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	if runtime.IsValidateOnly(ctx) {
+		return &sub.StringMessage{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.ABitOfEverythingService/Echo", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.Echo(ctx, req.(*sub.StringMessage), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -420,8 +570,8 @@ func request_ABitOfEverythingService_DeepPathEcho_0(ctx context.Context, marshal
 	var protoReq ABitOfEverything
 	var metadata runtime.ServerMetadata
 
-	if req.ContentLength > 0 {
-		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq); err != nil {
+	if req.Body != nil {
+		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq); err != nil && err != io.EOF {
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 		}
 	}
@@ -444,7 +594,23 @@ func request_ABitOfEverythingService_DeepPathEcho_0(ctx context.Context, marshal
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "single_nested.name", err)
 	}
 
-	msg, err := client.DeepPathEcho(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	if runtime.IsValidateOnly(ctx) {
+		return &ABitOfEverything{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.ABitOfEverythingService/DeepPathEcho", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.DeepPathEcho(ctx, req.(*ABitOfEverything), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -453,7 +619,23 @@ func request_ABitOfEverythingService_Timeout_0(ctx context.Context, marshaler ru
 	var protoReq empty.Empty
 	var metadata runtime.ServerMetadata
 
-	msg, err := client.Timeout(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	if runtime.IsValidateOnly(ctx) {
+		return &empty.Empty{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.ABitOfEverythingService/Timeout", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.Timeout(ctx, req.(*empty.Empty), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -462,7 +644,23 @@ func request_ABitOfEverythingService_ErrorWithDetails_0(ctx context.Context, mar
 	var protoReq empty.Empty
 	var metadata runtime.ServerMetadata
 
-	msg, err := client.ErrorWithDetails(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	if runtime.IsValidateOnly(ctx) {
+		return &empty.Empty{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.ABitOfEverythingService/ErrorWithDetails", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.ErrorWithDetails(ctx, req.(*empty.Empty), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -471,7 +669,7 @@ func request_ABitOfEverythingService_GetMessageWithBody_0(ctx context.Context, m
 	var protoReq MessageWithBody
 	var metadata runtime.ServerMetadata
 
-	if req.ContentLength > 0 {
+	if req.Body != nil {
 		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq.Data); err != nil {
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 		}
@@ -495,7 +693,23 @@ func request_ABitOfEverythingService_GetMessageWithBody_0(ctx context.Context, m
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "id", err)
 	}
 
-	msg, err := client.GetMessageWithBody(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	if runtime.IsValidateOnly(ctx) {
+		return &empty.Empty{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.ABitOfEverythingService/GetMessageWithBody", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.GetMessageWithBody(ctx, req.(*MessageWithBody), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -504,8 +718,8 @@ func request_ABitOfEverythingService_PostWithEmptyBody_0(ctx context.Context, ma
 	var protoReq Body
 	var metadata runtime.ServerMetadata
 
-	if req.ContentLength > 0 {
-		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq); err != nil {
+	if req.Body != nil {
+		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq); err != nil && err != io.EOF {
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 		}
 	}
@@ -528,7 +742,23 @@ func request_ABitOfEverythingService_PostWithEmptyBody_0(ctx context.Context, ma
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "name", err)
 	}
 
-	msg, err := client.PostWithEmptyBody(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	if runtime.IsValidateOnly(ctx) {
+		return &empty.Empty{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.ABitOfEverythingService/PostWithEmptyBody", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.PostWithEmptyBody(ctx, req.(*Body), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -537,7 +767,23 @@ func request_CamelCaseServiceName_Empty_0(ctx context.Context, marshaler runtime
 	var protoReq empty.Empty
 	var metadata runtime.ServerMetadata
 
-	msg, err := client.Empty(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	if runtime.IsValidateOnly(ctx) {
+		return &empty.Empty{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.CamelCaseServiceName/Empty", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.Empty(ctx, req.(*empty.Empty), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -54,11 +54,29 @@ func request_EchoService_Echo_0(ctx context.Context, marshaler runtime.Marshaler
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "id", err)
 	}
 
-	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_EchoService_Echo_0); err != nil {
+	if err := runtime.CheckQueryParameters(ctx, &protoReq, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.PopulateQueryParametersContext(ctx, &protoReq, req.URL.Query(), filter_EchoService_Echo_0); err != nil {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
+	if err := runtime.PopulateQueryDefaults(ctx, &protoReq, req.URL.Query(), filter_EchoService_Echo_0); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
 
-	msg, err := client.Echo(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if runtime.IsValidateOnly(ctx) {
+		return &SimpleMessage{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.EchoService/Echo", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.Echo(ctx, req.(*SimpleMessage), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -96,7 +114,23 @@ func request_EchoService_Echo_1(ctx context.Context, marshaler runtime.Marshaler
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "num", err)
 	}
 
-	msg, err := client.Echo(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	if runtime.IsValidateOnly(ctx) {
+		return &SimpleMessage{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.EchoService/Echo", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.Echo(ctx, req.(*SimpleMessage), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -105,13 +139,29 @@ func request_EchoService_EchoBody_0(ctx context.Context, marshaler runtime.Marsh
 	var protoReq SimpleMessage
 	var metadata runtime.ServerMetadata
 
-	if req.ContentLength > 0 {
-		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq); err != nil {
+	if req.Body != nil {
+		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq); err != nil && err != io.EOF {
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 		}
 	}
 
-	msg, err := client.EchoBody(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	if runtime.IsValidateOnly(ctx) {
+		return &SimpleMessage{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.EchoService/EchoBody", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.EchoBody(ctx, req.(*SimpleMessage), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
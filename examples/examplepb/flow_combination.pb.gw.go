@@ -32,7 +32,23 @@ func request_FlowCombination_RpcEmptyRpc_0(ctx context.Context, marshaler runtim
 	var protoReq EmptyProto
 	var metadata runtime.ServerMetadata
 
-	msg, err := client.RpcEmptyRpc(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	if runtime.IsValidateOnly(ctx) {
+		return &EmptyProto{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.FlowCombination/RpcEmptyRpc", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.RpcEmptyRpc(ctx, req.(*EmptyProto), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -41,7 +57,18 @@ func request_FlowCombination_RpcEmptyStream_0(ctx context.Context, marshaler run
 	var protoReq EmptyProto
 	var metadata runtime.ServerMetadata
 
-	stream, err := client.RpcEmptyStream(ctx, &protoReq)
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	stream, err := client.RpcEmptyStream(ctx, &protoReq, runtime.CallOptionsFromContext(ctx)...)
 	if err != nil {
 		return nil, metadata, err
 	}
@@ -56,18 +83,21 @@ func request_FlowCombination_RpcEmptyStream_0(ctx context.Context, marshaler run
 
 func request_FlowCombination_StreamEmptyRpc_0(ctx context.Context, marshaler runtime.Marshaler, client FlowCombinationClient, req *http.Request, pathParams map[string]string) (proto.Message, runtime.ServerMetadata, error) {
 	var metadata runtime.ServerMetadata
-	stream, err := client.StreamEmptyRpc(ctx)
+	stream, err := client.StreamEmptyRpc(ctx, runtime.CallOptionsFromContext(ctx)...)
 	if err != nil {
 		grpclog.Printf("Failed to start streaming: %v", err)
 		return nil, metadata, err
 	}
-	dec := marshaler.NewDecoder(req.Body)
+	dec := runtime.LimitClientStream(ctx, runtime.NewRequestStreamDecoder(marshaler, req), nil)
 	for {
 		var protoReq EmptyProto
 		err = dec.Decode(&protoReq)
 		if err == io.EOF {
 			break
 		}
+		if err == runtime.ErrClientStreamLimitExceeded {
+			return nil, metadata, err
+		}
 		if err != nil {
 			grpclog.Printf("Failed to decode request: %v", err)
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
@@ -97,12 +127,13 @@ func request_FlowCombination_StreamEmptyRpc_0(ctx context.Context, marshaler run
 
 func request_FlowCombination_StreamEmptyStream_0(ctx context.Context, marshaler runtime.Marshaler, client FlowCombinationClient, req *http.Request, pathParams map[string]string) (FlowCombination_StreamEmptyStreamClient, runtime.ServerMetadata, error) {
 	var metadata runtime.ServerMetadata
-	stream, err := client.StreamEmptyStream(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	dec := runtime.LimitClientStream(ctx, runtime.NewRequestStreamDecoder(marshaler, req), cancel)
+	stream, err := client.StreamEmptyStream(ctx, runtime.CallOptionsFromContext(ctx)...)
 	if err != nil {
 		grpclog.Printf("Failed to start streaming: %v", err)
 		return nil, metadata, err
 	}
-	dec := marshaler.NewDecoder(req.Body)
 	handleSend := func() error {
 		var protoReq EmptyProto
 		err = dec.Decode(&protoReq)
@@ -151,13 +182,29 @@ func request_FlowCombination_RpcBodyRpc_0(ctx context.Context, marshaler runtime
 	var protoReq NonEmptyProto
 	var metadata runtime.ServerMetadata
 
-	if req.ContentLength > 0 {
-		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq); err != nil {
+	if req.Body != nil {
+		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq); err != nil && err != io.EOF {
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 		}
 	}
 
-	msg, err := client.RpcBodyRpc(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	if runtime.IsValidateOnly(ctx) {
+		return &EmptyProto{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.FlowCombination/RpcBodyRpc", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.RpcBodyRpc(ctx, req.(*NonEmptyProto), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -206,7 +253,23 @@ func request_FlowCombination_RpcBodyRpc_1(ctx context.Context, marshaler runtime
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "c", err)
 	}
 
-	msg, err := client.RpcBodyRpc(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	if runtime.IsValidateOnly(ctx) {
+		return &EmptyProto{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.FlowCombination/RpcBodyRpc", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.RpcBodyRpc(ctx, req.(*NonEmptyProto), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -219,11 +282,29 @@ func request_FlowCombination_RpcBodyRpc_2(ctx context.Context, marshaler runtime
 	var protoReq NonEmptyProto
 	var metadata runtime.ServerMetadata
 
-	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyRpc_2); err != nil {
+	if err := runtime.CheckQueryParameters(ctx, &protoReq, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.PopulateQueryParametersContext(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyRpc_2); err != nil {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
+	if err := runtime.PopulateQueryDefaults(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyRpc_2); err != nil {
+		return nil, metadata, err
+	}
 
-	msg, err := client.RpcBodyRpc(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	if runtime.IsValidateOnly(ctx) {
+		return &EmptyProto{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.FlowCombination/RpcBodyRpc", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.RpcBodyRpc(ctx, req.(*NonEmptyProto), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -232,7 +313,7 @@ func request_FlowCombination_RpcBodyRpc_3(ctx context.Context, marshaler runtime
 	var protoReq NonEmptyProto
 	var metadata runtime.ServerMetadata
 
-	if req.ContentLength > 0 {
+	if req.Body != nil {
 		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq.C); err != nil {
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 		}
@@ -267,7 +348,23 @@ func request_FlowCombination_RpcBodyRpc_3(ctx context.Context, marshaler runtime
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "b", err)
 	}
 
-	msg, err := client.RpcBodyRpc(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	if runtime.IsValidateOnly(ctx) {
+		return &EmptyProto{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.FlowCombination/RpcBodyRpc", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.RpcBodyRpc(ctx, req.(*NonEmptyProto), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -280,17 +377,35 @@ func request_FlowCombination_RpcBodyRpc_4(ctx context.Context, marshaler runtime
 	var protoReq NonEmptyProto
 	var metadata runtime.ServerMetadata
 
-	if req.ContentLength > 0 {
+	if req.Body != nil {
 		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq.C); err != nil {
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 		}
 	}
 
-	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyRpc_4); err != nil {
+	if err := runtime.CheckQueryParameters(ctx, &protoReq, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.PopulateQueryParametersContext(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyRpc_4); err != nil {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
+	if err := runtime.PopulateQueryDefaults(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyRpc_4); err != nil {
+		return nil, metadata, err
+	}
 
-	msg, err := client.RpcBodyRpc(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	if runtime.IsValidateOnly(ctx) {
+		return &EmptyProto{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.FlowCombination/RpcBodyRpc", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.RpcBodyRpc(ctx, req.(*NonEmptyProto), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -303,7 +418,7 @@ func request_FlowCombination_RpcBodyRpc_5(ctx context.Context, marshaler runtime
 	var protoReq NonEmptyProto
 	var metadata runtime.ServerMetadata
 
-	if req.ContentLength > 0 {
+	if req.Body != nil {
 		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq.C); err != nil {
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 		}
@@ -327,11 +442,29 @@ func request_FlowCombination_RpcBodyRpc_5(ctx context.Context, marshaler runtime
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "a", err)
 	}
 
-	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyRpc_5); err != nil {
+	if err := runtime.CheckQueryParameters(ctx, &protoReq, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.PopulateQueryParametersContext(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyRpc_5); err != nil {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
+	if err := runtime.PopulateQueryDefaults(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyRpc_5); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
 
-	msg, err := client.RpcBodyRpc(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if runtime.IsValidateOnly(ctx) {
+		return &EmptyProto{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.FlowCombination/RpcBodyRpc", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.RpcBodyRpc(ctx, req.(*NonEmptyProto), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -362,11 +495,29 @@ func request_FlowCombination_RpcBodyRpc_6(ctx context.Context, marshaler runtime
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "a", err)
 	}
 
-	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyRpc_6); err != nil {
+	if err := runtime.CheckQueryParameters(ctx, &protoReq, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.PopulateQueryParametersContext(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyRpc_6); err != nil {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
+	if err := runtime.PopulateQueryDefaults(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyRpc_6); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
 
-	msg, err := client.RpcBodyRpc(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if runtime.IsValidateOnly(ctx) {
+		return &EmptyProto{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.FlowCombination/RpcBodyRpc", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.RpcBodyRpc(ctx, req.(*NonEmptyProto), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -397,11 +548,29 @@ func request_FlowCombination_RpcPathSingleNestedRpc_0(ctx context.Context, marsh
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "a.str", err)
 	}
 
-	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_FlowCombination_RpcPathSingleNestedRpc_0); err != nil {
+	if err := runtime.CheckQueryParameters(ctx, &protoReq, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.PopulateQueryParametersContext(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcPathSingleNestedRpc_0); err != nil {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
+	if err := runtime.PopulateQueryDefaults(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcPathSingleNestedRpc_0); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
 
-	msg, err := client.RpcPathSingleNestedRpc(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if runtime.IsValidateOnly(ctx) {
+		return &EmptyProto{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.FlowCombination/RpcPathSingleNestedRpc", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.RpcPathSingleNestedRpc(ctx, req.(*SingleNestedProto), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -414,7 +583,7 @@ func request_FlowCombination_RpcPathNestedRpc_0(ctx context.Context, marshaler r
 	var protoReq NestedProto
 	var metadata runtime.ServerMetadata
 
-	if req.ContentLength > 0 {
+	if req.Body != nil {
 		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq.C); err != nil {
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 		}
@@ -449,11 +618,29 @@ func request_FlowCombination_RpcPathNestedRpc_0(ctx context.Context, marshaler r
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "b", err)
 	}
 
-	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_FlowCombination_RpcPathNestedRpc_0); err != nil {
+	if err := runtime.CheckQueryParameters(ctx, &protoReq, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.PopulateQueryParametersContext(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcPathNestedRpc_0); err != nil {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
+	if err := runtime.PopulateQueryDefaults(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcPathNestedRpc_0); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
 
-	msg, err := client.RpcPathNestedRpc(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if runtime.IsValidateOnly(ctx) {
+		return &EmptyProto{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.FlowCombination/RpcPathNestedRpc", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.RpcPathNestedRpc(ctx, req.(*NestedProto), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -484,11 +671,29 @@ func request_FlowCombination_RpcPathNestedRpc_1(ctx context.Context, marshaler r
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "a.str", err)
 	}
 
-	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_FlowCombination_RpcPathNestedRpc_1); err != nil {
+	if err := runtime.CheckQueryParameters(ctx, &protoReq, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.PopulateQueryParametersContext(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcPathNestedRpc_1); err != nil {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
+	if err := runtime.PopulateQueryDefaults(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcPathNestedRpc_1); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
 
-	msg, err := client.RpcPathNestedRpc(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if runtime.IsValidateOnly(ctx) {
+		return &EmptyProto{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.FlowCombination/RpcPathNestedRpc", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.RpcPathNestedRpc(ctx, req.(*NestedProto), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -501,7 +706,7 @@ func request_FlowCombination_RpcPathNestedRpc_2(ctx context.Context, marshaler r
 	var protoReq NestedProto
 	var metadata runtime.ServerMetadata
 
-	if req.ContentLength > 0 {
+	if req.Body != nil {
 		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq.C); err != nil {
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 		}
@@ -525,11 +730,29 @@ func request_FlowCombination_RpcPathNestedRpc_2(ctx context.Context, marshaler r
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "a.str", err)
 	}
 
-	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_FlowCombination_RpcPathNestedRpc_2); err != nil {
+	if err := runtime.CheckQueryParameters(ctx, &protoReq, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.PopulateQueryParametersContext(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcPathNestedRpc_2); err != nil {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
+	if err := runtime.PopulateQueryDefaults(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcPathNestedRpc_2); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
 
-	msg, err := client.RpcPathNestedRpc(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if runtime.IsValidateOnly(ctx) {
+		return &EmptyProto{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.FlowCombination/RpcPathNestedRpc", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.RpcPathNestedRpc(ctx, req.(*NestedProto), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 
 }
@@ -538,13 +761,24 @@ func request_FlowCombination_RpcBodyStream_0(ctx context.Context, marshaler runt
 	var protoReq NonEmptyProto
 	var metadata runtime.ServerMetadata
 
-	if req.ContentLength > 0 {
-		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq); err != nil {
+	if req.Body != nil {
+		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq); err != nil && err != io.EOF {
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 		}
 	}
 
-	stream, err := client.RpcBodyStream(ctx, &protoReq)
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	stream, err := client.RpcBodyStream(ctx, &protoReq, runtime.CallOptionsFromContext(ctx)...)
 	if err != nil {
 		return nil, metadata, err
 	}
@@ -601,7 +835,18 @@ func request_FlowCombination_RpcBodyStream_1(ctx context.Context, marshaler runt
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "c", err)
 	}
 
-	stream, err := client.RpcBodyStream(ctx, &protoReq)
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	stream, err := client.RpcBodyStream(ctx, &protoReq, runtime.CallOptionsFromContext(ctx)...)
 	if err != nil {
 		return nil, metadata, err
 	}
@@ -622,11 +867,24 @@ func request_FlowCombination_RpcBodyStream_2(ctx context.Context, marshaler runt
 	var protoReq NonEmptyProto
 	var metadata runtime.ServerMetadata
 
-	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyStream_2); err != nil {
+	if err := runtime.CheckQueryParameters(ctx, &protoReq, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.PopulateQueryParametersContext(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyStream_2); err != nil {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
+	if err := runtime.PopulateQueryDefaults(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyStream_2); err != nil {
+		return nil, metadata, err
+	}
 
-	stream, err := client.RpcBodyStream(ctx, &protoReq)
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	stream, err := client.RpcBodyStream(ctx, &protoReq, runtime.CallOptionsFromContext(ctx)...)
 	if err != nil {
 		return nil, metadata, err
 	}
@@ -643,7 +901,7 @@ func request_FlowCombination_RpcBodyStream_3(ctx context.Context, marshaler runt
 	var protoReq NonEmptyProto
 	var metadata runtime.ServerMetadata
 
-	if req.ContentLength > 0 {
+	if req.Body != nil {
 		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq.C); err != nil {
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 		}
@@ -678,7 +936,18 @@ func request_FlowCombination_RpcBodyStream_3(ctx context.Context, marshaler runt
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "b", err)
 	}
 
-	stream, err := client.RpcBodyStream(ctx, &protoReq)
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	stream, err := client.RpcBodyStream(ctx, &protoReq, runtime.CallOptionsFromContext(ctx)...)
 	if err != nil {
 		return nil, metadata, err
 	}
@@ -699,17 +968,30 @@ func request_FlowCombination_RpcBodyStream_4(ctx context.Context, marshaler runt
 	var protoReq NonEmptyProto
 	var metadata runtime.ServerMetadata
 
-	if req.ContentLength > 0 {
+	if req.Body != nil {
 		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq.C); err != nil {
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 		}
 	}
 
-	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyStream_4); err != nil {
+	if err := runtime.CheckQueryParameters(ctx, &protoReq, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.PopulateQueryParametersContext(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyStream_4); err != nil {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
+	if err := runtime.PopulateQueryDefaults(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyStream_4); err != nil {
+		return nil, metadata, err
+	}
 
-	stream, err := client.RpcBodyStream(ctx, &protoReq)
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	stream, err := client.RpcBodyStream(ctx, &protoReq, runtime.CallOptionsFromContext(ctx)...)
 	if err != nil {
 		return nil, metadata, err
 	}
@@ -730,7 +1012,7 @@ func request_FlowCombination_RpcBodyStream_5(ctx context.Context, marshaler runt
 	var protoReq NonEmptyProto
 	var metadata runtime.ServerMetadata
 
-	if req.ContentLength > 0 {
+	if req.Body != nil {
 		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq.C); err != nil {
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 		}
@@ -754,11 +1036,24 @@ func request_FlowCombination_RpcBodyStream_5(ctx context.Context, marshaler runt
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "a", err)
 	}
 
-	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyStream_5); err != nil {
+	if err := runtime.CheckQueryParameters(ctx, &protoReq, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.PopulateQueryParametersContext(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyStream_5); err != nil {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
+	if err := runtime.PopulateQueryDefaults(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyStream_5); err != nil {
+		return nil, metadata, err
+	}
 
-	stream, err := client.RpcBodyStream(ctx, &protoReq)
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	stream, err := client.RpcBodyStream(ctx, &protoReq, runtime.CallOptionsFromContext(ctx)...)
 	if err != nil {
 		return nil, metadata, err
 	}
@@ -797,11 +1092,24 @@ func request_FlowCombination_RpcBodyStream_6(ctx context.Context, marshaler runt
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "a", err)
 	}
 
-	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyStream_6); err != nil {
+	if err := runtime.CheckQueryParameters(ctx, &protoReq, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.PopulateQueryParametersContext(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyStream_6); err != nil {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
+	if err := runtime.PopulateQueryDefaults(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcBodyStream_6); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
 
-	stream, err := client.RpcBodyStream(ctx, &protoReq)
+	stream, err := client.RpcBodyStream(ctx, &protoReq, runtime.CallOptionsFromContext(ctx)...)
 	if err != nil {
 		return nil, metadata, err
 	}
@@ -840,11 +1148,24 @@ func request_FlowCombination_RpcPathSingleNestedStream_0(ctx context.Context, ma
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "a.str", err)
 	}
 
-	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_FlowCombination_RpcPathSingleNestedStream_0); err != nil {
+	if err := runtime.CheckQueryParameters(ctx, &protoReq, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.PopulateQueryParametersContext(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcPathSingleNestedStream_0); err != nil {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
+	if err := runtime.PopulateQueryDefaults(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcPathSingleNestedStream_0); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
 
-	stream, err := client.RpcPathSingleNestedStream(ctx, &protoReq)
+	stream, err := client.RpcPathSingleNestedStream(ctx, &protoReq, runtime.CallOptionsFromContext(ctx)...)
 	if err != nil {
 		return nil, metadata, err
 	}
@@ -865,7 +1186,7 @@ func request_FlowCombination_RpcPathNestedStream_0(ctx context.Context, marshale
 	var protoReq NestedProto
 	var metadata runtime.ServerMetadata
 
-	if req.ContentLength > 0 {
+	if req.Body != nil {
 		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq.C); err != nil {
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 		}
@@ -900,11 +1221,24 @@ func request_FlowCombination_RpcPathNestedStream_0(ctx context.Context, marshale
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "b", err)
 	}
 
-	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_FlowCombination_RpcPathNestedStream_0); err != nil {
+	if err := runtime.CheckQueryParameters(ctx, &protoReq, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.PopulateQueryParametersContext(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcPathNestedStream_0); err != nil {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
+	if err := runtime.PopulateQueryDefaults(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcPathNestedStream_0); err != nil {
+		return nil, metadata, err
+	}
 
-	stream, err := client.RpcPathNestedStream(ctx, &protoReq)
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	stream, err := client.RpcPathNestedStream(ctx, &protoReq, runtime.CallOptionsFromContext(ctx)...)
 	if err != nil {
 		return nil, metadata, err
 	}
@@ -943,11 +1277,24 @@ func request_FlowCombination_RpcPathNestedStream_1(ctx context.Context, marshale
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "a.str", err)
 	}
 
-	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_FlowCombination_RpcPathNestedStream_1); err != nil {
+	if err := runtime.CheckQueryParameters(ctx, &protoReq, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.PopulateQueryParametersContext(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcPathNestedStream_1); err != nil {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
+	if err := runtime.PopulateQueryDefaults(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcPathNestedStream_1); err != nil {
+		return nil, metadata, err
+	}
 
-	stream, err := client.RpcPathNestedStream(ctx, &protoReq)
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	stream, err := client.RpcPathNestedStream(ctx, &protoReq, runtime.CallOptionsFromContext(ctx)...)
 	if err != nil {
 		return nil, metadata, err
 	}
@@ -968,7 +1315,7 @@ func request_FlowCombination_RpcPathNestedStream_2(ctx context.Context, marshale
 	var protoReq NestedProto
 	var metadata runtime.ServerMetadata
 
-	if req.ContentLength > 0 {
+	if req.Body != nil {
 		if err := marshaler.NewDecoder(req.Body).Decode(&protoReq.C); err != nil {
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 		}
@@ -992,11 +1339,24 @@ func request_FlowCombination_RpcPathNestedStream_2(ctx context.Context, marshale
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "a.str", err)
 	}
 
-	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_FlowCombination_RpcPathNestedStream_2); err != nil {
+	if err := runtime.CheckQueryParameters(ctx, &protoReq, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.PopulateQueryParametersContext(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcPathNestedStream_2); err != nil {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
+	if err := runtime.PopulateQueryDefaults(ctx, &protoReq, req.URL.Query(), filter_FlowCombination_RpcPathNestedStream_2); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
 
-	stream, err := client.RpcPathNestedStream(ctx, &protoReq)
+	stream, err := client.RpcPathNestedStream(ctx, &protoReq, runtime.CallOptionsFromContext(ctx)...)
 	if err != nil {
 		return nil, metadata, err
 	}
@@ -32,18 +32,21 @@ var _ = utilities.NewDoubleArray
 
 func request_StreamService_BulkCreate_0(ctx context.Context, marshaler runtime.Marshaler, client StreamServiceClient, req *http.Request, pathParams map[string]string) (proto.Message, runtime.ServerMetadata, error) {
 	var metadata runtime.ServerMetadata
-	stream, err := client.BulkCreate(ctx)
+	stream, err := client.BulkCreate(ctx, runtime.CallOptionsFromContext(ctx)...)
 	if err != nil {
 		grpclog.Printf("Failed to start streaming: %v", err)
 		return nil, metadata, err
 	}
-	dec := marshaler.NewDecoder(req.Body)
+	dec := runtime.LimitClientStream(ctx, runtime.NewRequestStreamDecoder(marshaler, req), nil)
 	for {
 		var protoReq ABitOfEverything
 		err = dec.Decode(&protoReq)
 		if err == io.EOF {
 			break
 		}
+		if err == runtime.ErrClientStreamLimitExceeded {
+			return nil, metadata, err
+		}
 		if err != nil {
 			grpclog.Printf("Failed to decode request: %v", err)
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
@@ -75,7 +78,18 @@ func request_StreamService_List_0(ctx context.Context, marshaler runtime.Marshal
 	var protoReq empty.Empty
 	var metadata runtime.ServerMetadata
 
-	stream, err := client.List(ctx, &protoReq)
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+
+	stream, err := client.List(ctx, &protoReq, runtime.CallOptionsFromContext(ctx)...)
 	if err != nil {
 		return nil, metadata, err
 	}
@@ -90,12 +104,13 @@ func request_StreamService_List_0(ctx context.Context, marshaler runtime.Marshal
 
 func request_StreamService_BulkEcho_0(ctx context.Context, marshaler runtime.Marshaler, client StreamServiceClient, req *http.Request, pathParams map[string]string) (StreamService_BulkEchoClient, runtime.ServerMetadata, error) {
 	var metadata runtime.ServerMetadata
-	stream, err := client.BulkEcho(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	dec := runtime.LimitClientStream(ctx, runtime.NewRequestStreamDecoder(marshaler, req), cancel)
+	stream, err := client.BulkEcho(ctx, runtime.CallOptionsFromContext(ctx)...)
 	if err != nil {
 		grpclog.Printf("Failed to start streaming: %v", err)
 		return nil, metadata, err
 	}
-	dec := marshaler.NewDecoder(req.Body)
 	handleSend := func() error {
 		var protoReq sub.StringMessage
 		err = dec.Decode(&protoReq)
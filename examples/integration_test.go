@@ -28,6 +28,7 @@ type errorBody struct {
 	Error   string        `json:"error"`
 	Code    int           `json:"code"`
 	Details []interface{} `json:"details"`
+	Status  string        `json:"status"`
 }
 
 func TestEcho(t *testing.T) {
@@ -469,6 +470,10 @@ func testABELookupNotFound(t *testing.T, port int) {
 		t.Errorf("msg.Code = %d; want %d", got, want)
 		return
 	}
+	if got, want := msg.Status, "NOT_FOUND"; got != want {
+		t.Errorf("msg.Status = %q; want %q", got, want)
+		return
+	}
 
 	if got, want := msg.Error, "not found"; got != want {
 		t.Errorf("msg.Error = %s; want %s", got, want)
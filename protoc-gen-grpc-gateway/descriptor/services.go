@@ -183,7 +183,7 @@ func extractAPIOptions(meth *descriptor.MethodDescriptorProto) (*options.HttpRul
 
 func (r *Registry) newParam(meth *Method, path string) (Parameter, error) {
 	msg := meth.RequestType
-	fields, err := r.resolveFiledPath(msg, path)
+	fields, err := r.resolveFiledPath(msg, path, false)
 	if err != nil {
 		return Parameter{}, err
 	}
@@ -216,7 +216,7 @@ func (r *Registry) newBody(meth *Method, path string) (*Body, error) {
 	case "*":
 		return &Body{FieldPath: nil}, nil
 	}
-	fields, err := r.resolveFiledPath(msg, path)
+	fields, err := r.resolveFiledPath(msg, path, true)
 	if err != nil {
 		return nil, err
 	}
@@ -234,15 +234,17 @@ func lookupField(msg *Message, name string) *Field {
 	return nil
 }
 
-// resolveFieldPath resolves "path" into a list of fieldDescriptor, starting from "msg".
-func (r *Registry) resolveFiledPath(msg *Message, path string) ([]FieldPathComponent, error) {
+// resolveFiledPath resolves the components of "path" in "msg". Repeated fields are only allowed
+// as the last component, and only if "allowRepeatedLeaf" is true, e.g. for the body of a request.
+func (r *Registry) resolveFiledPath(msg *Message, path string, allowRepeatedLeaf bool) ([]FieldPathComponent, error) {
 	if path == "" {
 		return nil, nil
 	}
 
 	root := msg
 	var result []FieldPathComponent
-	for i, c := range strings.Split(path, ".") {
+	components := strings.Split(path, ".")
+	for i, c := range components {
 		if i > 0 {
 			f := result[i-1].Target
 			switch f.GetType() {
@@ -262,7 +264,8 @@ func (r *Registry) resolveFiledPath(msg *Message, path string) ([]FieldPathCompo
 		if f == nil {
 			return nil, fmt.Errorf("no field %q found in %s", path, root.GetName())
 		}
-		if f.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+		isLeaf := i == len(components)-1
+		if f.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED && !(isLeaf && allowRepeatedLeaf) {
 			return nil, fmt.Errorf("repeated field not allowed in field path: %s in %s", f.GetName(), path)
 		}
 		result = append(result, FieldPathComponent{Name: c, Target: f})
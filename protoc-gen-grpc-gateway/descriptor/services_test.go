@@ -924,9 +924,10 @@ func TestExtractServicesWithError(t *testing.T) {
 
 func TestResolveFieldPath(t *testing.T) {
 	for _, spec := range []struct {
-		src     string
-		path    string
-		wantErr bool
+		src               string
+		path              string
+		allowRepeatedLeaf bool
+		wantErr           bool
 	}{
 		{
 			src: `
@@ -981,6 +982,35 @@ func TestResolveFieldPath(t *testing.T) {
 			path:    "string",
 			wantErr: true,
 		},
+		// repeated field as the body
+		{
+			src: `
+				name: 'example.proto'
+				package: 'example'
+				message_type <
+					name: 'ExampleMessage'
+					field <
+						name: 'nested'
+						type: TYPE_MESSAGE
+						type_name: 'AnotherMessage'
+						label: LABEL_OPTIONAL
+						number: 1
+					>
+				>
+				message_type <
+					name: 'AnotherMessage'
+					field <
+						name: 'string'
+						type: TYPE_STRING
+						label: LABEL_REPEATED
+						number: 1
+					>
+				>
+			`,
+			path:              "nested.string",
+			allowRepeatedLeaf: true,
+			wantErr:           false,
+		},
 		// nested field
 		{
 			src: `
@@ -1083,8 +1113,9 @@ func TestResolveFieldPath(t *testing.T) {
 					>
 				>
 			`,
-			path:    "nested.nested2.terminal",
-			wantErr: true,
+			path:              "nested.nested2.terminal",
+			allowRepeatedLeaf: true,
+			wantErr:           true,
 		},
 	} {
 		var file descriptor.FileDescriptorProto
@@ -1097,7 +1128,7 @@ func TestResolveFieldPath(t *testing.T) {
 		if err != nil {
 			t.Fatalf("reg.LookupFile(%q) failed with %v; want success; on file=%s", file.GetName(), err, spec.src)
 		}
-		_, err = reg.resolveFiledPath(f.Messages[0], spec.path)
+		_, err = reg.resolveFiledPath(f.Messages[0], spec.path, spec.allowRepeatedLeaf)
 		if got, want := err != nil, spec.wantErr; got != want {
 			if want {
 				t.Errorf("reg.resolveFiledPath(%q, %q) succeeded; want an error", f.Messages[0].GetName(), spec.path)
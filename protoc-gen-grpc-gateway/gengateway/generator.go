@@ -102,13 +102,21 @@ func (g *generator) generate(file *descriptor.File) (string, error) {
 	}
 	for _, svc := range file.Services {
 		for _, m := range svc.Methods {
-			pkg := m.RequestType.File.GoPkg
-			if m.Options == nil || !proto.HasExtension(m.Options, options.E_Http) ||
-				pkg == file.GoPkg || pkgSeen[pkg.Path] {
+			if m.Options == nil || !proto.HasExtension(m.Options, options.E_Http) {
 				continue
 			}
-			pkgSeen[pkg.Path] = true
-			imports = append(imports, pkg)
+			pkgs := []descriptor.GoPackage{m.RequestType.File.GoPkg}
+			if !m.GetClientStreaming() && !m.GetServerStreaming() {
+				// The response type of unary methods is instantiated for validate-only requests.
+				pkgs = append(pkgs, m.ResponseType.File.GoPkg)
+			}
+			for _, pkg := range pkgs {
+				if pkg == file.GoPkg || pkgSeen[pkg.Path] {
+					continue
+				}
+				pkgSeen[pkg.Path] = true
+				imports = append(imports, pkg)
+			}
 		}
 	}
 	return applyTemplate(param{File: file, Imports: imports, UseRequestContext: g.useRequestContext})
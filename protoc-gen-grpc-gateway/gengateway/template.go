@@ -7,6 +7,8 @@ import (
 	"text/template"
 
 	"github.com/golang/glog"
+	protodescriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	gogen "github.com/golang/protobuf/protoc-gen-go/generator"
 	"github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/descriptor"
 	"github.com/grpc-ecosystem/grpc-gateway/utilities"
 )
@@ -53,6 +55,37 @@ func (b binding) QueryParamFilter() queryParamFilter {
 	return queryParamFilter{utilities.NewDoubleArray(seqs)}
 }
 
+// NestedBodyPath returns the path of the body field if it is nested in another field of the request message,
+// e.g. "resource.data". The messages along the path must be allocated before the body is decoded into it.
+func (b binding) NestedBodyPath() string {
+	if b.Body == nil || len(b.Body.FieldPath) < 2 {
+		return ""
+	}
+	return b.Body.FieldPath.String()
+}
+
+// AllowsEmptyBody returns whether the body of the binding is mapped to the whole request message, i.e. "*", in
+// which case an empty body is decoded as an empty request message. Empty bodies mapped to a field are rejected.
+func (b binding) AllowsEmptyBody() bool {
+	return b.Body != nil && len(b.Body.FieldPath) == 0
+}
+
+// UpdateMaskField returns the Go name of the "update_mask" field of the request message if the binding is
+// a PATCH whose body is mapped to another field and the request message has such a google.protobuf.FieldMask field.
+// The mask of the fields present in a JSON Merge Patch body is then set into it.
+func (b binding) UpdateMaskField() string {
+	if b.HTTPMethod != "PATCH" || b.Body == nil || len(b.Body.FieldPath) == 0 {
+		return ""
+	}
+	for _, f := range b.Method.RequestType.Fields {
+		if f.GetName() == "update_mask" && f.GetTypeName() == ".google.protobuf.FieldMask" &&
+			f.GetLabel() != protodescriptor.FieldDescriptorProto_LABEL_REPEATED {
+			return gogen.CamelCase(f.GetName())
+		}
+	}
+	return ""
+}
+
 // queryParamFilter is a wrapper of utilities.DoubleArray which provides String() to output DoubleArray.Encoding in a stable and predictable format.
 type queryParamFilter struct {
 	*utilities.DoubleArray
@@ -155,18 +188,21 @@ func request_{{.Method.Service.GetName}}_{{.Method.GetName}}_{{.Index}}(ctx cont
 	_ = template.Must(handlerTemplate.New("client-streaming-request-func").Parse(`
 {{template "request-func-signature" .}} {
 	var metadata runtime.ServerMetadata
-	stream, err := client.{{.Method.GetName}}(ctx)
+	stream, err := client.{{.Method.GetName}}(ctx, runtime.CallOptionsFromContext(ctx)...)
 	if err != nil {
 		grpclog.Printf("Failed to start streaming: %v", err)
 		return nil, metadata, err
 	}
-	dec := marshaler.NewDecoder(req.Body)
+	dec := runtime.LimitClientStream(ctx, runtime.NewRequestStreamDecoder(marshaler, req), nil)
 	for {
 		var protoReq {{.Method.RequestType.GoType .Method.Service.File.GoPkg.Path}}
 		err = dec.Decode(&protoReq)
 		if err == io.EOF {
 			break
 		}
+		if err == runtime.ErrClientStreamLimitExceeded {
+			return nil, metadata, err
+		}
 		if err != nil {
 			grpclog.Printf("Failed to decode request: %v", err)
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
@@ -207,10 +243,27 @@ var (
 	var protoReq {{.Method.RequestType.GoType .Method.Service.File.GoPkg.Path}}
 	var metadata runtime.ServerMetadata
 {{if .Body}}
-	if req.ContentLength > 0 {
-		if err := marshaler.NewDecoder(req.Body).Decode(&{{.Body.RHS "protoReq"}}); err != nil {
+	if req.Body != nil {
+{{- if .NestedBodyPath}}
+		if err := runtime.AllocateFieldPath(&protoReq, {{.NestedBodyPath | printf "%q"}}); err != nil {
+			return nil, metadata, status.Errorf(codes.Internal, "%v", err)
+		}
+{{- end}}
+{{- if .UpdateMaskField}}
+		if runtime.IsMergePatch(ctx, req) {
+			mask, err := runtime.DecodeMergePatch(marshaler, req.Body, &{{.Body.RHS "protoReq"}})
+			if err != nil {
+				return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
+			}
+			protoReq.{{.UpdateMaskField}} = mask
+		} else if err := marshaler.NewDecoder(req.Body).Decode(&{{.Body.RHS "protoReq"}}); err != nil {
 			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 		}
+{{- else}}
+		if err := marshaler.NewDecoder(req.Body).Decode(&{{.Body.RHS "protoReq"}}); err != nil{{if .AllowsEmptyBody}} && err != io.EOF{{end}} {
+			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+{{- end}}
 	}
 {{end}}
 {{if .PathParams}}
@@ -236,12 +289,28 @@ var (
 	{{end}}
 {{end}}
 {{if .HasQueryParam}}
-	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_{{.Method.Service.GetName}}_{{.Method.GetName}}_{{.Index}}); err != nil {
+	if err := runtime.CheckQueryParameters(ctx, &protoReq, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.PopulateQueryParametersContext(ctx, &protoReq, req.URL.Query(), filter_{{.Method.Service.GetName}}_{{.Method.GetName}}_{{.Index}}); err != nil {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
+	if err := runtime.PopulateQueryDefaults(ctx, &protoReq, req.URL.Query(), filter_{{.Method.Service.GetName}}_{{.Method.GetName}}_{{.Index}}); err != nil {
+		return nil, metadata, err
+	}
+{{else}}
+	if err := runtime.CheckQueryParameters(ctx, nil, req.URL.Query()); err != nil {
+		return nil, metadata, err
+	}
 {{end}}
+	if err := runtime.TransformRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
+	if err := runtime.ValidateRequest(ctx, &protoReq); err != nil {
+		return nil, metadata, err
+	}
 {{if .Method.GetServerStreaming}}
-	stream, err := client.{{.Method.GetName}}(ctx, &protoReq)
+	stream, err := client.{{.Method.GetName}}(ctx, &protoReq, runtime.CallOptionsFromContext(ctx)...)
 	if err != nil {
 		return nil, metadata, err
 	}
@@ -252,7 +321,12 @@ var (
 	metadata.HeaderMD = header
 	return stream, metadata, nil
 {{else}}
-	msg, err := client.{{.Method.GetName}}(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if runtime.IsValidateOnly(ctx) {
+		return &{{.Method.ResponseType.GoType .Method.Service.File.GoPkg.Path}}{}, metadata, nil
+	}
+	msg, err := runtime.InvokeUnary(ctx, "/{{with .Method.Service.File.GetPackage}}{{.}}.{{end}}{{.Method.Service.GetName}}/{{.Method.GetName}}", &protoReq, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return client.{{.Method.GetName}}(ctx, req.(*{{.Method.RequestType.GoType .Method.Service.File.GoPkg.Path}}), append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))...)
+	})
 	return msg, metadata, err
 {{end}}
 }`))
@@ -260,12 +334,13 @@ var (
 	_ = template.Must(handlerTemplate.New("bidi-streaming-request-func").Parse(`
 {{template "request-func-signature" .}} {
 	var metadata runtime.ServerMetadata
-	stream, err := client.{{.Method.GetName}}(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	dec := runtime.LimitClientStream(ctx, runtime.NewRequestStreamDecoder(marshaler, req), cancel)
+	stream, err := client.{{.Method.GetName}}(ctx, runtime.CallOptionsFromContext(ctx)...)
 	if err != nil {
 		grpclog.Printf("Failed to start streaming: %v", err)
 		return nil, metadata, err
 	}
-	dec := marshaler.NewDecoder(req.Body)
 	handleSend := func() error {
 		var protoReq {{.Method.RequestType.GoType .Method.Service.File.GoPkg.Path}}
 		err = dec.Decode(&protoReq)
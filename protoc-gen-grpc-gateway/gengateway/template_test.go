@@ -230,6 +230,9 @@ func TestApplyTemplateRequestWithoutClientStreaming(t *testing.T) {
 		if want := spec.sigWant; !strings.Contains(got, want) {
 			t.Errorf("applyTemplate(%#v) = %s; want to contain %s", file, got, want)
 		}
+		if want := `runtime.AllocateFieldPath(&protoReq, "nested.bool")`; !strings.Contains(got, want) {
+			t.Errorf("applyTemplate(%#v) = %s; want to contain %s", file, got, want)
+		}
 		if want := `marshaler.NewDecoder(req.Body).Decode(&protoReq.GetNested().Bool)`; !strings.Contains(got, want) {
 			t.Errorf("applyTemplate(%#v) = %s; want to contain %s", file, got, want)
 		}
@@ -391,7 +394,7 @@ func TestApplyTemplateRequestWithClientStreaming(t *testing.T) {
 		if want := spec.sigWant; !strings.Contains(got, want) {
 			t.Errorf("applyTemplate(%#v) = %s; want to contain %s", file, got, want)
 		}
-		if want := `marshaler.NewDecoder(req.Body)`; !strings.Contains(got, want) {
+		if want := `runtime.NewRequestStreamDecoder(marshaler, req)`; !strings.Contains(got, want) {
 			t.Errorf("applyTemplate(%#v) = %s; want to contain %s", file, got, want)
 		}
 		if want := `func RegisterExampleServiceHandler(ctx context.Context, mux *runtime.ServeMux, conn *grpc.ClientConn) error {`; !strings.Contains(got, want) {
@@ -402,3 +405,73 @@ func TestApplyTemplateRequestWithClientStreaming(t *testing.T) {
 		}
 	}
 }
+
+func TestBindingUpdateMaskField(t *testing.T) {
+	msgdesc := &protodescriptor.DescriptorProto{
+		Name: proto.String("UpdateRequest"),
+		Field: []*protodescriptor.FieldDescriptorProto{
+			{
+				Name:     proto.String("item"),
+				Label:    protodescriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     protodescriptor.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+				TypeName: proto.String(".example.Item"),
+				Number:   proto.Int32(1),
+			},
+			{
+				Name:     proto.String("update_mask"),
+				Label:    protodescriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     protodescriptor.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+				TypeName: proto.String(".google.protobuf.FieldMask"),
+				Number:   proto.Int32(2),
+			},
+		},
+	}
+	msg := &descriptor.Message{DescriptorProto: msgdesc}
+	for _, f := range msgdesc.GetField() {
+		msg.Fields = append(msg.Fields, &descriptor.Field{Message: msg, FieldDescriptorProto: f})
+	}
+	itemBody := &descriptor.Body{FieldPath: descriptor.FieldPath{{Name: "item", Target: msg.Fields[0]}}}
+
+	for _, spec := range []struct {
+		httpMethod string
+		body       *descriptor.Body
+		want       string
+	}{
+		{httpMethod: "PATCH", body: itemBody, want: "UpdateMask"},
+		{httpMethod: "PUT", body: itemBody},
+		{httpMethod: "PATCH", body: &descriptor.Body{}},
+		{httpMethod: "PATCH"},
+	} {
+		b := binding{Binding: &descriptor.Binding{
+			Method:     &descriptor.Method{RequestType: msg},
+			HTTPMethod: spec.httpMethod,
+			Body:       spec.body,
+		}}
+		if got := b.UpdateMaskField(); got != spec.want {
+			t.Errorf("UpdateMaskField() = %q; want %q; method=%s, body=%v", got, spec.want, spec.httpMethod, spec.body)
+		}
+	}
+}
+
+func TestBindingAllowsEmptyBody(t *testing.T) {
+	msg := &descriptor.Message{DescriptorProto: &protodescriptor.DescriptorProto{Name: proto.String("Request")}}
+	dataBody := &descriptor.Body{FieldPath: descriptor.FieldPath{{Name: "data"}}}
+
+	for _, spec := range []struct {
+		body *descriptor.Body
+		want bool
+	}{
+		{body: &descriptor.Body{}, want: true},
+		{body: dataBody},
+		{},
+	} {
+		b := binding{Binding: &descriptor.Binding{
+			Method:     &descriptor.Method{RequestType: msg},
+			HTTPMethod: "POST",
+			Body:       spec.body,
+		}}
+		if got := b.AllowsEmptyBody(); got != spec.want {
+			t.Errorf("AllowsEmptyBody() = %v; want %v; body=%v", got, spec.want, spec.body)
+		}
+	}
+}
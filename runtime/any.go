@@ -0,0 +1,42 @@
+package runtime
+
+import (
+	"net/http"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	"google.golang.org/grpc/grpclog"
+)
+
+// WithAnyResponseUnwrapping returns a ServeMuxOption which makes ForwardResponseMessage write the message
+// packed in google.protobuf.Any responses in place of the Any envelope, e.g. {"id":"foo"} rather than
+// {"@type":"type.googleapis.com/...","id":"foo"}. The message type is resolved from the type URL with the
+// registry of the proto package, and Any responses of types which are not linked in are written as they are.
+// If "typeHeader" is not empty, the type URL of the unwrapped message is given in the response header of that name.
+//
+// Forward response options see the unwrapped message.
+func WithAnyResponseUnwrapping(typeHeader string) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.unwrapAny = true
+		serveMux.anyTypeHeader = typeHeader
+	}
+}
+
+// unwrapAnyResponse returns the message packed in "resp" if it is a google.protobuf.Any
+// and WithAnyResponseUnwrapping is given, or "resp" otherwise.
+func unwrapAnyResponse(mux *ServeMux, w http.ResponseWriter, resp proto.Message) proto.Message {
+	a, ok := resp.(*any.Any)
+	if !ok || !mux.unwrapAny || a == nil {
+		return resp
+	}
+	var msg ptypes.DynamicAny
+	if err := ptypes.UnmarshalAny(a, &msg); err != nil {
+		grpclog.Printf("Failed to unwrap Any response of type %q: %v", a.TypeUrl, err)
+		return resp
+	}
+	if mux.anyTypeHeader != "" {
+		w.Header().Set(mux.anyTypeHeader, a.TypeUrl)
+	}
+	return msg.Message
+}
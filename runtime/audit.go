@@ -0,0 +1,112 @@
+package runtime
+
+import (
+	"net/http"
+	"strings"
+)
+
+// AuditEvent is the audit record of a request served by a ServeMux configured with WithAuditLog.
+type AuditEvent struct {
+	// Actor is the value of the metadata configured with WithAuditActorKey, if any.
+	Actor string
+	// Action identifies the matched route by its HTTP method and path pattern, e.g. "GET /v1/{name=shelves/*}".
+	Action string
+	// Resource holds the path parameters captured by the matched pattern.
+	Resource map[string]string
+	// Status is the HTTP status code of the response.
+	Status int
+}
+
+// WithAuditLog returns a ServeMuxOption which makes the ServeMux pass an AuditEvent to "fn"
+// once each request matching a registered pattern is completed.
+func WithAuditLog(fn func(AuditEvent)) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.auditLog = fn
+	}
+}
+
+// WithAuditActorKey returns a ServeMuxOption which sets the key of the incoming metadata, as mapped
+// from the request headers by the incoming header matcher, whose value is the actor of AuditEvent.
+func WithAuditActorKey(key string) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.auditActorKey = strings.ToLower(key)
+	}
+}
+
+// auditActor returns the value of the incoming metadata configured with WithAuditActorKey from the headers of "r".
+func (s *ServeMux) auditActor(r *http.Request) string {
+	if s.auditActorKey == "" {
+		return ""
+	}
+	for key, vals := range r.Header {
+		if len(vals) == 0 {
+			continue
+		}
+		// As in AnnotateContext, the 'authorization' header is passed through with no prefix.
+		if strings.ToLower(key) == "authorization" && s.auditActorKey == "authorization" {
+			return vals[0]
+		}
+		if h, ok := s.incomingHeaderMatcher(key); ok && strings.ToLower(h) == s.auditActorKey {
+			return vals[0]
+		}
+	}
+	return ""
+}
+
+// statusResponseWriter records the status code of the response written through it.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// responseWriter returns "w" as an http.ResponseWriter which implements http.Flusher and http.CloseNotifier
+// only if the writer it wraps does, so that handlers can still tell whether responses can be streamed.
+func (w *statusResponseWriter) responseWriter() http.ResponseWriter {
+	_, flusher := w.ResponseWriter.(http.Flusher)
+	cn, closeNotifier := w.ResponseWriter.(http.CloseNotifier)
+	switch {
+	case flusher && closeNotifier:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			http.CloseNotifier
+		}{w, statusFlusher{w}, cn}
+	case flusher:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+		}{w, statusFlusher{w}}
+	case closeNotifier:
+		return struct {
+			http.ResponseWriter
+			http.CloseNotifier
+		}{w, cn}
+	}
+	return w
+}
+
+// statusFlusher flushes the writer wrapped by a statusResponseWriter, which must be an http.Flusher.
+type statusFlusher struct {
+	*statusResponseWriter
+}
+
+func (w statusFlusher) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.(http.Flusher).Flush()
+}
@@ -0,0 +1,80 @@
+package runtime_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/utilities"
+)
+
+func TestMuxServeHTTPAuditLog(t *testing.T) {
+	var events []runtime.AuditEvent
+	mux := runtime.NewServeMux(
+		runtime.WithAuditLog(func(ev runtime.AuditEvent) { events = append(events, ev) }),
+		runtime.WithAuditActorKey("User"),
+	)
+	// /v1/{name=shelves/*}
+	pat := runtime.MustPattern(runtime.NewPattern(1, []int{
+		int(utilities.OpLitPush), 0, int(utilities.OpLitPush), 1, int(utilities.OpPush), 0,
+		int(utilities.OpConcatN), 2, int(utilities.OpCapture), 2,
+	}, []string{"v1", "shelves", "name"}, ""))
+	mux.Handle("DELETE", pat, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	// Handlers writing nothing are answered with http.StatusOK.
+	mux.Handle("GET", pat, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {})
+
+	r := httptest.NewRequest("DELETE", "http://host.example/v1/shelves/1", nil)
+	r.Header.Set("Grpc-Metadata-User", "alice")
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "http://host.example/v1/shelves/2", nil))
+	// Unmatched requests are not audited.
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("DELETE", "http://host.example/v2/shelves/1", nil))
+
+	want := []runtime.AuditEvent{
+		{
+			Actor:    "alice",
+			Action:   "DELETE /v1/{name=shelves/*}",
+			Resource: map[string]string{"name": "shelves/1"},
+			Status:   http.StatusForbidden,
+		},
+		{
+			Action:   "GET /v1/{name=shelves/*}",
+			Resource: map[string]string{"name": "shelves/2"},
+			Status:   http.StatusOK,
+		},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %#v; want %#v", events, want)
+	}
+}
+
+// plainResponseWriter is an http.ResponseWriter which cannot be flushed.
+type plainResponseWriter struct {
+	http.ResponseWriter
+}
+
+func TestMuxServeHTTPAuditLogFlusher(t *testing.T) {
+	mux := runtime.NewServeMux(runtime.WithAuditLog(func(runtime.AuditEvent) {}))
+	pat := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0}, []string{"stream"}, ""))
+	var flusher bool
+	mux.Handle("GET", pat, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		_, flusher = w.(http.Flusher)
+	})
+
+	for _, spec := range []struct {
+		w    http.ResponseWriter
+		want bool
+	}{
+		{w: httptest.NewRecorder(), want: true},
+		{w: plainResponseWriter{httptest.NewRecorder()}, want: false},
+	} {
+		mux.ServeHTTP(spec.w, httptest.NewRequest("GET", "http://host.example/stream", nil))
+		if flusher != spec.want {
+			t.Errorf("w.(http.Flusher) = _, %t; want %t; writer = %T", flusher, spec.want, spec.w)
+		}
+	}
+}
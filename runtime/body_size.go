@@ -0,0 +1,113 @@
+package runtime
+
+import (
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"golang.org/x/net/context"
+)
+
+type bodySizesKey struct{}
+
+// bodySizes holds the sizes of the bodies of a request and its response.
+type bodySizes struct {
+	request  int64
+	response int64
+}
+
+// WithBodySizes returns a copy of "ctx" in which a ServeMux records the size of the request body it reads
+// and of the response body it writes, e.g. for logging middleware to report them without capturing the bodies:
+//
+//	r = r.WithContext(runtime.WithBodySizes(r.Context()))
+//	mux.ServeHTTP(w, r)
+//	log.Printf("%s: %d bytes in, %d bytes out", r.URL.Path, runtime.RequestBodySize(r.Context()), runtime.ResponseBodySize(r.Context()))
+//
+// The response body is accounted as encoded by the marshaler, before any compression by WithCompression.
+func WithBodySizes(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bodySizesKey{}, new(bodySizes))
+}
+
+// RequestBodySize returns the number of bytes of the request body read by the ServeMux so far,
+// or 0 if "ctx" was not derived from WithBodySizes.
+func RequestBodySize(ctx context.Context) int64 {
+	sizes, ok := ctx.Value(bodySizesKey{}).(*bodySizes)
+	if !ok {
+		return 0
+	}
+	return atomic.LoadInt64(&sizes.request)
+}
+
+// ResponseBodySize returns the number of bytes of the response body written by the ServeMux so far,
+// or 0 if "ctx" was not derived from WithBodySizes.
+func ResponseBodySize(ctx context.Context) int64 {
+	sizes, ok := ctx.Value(bodySizesKey{}).(*bodySizes)
+	if !ok {
+		return 0
+	}
+	return atomic.LoadInt64(&sizes.response)
+}
+
+// countBodySizes wraps the body of "r" and "w" to record their sizes if the context of "r" was derived from WithBodySizes.
+func countBodySizes(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request) {
+	sizes, ok := r.Context().Value(bodySizesKey{}).(*bodySizes)
+	if !ok {
+		return w, r
+	}
+	if r.Body != nil {
+		r.Body = &sizeReadCloser{ReadCloser: r.Body, n: &sizes.request}
+	}
+	sw := &sizeResponseWriter{ResponseWriter: w, n: &sizes.response}
+	return sw.responseWriter(), r
+}
+
+// sizeReadCloser counts the bytes read through it.
+type sizeReadCloser struct {
+	io.ReadCloser
+	n *int64
+}
+
+func (r *sizeReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	atomic.AddInt64(r.n, int64(n))
+	return n, err
+}
+
+// sizeResponseWriter counts the bytes of the body written through it.
+type sizeResponseWriter struct {
+	http.ResponseWriter
+	n *int64
+}
+
+func (w *sizeResponseWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	atomic.AddInt64(w.n, int64(n))
+	return n, err
+}
+
+// responseWriter returns "w" as an http.ResponseWriter which implements http.Flusher and http.CloseNotifier
+// only if the writer it wraps does, so that handlers can still tell whether responses can be streamed
+// and cancel calls when clients go away.
+func (w *sizeResponseWriter) responseWriter() http.ResponseWriter {
+	f, flusher := w.ResponseWriter.(http.Flusher)
+	cn, closeNotifier := w.ResponseWriter.(http.CloseNotifier)
+	switch {
+	case flusher && closeNotifier:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			http.CloseNotifier
+		}{w, f, cn}
+	case flusher:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+		}{w, f}
+	case closeNotifier:
+		return struct {
+			http.ResponseWriter
+			http.CloseNotifier
+		}{w, cn}
+	}
+	return w
+}
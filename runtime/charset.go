@@ -0,0 +1,66 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// CharsetMarshaler is a Marshaler which can decode request bodies in charsets other than UTF-8.
+// MarshalerForRequest rejects the bodies in such charsets unless the inbound Marshaler implements it.
+type CharsetMarshaler interface {
+	Marshaler
+	// WithCharset returns a Marshaler which decodes request bodies encoded in "charset",
+	// or an error if "charset" is not supported.
+	WithCharset(charset string) (Marshaler, error)
+}
+
+// RequestCharset returns the lower-cased charset parameter of the Content-Type of "r", or "" if it has none.
+func RequestCharset(r *http.Request) string {
+	_, params, err := mime.ParseMediaType(r.Header.Get(contentTypeHeader))
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(params["charset"])
+}
+
+// isUTF8Charset returns whether request bodies in "charset" can be decoded as UTF-8.
+func isUTF8Charset(charset string) bool {
+	switch charset {
+	case "", "utf-8", "utf8", "us-ascii":
+		return true
+	}
+	return false
+}
+
+// charsetMarshaler returns the Marshaler to decode request bodies in "charset" with "inbound".
+func charsetMarshaler(inbound Marshaler, charset string) Marshaler {
+	if isUTF8Charset(charset) {
+		return inbound
+	}
+	if cm, ok := inbound.(CharsetMarshaler); ok {
+		m, err := cm.WithCharset(charset)
+		if err == nil {
+			return m
+		}
+		return &charsetErrorMarshaler{Marshaler: inbound, err: err}
+	}
+	return &charsetErrorMarshaler{Marshaler: inbound, err: fmt.Errorf("unsupported charset %q of request body", charset)}
+}
+
+// charsetErrorMarshaler is a Marshaler which fails to decode request bodies in an unsupported charset
+// instead of decoding them as garbage.
+type charsetErrorMarshaler struct {
+	Marshaler
+	err error
+}
+
+func (m *charsetErrorMarshaler) Unmarshal(data []byte, v interface{}) error {
+	return m.err
+}
+
+func (m *charsetErrorMarshaler) NewDecoder(r io.Reader) Decoder {
+	return DecoderFunc(func(v interface{}) error { return m.err })
+}
@@ -0,0 +1,82 @@
+package runtime
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+)
+
+// ClientCertField is a field of the client certificate of a TLS request forwarded by WithClientCertMetadata.
+type ClientCertField int
+
+const (
+	// ClientCertCommonName forwards the common name of the subject in the MetadataClientCertCommonName key.
+	ClientCertCommonName ClientCertField = iota
+	// ClientCertSubject forwards the distinguished name of the subject, e.g. "CN=foo,O=Example",
+	// in the MetadataClientCertSubject key.
+	ClientCertSubject
+	// ClientCertSANs forwards each subject alternative name in the MetadataClientCertSAN key, prefixed with its
+	// type as in "DNS:foo.example.com", "email:foo@example.com", "IP:10.0.0.1" and "URI:spiffe://example.com/foo".
+	ClientCertSANs
+	// ClientCertFingerprint forwards the hex-encoded SHA-256 digest of the DER encoding of the certificate
+	// in the MetadataClientCertFingerprint key.
+	ClientCertFingerprint
+)
+
+const (
+	// MetadataClientCertCommonName is the gRPC metadata key of ClientCertCommonName.
+	MetadataClientCertCommonName = MetadataPrefix + "client-cert-cn"
+	// MetadataClientCertSubject is the gRPC metadata key of ClientCertSubject.
+	MetadataClientCertSubject = MetadataPrefix + "client-cert-subject"
+	// MetadataClientCertSAN is the gRPC metadata key of ClientCertSANs.
+	MetadataClientCertSAN = MetadataPrefix + "client-cert-san"
+	// MetadataClientCertFingerprint is the gRPC metadata key of ClientCertFingerprint.
+	MetadataClientCertFingerprint = MetadataPrefix + "client-cert-fingerprint"
+)
+
+// WithClientCertMetadata returns a ServeMuxOption which forwards the "fields" of the client certificate of
+// TLS requests, e.g. with mutual TLS, to the backend in gRPC metadata. Nothing is forwarded for requests
+// which are not over TLS or whose client did not present a certificate, e.g. because none was requested.
+// The certificate is the leaf of the chain presented by the client, as verified by the http.Server if its
+// tls.Config requires it.
+func WithClientCertMetadata(fields ...ClientCertField) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.clientCertFields = append(serveMux.clientCertFields, fields...)
+	}
+}
+
+// clientCertMetadata returns the metadata pairs of the client certificate of "req" for WithClientCertMetadata.
+func clientCertMetadata(mux *ServeMux, req *http.Request) []string {
+	if len(mux.clientCertFields) == 0 || req.TLS == nil || len(req.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	cert := req.TLS.PeerCertificates[0]
+	var pairs []string
+	for _, field := range mux.clientCertFields {
+		switch field {
+		case ClientCertCommonName:
+			if cert.Subject.CommonName != "" {
+				pairs = append(pairs, MetadataClientCertCommonName, cert.Subject.CommonName)
+			}
+		case ClientCertSubject:
+			pairs = append(pairs, MetadataClientCertSubject, cert.Subject.String())
+		case ClientCertSANs:
+			for _, name := range cert.DNSNames {
+				pairs = append(pairs, MetadataClientCertSAN, "DNS:"+name)
+			}
+			for _, email := range cert.EmailAddresses {
+				pairs = append(pairs, MetadataClientCertSAN, "email:"+email)
+			}
+			for _, ip := range cert.IPAddresses {
+				pairs = append(pairs, MetadataClientCertSAN, "IP:"+ip.String())
+			}
+			for _, uri := range cert.URIs {
+				pairs = append(pairs, MetadataClientCertSAN, "URI:"+uri.String())
+			}
+		case ClientCertFingerprint:
+			sum := sha256.Sum256(cert.Raw)
+			pairs = append(pairs, MetadataClientCertFingerprint, hex.EncodeToString(sum[:]))
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,72 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrClientStreamLimitExceeded is returned by the Decoder of LimitClientStream
+// once the request stream has more messages than allowed by WithMaxClientStreamMessages.
+var ErrClientStreamLimitExceeded = status.Error(codes.ResourceExhausted, "client stream exceeded the maximum number of messages")
+
+type maxClientStreamMessagesKey struct{}
+
+// LimitClientStream returns a Decoder which decodes the messages of a client stream with "dec"
+// and fails with ErrClientStreamLimitExceeded once it decoded more messages than allowed by
+// WithMaxClientStreamMessages for the ServeMux in "ctx". "cancel", if not nil, is called at the same
+// time so that the RPC is also canceled when the error cannot be returned to the client, e.g. because
+// the response of a bidirectional stream has already started.
+// "dec" is returned as is if no limit is set.
+func LimitClientStream(ctx context.Context, dec Decoder, cancel context.CancelFunc) Decoder {
+	max, _ := ctx.Value(maxClientStreamMessagesKey{}).(int)
+	if max <= 0 {
+		return dec
+	}
+	var count int
+	return DecoderFunc(func(v interface{}) error {
+		if err := dec.Decode(v); err != nil {
+			return err
+		}
+		if count++; count > max {
+			if cancel != nil {
+				cancel()
+			}
+			return ErrClientStreamLimitExceeded
+		}
+		return nil
+	})
+}
+
+// NewRequestStreamDecoder returns a Decoder which decodes the messages of a client stream from the body of "req"
+// with "marshaler". Besides the successive messages understood by the Decoder of "marshaler", e.g. newline-delimited
+// JSON, a multipart body, e.g. "multipart/mixed", is accepted, in which case each part is decoded as one message.
+// This lets clients which can only send a single request body, such as browsers, stream several messages.
+func NewRequestStreamDecoder(marshaler Marshaler, req *http.Request) Decoder {
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return marshaler.NewDecoder(req.Body)
+	}
+	mr := multipart.NewReader(req.Body, params["boundary"])
+	return DecoderFunc(func(v interface{}) error {
+		part, err := mr.NextPart()
+		if err != nil {
+			return err
+		}
+		defer part.Close()
+		if err := marshaler.NewDecoder(part).Decode(v); err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("empty part in multipart request")
+			}
+			return err
+		}
+		return nil
+	})
+}
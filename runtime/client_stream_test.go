@@ -0,0 +1,231 @@
+package runtime_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	goruntime "runtime"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestLimitClientStream(t *testing.T) {
+	const body = `{"id": "a"} {"id": "b"} {"id": "c"}`
+	for _, spec := range []struct {
+		opts []runtime.ServeMuxOption
+		want int
+		err  error
+	}{
+		{
+			want: 3,
+			err:  io.EOF,
+		},
+		{
+			opts: []runtime.ServeMuxOption{runtime.WithMaxClientStreamMessages(3)},
+			want: 3,
+			err:  io.EOF,
+		},
+		{
+			opts: []runtime.ServeMuxOption{runtime.WithMaxClientStreamMessages(2)},
+			want: 2,
+			err:  runtime.ErrClientStreamLimitExceeded,
+		},
+	} {
+		req, err := http.NewRequest("POST", "http://example.com/v1/bulk", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("http.NewRequest failed with %v; want success", err)
+		}
+		ctx, err := runtime.AnnotateContext(context.Background(), runtime.NewServeMux(spec.opts...), req)
+		if err != nil {
+			t.Fatalf("runtime.AnnotateContext(ctx, mux, req) failed with %v; want success", err)
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		dec := runtime.LimitClientStream(ctx, (&runtime.JSONPb{}).NewDecoder(req.Body), cancel)
+
+		var got int
+		for {
+			var msg examplepb.SimpleMessage
+			if err = dec.Decode(&msg); err != nil {
+				break
+			}
+			got++
+		}
+		if err != spec.err {
+			t.Errorf("dec.Decode(&msg) failed with %v; want %v", err, spec.err)
+		}
+		if got != spec.want {
+			t.Errorf("decoded %d messages; want %d", got, spec.want)
+		}
+		if canceled := ctx.Err() != nil; canceled != (spec.err != io.EOF) {
+			t.Errorf("ctx.Err() = %v; want canceled = %t", ctx.Err(), !canceled)
+		}
+		cancel()
+	}
+
+	if s, _ := status.FromError(runtime.ErrClientStreamLimitExceeded); s.Code() != codes.ResourceExhausted {
+		t.Errorf("status.FromError(runtime.ErrClientStreamLimitExceeded).Code() = %v; want %v", s.Code(), codes.ResourceExhausted)
+	}
+}
+
+func TestNewRequestStreamDecoder(t *testing.T) {
+	const multipartBody = "--boundary\r\n" +
+		"Content-Type: application/json\r\n\r\n" +
+		`{"id": "a"}` + "\r\n" +
+		"--boundary\r\n\r\n" +
+		`{"id": "b"}` + "\r\n" +
+		"--boundary--\r\n"
+	for _, spec := range []struct {
+		contentType string
+		body        string
+		want        []string
+		wantErr     bool
+	}{
+		{
+			contentType: "application/x-ndjson",
+			body:        "{\"id\": \"a\"}\n{\"id\": \"b\"}\n",
+			want:        []string{"a", "b"},
+		},
+		{
+			contentType: "multipart/mixed; boundary=boundary",
+			body:        multipartBody,
+			want:        []string{"a", "b"},
+		},
+		{
+			contentType: "multipart/mixed; boundary=boundary",
+			body:        "--boundary\r\n\r\n\r\n--boundary--\r\n",
+			wantErr:     true,
+		},
+	} {
+		req, err := http.NewRequest("POST", "http://example.com/v1/bulk", strings.NewReader(spec.body))
+		if err != nil {
+			t.Fatalf("http.NewRequest failed with %v; want success", err)
+		}
+		req.Header.Set("Content-Type", spec.contentType)
+		dec := runtime.NewRequestStreamDecoder(&runtime.JSONPb{}, req)
+
+		var got []string
+		for {
+			var msg examplepb.SimpleMessage
+			if err = dec.Decode(&msg); err != nil {
+				break
+			}
+			got = append(got, msg.Id)
+		}
+		if spec.wantErr {
+			if err == io.EOF {
+				t.Errorf("dec.Decode(&msg) ended with io.EOF; want an error; content type = %q", spec.contentType)
+			}
+			continue
+		}
+		if err != io.EOF {
+			t.Errorf("dec.Decode(&msg) failed with %v; want io.EOF; content type = %q", err, spec.contentType)
+		}
+		if !reflect.DeepEqual(got, spec.want) {
+			t.Errorf("decoded %q; want %q; content type = %q", got, spec.want, spec.contentType)
+		}
+	}
+}
+
+// bulkCreateBody is an io.Reader which generates "n" newline-delimited ABitOfEverything messages on the fly.
+type bulkCreateBody struct {
+	n, generated int
+	pending      []byte
+}
+
+func (b *bulkCreateBody) Read(p []byte) (int, error) {
+	if len(b.pending) == 0 {
+		if b.generated == b.n {
+			return 0, io.EOF
+		}
+		b.pending = []byte(fmt.Sprintf("{\"uuid\":\"%08d\",\"string_value\":\"%s\"}\n", b.generated, strings.Repeat("x", 64)))
+		b.generated++
+	}
+	n := copy(p, b.pending)
+	b.pending = b.pending[n:]
+	return n, nil
+}
+
+// bulkCreateClient is a StreamServiceClient whose BulkCreate calls discard the messages sent,
+// calling "onSend" with the number of messages sent so far.
+type bulkCreateClient struct {
+	examplepb.StreamServiceClient
+	onSend func(n int)
+}
+
+func (c *bulkCreateClient) BulkCreate(ctx context.Context, _ ...grpc.CallOption) (examplepb.StreamService_BulkCreateClient, error) {
+	return &bulkCreateStream{onSend: c.onSend}, nil
+}
+
+type bulkCreateStream struct {
+	grpc.ClientStream
+	onSend func(n int)
+	sent   int
+}
+
+func (s *bulkCreateStream) Send(*examplepb.ABitOfEverything) error {
+	s.sent++
+	s.onSend(s.sent)
+	return nil
+}
+
+func (s *bulkCreateStream) CloseSend() error                    { return nil }
+func (s *bulkCreateStream) Header() (metadata.MD, error)        { return nil, nil }
+func (s *bulkCreateStream) Trailer() metadata.MD                { return nil }
+func (s *bulkCreateStream) CloseAndRecv() (*empty.Empty, error) { return new(empty.Empty), nil }
+
+func heapInUse() uint64 {
+	goruntime.GC()
+	var stats goruntime.MemStats
+	goruntime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+func TestClientStreamingHandlerBoundedMemory(t *testing.T) {
+	const (
+		count = 200000
+		// maxHeapGrowth bounds the live heap while the request is being streamed,
+		// far below the size of the body, which is about 20MB.
+		maxHeapGrowth = 4 << 20
+	)
+	var growth uint64
+	base := heapInUse()
+	client := &bulkCreateClient{onSend: func(n int) {
+		if n != count/2 {
+			return
+		}
+		if heap := heapInUse(); heap > base {
+			growth = heap - base
+		}
+	}}
+	mux := runtime.NewServeMux()
+	if err := examplepb.RegisterStreamServiceHandlerClient(context.Background(), mux, client); err != nil {
+		t.Fatalf("examplepb.RegisterStreamServiceHandlerClient(ctx, mux, client) failed with %v; want success", err)
+	}
+
+	body := &bulkCreateBody{n: count}
+	r := httptest.NewRequest("POST", "http://example.com/v1/example/a_bit_of_everything/bulk", body)
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Fatalf("w.Code = %d; want %d; body = %s", got, want, w.Body.Bytes())
+	}
+	if body.generated != count {
+		t.Errorf("generated %d messages; want %d", body.generated, count)
+	}
+	if growth > maxHeapGrowth {
+		t.Errorf("heap grew by %d bytes while streaming the request; want at most %d", growth, maxHeapGrowth)
+	}
+}
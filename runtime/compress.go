@@ -0,0 +1,246 @@
+package runtime
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/grpclog"
+)
+
+// DefaultNonCompressibleContentTypes are the content types whose responses are not compressed
+// by WithCompression unless others are given. An entry ending with "/" matches all subtypes.
+var DefaultNonCompressibleContentTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"application/gzip",
+	"application/zip",
+	"application/x-7z-compressed",
+	"application/octet-stream",
+}
+
+type compressionConfig struct {
+	minSize      int
+	skipPrefixes []string
+}
+
+// WithCompression returns a ServeMuxOption which makes the ServeMux gzip the responses to requests
+// accepting it, except those whose Content-Type is one of "skipContentTypes", which defaults to
+// DefaultNonCompressibleContentTypes, and those whose body is smaller than "minSize" bytes.
+// The size of streamed responses is unknown when they are flushed, so only their content type is considered;
+// each flush of such a response also flushes the compressed data so that clients receive every message as it is sent.
+func WithCompression(minSize int, skipContentTypes ...string) ServeMuxOption {
+	if len(skipContentTypes) == 0 {
+		skipContentTypes = DefaultNonCompressibleContentTypes
+	}
+	return func(serveMux *ServeMux) {
+		serveMux.compression = &compressionConfig{minSize: minSize, skipPrefixes: skipContentTypes}
+	}
+}
+
+// WithCompressionLevel returns a ServeMuxOption which sets the gzip level of the responses compressed by
+// WithCompression, from gzip.HuffmanOnly to gzip.BestCompression. It defaults to gzip.DefaultCompression.
+func WithCompressionLevel(level int) ServeMuxOption {
+	return WithCompressionLevelFunc(func(*http.Request) int {
+		return level
+	})
+}
+
+// WithCompressionLevelFunc returns a ServeMuxOption which selects the gzip level of each response compressed
+// by WithCompression with "f", e.g. gzip.BestCompression for large exports and gzip.BestSpeed for interactive
+// endpoints. An invalid level falls back to gzip.DefaultCompression.
+func WithCompressionLevelFunc(f func(r *http.Request) int) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.compressionLevel = f
+	}
+}
+
+// gzipLevel returns the gzip level of the response to "r".
+func (s *ServeMux) gzipLevel(r *http.Request) int {
+	if s.compressionLevel == nil {
+		return gzip.DefaultCompression
+	}
+	level := s.compressionLevel(r)
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		grpclog.Printf("Invalid gzip level %d, using the default level", level)
+		return gzip.DefaultCompression
+	}
+	return level
+}
+
+// gzipWriterPools holds the reusable gzip writers of each level, indexed from gzip.HuffmanOnly.
+var gzipWriterPools [gzip.BestCompression - gzip.HuffmanOnly + 1]sync.Pool
+
+// getGzipWriter returns a gzip writer of "level" writing into "w".
+func getGzipWriter(w io.Writer, level int) *gzip.Writer {
+	if gz, ok := gzipWriterPools[level-gzip.HuffmanOnly].Get().(*gzip.Writer); ok {
+		gz.Reset(w)
+		return gz
+	}
+	// The level has been validated by gzipLevel.
+	gz, _ := gzip.NewWriterLevel(w, level)
+	return gz
+}
+
+// putGzipWriter returns the writer "gz" of "level" to its pool once it is closed.
+func putGzipWriter(gz *gzip.Writer, level int) {
+	gzipWriterPools[level-gzip.HuffmanOnly].Put(gz)
+}
+
+// acceptsGzip reports whether the Accept-Encoding header of "r" accepts gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, v := range r.Header["Accept-Encoding"] {
+		for _, coding := range strings.Split(v, ",") {
+			params := strings.Split(coding, ";")
+			if name := strings.TrimSpace(params[0]); name != "gzip" && name != "*" {
+				continue
+			}
+			accepted := true
+			for _, p := range params[1:] {
+				p = strings.TrimSpace(p)
+				if !strings.HasPrefix(p, "q=") {
+					continue
+				}
+				if q, err := strconv.ParseFloat(p[len("q="):], 64); err == nil && q == 0 {
+					accepted = false
+				}
+			}
+			if accepted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compressible reports whether responses of "contentType" are worth compressing.
+func (c *compressionConfig) compressible(contentType string) bool {
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	for _, skip := range c.skipPrefixes {
+		if skip == contentType || strings.HasSuffix(skip, "/") && strings.HasPrefix(contentType, skip) {
+			return false
+		}
+	}
+	return true
+}
+
+// compressWriter gzips the response written through it once it knows that it is worth it.
+// The body is buffered until it reaches the minimum size, is flushed or is complete.
+type compressWriter struct {
+	http.ResponseWriter
+	config *compressionConfig
+	level  int
+
+	code    int
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+func newCompressWriter(w http.ResponseWriter, config *compressionConfig, level int) *compressWriter {
+	w.Header().Add("Vary", "Accept-Encoding")
+	return &compressWriter{ResponseWriter: w, config: config, level: level}
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		if !w.worthCompressing() {
+			w.decide(false)
+		} else if len(w.buf)+len(p) < w.config.minSize {
+			w.buf = append(w.buf, p...)
+			return len(p), nil
+		} else {
+			w.decide(true)
+		}
+	}
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush writes the buffered response. Since the final size of the body is unknown,
+// it is compressed if its content type is compressible.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(w.worthCompressing())
+	}
+	if w.gz != nil {
+		if err := w.gz.Flush(); err != nil {
+			grpclog.Printf("Failed to flush compressed response: %v", err)
+		}
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *compressWriter) CloseNotify() <-chan bool {
+	if cn, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+// finish writes the rest of the response once the handler returned.
+func (w *compressWriter) finish() {
+	if !w.decided {
+		// The complete body is smaller than the minimum size.
+		w.decide(false)
+	}
+	if w.gz != nil {
+		if err := w.gz.Close(); err != nil {
+			grpclog.Printf("Failed to finish compressed response: %v", err)
+		}
+		putGzipWriter(w.gz, w.level)
+		w.gz = nil
+	}
+}
+
+func (w *compressWriter) worthCompressing() bool {
+	h := w.Header()
+	return h.Get("Content-Encoding") == "" && w.config.compressible(h.Get("Content-Type"))
+}
+
+// decide writes the header of the response, compressed or not, followed by the buffered body.
+func (w *compressWriter) decide(compress bool) {
+	w.decided = true
+	if compress {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Del("Content-Length")
+		w.gz = getGzipWriter(w.ResponseWriter, w.level)
+	}
+	if w.code != 0 {
+		w.ResponseWriter.WriteHeader(w.code)
+	}
+	if len(w.buf) == 0 {
+		return
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	if err != nil {
+		grpclog.Printf("Failed to write response: %v", err)
+	}
+	w.buf = nil
+}
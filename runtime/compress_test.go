@@ -0,0 +1,194 @@
+package runtime_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/utilities"
+)
+
+func TestMuxServeHTTPCompression(t *testing.T) {
+	large := strings.Repeat("x", 1000)
+	for _, spec := range []struct {
+		name           string
+		contentType    string
+		body           string
+		flush          bool
+		acceptEncoding string
+		wantGzip       bool
+	}{
+		{name: "large", contentType: "application/json", body: large, acceptEncoding: "gzip", wantGzip: true},
+		{name: "small", contentType: "application/json", body: "{}", acceptEncoding: "gzip"},
+		{name: "image", contentType: "image/png", body: large, acceptEncoding: "gzip"},
+		{name: "not_accepted", contentType: "application/json", body: large},
+		{name: "rejected", contentType: "application/json", body: large, acceptEncoding: "gzip;q=0"},
+		{name: "small_stream", contentType: "application/json", body: "{}", flush: true, acceptEncoding: "deflate, gzip", wantGzip: true},
+		{name: "image_stream", contentType: "image/png", body: "{}", flush: true, acceptEncoding: "gzip"},
+	} {
+		t.Run(spec.name, func(t *testing.T) {
+			mux := runtime.NewServeMux(runtime.WithCompression(100))
+			pat := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0}, []string{"foo"}, ""))
+			mux.Handle("GET", pat, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+				w.Header().Set("Content-Type", spec.contentType)
+				w.WriteHeader(http.StatusAccepted)
+				w.Write([]byte(spec.body))
+				if spec.flush {
+					w.(http.Flusher).Flush()
+				}
+			})
+
+			r := httptest.NewRequest("GET", "http://host.example/foo", nil)
+			if spec.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", spec.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if got, want := w.Code, http.StatusAccepted; got != want {
+				t.Errorf("w.Code = %d; want %d", got, want)
+			}
+			body := w.Body.String()
+			if spec.wantGzip {
+				if got, want := w.Header().Get("Content-Encoding"), "gzip"; got != want {
+					t.Fatalf("Content-Encoding = %q; want %q", got, want)
+				}
+				zr, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatalf("gzip.NewReader(w.Body) failed with %v; want success", err)
+				}
+				buf, err := ioutil.ReadAll(zr)
+				if err != nil {
+					t.Fatalf("ioutil.ReadAll(zr) failed with %v; want success", err)
+				}
+				body = string(buf)
+			} else if got := w.Header().Get("Content-Encoding"); got != "" {
+				t.Errorf("Content-Encoding = %q; want none", got)
+			}
+			if body != spec.body {
+				t.Errorf("body = %q; want %q", body, spec.body)
+			}
+		})
+	}
+}
+
+func TestForwardResponseStreamCompression(t *testing.T) {
+	mux := runtime.NewServeMux(runtime.WithCompression(1000))
+	pat := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0}, []string{"foo"}, ""))
+	w := httptest.NewRecorder()
+	// received returns the decompressed body which the client received so far.
+	received := func() string {
+		zr, err := gzip.NewReader(bytes.NewReader(w.Body.Bytes()))
+		if err != nil {
+			t.Fatalf("gzip.NewReader(w.Body) failed with %v; want success", err)
+		}
+		buf, err := ioutil.ReadAll(zr)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			t.Fatalf("ioutil.ReadAll(zr) failed with %v; want success or %v", err, io.ErrUnexpectedEOF)
+		}
+		return string(buf)
+	}
+
+	msgs := []string{"foo", "bar"}
+	mux.Handle("GET", pat, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		var count int
+		recv := func() (proto.Message, error) {
+			if count > 0 {
+				// The previous message was flushed although it is smaller than the minimum size.
+				want := fmt.Sprintf("{\"result\":{\"id\":%q}}\n", msgs[count-1])
+				if got := received(); !strings.HasSuffix(got, want) {
+					t.Errorf("received %q before message %d; want it to end with %q", got, count, want)
+				}
+			}
+			if count == len(msgs) {
+				return nil, io.EOF
+			}
+			count++
+			return &pb.SimpleMessage{Id: msgs[count-1]}, nil
+		}
+		ctx := runtime.NewServerMetadataContext(r.Context(), runtime.ServerMetadata{})
+		runtime.ForwardResponseStream(ctx, mux, &runtime.JSONPb{}, w, r, recv)
+	})
+
+	r := httptest.NewRequest("GET", "http://host.example/foo", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	mux.ServeHTTP(w, r)
+
+	if got, want := w.Header().Get("Content-Encoding"), "gzip"; got != want {
+		t.Fatalf("Content-Encoding = %q; want %q", got, want)
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader(w.Body) failed with %v; want success", err)
+	}
+	// The gzip stream is terminated once the response stream ends.
+	buf, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll(zr) failed with %v; want success", err)
+	}
+	if got, want := string(buf), "{\"result\":{\"id\":\"foo\"}}\n{\"result\":{\"id\":\"bar\"}}\n"; got != want {
+		t.Errorf("body = %q; want %q", got, want)
+	}
+}
+
+func TestMuxServeHTTPCompressionLevel(t *testing.T) {
+	body := strings.Repeat("x", 1000)
+	mux := runtime.NewServeMux(
+		runtime.WithCompression(100),
+		runtime.WithCompressionLevelFunc(func(r *http.Request) int {
+			if r.URL.Query().Get("export") != "" {
+				return gzip.BestCompression
+			}
+			return gzip.BestSpeed
+		}),
+	)
+	pat := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0}, []string{"foo"}, ""))
+	mux.Handle("GET", pat, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	})
+
+	for _, spec := range []struct {
+		url string
+		// xfl is the extra flags byte of the gzip header, which tells the level.
+		xfl byte
+	}{
+		{url: "http://host.example/foo", xfl: 4},
+		{url: "http://host.example/foo?export=1", xfl: 2},
+		// Writers are reused from the pool of their level.
+		{url: "http://host.example/foo", xfl: 4},
+	} {
+		r := httptest.NewRequest("GET", spec.url, nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		buf := w.Body.Bytes()
+		if len(buf) < 10 {
+			t.Fatalf("%s: body = %q; want a gzip stream", spec.url, buf)
+		}
+		if got := buf[8]; got != spec.xfl {
+			t.Errorf("%s: XFL = %d; want %d", spec.url, got, spec.xfl)
+		}
+		zr, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatalf("gzip.NewReader(w.Body) failed with %v; want success", err)
+		}
+		got, err := ioutil.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("ioutil.ReadAll(zr) failed with %v; want success", err)
+		}
+		if string(got) != body {
+			t.Errorf("%s: body = %q; want %q", spec.url, got, body)
+		}
+	}
+}
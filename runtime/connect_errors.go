@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/status"
+)
+
+var _ ProtoErrorHandlerFunc = ConnectHTTPErrorHandler
+
+// connectCodeNames maps the gRPC codes to the names used in the error envelope of the Connect protocol.
+var connectCodeNames = map[codes.Code]string{
+	codes.Canceled:           "canceled",
+	codes.Unknown:            "unknown",
+	codes.InvalidArgument:    "invalid_argument",
+	codes.DeadlineExceeded:   "deadline_exceeded",
+	codes.NotFound:           "not_found",
+	codes.AlreadyExists:      "already_exists",
+	codes.PermissionDenied:   "permission_denied",
+	codes.Unauthenticated:    "unauthenticated",
+	codes.ResourceExhausted:  "resource_exhausted",
+	codes.FailedPrecondition: "failed_precondition",
+	codes.Aborted:            "aborted",
+	codes.OutOfRange:         "out_of_range",
+	codes.Unimplemented:      "unimplemented",
+	codes.Internal:           "internal",
+	codes.Unavailable:        "unavailable",
+	codes.DataLoss:           "data_loss",
+}
+
+// ConnectCodeName returns the name of a gRPC error code in the Connect protocol, e.g. "not_found".
+func ConnectCodeName(code codes.Code) string {
+	if name, ok := connectCodeNames[code]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+type connectError struct {
+	Code    string               `json:"code"`
+	Message string               `json:"message,omitempty"`
+	Details []connectErrorDetail `json:"details,omitempty"`
+}
+
+type connectErrorDetail struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// ConnectHTTPErrorHandler is a ProtoErrorHandlerFunc which replies with the error envelope of the Connect protocol,
+// e.g. {"code":"not_found","message":"...","details":[...]}. Register it with WithProtoErrorHandler.
+// The status code is mapped by HTTPStatusFromCode as in DefaultHTTPProtoErrorHandler.
+//
+// The envelope is always JSON regardless of "marshaler", as the protocol requires. Each detail of the status
+// is given by the fully-qualified name of its type and its binary encoding in unpadded base64.
+func ConnectHTTPErrorHandler(ctx context.Context, mux *ServeMux, marshaler Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	const fallback = `{"code": "internal", "message": "failed to marshal error message"}`
+
+	w.Header().Del("Trailer")
+	handleEchoedHeaders(w, mux, r)
+
+	s, ok := status.FromError(err)
+	if !ok {
+		s = status.New(codes.Unknown, err.Error())
+	}
+	if handleEmptyResponseOnNotFound(ctx, mux, marshaler, w, r, s) {
+		return
+	}
+	s = sanitizeErrorMessage(ctx, mux, s)
+
+	body := connectError{
+		Code:    ConnectCodeName(s.Code()),
+		Message: s.Message(),
+	}
+	for _, detail := range s.Proto().GetDetails() {
+		typeURL := detail.GetTypeUrl()
+		body.Details = append(body.Details, connectErrorDetail{
+			Type:  typeURL[strings.LastIndex(typeURL, "/")+1:],
+			Value: base64.RawStdEncoding.EncodeToString(detail.GetValue()),
+		})
+	}
+
+	buf, merr := json.Marshal(body)
+	w.Header().Set("Content-Type", "application/json")
+	if merr != nil {
+		grpclog.Printf("Failed to marshal error message %v: %v", body, merr)
+		observeError(ctx, mux, err, s.Code(), http.StatusInternalServerError)
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := io.WriteString(w, fallback); err != nil {
+			grpclog.Printf("Failed to write response: %v", err)
+		}
+		return
+	}
+
+	md, ok := ServerMetadataFromContext(ctx)
+	if !ok {
+		grpclog.Printf("Failed to extract ServerMetadata from context")
+	}
+
+	handleForwardResponseServerMetadata(w, mux, md)
+	handleForwardResponseTrailerHeader(w, mux, md)
+	handleAuthenticateChallenge(w, mux, s.Code())
+	st := HTTPStatusFromCode(s.Code())
+	observeError(ctx, mux, err, s.Code(), st)
+	w.WriteHeader(st)
+	if _, err := w.Write(buf); err != nil {
+		grpclog.Printf("Failed to write response: %v", err)
+	}
+
+	handleForwardResponseTrailer(w, mux, md)
+}
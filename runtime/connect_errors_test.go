@@ -0,0 +1,107 @@
+package runtime_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestConnectHTTPErrorHandler(t *testing.T) {
+	ctx := context.Background()
+	req, _ := http.NewRequest("GET", "", nil)
+	detail := &errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "name", Description: "required"}},
+	}
+	s, err := status.New(codes.InvalidArgument, "bad name").WithDetails(detail)
+	if err != nil {
+		t.Fatalf("s.WithDetails(%v) failed with %v; want success", detail, err)
+	}
+
+	for _, spec := range []struct {
+		err     error
+		status  int
+		code    string
+		msg     string
+		details int
+	}{
+		{
+			err:     s.Err(),
+			status:  http.StatusBadRequest,
+			code:    "invalid_argument",
+			msg:     "bad name",
+			details: 1,
+		},
+		{
+			err:    status.Error(codes.NotFound, "no such resource"),
+			status: http.StatusNotFound,
+			code:   "not_found",
+			msg:    "no such resource",
+		},
+		{
+			err:    errors.New("example error"),
+			status: http.StatusInternalServerError,
+			code:   "unknown",
+			msg:    "example error",
+		},
+	} {
+		w := httptest.NewRecorder()
+		runtime.ConnectHTTPErrorHandler(ctx, runtime.NewServeMux(), &runtime.ProtoMarshaller{}, w, req, spec.err)
+
+		if got, want := w.Code, spec.status; got != want {
+			t.Errorf("w.Code = %d; want %d; on spec.err=%v", got, want, spec.err)
+		}
+		if got, want := w.Header().Get("Content-Type"), "application/json"; got != want {
+			t.Errorf("Content-Type = %q; want %q; on spec.err=%v", got, want, spec.err)
+		}
+		var body struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+			Details []struct {
+				Type  string `json:"type"`
+				Value string `json:"value"`
+			} `json:"details"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Errorf("json.Unmarshal(%q, &body) failed with %v; want success", w.Body.Bytes(), err)
+			continue
+		}
+		if got, want := body.Code, spec.code; got != want {
+			t.Errorf("body.Code = %q; want %q; on spec.err=%v", got, want, spec.err)
+		}
+		if got, want := body.Message, spec.msg; got != want {
+			t.Errorf("body.Message = %q; want %q; on spec.err=%v", got, want, spec.err)
+		}
+		if got, want := len(body.Details), spec.details; got != want {
+			t.Errorf("len(body.Details) = %d; want %d; on spec.err=%v", got, want, spec.err)
+			continue
+		}
+		for _, d := range body.Details {
+			if got, want := d.Type, "google.rpc.BadRequest"; got != want {
+				t.Errorf("d.Type = %q; want %q", got, want)
+			}
+			buf, err := base64.RawStdEncoding.DecodeString(d.Value)
+			if err != nil {
+				t.Errorf("base64.RawStdEncoding.DecodeString(%q) failed with %v; want success", d.Value, err)
+				continue
+			}
+			var got errdetails.BadRequest
+			if err := proto.Unmarshal(buf, &got); err != nil {
+				t.Errorf("proto.Unmarshal(%q, &got) failed with %v; want success", buf, err)
+				continue
+			}
+			if !proto.Equal(&got, detail) {
+				t.Errorf("detail = %v; want %v", &got, detail)
+			}
+		}
+	}
+}
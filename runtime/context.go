@@ -1,14 +1,19 @@
 package runtime
 
 import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"net"
 	"net/http"
+	"net/textproto"
 	"strconv"
 	"strings"
 	"time"
 
 	"golang.org/x/net/context"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/grpclog"
 	"google.golang.org/grpc/metadata"
@@ -55,17 +60,49 @@ func AnnotateContext(ctx context.Context, mux *ServeMux, req *http.Request) (con
 		}
 	}
 
+	var forwarded, forwardedSize int
 	for key, vals := range req.Header {
+		if mux.joinHeaderValues && len(vals) > 1 {
+			vals = []string{strings.Join(vals, ", ")}
+		}
 		for _, val := range vals {
 			// For backwards-compatibility, pass through 'authorization' header with no prefix.
 			if strings.ToLower(key) == "authorization" {
 				pairs = append(pairs, "authorization", val)
 			}
 			if h, ok := mux.incomingHeaderMatcher(key); ok {
+				forwarded++
+				forwardedSize += len(h) + len(val)
+				if mux.maxMetadataHeaders > 0 && forwarded > mux.maxMetadataHeaders {
+					return nil, status.Errorf(codes.InvalidArgument, "too many headers: more than %d forwarded", mux.maxMetadataHeaders)
+				}
+				if mux.maxMetadataSize > 0 && forwardedSize > mux.maxMetadataSize {
+					return nil, status.Errorf(codes.InvalidArgument, "headers too large: more than %d bytes forwarded", mux.maxMetadataSize)
+				}
+				if isBinHeader(h) {
+					b, err := decodeBinHeader(val)
+					if err != nil {
+						return nil, status.Errorf(codes.InvalidArgument, "invalid binary header %s: %v", key, err)
+					}
+					val = string(b)
+				}
 				pairs = append(pairs, h, val)
 			}
 		}
 	}
+	if mux.requestIDHeader != "" {
+		id := requestIDForRequest(mux, req)
+		ctx = context.WithValue(ctx, requestIDKey{}, id)
+		pairs = append(pairs, strings.ToLower(mux.requestIDHeader), id)
+	}
+	pairs = append(pairs, traceMetadata(ctx)...)
+	pairs = append(pairs, routeMetadata(req)...)
+	pairs = append(pairs, clientCertMetadata(mux, req)...)
+	if mux.acceptLanguageKey != "" {
+		if lang, ok := preferredLanguage(req); ok {
+			pairs = append(pairs, mux.acceptLanguageKey, lang)
+		}
+	}
 	if host := req.Header.Get(xForwardedHost); host != "" {
 		pairs = append(pairs, strings.ToLower(xForwardedHost), host)
 	} else if req.Host != "" {
@@ -87,11 +124,54 @@ func AnnotateContext(ctx context.Context, mux *ServeMux, req *http.Request) (con
 	if timeout != 0 {
 		ctx, _ = context.WithTimeout(ctx, timeout)
 	}
+	if mux.shutdownCtx != nil {
+		ctx = withShutdown(ctx, mux.shutdownCtx)
+	}
+	if opts := callOptionsForRequest(ctx, mux, req); len(opts) > 0 {
+		ctx = context.WithValue(ctx, callOptionsKey{}, opts)
+	}
+	if len(mux.queryDefaults) > 0 {
+		ctx = context.WithValue(ctx, queryDefaultsKey{}, mux.queryDefaults)
+	}
+	if mux.strictQueryParams != nil {
+		ctx = context.WithValue(ctx, strictQueryParamsKey{}, mux.strictQueryParams)
+	}
+	if mux.querySeparator != 0 {
+		ctx = context.WithValue(ctx, querySeparatorKey{}, mux.querySeparator)
+	}
+	if mux.queryBools != nil {
+		ctx = context.WithValue(ctx, queryBoolsKey{}, mux.queryBools)
+	}
+	if mux.maxClientStreamMessages > 0 {
+		ctx = context.WithValue(ctx, maxClientStreamMessagesKey{}, mux.maxClientStreamMessages)
+	}
+	if mux.serverTiming {
+		ctx = context.WithValue(ctx, serverTimingKey{}, new(serverTiming))
+	}
+	ctx = withRetry(ctx, mux, req)
+	if len(mux.unaryInterceptors) > 0 {
+		ctx = context.WithValue(ctx, unaryInterceptorsKey{}, mux.unaryInterceptors)
+	}
+	if len(mux.requestTransformers) > 0 {
+		ctx = context.WithValue(ctx, requestTransformersKey{}, mux.requestTransformers)
+	}
+	if mux.mergePatch {
+		ctx = context.WithValue(ctx, mergePatchKey{}, true)
+	}
+	if mux.validateRequests {
+		ctx = context.WithValue(ctx, requestValidationKey{}, true)
+	}
+	if mux.validateOnly && isValidateOnlyRequest(req) {
+		ctx = context.WithValue(ctx, validateOnlyKey{}, true)
+	}
 	if len(pairs) == 0 {
 		return ctx, nil
 	}
 	md := metadata.Pairs(pairs...)
 	if mux.metadataAnnotator != nil {
+		// Let the annotator know which format the response will be written in.
+		_, outbound := MarshalerForRequest(mux, req)
+		ctx = context.WithValue(ctx, outboundMarshalerKey{}, outbound)
 		md = metadata.Join(md, mux.metadataAnnotator(ctx, req))
 	}
 	return metadata.NewOutgoingContext(ctx, md), nil
@@ -116,6 +196,110 @@ func ServerMetadataFromContext(ctx context.Context) (md ServerMetadata, ok bool)
 	return
 }
 
+// MetadataFromResponseHeader returns the header metadata forwarded to "header" with the default
+// outgoing header matcher, i.e. the values of the headers prefixed with MetadataHeaderPrefix.
+// The values of binary keys, which end in "-bin", are decoded from base64 as in gRPC; a value
+// which is not valid base64 is kept as is.
+func MetadataFromResponseHeader(header http.Header) metadata.MD {
+	md := metadata.MD{}
+	for k, vs := range header {
+		k = textproto.CanonicalMIMEHeaderKey(k)
+		if !strings.HasPrefix(k, MetadataHeaderPrefix) || len(k) == len(MetadataHeaderPrefix) {
+			continue
+		}
+		key := strings.ToLower(k[len(MetadataHeaderPrefix):])
+		for _, v := range vs {
+			if isBinHeader(key) {
+				if b, err := decodeBinHeader(v); err == nil {
+					v = string(b)
+				}
+			}
+			md[key] = append(md[key], v)
+		}
+	}
+	return md
+}
+
+// isBinHeader reports whether "key" is a binary metadata key, whose values are base64-encoded in HTTP headers.
+func isBinHeader(key string) bool {
+	return strings.HasSuffix(strings.ToLower(key), "-bin")
+}
+
+// encodeBinHeader encodes the value of a binary header as gRPC does.
+func encodeBinHeader(v string) string {
+	return base64.RawStdEncoding.EncodeToString([]byte(v))
+}
+
+// decodeBinHeader decodes the value of a binary header, which may or may not be padded.
+func decodeBinHeader(v string) ([]byte, error) {
+	if len(v)%4 == 0 {
+		return base64.StdEncoding.DecodeString(v)
+	}
+	return base64.RawStdEncoding.DecodeString(v)
+}
+
+type callOptionsKey struct{}
+
+// CallOptionsFromContext returns the grpc.CallOptions configured on the ServeMux for the request being handled in ctx.
+func CallOptionsFromContext(ctx context.Context) []grpc.CallOption {
+	opts, _ := ctx.Value(callOptionsKey{}).([]grpc.CallOption)
+	// Cap the slice so that callers appending to it never share its backing array.
+	return opts[:len(opts):len(opts)]
+}
+
+func callOptionsForRequest(ctx context.Context, mux *ServeMux, req *http.Request) []grpc.CallOption {
+	var opts []grpc.CallOption
+	opts = append(opts, mux.callOptions...)
+	if mux.callOptionsFunc != nil {
+		opts = append(opts, mux.callOptionsFunc(ctx, req)...)
+	}
+	return opts
+}
+
+type outboundMarshalerKey struct{}
+
+// OutboundMarshalerFromContext returns the outbound Marshaler negotiated for the request.
+// It is available to metadata annotators given to WithMetadata.
+func OutboundMarshalerFromContext(ctx context.Context) (m Marshaler, ok bool) {
+	m, ok = ctx.Value(outboundMarshalerKey{}).(Marshaler)
+	return
+}
+
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the request ID in ctx, as configured by WithRequestIDHeader.
+func RequestIDFromContext(ctx context.Context) (id string, ok bool) {
+	id, ok = ctx.Value(requestIDKey{}).(string)
+	return
+}
+
+// newRequestIDContext returns a context carrying the request ID of req, or ctx itself
+// if the mux does not correlate requests.
+func newRequestIDContext(ctx context.Context, mux *ServeMux, req *http.Request) context.Context {
+	if mux.requestIDHeader == "" {
+		return ctx
+	}
+	if _, ok := RequestIDFromContext(ctx); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, requestIDForRequest(mux, req))
+}
+
+// requestIDForRequest returns the request ID carried by req. If there is none, a new ID is
+// generated and stored back into the request header so that later stages observe the same ID.
+func requestIDForRequest(mux *ServeMux, req *http.Request) string {
+	if id := req.Header.Get(mux.requestIDHeader); id != "" {
+		return id
+	}
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		grpclog.Printf("Failed to generate request id: %v", err)
+	}
+	id := hex.EncodeToString(b[:])
+	req.Header.Set(mux.requestIDHeader, id)
+	return id
+}
+
 func timeoutDecode(s string) (time.Duration, error) {
 	size := len(s)
 	if size < 2 {
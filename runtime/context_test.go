@@ -1,13 +1,25 @@
 package runtime_test
 
 import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"reflect"
 	"testing"
 	"time"
 
+	pb "github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -68,6 +80,28 @@ func TestAnnotateContext_ForwardsGrpcMetadata(t *testing.T) {
 	}
 }
 
+func TestAnnotateContext_JoinedHeaderValues(t *testing.T) {
+	ctx := context.Background()
+	request, err := http.NewRequest("GET", "http://www.example.com", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(%q, %q, nil) failed with %v; want success", "GET", "http://www.example.com", err)
+	}
+	request.Header.Add("Grpc-Metadata-FooBar", "Value1")
+	request.Header.Add("Grpc-Metadata-Foo-BAZ", "Value2")
+	request.Header.Add("Grpc-Metadata-foo-bAz", "Value3")
+	annotated, err := runtime.AnnotateContext(ctx, runtime.NewServeMux(runtime.WithJoinedHeaderValues()), request)
+	if err != nil {
+		t.Fatalf("runtime.AnnotateContext(ctx, %#v) failed with %v; want success", request, err)
+	}
+	md, _ := metadata.FromOutgoingContext(annotated)
+	if got, want := md["foobar"], []string{"Value1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf(`md["foobar"] = %q; want %q`, got, want)
+	}
+	if got, want := md["foo-baz"], []string{"Value2, Value3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf(`md["foo-baz"] = %q; want %q`, got, want)
+	}
+}
+
 func TestAnnotateContext_XForwardedFor(t *testing.T) {
 	ctx := context.Background()
 	request, err := http.NewRequest("GET", "http://bar.foo.example.com", nil)
@@ -170,3 +204,236 @@ func TestAnnotateContext_SupportsTimeouts(t *testing.T) {
 		}
 	}
 }
+
+func TestAnnotateContext_CallOptions(t *testing.T) {
+	ctx := context.Background()
+	request, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf(`http.NewRequest("GET", "http://example.com", nil failed with %v; want success`, err)
+	}
+	annotated, err := runtime.AnnotateContext(ctx, runtime.NewServeMux(), request)
+	if err != nil {
+		t.Fatalf("runtime.AnnotateContext(ctx, %#v) failed with %v; want success", request, err)
+	}
+	if got := runtime.CallOptionsFromContext(annotated); len(got) != 0 {
+		t.Errorf("runtime.CallOptionsFromContext(annotated) = %v; want empty", got)
+	}
+
+	var called bool
+	mux := runtime.NewServeMux(
+		runtime.WithCallOptions(grpc.MaxCallRecvMsgSize(1<<24)),
+		runtime.WithCallOptionsFunc(func(_ context.Context, req *http.Request) []grpc.CallOption {
+			called = req == request
+			return []grpc.CallOption{grpc.FailFast(false)}
+		}),
+	)
+	annotated, err = runtime.AnnotateContext(ctx, mux, request)
+	if err != nil {
+		t.Fatalf("runtime.AnnotateContext(ctx, %#v) failed with %v; want success", request, err)
+	}
+	if !called {
+		t.Errorf("call options func was not called with the request")
+	}
+	opts := runtime.CallOptionsFromContext(annotated)
+	if got, want := len(opts), 2; got != want {
+		t.Fatalf("len(runtime.CallOptionsFromContext(annotated)) = %d; want %d", got, want)
+	}
+	if got, want := cap(opts), 2; got != want {
+		t.Errorf("cap(runtime.CallOptionsFromContext(annotated)) = %d; want %d", got, want)
+	}
+}
+
+func TestAnnotateContext_OutboundMarshalerForAnnotator(t *testing.T) {
+	ctx := context.Background()
+	request, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf(`http.NewRequest("GET", "http://example.com", nil failed with %v; want success`, err)
+	}
+	request.Header.Set("Accept", "application/octet-stream")
+
+	var contentType string
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption("application/octet-stream", &runtime.ProtoMarshaller{}),
+		runtime.WithMetadata(func(ctx context.Context, _ *http.Request) metadata.MD {
+			m, ok := runtime.OutboundMarshalerFromContext(ctx)
+			if !ok {
+				t.Errorf("runtime.OutboundMarshalerFromContext(ctx) = _, false; want _, true")
+				return nil
+			}
+			contentType = m.ContentType()
+			return metadata.Pairs("response-format", contentType)
+		}),
+	)
+	annotated, err := runtime.AnnotateContext(ctx, mux, request)
+	if err != nil {
+		t.Fatalf("runtime.AnnotateContext(ctx, %#v) failed with %v; want success", request, err)
+	}
+	if got, want := contentType, "application/octet-stream"; got != want {
+		t.Errorf("content type seen by annotator = %q; want %q", got, want)
+	}
+	md, _ := metadata.FromOutgoingContext(annotated)
+	if got, want := md["response-format"], []string{"application/octet-stream"}; !reflect.DeepEqual(got, want) {
+		t.Errorf(`md["response-format"] = %q; want %q`, got, want)
+	}
+}
+
+func TestMetadataFromResponseHeader(t *testing.T) {
+	md := metadata.Pairs(
+		"foo", "bar",
+		"foo", "baz",
+		"trace-bin", "\x00\x01\xfe",
+	)
+	ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{HeaderMD: md})
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf(`http.NewRequest("GET", "http://example.com", nil) failed with %v; want success`, err)
+	}
+	w := httptest.NewRecorder()
+	runtime.ForwardResponseMessage(ctx, runtime.NewServeMux(), &runtime.JSONPb{}, w, req, &pb.SimpleMessage{Id: "foo"})
+	if got, want := w.Header().Get("Grpc-Metadata-Trace-Bin"), base64.RawStdEncoding.EncodeToString([]byte("\x00\x01\xfe")); got != want {
+		t.Errorf(`w.Header().Get("Grpc-Metadata-Trace-Bin") = %q; want %q`, got, want)
+	}
+	w.Header().Set("Grpc-Metadata-Raw-Bin", "not base64!")
+	w.Header().Set("X-Unrelated", "qux")
+
+	got := runtime.MetadataFromResponseHeader(w.Header())
+	want := metadata.Pairs(
+		"foo", "bar",
+		"foo", "baz",
+		"trace-bin", "\x00\x01\xfe",
+		"raw-bin", "not base64!",
+	)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("runtime.MetadataFromResponseHeader(%v) = %q; want %q", w.Header(), got, want)
+	}
+}
+
+func TestAnnotateContext_BinaryHeaders(t *testing.T) {
+	for _, spec := range []struct {
+		val     string
+		want    string
+		wantErr bool
+	}{
+		{val: base64.StdEncoding.EncodeToString([]byte("\x00\x01\xfe\xff")), want: "\x00\x01\xfe\xff"},
+		{val: base64.RawStdEncoding.EncodeToString([]byte("\x00\x01\xfe\xff")), want: "\x00\x01\xfe\xff"},
+		{val: "not base64!", wantErr: true},
+	} {
+		request, err := http.NewRequest("GET", "http://example.com", nil)
+		if err != nil {
+			t.Fatalf(`http.NewRequest("GET", "http://example.com", nil) failed with %v; want success`, err)
+		}
+		request.Header.Set("Grpc-Metadata-Trace-Bin", spec.val)
+		annotated, err := runtime.AnnotateContext(context.Background(), runtime.NewServeMux(), request)
+		if spec.wantErr {
+			if err == nil {
+				t.Errorf("runtime.AnnotateContext(ctx, mux, req) succeeded with %q; want failure", spec.val)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("runtime.AnnotateContext(ctx, mux, req) failed with %v; want success; value = %q", err, spec.val)
+			continue
+		}
+		md, _ := metadata.FromOutgoingContext(annotated)
+		if got, want := md["trace-bin"], []string{spec.want}; !reflect.DeepEqual(got, want) {
+			t.Errorf(`md["trace-bin"] = %q; want %q; value = %q`, got, want, spec.val)
+		}
+	}
+}
+
+func TestAnnotateContext_MaxIncomingMetadata(t *testing.T) {
+	for _, spec := range []struct {
+		count, size int
+		wantErr     bool
+	}{
+		{count: 0, size: 0},
+		{count: 2, size: 0},
+		{count: 1, size: 0, wantErr: true},
+		{count: 0, size: 22},
+		{count: 0, size: 21, wantErr: true},
+	} {
+		request, err := http.NewRequest("GET", "http://example.com", nil)
+		if err != nil {
+			t.Fatalf(`http.NewRequest("GET", "http://example.com", nil) failed with %v; want success`, err)
+		}
+		request.Header.Set("Grpc-Metadata-Foo", "12345678")
+		request.Header.Set("Grpc-Metadata-Bar", "abcdefgh")
+		// Headers dropped by the incoming header matcher are not accounted.
+		request.Header.Set("X-Unforwarded", "very long value which is not forwarded")
+		mux := runtime.NewServeMux(runtime.WithMaxIncomingMetadata(spec.count, spec.size))
+		_, err = runtime.AnnotateContext(context.Background(), mux, request)
+		if spec.wantErr {
+			if got, want := grpc.Code(err), codes.InvalidArgument; got != want {
+				t.Errorf("grpc.Code(err) = %v; want %v; count = %d, size = %d", got, want, spec.count, spec.size)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("runtime.AnnotateContext(ctx, mux, req) failed with %v; want success; count = %d, size = %d", err, spec.count, spec.size)
+		}
+	}
+}
+
+func TestAnnotateContext_ClientCertMetadata(t *testing.T) {
+	uri, err := url.Parse("spiffe://example.com/foo")
+	if err != nil {
+		t.Fatalf("url.Parse failed with %v; want success", err)
+	}
+	cert := &x509.Certificate{
+		Raw:            []byte("certificate"),
+		Subject:        pkix.Name{CommonName: "foo", Organization: []string{"Example"}},
+		DNSNames:       []string{"foo.example.com"},
+		EmailAddresses: []string{"foo@example.com"},
+		IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+		URIs:           []*url.URL{uri},
+	}
+	sum := sha256.Sum256(cert.Raw)
+	mux := runtime.NewServeMux(runtime.WithClientCertMetadata(runtime.ClientCertCommonName, runtime.ClientCertSubject, runtime.ClientCertSANs, runtime.ClientCertFingerprint))
+	keys := []string{runtime.MetadataClientCertCommonName, runtime.MetadataClientCertSubject, runtime.MetadataClientCertSAN, runtime.MetadataClientCertFingerprint}
+	for _, spec := range []struct {
+		name string
+		tls  *tls.ConnectionState
+		want metadata.MD
+	}{
+		{
+			name: "client certificate",
+			tls:  &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+			want: metadata.MD{
+				runtime.MetadataClientCertCommonName:  {"foo"},
+				runtime.MetadataClientCertSubject:     {"CN=foo,O=Example"},
+				runtime.MetadataClientCertSAN:         {"DNS:foo.example.com", "email:foo@example.com", "IP:10.0.0.1", "URI:spiffe://example.com/foo"},
+				runtime.MetadataClientCertFingerprint: {hex.EncodeToString(sum[:])},
+			},
+		},
+		{
+			name: "no client certificate",
+			tls:  &tls.ConnectionState{},
+			want: metadata.MD{},
+		},
+		{
+			name: "no TLS",
+			want: metadata.MD{},
+		},
+	} {
+		request, err := http.NewRequest("GET", "https://www.example.com", nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest(%q, %q, nil) failed with %v; want success", "GET", "https://www.example.com", err)
+		}
+		request.TLS = spec.tls
+		annotated, err := runtime.AnnotateContext(context.Background(), mux, request)
+		if err != nil {
+			t.Errorf("%s: runtime.AnnotateContext(ctx, %#v) failed with %v; want success", spec.name, request, err)
+			continue
+		}
+		md, _ := metadata.FromOutgoingContext(annotated)
+		got := metadata.MD{}
+		for _, key := range keys {
+			if vals, ok := md[key]; ok {
+				got[key] = vals
+			}
+		}
+		if !reflect.DeepEqual(got, spec.want) {
+			t.Errorf("%s: client certificate metadata = %q; want %q", spec.name, got, spec.want)
+		}
+	}
+}
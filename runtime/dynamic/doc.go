@@ -0,0 +1,8 @@
+/*
+Package dynamic serves gRPC methods which are only known from their descriptors at runtime, e.g. in a generic
+proxy, with a runtime.ServeMux and dynamic messages instead of the handlers generated by protoc-gen-grpc-gateway.
+
+Unlike package runtime, it depends on google.golang.org/protobuf, for its descriptor registries and dynamic
+messages, so that only the servers which import it pull that module in.
+*/
+package dynamic
@@ -0,0 +1,103 @@
+package dynamic
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// JSONPb is a runtime.Marshaler which marshals/unmarshals messages, including the dynamic messages of
+// Types, into/from JSON with "google.golang.org/protobuf/encoding/protojson". The types of
+// google.protobuf.Any fields and extensions are resolved with Types, so that those only known from
+// descriptors at runtime are supported. Other values, e.g. the bodies of errors, are marshaled as in
+// runtime.JSONPb, whose options apply to messages as well.
+type JSONPb struct {
+	runtime.JSONPb
+	// Types resolves the types of google.protobuf.Any fields and extensions.
+	// The types linked into the binary are used if it is nil.
+	Types *Types
+}
+
+// Marshal marshals "v" into JSON.
+func (j *JSONPb) Marshal(v interface{}) ([]byte, error) {
+	if m, ok := v.(protoreflect.ProtoMessage); ok {
+		opts := protojson.MarshalOptions{
+			Indent:          j.Indent,
+			UseProtoNames:   j.OrigName,
+			UseEnumNumbers:  j.EnumsAsInts,
+			EmitUnpopulated: j.EmitDefaults,
+			Resolver:        j.resolver(),
+		}
+		return opts.Marshal(m)
+	}
+
+	// Marshal maps of messages, e.g. stream chunks, value by value.
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map {
+		return j.JSONPb.Marshal(v)
+	}
+	m := make(map[string]*json.RawMessage)
+	for _, k := range rv.MapKeys() {
+		buf, err := j.Marshal(rv.MapIndex(k).Interface())
+		if err != nil {
+			return nil, err
+		}
+		m[fmt.Sprintf("%v", k.Interface())] = (*json.RawMessage)(&buf)
+	}
+	if j.Indent != "" {
+		return json.MarshalIndent(m, "", j.Indent)
+	}
+	return json.Marshal(m)
+}
+
+// Unmarshal unmarshals JSON "data" into "v".
+func (j *JSONPb) Unmarshal(data []byte, v interface{}) error {
+	if m, ok := v.(protoreflect.ProtoMessage); ok {
+		opts := protojson.UnmarshalOptions{Resolver: j.resolver()}
+		return opts.Unmarshal(data, m)
+	}
+	return j.JSONPb.Unmarshal(data, v)
+}
+
+// NewDecoder returns a Decoder which reads JSON stream from "r".
+func (j *JSONPb) NewDecoder(r io.Reader) runtime.Decoder {
+	d := json.NewDecoder(r)
+	return runtime.DecoderFunc(func(v interface{}) error {
+		var raw json.RawMessage
+		if err := d.Decode(&raw); err != nil {
+			return err
+		}
+		return j.Unmarshal(raw, v)
+	})
+}
+
+// NewEncoder returns an Encoder which writes JSON stream into "w".
+func (j *JSONPb) NewEncoder(w io.Writer) runtime.Encoder {
+	return runtime.EncoderFunc(func(v interface{}) error {
+		buf, err := j.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(buf)
+		return err
+	})
+}
+
+// dynamicResolver resolves the types of google.protobuf.Any fields and extensions.
+type dynamicResolver interface {
+	protoregistry.MessageTypeResolver
+	protoregistry.ExtensionTypeResolver
+}
+
+func (j *JSONPb) resolver() dynamicResolver {
+	if j.Types == nil {
+		return protoregistry.GlobalTypes
+	}
+	return j.Types.types
+}
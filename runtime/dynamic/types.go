@@ -0,0 +1,220 @@
+package dynamic
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Types resolves the message types and methods of services which are only known from their descriptors
+// at runtime, e.g. in a generic proxy, so that a runtime.ServeMux can serve them with dynamic messages instead of
+// generated Go types. See UnaryHandler and JSONPb.
+type Types struct {
+	files *protoregistry.Files
+	types *dynamicpb.Types
+}
+
+// NewTypes returns the Types of the files in "set", which must include their imports,
+// e.g. as written by protoc with --descriptor_set_out and --include_imports.
+func NewTypes(set *descriptorpb.FileDescriptorSet) (*Types, error) {
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, err
+	}
+	return &Types{files: files, types: dynamicpb.NewTypes(files)}, nil
+}
+
+// NewMessage returns a new empty dynamic message of the type whose fully-qualified name is "name", e.g. "foo.v1.Bar".
+func (t *Types) NewMessage(name string) (proto.Message, error) {
+	mt, err := t.types.FindMessageByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, err
+	}
+	return dynamicpb.NewMessage(mt.Descriptor()), nil
+}
+
+// method returns the descriptor of the method named by "fullMethod", e.g. "/foo.v1.Bars/GetBar".
+func (t *Types) method(fullMethod string) (protoreflect.MethodDescriptor, error) {
+	name := strings.Replace(strings.TrimPrefix(fullMethod, "/"), "/", ".", 1)
+	d, err := t.files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, err
+	}
+	md, ok := d.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a method", name)
+	}
+	return md, nil
+}
+
+// UnaryHandler returns a runtime.HandlerFunc which serves the unary method "fullMethod", e.g. "/foo.v1.Bars/GetBar",
+// of "conn" with dynamic messages, as a generated handler would. Register it with mux.Handle.
+//
+// The body of the request, if any, is decoded into the whole request message by the inbound marshaler, which
+// must support dynamic messages, e.g. JSONPb. Path parameters and query parameters are then set into the
+// fields at their dot-separated paths of proto field names. Query parameters of unknown fields are ignored.
+func (t *Types) UnaryHandler(mux *runtime.ServeMux, conn *grpc.ClientConn, fullMethod string) (runtime.HandlerFunc, error) {
+	md, err := t.method(fullMethod)
+	if err != nil {
+		return nil, err
+	}
+	if md.IsStreamingClient() || md.IsStreamingServer() {
+		return nil, fmt.Errorf("%s is a streaming method", fullMethod)
+	}
+	return func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+		inboundMarshaler, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
+		rctx, err := runtime.AnnotateContext(ctx, mux, req)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+
+		var metadata runtime.ServerMetadata
+		resp, err := invokeDynamic(rctx, conn, fullMethod, md, inboundMarshaler, req, pathParams, &metadata)
+		ctx = runtime.NewServerMetadataContext(ctx, metadata)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+		runtime.ForwardResponseMessage(ctx, mux, outboundMarshaler, w, req, resp, mux.GetForwardResponseOptions()...)
+	}, nil
+}
+
+func invokeDynamic(ctx context.Context, conn *grpc.ClientConn, fullMethod string, md protoreflect.MethodDescriptor, marshaler runtime.Marshaler, req *http.Request, pathParams map[string]string, metadata *runtime.ServerMetadata) (proto.Message, error) {
+	in := dynamicpb.NewMessage(md.Input())
+	if req.Body != nil && req.ContentLength != 0 {
+		if err := marshaler.NewDecoder(req.Body).Decode(in); err != nil && err != io.EOF {
+			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+	}
+	for param, val := range pathParams {
+		if err := populateDynamicField(in, strings.Split(param, "."), []string{val}, false); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", param, err)
+		}
+	}
+	for key, values := range req.URL.Query() {
+		if _, ok := pathParams[key]; ok {
+			continue
+		}
+		if err := populateDynamicField(in, strings.Split(key, "."), values, true); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+	}
+	return runtime.InvokeUnary(ctx, fullMethod, in, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		out := dynamicpb.NewMessage(md.Output())
+		opts := append(runtime.CallOptionsFromContext(ctx), grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+		if err := conn.Invoke(ctx, fullMethod, req, out, opts...); err != nil {
+			return nil, err
+		}
+		return out, nil
+	})
+}
+
+// populateDynamicField sets "values" into the field at "fieldPath" of the dynamic message "msg", allocating the
+// messages along the path. A repeated field gets all the values, other fields the first one. If "ignoreUnknown"
+// is true, a path which does not name a field is ignored.
+func populateDynamicField(msg *dynamicpb.Message, fieldPath []string, values []string, ignoreUnknown bool) error {
+	var m protoreflect.Message = msg
+	for i, name := range fieldPath {
+		fields := m.Descriptor().Fields()
+		fd := fields.ByName(protoreflect.Name(name))
+		if fd == nil {
+			fd = fields.ByJSONName(name)
+		}
+		if fd == nil {
+			if ignoreUnknown {
+				return nil
+			}
+			return fmt.Errorf("no field %s in %s", strings.Join(fieldPath, "."), msg.Descriptor().FullName())
+		}
+		if i < len(fieldPath)-1 {
+			if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+				return fmt.Errorf("non-aggregate type in the mid of path: %s", strings.Join(fieldPath, "."))
+			}
+			m = m.Mutable(fd).Message()
+			continue
+		}
+
+		if fd.IsMap() || fd.Message() != nil {
+			return fmt.Errorf("unsupported field type of %s", strings.Join(fieldPath, "."))
+		}
+		if len(values) == 0 {
+			return fmt.Errorf("no value of field: %s", strings.Join(fieldPath, "."))
+		}
+		if !fd.IsList() {
+			v, err := dynamicScalar(fd, values[0])
+			if err != nil {
+				return err
+			}
+			m.Set(fd, v)
+			return nil
+		}
+		list := m.Mutable(fd).List()
+		for _, val := range values {
+			v, err := dynamicScalar(fd, val)
+			if err != nil {
+				return err
+			}
+			list.Append(v)
+		}
+	}
+	return nil
+}
+
+// dynamicScalar parses "val" into a value of the scalar or enum field "fd".
+func dynamicScalar(fd protoreflect.FieldDescriptor, val string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		b, err := runtime.QueryBool(val)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		i, err := strconv.ParseInt(val, 10, 32)
+		return protoreflect.ValueOfInt32(int32(i)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		i, err := strconv.ParseInt(val, 10, 64)
+		return protoreflect.ValueOfInt64(i), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		u, err := strconv.ParseUint(val, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(u)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		u, err := strconv.ParseUint(val, 10, 64)
+		return protoreflect.ValueOfUint64(u), err
+	case protoreflect.FloatKind:
+		f, err := strconv.ParseFloat(val, 32)
+		return protoreflect.ValueOfFloat32(float32(f)), err
+	case protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(val, 64)
+		return protoreflect.ValueOfFloat64(f), err
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(val), nil
+	case protoreflect.BytesKind:
+		b, err := runtime.Bytes(val)
+		return protoreflect.ValueOfBytes(b), err
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(val)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		n, err := strconv.ParseInt(val, 10, 32)
+		if err != nil || fd.Enum().Values().ByNumber(protoreflect.EnumNumber(n)) == nil {
+			return protoreflect.Value{}, fmt.Errorf("%s is not a valid %s", val, fd.Enum().FullName())
+		}
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), nil
+	}
+	return protoreflect.Value{}, fmt.Errorf("unsupported field type %s", fd.Kind())
+}
@@ -0,0 +1,167 @@
+package dynamic_test
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime/dynamic"
+	"github.com/grpc-ecosystem/grpc-gateway/utilities"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	protov2 "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// dynamicFileSet describes a service which is only known from its descriptors.
+func dynamicFileSet() *descriptorpb.FileDescriptorSet {
+	field := func(name string, num int32, typ descriptorpb.FieldDescriptorProto_Type, label descriptorpb.FieldDescriptorProto_Label, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     protov2.String(name),
+			JsonName: protov2.String(strings.Replace(name, "_s", "S", 1)),
+			Number:   protov2.Int32(num),
+			Type:     typ.Enum(),
+			Label:    label.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = protov2.String(typeName)
+		}
+		return f
+	}
+	const (
+		optional = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+		repeated = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	)
+	return &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:    protov2.String("dyn/v1/things.proto"),
+		Package: protov2.String("dyn.v1"),
+		Syntax:  protov2.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: protov2.String("Nested"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("flag", 1, descriptorpb.FieldDescriptorProto_TYPE_BOOL, optional, ""),
+				},
+			},
+			{
+				Name: protov2.String("GetThingRequest"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional, ""),
+					field("page_size", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, optional, ""),
+					field("tags", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING, repeated, ""),
+					field("nested", 4, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, optional, ".dyn.v1.Nested"),
+				},
+			},
+			{
+				Name: protov2.String("Thing"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional, ""),
+					field("page_size", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, optional, ""),
+					field("tags", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING, repeated, ""),
+					field("flag", 4, descriptorpb.FieldDescriptorProto_TYPE_BOOL, optional, ""),
+				},
+			},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: protov2.String("Things"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       protov2.String("GetThing"),
+				InputType:  protov2.String(".dyn.v1.GetThingRequest"),
+				OutputType: protov2.String(".dyn.v1.Thing"),
+			}},
+		}},
+	}}}
+}
+
+func TestTypesUnaryHandler(t *testing.T) {
+	types, err := dynamic.NewTypes(dynamicFileSet())
+	if err != nil {
+		t.Fatalf("dynamic.NewTypes(set) failed with %v; want success", err)
+	}
+
+	// The backend copies the request into a Thing.
+	server := grpc.NewServer(grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		req, err := types.NewMessage("dyn.v1.GetThingRequest")
+		if err != nil {
+			return err
+		}
+		if err := stream.RecvMsg(req); err != nil {
+			return err
+		}
+		resp, err := types.NewMessage("dyn.v1.Thing")
+		if err != nil {
+			return err
+		}
+		in, out := req.(*dynamicpb.Message), resp.(*dynamicpb.Message)
+		for _, name := range []protoreflect.Name{"name", "page_size"} {
+			out.Set(out.Descriptor().Fields().ByName(name), in.Get(in.Descriptor().Fields().ByName(name)))
+		}
+		tags := in.Get(in.Descriptor().Fields().ByName("tags")).List()
+		outTags := out.Mutable(out.Descriptor().Fields().ByName("tags")).List()
+		for i := 0; i < tags.Len(); i++ {
+			outTags.Append(tags.Get(i))
+		}
+		nested := in.Get(in.Descriptor().Fields().ByName("nested")).Message()
+		out.Set(out.Descriptor().Fields().ByName("flag"), nested.Get(nested.Descriptor().Fields().ByName("flag")))
+		if err := stream.SetHeader(metadata.Pairs("foo", "bar")); err != nil {
+			return err
+		}
+		return stream.SendMsg(out)
+	}))
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen(%q, %q) failed with %v; want success", "tcp", "127.0.0.1:0", err)
+	}
+	go server.Serve(lis)
+	defer server.Stop()
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc.Dial(%q) failed with %v; want success", lis.Addr(), err)
+	}
+	defer conn.Close()
+
+	mux := runtime.NewServeMux(runtime.WithMarshalerOption(runtime.MIMEWildcard, &dynamic.JSONPb{Types: types}))
+	h, err := types.UnaryHandler(mux, conn, "/dyn.v1.Things/GetThing")
+	if err != nil {
+		t.Fatalf("types.UnaryHandler(mux, conn, %q) failed with %v; want success", "/dyn.v1.Things/GetThing", err)
+	}
+	pat := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0, int(utilities.OpLitPush), 1, int(utilities.OpPush), 0, int(utilities.OpConcatN), 1, int(utilities.OpCapture), 2}, []string{"v1", "things", "name"}, ""))
+	mux.Handle("POST", pat, h)
+
+	req := httptest.NewRequest("POST", "http://example.com/v1/things/foo?tags=a&tags=b&unknown=x", strings.NewReader(`{"pageSize": 3, "nested": {"flag": true}}`))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Fatalf("w.Code = %d; want %d; body = %s", got, want, w.Body)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("json.Unmarshal(%s, &got) failed with %v; want success", w.Body, err)
+	}
+	want := map[string]interface{}{"name": "foo", "pageSize": 3.0, "tags": []interface{}{"a", "b"}, "flag": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("response = %v; want %v", got, want)
+	}
+	if got, want := w.Header().Get("Grpc-Metadata-Foo"), "bar"; got != want {
+		t.Errorf("Grpc-Metadata-Foo = %q; want %q", got, want)
+	}
+
+	req = httptest.NewRequest("POST", "http://example.com/v1/things/foo?page_size=x", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if got, want := w.Code, http.StatusBadRequest; got != want {
+		t.Errorf("w.Code = %d; want %d; body = %s", got, want, w.Body)
+	}
+
+	if _, err := types.UnaryHandler(mux, conn, "/dyn.v1.Things/NoSuchMethod"); err == nil {
+		t.Errorf("types.UnaryHandler(mux, conn, %q) succeeded; want an error", "/dyn.v1.Things/NoSuchMethod")
+	}
+}
@@ -0,0 +1,28 @@
+package runtime
+
+import (
+	"net/http"
+)
+
+// WithEchoedHeaders returns a ServeMuxOption which copies the request headers named in "headers",
+// e.g. "X-Correlation-Id", verbatim into the responses of ForwardResponseMessage, ForwardResponseStream
+// and the error handlers of this package. Headers absent from the request are not set.
+func WithEchoedHeaders(headers ...string) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		for _, h := range headers {
+			serveMux.echoedHeaders = append(serveMux.echoedHeaders, http.CanonicalHeaderKey(h))
+		}
+	}
+}
+
+// handleEchoedHeaders copies the headers given to WithEchoedHeaders from "r" into the response.
+func handleEchoedHeaders(w http.ResponseWriter, mux *ServeMux, r *http.Request) {
+	if mux == nil || r == nil {
+		return
+	}
+	for _, h := range mux.echoedHeaders {
+		if vs, ok := r.Header[h]; ok {
+			w.Header()[h] = append([]string(nil), vs...)
+		}
+	}
+}
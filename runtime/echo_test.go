@@ -0,0 +1,58 @@
+package runtime_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	pb "github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestEchoedHeaders(t *testing.T) {
+	mux := runtime.NewServeMux(runtime.WithEchoedHeaders("x-correlation-id", "X-Absent"))
+	ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+	for _, spec := range []struct {
+		name    string
+		forward func(w *httptest.ResponseRecorder, req *http.Request)
+	}{
+		{
+			name: "ForwardResponseMessage",
+			forward: func(w *httptest.ResponseRecorder, req *http.Request) {
+				runtime.ForwardResponseMessage(ctx, mux, &runtime.JSONPb{}, w, req, &pb.SimpleMessage{Id: "foo"})
+			},
+		},
+		{
+			name: "DefaultHTTPError",
+			forward: func(w *httptest.ResponseRecorder, req *http.Request) {
+				runtime.DefaultHTTPError(ctx, mux, &runtime.JSONPb{}, w, req, status.Error(codes.NotFound, "not found"))
+			},
+		},
+		{
+			name: "DefaultHTTPProtoErrorHandler",
+			forward: func(w *httptest.ResponseRecorder, req *http.Request) {
+				runtime.DefaultHTTPProtoErrorHandler(ctx, mux, &runtime.JSONPb{}, w, req, status.Error(codes.NotFound, "not found"))
+			},
+		},
+	} {
+		req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+		req.Header.Add("X-Correlation-Id", "abc")
+		req.Header.Add("X-Correlation-Id", "def")
+		req.Header.Set("X-Other", "ignored")
+		w := httptest.NewRecorder()
+		spec.forward(w, req)
+
+		if got, want := w.Header()["X-Correlation-Id"], []string{"abc", "def"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: X-Correlation-Id = %q; want %q", spec.name, got, want)
+		}
+		for _, h := range []string{"X-Absent", "X-Other"} {
+			if got, ok := w.Header()[h]; ok {
+				t.Errorf("%s: %s = %q; want no header", spec.name, h, got)
+			}
+		}
+	}
+}
@@ -54,6 +54,34 @@ func HTTPStatusFromCode(code codes.Code) int {
 	return http.StatusInternalServerError
 }
 
+var codeNames = map[codes.Code]string{
+	codes.OK:                 "OK",
+	codes.Canceled:           "CANCELLED",
+	codes.Unknown:            "UNKNOWN",
+	codes.InvalidArgument:    "INVALID_ARGUMENT",
+	codes.DeadlineExceeded:   "DEADLINE_EXCEEDED",
+	codes.NotFound:           "NOT_FOUND",
+	codes.AlreadyExists:      "ALREADY_EXISTS",
+	codes.PermissionDenied:   "PERMISSION_DENIED",
+	codes.Unauthenticated:    "UNAUTHENTICATED",
+	codes.ResourceExhausted:  "RESOURCE_EXHAUSTED",
+	codes.FailedPrecondition: "FAILED_PRECONDITION",
+	codes.Aborted:            "ABORTED",
+	codes.OutOfRange:         "OUT_OF_RANGE",
+	codes.Unimplemented:      "UNIMPLEMENTED",
+	codes.Internal:           "INTERNAL",
+	codes.Unavailable:        "UNAVAILABLE",
+	codes.DataLoss:           "DATA_LOSS",
+}
+
+// CodeName returns the canonical name of a gRPC error code as in google.rpc.Code, e.g. "NOT_FOUND".
+func CodeName(code codes.Code) string {
+	if name, ok := codeNames[code]; ok {
+		return name
+	}
+	return "UNKNOWN"
+}
+
 var (
 	// HTTPError replies to the request with the error.
 	// You can set a custom function to this variable to customize error format.
@@ -66,6 +94,8 @@ type errorBody struct {
 	Error   string          `protobuf:"bytes,1,name=error" json:"error"`
 	Code    int32           `protobuf:"varint,2,name=code" json:"code"`
 	Details []proto.Message `protobuf:"bytes,3,name=details" json:"details"`
+	// Status is the canonical name of Code, e.g. "NOT_FOUND".
+	Status string `protobuf:"bytes,4,name=status" json:"status"`
 }
 
 // Make this also conform to proto.Message for builtin JSONPb Marshaler
@@ -79,22 +109,32 @@ func (*errorBody) ProtoMessage()    {}
 //
 // The response body returned by this function is a JSON object,
 // which contains a member whose key is "error" and whose value is err.Error().
-func DefaultHTTPError(ctx context.Context, mux *ServeMux, marshaler Marshaler, w http.ResponseWriter, _ *http.Request, err error) {
+// The gRPC code is given both as a number in "code" and by name in "status".
+// Responses to codes.Unauthenticated errors carry the challenge given to WithAuthenticateChallenge.
+// The request headers given to WithEchoedHeaders are copied into the response.
+// The message is rewritten by the function given to WithErrorMessageSanitizer, if any.
+func DefaultHTTPError(ctx context.Context, mux *ServeMux, marshaler Marshaler, w http.ResponseWriter, r *http.Request, err error) {
 	const (
 		fallback     = `{"error": "failed to marshal error message"}`
 		fallbackType = `application/json`
 	)
 
 	w.Header().Del("Trailer")
+	handleEchoedHeaders(w, mux, r)
 
 	s, ok := status.FromError(err)
 	if !ok {
 		s = status.New(codes.Unknown, err.Error())
 	}
+	if handleEmptyResponseOnNotFound(ctx, mux, marshaler, w, r, s) {
+		return
+	}
+	s = sanitizeErrorMessage(ctx, mux, s)
 
 	body := &errorBody{
-		Error: s.Message(),
-		Code:  int32(s.Code()),
+		Error:  s.Message(),
+		Code:   int32(s.Code()),
+		Status: CodeName(s.Code()),
 	}
 
 	for _, detail := range s.Details() {
@@ -107,6 +147,7 @@ func DefaultHTTPError(ctx context.Context, mux *ServeMux, marshaler Marshaler, w
 	if merr != nil {
 		grpclog.Printf("Failed to marshal error message %q: %v", body, merr)
 		w.Header().Set("Content-Type", fallbackType)
+		observeError(ctx, mux, err, s.Code(), http.StatusInternalServerError)
 		w.WriteHeader(http.StatusInternalServerError)
 		if _, err := io.WriteString(w, fallback); err != nil {
 			grpclog.Printf("Failed to write response: %v", err)
@@ -121,14 +162,94 @@ func DefaultHTTPError(ctx context.Context, mux *ServeMux, marshaler Marshaler, w
 	}
 
 	handleForwardResponseServerMetadata(w, mux, md)
-	handleForwardResponseTrailerHeader(w, md)
+	handleForwardResponseTrailerHeader(w, mux, md)
+	handleStatusTrailerHeader(w, mux)
+	handleAuthenticateChallenge(w, mux, s.Code())
 	st := HTTPStatusFromCode(s.Code())
+	observeError(ctx, mux, err, s.Code(), st)
 	w.WriteHeader(st)
 	if _, err := w.Write(buf); err != nil {
 		grpclog.Printf("Failed to write response: %v", err)
 	}
 
-	handleForwardResponseTrailer(w, md)
+	handleForwardResponseTrailer(w, mux, md)
+	handleStatusTrailer(w, mux, s.Code(), s.Message())
+}
+
+// observeError notifies the observer given to WithErrorObserver, if any, of an error response.
+func observeError(ctx context.Context, mux *ServeMux, err error, code codes.Code, httpStatus int) {
+	if mux != nil && mux.errorObserver != nil {
+		mux.errorObserver(ctx, err, code, httpStatus)
+	}
+}
+
+// sanitizeErrorMessage returns "s" with its message rewritten by the function given to WithErrorMessageSanitizer, if any.
+func sanitizeErrorMessage(ctx context.Context, mux *ServeMux, s *status.Status) *status.Status {
+	if mux == nil || mux.errorMessageSanitizer == nil {
+		return s
+	}
+	msg := mux.errorMessageSanitizer(ctx, s.Code(), s.Message())
+	if msg == s.Message() {
+		return s
+	}
+	pb := s.Proto()
+	pb.Message = msg
+	return status.FromProto(pb)
+}
+
+// sanitizeError returns "err" with its message rewritten by the function given to WithErrorMessageSanitizer,
+// if any, for the errors which are not written by the error handlers, e.g. those of response streams.
+func sanitizeError(ctx context.Context, mux *ServeMux, err error) error {
+	if mux == nil || mux.errorMessageSanitizer == nil {
+		return err
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		s = status.New(codes.Unknown, err.Error())
+	}
+	if sanitized := sanitizeErrorMessage(ctx, mux, s); sanitized != s {
+		return sanitized.Err()
+	}
+	return err
+}
+
+// handleAuthenticateChallenge sets the WWW-Authenticate header given to WithAuthenticateChallenge
+// on responses to codes.Unauthenticated errors, unless the backend sent one in its header metadata.
+func handleAuthenticateChallenge(w http.ResponseWriter, mux *ServeMux, code codes.Code) {
+	if mux == nil || mux.authenticateChallenge == "" || code != codes.Unauthenticated {
+		return
+	}
+	if w.Header().Get("WWW-Authenticate") == "" {
+		w.Header().Set("WWW-Authenticate", mux.authenticateChallenge)
+	}
+}
+
+// handleEmptyResponseOnNotFound replies with an empty response instead of "s" if it is a codes.NotFound
+// status for a route configured with WithEmptyResponseOnNotFound. It reports whether it replied.
+func handleEmptyResponseOnNotFound(ctx context.Context, mux *ServeMux, marshaler Marshaler, w http.ResponseWriter, r *http.Request, s *status.Status) bool {
+	if s.Code() != codes.NotFound {
+		return false
+	}
+	resp, ok := mux.emptyResponseOnNotFound(r)
+	if !ok {
+		return false
+	}
+	buf, err := marshaler.Marshal(resp)
+	if err != nil {
+		grpclog.Printf("Failed to marshal empty response %q: %v", resp, err)
+		return false
+	}
+
+	md, ok := ServerMetadataFromContext(ctx)
+	if !ok {
+		grpclog.Printf("Failed to extract ServerMetadata from context")
+	}
+	handleForwardResponseServerMetadata(w, mux, md)
+	w.Header().Set("Content-Type", marshaler.ContentType())
+	if _, err := w.Write(buf); err != nil {
+		grpclog.Printf("Failed to write response: %v", err)
+	}
+	return true
 }
 
 // DefaultOtherErrorHandler is the default implementation of OtherErrorHandler.
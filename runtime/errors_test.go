@@ -6,12 +6,17 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/golang/protobuf/proto"
+	pb "github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/utilities"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
@@ -52,18 +57,21 @@ func TestDefaultHTTPError(t *testing.T) {
 		err       error
 		status    int
 		msg       string
+		code      string
 		marshaler runtime.Marshaler
 	}{
 		{
 			err:       fmt.Errorf("example error"),
 			status:    http.StatusInternalServerError,
 			msg:       "example error",
+			code:      "UNKNOWN",
 			marshaler: &runtime.JSONBuiltin{},
 		},
 		{
 			err:       status.Error(codes.NotFound, "no such resource"),
 			status:    http.StatusNotFound,
 			msg:       "no such resource",
+			code:      "NOT_FOUND",
 			marshaler: &runtime.JSONBuiltin{},
 		},
 		{
@@ -99,5 +107,161 @@ func TestDefaultHTTPError(t *testing.T) {
 		if got, want := body["error"].(string), spec.msg; !strings.Contains(got, want) {
 			t.Errorf(`body["error"] = %q; want %q; on spec.err=%v`, got, want, spec.err)
 		}
+		if spec.code == "" {
+			continue
+		}
+		if got, want := body["status"], spec.code; got != want {
+			t.Errorf(`body["status"] = %v; want %q; on spec.err=%v`, got, want, spec.err)
+		}
+	}
+}
+
+func TestDefaultHTTPErrorEmptyResponseOnNotFound(t *testing.T) {
+	pat := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0}, []string{"items"}, ""))
+	mux := runtime.NewServeMux(runtime.WithEmptyResponseOnNotFound("GET", pat, func() proto.Message {
+		return new(pb.ABitOfEverything)
+	}))
+	ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+	marshaler := &runtime.JSONPb{OrigName: true, EmitDefaults: true}
+
+	for _, spec := range []struct {
+		method, path string
+		err          error
+		status       int
+	}{
+		{method: "GET", path: "/items", err: status.Error(codes.NotFound, "no items"), status: http.StatusOK},
+		{method: "GET", path: "/items", err: status.Error(codes.Internal, "broken"), status: http.StatusInternalServerError},
+		{method: "GET", path: "/other", err: status.Error(codes.NotFound, "no items"), status: http.StatusNotFound},
+		{method: "POST", path: "/items", err: status.Error(codes.NotFound, "no items"), status: http.StatusNotFound},
+	} {
+		req := httptest.NewRequest(spec.method, "http://example.com"+spec.path, nil)
+		w := httptest.NewRecorder()
+		runtime.DefaultHTTPError(ctx, mux, marshaler, w, req, spec.err)
+
+		if got, want := w.Code, spec.status; got != want {
+			t.Errorf("w.Code = %d; want %d; on %s %s with %v", got, want, spec.method, spec.path, spec.err)
+		}
+		if spec.status != http.StatusOK {
+			continue
+		}
+		if got, want := w.Body.String(), `"nested":[]`; !strings.Contains(got, want) {
+			t.Errorf("w.Body = %q; want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestDefaultHTTPErrorObserver(t *testing.T) {
+	type observation struct {
+		err    error
+		code   codes.Code
+		status int
+	}
+	var observed []observation
+	mux := runtime.NewServeMux(runtime.WithErrorObserver(func(_ context.Context, err error, code codes.Code, httpStatus int) {
+		observed = append(observed, observation{err: err, code: code, status: httpStatus})
+	}))
+	ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+
+	errs := []error{
+		status.Error(codes.NotFound, "not found"),
+		errors.New("example error"),
+	}
+	for _, err := range errs {
+		runtime.DefaultHTTPError(ctx, mux, &runtime.JSONPb{}, httptest.NewRecorder(), req, err)
+	}
+
+	want := []observation{
+		{err: errs[0], code: codes.NotFound, status: http.StatusNotFound},
+		{err: errs[1], code: codes.Unknown, status: http.StatusInternalServerError},
+	}
+	if !reflect.DeepEqual(observed, want) {
+		t.Errorf("observed = %v; want %v", observed, want)
+	}
+}
+
+func TestDefaultHTTPErrorMessageSanitizer(t *testing.T) {
+	var observed []error
+	mux := runtime.NewServeMux(
+		runtime.WithErrorMessageSanitizer(func(_ context.Context, code codes.Code, msg string) string {
+			if code == codes.Internal {
+				return "internal error"
+			}
+			return msg
+		}),
+		runtime.WithErrorObserver(func(_ context.Context, err error, _ codes.Code, _ int) {
+			observed = append(observed, err)
+		}),
+	)
+	ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+
+	for _, spec := range []struct {
+		err  error
+		want string
+	}{
+		{err: status.Error(codes.Internal, "dial tcp 10.0.0.1:5432: connection refused"), want: "internal error"},
+		{err: status.Error(codes.NotFound, "not found"), want: "not found"},
+	} {
+		w := httptest.NewRecorder()
+		runtime.DefaultHTTPError(ctx, mux, &runtime.JSONPb{}, w, req, spec.err)
+
+		var body map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("json.Unmarshal(%q, &body) failed with %v; want success", w.Body.Bytes(), err)
+		}
+		if got := body["error"]; got != spec.want {
+			t.Errorf("body[\"error\"] = %q; want %q", got, spec.want)
+		}
+	}
+	if got, want := status.Convert(observed[0]).Message(), "dial tcp 10.0.0.1:5432: connection refused"; got != want {
+		t.Errorf("observed message = %q; want %q", got, want)
+	}
+}
+
+func TestDefaultHTTPErrorAuthenticateChallenge(t *testing.T) {
+	const challenge = `Basic realm="example"`
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	for _, spec := range []struct {
+		opts []runtime.ServeMuxOption
+		md   runtime.ServerMetadata
+		err  error
+		want string
+	}{
+		{
+			err: status.Error(codes.Unauthenticated, "no credentials"),
+		},
+		{
+			opts: []runtime.ServeMuxOption{runtime.WithAuthenticateChallenge(challenge)},
+			err:  status.Error(codes.Unauthenticated, "no credentials"),
+			want: challenge,
+		},
+		{
+			opts: []runtime.ServeMuxOption{runtime.WithAuthenticateChallenge(challenge)},
+			err:  status.Error(codes.PermissionDenied, "forbidden"),
+		},
+		{
+			opts: []runtime.ServeMuxOption{
+				runtime.WithAuthenticateChallenge(challenge),
+				runtime.WithOutgoingHeaderMatcher(func(key string) (string, bool) { return key, true }),
+			},
+			md:   runtime.ServerMetadata{HeaderMD: metadata.Pairs("www-authenticate", `Bearer realm="backend"`)},
+			err:  status.Error(codes.Unauthenticated, "no credentials"),
+			want: `Bearer realm="backend"`,
+		},
+	} {
+		ctx := runtime.NewServerMetadataContext(context.Background(), spec.md)
+		mux := runtime.NewServeMux(spec.opts...)
+		w := httptest.NewRecorder()
+		runtime.DefaultHTTPError(ctx, mux, &runtime.JSONPb{}, w, req, spec.err)
+		if got := w.Header().Get("WWW-Authenticate"); got != spec.want {
+			t.Errorf("WWW-Authenticate = %q; want %q; err = %v", got, spec.want, spec.err)
+		}
+
+		w = httptest.NewRecorder()
+		runtime.DefaultHTTPProtoErrorHandler(ctx, mux, &runtime.JSONPb{}, w, req, spec.err)
+		if got := w.Header().Get("WWW-Authenticate"); got != spec.want {
+			t.Errorf("WWW-Authenticate from the proto error handler = %q; want %q; err = %v", got, spec.want, spec.err)
+		}
 	}
 }
@@ -0,0 +1,129 @@
+package runtime
+
+import (
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/status"
+)
+
+// ForwardFullDuplexStream serves a bidirectional streaming call over a single HTTP/2 request.
+// Request messages are decoded from the body of "req" with "inboundMarshaler" and sent to the stream
+// as soon as they arrive, while the response messages are concurrently forwarded to "w" as in ForwardResponseStream.
+//
+// "newStream" opens the gRPC stream with a context which is canceled when either direction fails or the
+// response is complete. "newRequest" and "newResponse" must return empty request and response messages.
+// "ctx" should be annotated with AnnotateContext beforehand. The body of "req" is closed, and no longer read,
+// once the function returns.
+//
+// Note that net/http only supports reading the request body while writing the response on HTTP/2 connections.
+func ForwardFullDuplexStream(ctx context.Context, mux *ServeMux, inboundMarshaler, outboundMarshaler Marshaler, w http.ResponseWriter, req *http.Request, newStream func(context.Context) (grpc.ClientStream, error), newRequest, newResponse func() proto.Message, opts ...func(context.Context, http.ResponseWriter, proto.Message) error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := newStream(ctx)
+	if err != nil {
+		grpclog.Printf("Failed to start streaming: %v", err)
+		HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+		return
+	}
+
+	s := &fullDuplexSender{stream: stream, cancel: cancel, done: make(chan struct{})}
+	go s.run(LimitClientStream(ctx, inboundMarshaler.NewDecoder(req.Body), nil), newRequest)
+	defer s.stop(req.Body)
+
+	header, err := stream.Header()
+	if err != nil {
+		grpclog.Printf("Failed to get header from client: %v", err)
+		HTTPError(ctx, mux, outboundMarshaler, w, req, s.cause(err))
+		return
+	}
+	ctx = NewServerMetadataContext(ctx, ServerMetadata{HeaderMD: header})
+
+	recv := func() (proto.Message, error) {
+		msg := newResponse()
+		if err := stream.RecvMsg(msg); err == io.EOF {
+			return nil, err
+		} else if err != nil {
+			return nil, s.cause(err)
+		}
+		return msg, nil
+	}
+	ForwardResponseStream(ctx, mux, outboundMarshaler, w, req, recv, opts...)
+}
+
+// fullDuplexSender sends the request messages of a full-duplex stream.
+type fullDuplexSender struct {
+	stream grpc.ClientStream
+	cancel context.CancelFunc
+	// done is closed once run returns.
+	done chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+func (s *fullDuplexSender) run(dec Decoder, newRequest func() proto.Message) {
+	defer close(s.done)
+	for {
+		msg := newRequest()
+		err := dec.Decode(msg)
+		if err == io.EOF {
+			break
+		}
+		if err == ErrClientStreamLimitExceeded {
+			s.fail(err)
+			return
+		}
+		if err != nil {
+			grpclog.Printf("Failed to decode request: %v", err)
+			s.fail(status.Errorf(codes.InvalidArgument, "%v", err))
+			return
+		}
+		if err := s.stream.SendMsg(msg); err != nil {
+			if err != io.EOF {
+				// io.EOF means that the stream was terminated by the server,
+				// whose status is reported to the receiving side.
+				grpclog.Printf("Failed to send request: %v", err)
+			}
+			return
+		}
+	}
+	if err := s.stream.CloseSend(); err != nil {
+		grpclog.Printf("Failed to terminate client stream: %v", err)
+	}
+}
+
+// stop cancels the call and waits for run to return, closing "body" to interrupt a pending read,
+// so that the request body is not read anymore once the handler returned.
+func (s *fullDuplexSender) stop(body io.Closer) {
+	s.cancel()
+	if err := body.Close(); err != nil {
+		grpclog.Printf("Failed to close request body: %v", err)
+	}
+	<-s.done
+}
+
+// fail records "err" as the cause of the termination of the call and cancels it.
+func (s *fullDuplexSender) fail(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+	s.cancel()
+}
+
+// cause returns the error which made the sender cancel the call, if any, or "err" otherwise.
+func (s *fullDuplexSender) cause(err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	return err
+}
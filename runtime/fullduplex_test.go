@@ -0,0 +1,165 @@
+package runtime_test
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// echoClientStream is a grpc.ClientStream which echoes the messages sent to it.
+type echoClientStream struct {
+	grpc.ClientStream
+	ctx  context.Context
+	msgs chan proto.Message
+	// echoed receives the messages once they are received back.
+	echoed chan proto.Message
+}
+
+func newEchoClientStream(ctx context.Context) *echoClientStream {
+	return &echoClientStream{
+		ctx:    ctx,
+		msgs:   make(chan proto.Message, 10),
+		echoed: make(chan proto.Message, 10),
+	}
+}
+
+func (s *echoClientStream) Header() (metadata.MD, error) { return metadata.MD{}, nil }
+
+func (s *echoClientStream) SendMsg(m interface{}) error {
+	s.msgs <- proto.Clone(m.(proto.Message))
+	return nil
+}
+
+func (s *echoClientStream) CloseSend() error {
+	close(s.msgs)
+	return nil
+}
+
+func (s *echoClientStream) RecvMsg(m interface{}) error {
+	select {
+	case msg, ok := <-s.msgs:
+		if !ok {
+			return io.EOF
+		}
+		proto.Merge(m.(proto.Message), msg)
+		s.echoed <- msg
+		return nil
+	case <-s.ctx.Done():
+		return status.Error(codes.Canceled, s.ctx.Err().Error())
+	}
+}
+
+func forwardFullDuplexStream(body io.Reader, onStream func(*echoClientStream)) *httptest.ResponseRecorder {
+	ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+	req := httptest.NewRequest("POST", "http://example.com/foo", body)
+	w := httptest.NewRecorder()
+	newStream := func(ctx context.Context) (grpc.ClientStream, error) {
+		s := newEchoClientStream(ctx)
+		if onStream != nil {
+			go onStream(s)
+		}
+		return s, nil
+	}
+	newMsg := func() proto.Message { return new(pb.SimpleMessage) }
+	marshaler := &runtime.JSONPb{}
+	runtime.ForwardFullDuplexStream(ctx, runtime.NewServeMux(), marshaler, marshaler, w, req, newStream, newMsg, newMsg)
+	return w
+}
+
+func TestForwardFullDuplexStream(t *testing.T) {
+	w := forwardFullDuplexStream(strings.NewReader(`{"id":"a"}{"id":"b"}`), nil)
+
+	if got, want := w.Body.String(), "{\"result\":{\"id\":\"a\"}}\n{\"result\":{\"id\":\"b\"}}\n"; got != want {
+		t.Errorf("w.Body = %q; want %q", got, want)
+	}
+}
+
+func TestForwardFullDuplexStreamConcurrent(t *testing.T) {
+	r, pw := io.Pipe()
+	// Send the second message only once the first one was echoed,
+	// which requires the request to be read while the response is written.
+	w := forwardFullDuplexStream(r, func(s *echoClientStream) {
+		io.WriteString(pw, `{"id":"a"}`)
+		<-s.echoed
+		io.WriteString(pw, `{"id":"b"}`)
+		pw.Close()
+	})
+
+	if got, want := w.Body.String(), "{\"result\":{\"id\":\"a\"}}\n{\"result\":{\"id\":\"b\"}}\n"; got != want {
+		t.Errorf("w.Body = %q; want %q", got, want)
+	}
+}
+
+func TestForwardFullDuplexStreamDecodeError(t *testing.T) {
+	r, pw := io.Pipe()
+	w := forwardFullDuplexStream(r, func(s *echoClientStream) {
+		io.WriteString(pw, `{"id":"a"}`)
+		<-s.echoed
+		io.WriteString(pw, `{"id":`)
+		pw.Close()
+	})
+
+	body := w.Body.String()
+	if want := "{\"result\":{\"id\":\"a\"}}\n"; !strings.HasPrefix(body, want) {
+		t.Errorf("w.Body = %q; want prefix %q", body, want)
+	}
+	if want := "\"grpcCode\":3"; !strings.Contains(body, want) {
+		t.Errorf("w.Body = %q; want an error with %q", body, want)
+	}
+}
+
+// blockingBody is a request body whose reads block until it is closed.
+type blockingBody struct {
+	closed chan struct{}
+	// unblocked is closed once a read returned.
+	unblocked chan struct{}
+}
+
+func (b *blockingBody) Read(p []byte) (int, error) {
+	<-b.closed
+	close(b.unblocked)
+	return 0, io.ErrClosedPipe
+}
+
+func (b *blockingBody) Close() error {
+	close(b.closed)
+	return nil
+}
+
+// failingClientStream is a grpc.ClientStream which fails to receive messages.
+type failingClientStream struct {
+	*echoClientStream
+}
+
+func (s failingClientStream) RecvMsg(m interface{}) error {
+	return status.Error(codes.Unavailable, "unavailable")
+}
+
+func TestForwardFullDuplexStreamWaitsForSender(t *testing.T) {
+	body := &blockingBody{closed: make(chan struct{}), unblocked: make(chan struct{})}
+	ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+	req := httptest.NewRequest("POST", "http://example.com/foo", body)
+	newStream := func(ctx context.Context) (grpc.ClientStream, error) {
+		return failingClientStream{newEchoClientStream(ctx)}, nil
+	}
+	newMsg := func() proto.Message { return new(pb.SimpleMessage) }
+	marshaler := &runtime.JSONPb{}
+	runtime.ForwardFullDuplexStream(ctx, runtime.NewServeMux(), marshaler, marshaler, httptest.NewRecorder(), req, newStream, newMsg, newMsg)
+
+	// The pending read of the request body has returned before the handler.
+	select {
+	case <-body.unblocked:
+	default:
+		t.Errorf("ForwardFullDuplexStream returned while reading the request body")
+	}
+}
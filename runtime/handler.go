@@ -1,38 +1,58 @@
 package runtime
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"net/http"
 	"net/textproto"
+	"sync"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/grpc-ecosystem/grpc-gateway/runtime/internal"
 	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/api/httpbody"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/grpclog"
 	"google.golang.org/grpc/status"
 )
 
 // ForwardResponseStream forwards the stream from gRPC server to REST client.
+// If the ServerMetadata in ctx carries header metadata, the response headers are flushed before
+// waiting for the first message. Otherwise they are deferred so that an error received first is
+// still reported with the corresponding HTTP status.
+// Each message is written and flushed to the client as soon as it is received, unless WithStreamCoalescing is given.
+// A Marshaler stored in ctx with WithMarshalerContext takes precedence over "marshaler".
+// The Content-Type of the response is the StreamContentType of "marshaler" if it is a StreamContentTyper.
+// With ProtoMarshaller, each message is written as is, preceded by its length encoded as a varint,
+// instead of in a delimited chunk. See NewProtoStreamDecoder.
+// Other messages are encoded directly into the response if "marshaler" is a StreamMarshaler or a JSONPb.
 func ForwardResponseStream(ctx context.Context, mux *ServeMux, marshaler Marshaler, w http.ResponseWriter, req *http.Request, recv func() (proto.Message, error), opts ...func(context.Context, http.ResponseWriter, proto.Message) error) {
+	ctx = newRequestIDContext(ctx, mux, req)
+	if m, ok := MarshalerFromContext(ctx); ok {
+		marshaler = m
+	}
 	f, ok := w.(http.Flusher)
 	if !ok {
-		grpclog.Printf("Flush not supported in %T", w)
+		logf(ctx, "Flush not supported in %T", w)
 		http.Error(w, "unexpected type of web server", http.StatusInternalServerError)
 		return
 	}
 
 	md, ok := ServerMetadataFromContext(ctx)
 	if !ok {
-		grpclog.Printf("Failed to extract ServerMetadata from context")
+		logf(ctx, "Failed to extract ServerMetadata from context")
 		http.Error(w, "unexpected error", http.StatusInternalServerError)
 		return
 	}
+	handleRouteHeaders(w, req)
 	handleForwardResponseServerMetadata(w, mux, md)
+	handleForwardResponseRequestID(ctx, w, mux)
+	handleEchoedHeaders(w, mux, req)
 
 	w.Header().Set("Transfer-Encoding", "chunked")
-	w.Header().Set("Content-Type", marshaler.ContentType())
+	w.Header().Set("Content-Type", streamContentType(marshaler))
 	if err := handleForwardResponseOptions(ctx, w, nil, opts); err != nil {
 		HTTPError(ctx, mux, marshaler, w, req, err)
 		return
@@ -45,95 +65,263 @@ func ForwardResponseStream(ctx context.Context, mux *ServeMux, marshaler Marshal
 		delimiter = []byte("\n")
 	}
 
+	if mux.maxStreamMessages > 0 {
+		recv = limitStreamMessages(recv, mux.maxStreamMessages)
+	}
+	if mux.shutdownCtx != nil {
+		recv = shutdownStreamError(recv, mux.shutdownCtx)
+	}
+
 	var wroteHeader bool
+	if len(md.HeaderMD) > 0 {
+		// The backend has sent its initial metadata, so the stream is established.
+		// Let the client see the headers without waiting for the first message.
+		w.WriteHeader(http.StatusOK)
+		f.Flush()
+		wroteHeader = true
+	}
+
+	sw := newStreamWriter(w, f, mux.streamCoalesceBytes, mux.streamCoalesceDelay)
+	defer sw.Close()
+
+	if mux.streamFraming {
+		forwardFramedResponseStream(ctx, mux, marshaler, w, sw, recv, opts)
+		return
+	}
+	if _, ok := marshaler.(*ProtoMarshaller); ok {
+		forwardProtoResponseStream(ctx, mux, marshaler, w, req, sw, recv, wroteHeader, opts)
+		return
+	}
+
+	hb := startStreamHeartbeat(sw, mux.streamHeartbeatInterval, mux.streamHeartbeatData)
+	defer hb.stop()
+	recv = hb.wrap(recv)
+
+	cw := &chunkWriter{w: sw, marshaler: marshaler}
+	enc := newStreamChunkEncoder(marshaler, cw)
+	var sentMessage bool
 	for {
 		resp, err := recv()
 		if err == io.EOF {
+			if !sentMessage && mux.emptyStreamBody != nil {
+				if _, err := sw.Write(mux.emptyStreamBody); err != nil {
+					logf(ctx, "Failed to send empty stream body: %v", err)
+				}
+			}
 			return
 		}
 		if err != nil {
-			handleForwardResponseStreamError(wroteHeader, marshaler, w, err)
+			sw.Close()
+			handleForwardResponseStreamError(ctx, mux, wroteHeader || hb.wroteHeader(), marshaler, w, err)
 			return
 		}
 		if err := handleForwardResponseOptions(ctx, w, resp, opts); err != nil {
-			handleForwardResponseStreamError(wroteHeader, marshaler, w, err)
+			sw.Close()
+			handleForwardResponseStreamError(ctx, mux, wroteHeader || hb.wroteHeader(), marshaler, w, err)
 			return
 		}
 
-		buf, err := marshaler.Marshal(streamChunk(resp, nil))
-		if err != nil {
-			grpclog.Printf("Failed to marshal response chunk: %v", err)
-			handleForwardResponseStreamError(wroteHeader, marshaler, w, err)
-			return
-		}
-		w.Header().Set("Content-Type", marshaler.ContentType())
-		if _, err = w.Write(buf); err != nil {
-			grpclog.Printf("Failed to send response chunk: %v", err)
+		cw.err = nil
+		if err := enc.Encode(streamChunk(ctx, mux, resp, nil)); err != nil {
+			if cw.err != nil {
+				logf(ctx, "Failed to send response chunk: %v", err)
+				return
+			}
+			logf(ctx, "Failed to marshal response chunk: %v", err)
+			sw.Close()
+			handleForwardResponseStreamError(ctx, mux, wroteHeader || hb.wroteHeader(), marshaler, w, err)
 			return
 		}
-		wroteHeader = true
-		if _, err = w.Write(delimiter); err != nil {
-			grpclog.Printf("Failed to send delimiter chunk: %v", err)
+		wroteHeader, sentMessage = true, true
+		if _, err = sw.Write(delimiter); err != nil {
+			logf(ctx, "Failed to send delimiter chunk: %v", err)
 			return
 		}
-		f.Flush()
+		sw.Flush()
 	}
 }
 
 func handleForwardResponseServerMetadata(w http.ResponseWriter, mux *ServeMux, md ServerMetadata) {
+	forwarded := make(http.Header)
 	for k, vs := range md.HeaderMD {
 		if h, ok := mux.outgoingHeaderMatcher(k); ok {
 			for _, v := range vs {
-				w.Header().Add(h, v)
+				forwarded.Add(h, metadataHeaderValue(k, v))
 			}
 		}
 	}
+	if mux.linkHeaderMetadataKey != "" {
+		for _, v := range md.HeaderMD[mux.linkHeaderMetadataKey] {
+			forwarded.Add("Link", v)
+		}
+	}
+	mergeForwardedHeaders(w, mux, forwarded)
+}
+
+func handleForwardResponseRequestID(ctx context.Context, w http.ResponseWriter, mux *ServeMux) {
+	if id, ok := RequestIDFromContext(ctx); ok {
+		w.Header().Set(mux.requestIDHeader, id)
+	}
 }
 
-func handleForwardResponseTrailerHeader(w http.ResponseWriter, md ServerMetadata) {
+func handleForwardResponseTrailerHeader(w http.ResponseWriter, mux *ServeMux, md ServerMetadata) {
+	if mux.outgoingTrailerMatcher != nil {
+		// The trailer metadata of unary calls is known before the response is written,
+		// so it can be sent in the response headers.
+		forwarded := make(http.Header)
+		for k, vs := range md.TrailerMD {
+			if h, ok := mux.outgoingTrailerMatcher(k); ok {
+				for _, v := range vs {
+					forwarded.Add(h, metadataHeaderValue(k, v))
+				}
+			}
+		}
+		mergeForwardedHeaders(w, mux, forwarded)
+		return
+	}
 	for k := range md.TrailerMD {
 		tKey := textproto.CanonicalMIMEHeaderKey(fmt.Sprintf("%s%s", MetadataTrailerPrefix, k))
 		w.Header().Add("Trailer", tKey)
 	}
 }
 
-func handleForwardResponseTrailer(w http.ResponseWriter, md ServerMetadata) {
+func handleForwardResponseTrailer(w http.ResponseWriter, mux *ServeMux, md ServerMetadata) {
+	if mux.outgoingTrailerMatcher != nil {
+		return
+	}
 	for k, vs := range md.TrailerMD {
 		tKey := fmt.Sprintf("%s%s", MetadataTrailerPrefix, k)
 		for _, v := range vs {
-			w.Header().Add(tKey, v)
+			w.Header().Add(tKey, metadataHeaderValue(k, v))
 		}
 	}
 }
 
+// metadataHeaderValue returns the value of the metadata "k" as it is forwarded in an HTTP header.
+// Values of binary keys are base64-encoded.
+func metadataHeaderValue(k, v string) string {
+	if isBinHeader(k) {
+		return encodeBinHeader(v)
+	}
+	return v
+}
+
 // ForwardResponseMessage forwards the message "resp" from gRPC server to REST client.
+// The content of a google.api.HttpBody response is written as is, honoring the Range header of GET requests.
+// A Marshaler stored in ctx with WithMarshalerContext takes precedence over "marshaler".
+// Forward response options may set the status of a successful response, e.g. 201 Created, with WriteHeader.
+// The status is written along with the body, and takes precedence over WithNoContentForEmpty.
+// A 304 Not Modified status, e.g. set by LastModifiedHeader, is written without a body.
+// Options given to WithForwardResponseBodyOption run once the body is marshaled, before anything is written.
 func ForwardResponseMessage(ctx context.Context, mux *ServeMux, marshaler Marshaler, w http.ResponseWriter, req *http.Request, resp proto.Message, opts ...func(context.Context, http.ResponseWriter, proto.Message) error) {
+	ctx = newRequestIDContext(ctx, mux, req)
+	if m, ok := MarshalerFromContext(ctx); ok {
+		marshaler = m
+	}
 	md, ok := ServerMetadataFromContext(ctx)
 	if !ok {
 		grpclog.Printf("Failed to extract ServerMetadata from context")
 	}
 
+	handleRouteHeaders(w, req)
 	handleForwardResponseServerMetadata(w, mux, md)
-	handleForwardResponseTrailerHeader(w, md)
-	w.Header().Set("Content-Type", marshaler.ContentType())
-	if err := handleForwardResponseOptions(ctx, w, resp, opts); err != nil {
+	handleForwardResponseRequestID(ctx, w, mux)
+	handleEchoedHeaders(w, mux, req)
+	handleForwardResponseTrailerHeader(w, mux, md)
+	handleStatusTrailerHeader(w, mux)
+	w.Header().Set("Content-Type", responseContentType(mux, req, marshaler))
+	resp = unwrapAnyResponse(mux, w, resp)
+	ctx = context.WithValue(ctx, httpMethodKey{}, req.Method)
+	ctx = context.WithValue(ctx, requestHeaderKey{}, req.Header)
+	sw := &deferredStatusWriter{ResponseWriter: w, status: http.StatusOK}
+	if err := handleForwardResponseOptions(ctx, sw, resp, opts); err != nil {
 		HTTPError(ctx, mux, marshaler, w, req, err)
 		return
 	}
+	if sw.status == http.StatusNotModified {
+		w.Header().Del("Content-Type")
+		w.Header().Del("Trailer")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if pref, ok := returnPreference(mux, req); ok {
+		w.Header().Set(PreferenceAppliedHeader, "return="+pref)
+		if pref == "minimal" {
+			handleServerTiming(ctx, w, 0, false)
+			w.Header().Del("Content-Type")
+			w.WriteHeader(sw.status)
+			handleForwardResponseTrailer(w, mux, md)
+			handleStatusTrailer(w, mux, codes.OK, "")
+			return
+		}
+	}
+
+	if body, ok := resp.(*httpbody.HttpBody); ok {
+		handleServerTiming(ctx, w, 0, false)
+		forwardHTTPBody(w, req, body, sw.status)
+		handleForwardResponseTrailer(w, mux, md)
+		handleStatusTrailer(w, mux, codes.OK, "")
+		return
+	}
 
+	start := time.Now()
 	buf, err := marshaler.Marshal(resp)
 	if err != nil {
 		grpclog.Printf("Marshal error: %v", err)
 		HTTPError(ctx, mux, marshaler, w, req, err)
 		return
 	}
+	if fields, ok := unknownFieldsFromRequest(req); ok {
+		buf = fields.appendTo(marshaler, resp, buf)
+	}
+	handleServerTiming(ctx, w, time.Since(start), true)
+
+	if mux.noContentForEmpty && sw.status == http.StatusOK && isEmptyResponse(resp, buf) {
+		w.Header().Del("Content-Type")
+		w.Header().Del("Trailer")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
 
-	w.Header().Set("Content-Type", marshaler.ContentType())
+	w.Header().Set("Content-Type", responseContentType(mux, req, marshaler))
+	for _, opt := range mux.responseBodyOptions {
+		if err := opt(ctx, sw, resp, buf); err != nil {
+			grpclog.Printf("Error handling ForwardResponseBodyOptions: %v", err)
+			HTTPError(ctx, mux, marshaler, w, req, err)
+			return
+		}
+	}
+	if sw.status != http.StatusOK {
+		w.WriteHeader(sw.status)
+	}
 	if _, err = w.Write(buf); err != nil {
 		grpclog.Printf("Failed to write response: %v", err)
 	}
 
-	handleForwardResponseTrailer(w, md)
+	handleForwardResponseTrailer(w, mux, md)
+	handleStatusTrailer(w, mux, codes.OK, "")
+}
+
+// deferredStatusWriter is the http.ResponseWriter given to the forward response options of unary responses.
+// It records the status they set so that it is written along with the response body, and not if the
+// response fails to marshal.
+type deferredStatusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *deferredStatusWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+// isEmptyResponse reports whether resp is a google.protobuf.Empty or marshals into a trivial body.
+func isEmptyResponse(resp proto.Message, buf []byte) bool {
+	if resp != nil && proto.MessageName(resp) == "google.protobuf.Empty" {
+		return true
+	}
+	buf = bytes.TrimSpace(buf)
+	return len(buf) == 0 || bytes.Equal(buf, []byte("{}"))
 }
 
 func handleForwardResponseOptions(ctx context.Context, w http.ResponseWriter, resp proto.Message, opts []func(context.Context, http.ResponseWriter, proto.Message) error) error {
@@ -149,21 +337,21 @@ func handleForwardResponseOptions(ctx context.Context, w http.ResponseWriter, re
 	return nil
 }
 
-func handleForwardResponseStreamError(wroteHeader bool, marshaler Marshaler, w http.ResponseWriter, err error) {
-	buf, merr := marshaler.Marshal(streamChunk(nil, err))
+func handleForwardResponseStreamError(ctx context.Context, mux *ServeMux, wroteHeader bool, marshaler Marshaler, w http.ResponseWriter, err error) {
+	buf, merr := marshaler.Marshal(streamChunk(ctx, mux, nil, err))
 	if merr != nil {
 		grpclog.Printf("Failed to marshal an error: %v", merr)
 		return
 	}
 	if !wroteHeader {
-		w.Header().Set("Content-Type", marshaler.ContentType())
+		w.Header().Set("Content-Type", streamContentType(marshaler))
 		s, ok := status.FromError(err)
 		if !ok {
 			s = status.New(codes.Unknown, err.Error())
 		}
 		w.WriteHeader(HTTPStatusFromCode(s.Code()))
 	}
-	if w.Header().Get("Content-Type") != marshaler.ContentType() {
+	if w.Header().Get("Content-Type") != streamContentType(marshaler) {
 		// Don't forward the error if client already started receiving a body of different type.
 		return
 	}
@@ -173,8 +361,169 @@ func handleForwardResponseStreamError(wroteHeader bool, marshaler Marshaler, w h
 	}
 }
 
-func streamChunk(result proto.Message, err error) map[string]proto.Message {
+// limitStreamMessages returns a recv function which fails with codes.ResourceExhausted
+// once "recv" returns more than "max" messages.
+func limitStreamMessages(recv func() (proto.Message, error), max int) func() (proto.Message, error) {
+	var count int
+	return func() (proto.Message, error) {
+		resp, err := recv()
+		if err != nil {
+			return resp, err
+		}
+		if count++; count > max {
+			return nil, status.Errorf(codes.ResourceExhausted, "stream exceeded the maximum of %d messages", max)
+		}
+		return resp, nil
+	}
+}
+
+// streamWriter writes the chunks of a streamed response.
+// If maxBytes is positive, chunks are coalesced into a buffer which is written once it holds
+// maxBytes or once maxDelay has passed since it started filling, whichever comes first.
+// Otherwise every chunk is written and flushed as is.
+type streamWriter struct {
+	w        http.ResponseWriter
+	f        http.Flusher
+	maxBytes int
+	maxDelay time.Duration
+
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	timer   *time.Timer
+	started bool
+	closed  bool
+	err     error
+}
+
+func newStreamWriter(w http.ResponseWriter, f http.Flusher, maxBytes int, maxDelay time.Duration) *streamWriter {
+	return &streamWriter{w: w, f: f, maxBytes: maxBytes, maxDelay: maxDelay}
+}
+
+// SetHeader sets a response header unless the response has already been started.
+func (s *streamWriter) SetHeader(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.started {
+		s.w.Header().Set(key, value)
+	}
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return 0, s.err
+	}
+	if s.maxBytes <= 0 {
+		s.started = true
+		return s.w.Write(p)
+	}
+	return s.buf.Write(p)
+}
+
+// Flush sends the written chunks to the client if they are due.
+func (s *streamWriter) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case s.maxBytes <= 0:
+		s.f.Flush()
+	case s.buf.Len() >= s.maxBytes:
+		s.flushLocked()
+	case s.timer == nil && s.maxDelay > 0 && s.buf.Len() > 0:
+		s.timer = time.AfterFunc(s.maxDelay, s.flushDelayed)
+	}
+}
+
+// Close sends any buffered chunks. The underlying writer is not used after Close returns.
+func (s *streamWriter) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.flushLocked()
+}
+
+func (s *streamWriter) flushDelayed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.flushLocked()
+	}
+}
+
+func (s *streamWriter) flushLocked() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if s.buf.Len() == 0 || s.err != nil {
+		return
+	}
+	s.started = true
+	if _, err := s.w.Write(s.buf.Bytes()); err != nil {
+		grpclog.Printf("Failed to send response chunks: %v", err)
+		s.err = err
+	}
+	s.buf.Reset()
+	s.f.Flush()
+}
+
+// newStreamChunkEncoder returns an Encoder which writes the stream chunks encoded by "marshaler" into "w",
+// directly if "marshaler" is a StreamMarshaler, or else as returned by its Marshal.
+// JSONPb and NDJSONMarshaler encode straight into "w", but types embedding them may override Marshal,
+// so only these exact types are encoded with their NewEncoder.
+func newStreamChunkEncoder(marshaler Marshaler, w io.Writer) Encoder {
+	switch m := marshaler.(type) {
+	case StreamMarshaler:
+		return m.NewStreamEncoder(w)
+	case *JSONPb:
+		return m.NewEncoder(w)
+	case *NDJSONMarshaler:
+		return m.NewEncoder(w)
+	}
+	return EncoderFunc(func(v interface{}) error {
+		buf, err := marshaler.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(buf)
+		return err
+	})
+}
+
+// chunkWriter writes the encoded stream chunks, setting the Content-Type of "marshaler" once a chunk is encoded
+// unless the response has already been started. It records the error of writing a chunk, to tell it from a
+// failure to encode it.
+type chunkWriter struct {
+	w         *streamWriter
+	marshaler Marshaler
+	err       error
+}
+
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	c.w.SetHeader("Content-Type", streamContentType(c.marshaler))
+	n, err := c.w.Write(p)
+	if err != nil {
+		c.err = err
+	}
+	return n, err
+}
+
+// logf logs a message prefixed with the request ID in ctx, if any.
+func logf(ctx context.Context, format string, args ...interface{}) {
+	if id, ok := RequestIDFromContext(ctx); ok {
+		format = "[%s] " + format
+		args = append([]interface{}{id}, args...)
+	}
+	grpclog.Printf(format, args...)
+}
+
+func streamChunk(ctx context.Context, mux *ServeMux, result proto.Message, err error) map[string]proto.Message {
 	if err != nil {
+		err = sanitizeError(ctx, mux, err)
 		grpcCode := codes.Unknown
 		if s, ok := status.FromError(err); ok {
 			grpcCode = s.Code()
@@ -190,7 +539,7 @@ func streamChunk(result proto.Message, err error) map[string]proto.Message {
 		}
 	}
 	if result == nil {
-		return streamChunk(nil, fmt.Errorf("empty response"))
+		return streamChunk(ctx, mux, nil, fmt.Errorf("empty response"))
 	}
 	return map[string]proto.Message{"result": result}
 }
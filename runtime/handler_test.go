@@ -1,19 +1,34 @@
 package runtime_test
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/golang/protobuf/ptypes/timestamp"
 	pb "github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
 	"github.com/grpc-ecosystem/grpc-gateway/runtime/internal"
+	"github.com/grpc-ecosystem/grpc-gateway/utilities"
 	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/api/httpbody"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 type errorStringMarshaller struct {
@@ -239,3 +254,1301 @@ func TestForwardResponseStreamCustomMarshaler(t *testing.T) {
 		})
 	}
 }
+
+func TestForwardResponseStreamRequestID(t *testing.T) {
+	msgs := []proto.Message{&pb.SimpleMessage{Id: "One"}, &pb.SimpleMessage{Id: "Two"}}
+	for _, spec := range []struct {
+		name  string
+		reqID string
+	}{
+		{name: "forwarded", reqID: "abc-123"},
+		{name: "generated"},
+	} {
+		t.Run(spec.name, func(t *testing.T) {
+			var count int
+			recv := func() (proto.Message, error) {
+				if count == len(msgs) {
+					return nil, io.EOF
+				}
+				count++
+				return msgs[count-1], nil
+			}
+			var seen []string
+			opt := func(ctx context.Context, w http.ResponseWriter, _ proto.Message) error {
+				id, ok := runtime.RequestIDFromContext(ctx)
+				if !ok {
+					t.Errorf("runtime.RequestIDFromContext(ctx) = _, false; want _, true")
+				}
+				seen = append(seen, id)
+				return nil
+			}
+
+			mux := runtime.NewServeMux(runtime.WithRequestIDHeader("X-Request-Id"))
+			ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+			req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+			if spec.reqID != "" {
+				req.Header.Set("X-Request-Id", spec.reqID)
+			}
+			resp := httptest.NewRecorder()
+
+			runtime.ForwardResponseStream(ctx, mux, &runtime.JSONPb{}, resp, req, recv, opt)
+
+			id := resp.Result().Header.Get("X-Request-Id")
+			if id == "" {
+				t.Fatalf("X-Request-Id header is missing")
+			}
+			if spec.reqID != "" && id != spec.reqID {
+				t.Errorf("X-Request-Id = %q; want %q", id, spec.reqID)
+			}
+			if got, want := len(seen), len(msgs)+1; got != want {
+				t.Fatalf("forward response option called %d times; want %d", got, want)
+			}
+			for i, s := range seen {
+				if s != id {
+					t.Errorf("seen[%d] = %q; want %q", i, s, id)
+				}
+			}
+		})
+	}
+}
+
+func TestForwardResponseMessageNoContent(t *testing.T) {
+	for _, spec := range []struct {
+		name   string
+		opts   []runtime.ServeMuxOption
+		resp   proto.Message
+		status int
+		body   string
+	}{
+		{
+			name:   "disabled",
+			resp:   &empty.Empty{},
+			status: http.StatusOK,
+			body:   "{}",
+		},
+		{
+			name:   "empty",
+			opts:   []runtime.ServeMuxOption{runtime.WithNoContentForEmpty()},
+			resp:   &empty.Empty{},
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "trivial",
+			opts:   []runtime.ServeMuxOption{runtime.WithNoContentForEmpty()},
+			resp:   &pb.SimpleMessage{},
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "non-empty",
+			opts:   []runtime.ServeMuxOption{runtime.WithNoContentForEmpty()},
+			resp:   &pb.SimpleMessage{Id: "foo"},
+			status: http.StatusOK,
+			body:   `{"id":"foo"}`,
+		},
+	} {
+		t.Run(spec.name, func(t *testing.T) {
+			ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+			req := httptest.NewRequest("DELETE", "http://example.com/foo", nil)
+			resp := httptest.NewRecorder()
+
+			runtime.ForwardResponseMessage(ctx, runtime.NewServeMux(spec.opts...), &runtime.JSONPb{}, resp, req, spec.resp)
+
+			if got, want := resp.Code, spec.status; got != want {
+				t.Errorf("resp.Code = %d; want %d", got, want)
+			}
+			if got, want := resp.Body.String(), spec.body; got != want {
+				t.Errorf("resp.Body = %q; want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestForwardResponseMessageStatusFromOption(t *testing.T) {
+	accepted := func(_ context.Context, w http.ResponseWriter, resp proto.Message) error {
+		switch msg := resp.(type) {
+		case *pb.SimpleMessage:
+			if msg.Id == "pending" {
+				w.WriteHeader(http.StatusAccepted)
+			}
+			if msg.Id == "invalid" {
+				return status.Error(codes.FailedPrecondition, "invalid")
+			}
+		case *httpbody.HttpBody:
+			w.WriteHeader(http.StatusAccepted)
+		}
+		return nil
+	}
+	for _, spec := range []struct {
+		name   string
+		opts   []runtime.ServeMuxOption
+		resp   proto.Message
+		status int
+		body   string
+	}{
+		{
+			name:   "unchanged",
+			resp:   &pb.SimpleMessage{Id: "foo"},
+			status: http.StatusOK,
+			body:   `{"id":"foo"}`,
+		},
+		{
+			name:   "accepted",
+			resp:   &pb.SimpleMessage{Id: "pending"},
+			status: http.StatusAccepted,
+			body:   `{"id":"pending"}`,
+		},
+		{
+			name:   "error",
+			resp:   &pb.SimpleMessage{Id: "invalid"},
+			status: http.StatusPreconditionFailed,
+		},
+		{
+			name:   "accepted without content",
+			opts:   []runtime.ServeMuxOption{runtime.WithNoContentForEmpty()},
+			resp:   &pb.SimpleMessage{Id: "pending"},
+			status: http.StatusAccepted,
+			body:   `{"id":"pending"}`,
+		},
+		{
+			name:   "http body",
+			resp:   &httpbody.HttpBody{ContentType: "text/plain", Data: []byte("pending")},
+			status: http.StatusAccepted,
+			body:   "pending",
+		},
+	} {
+		t.Run(spec.name, func(t *testing.T) {
+			ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+			req := httptest.NewRequest("POST", "http://example.com/foo", nil)
+			resp := httptest.NewRecorder()
+
+			runtime.ForwardResponseMessage(ctx, runtime.NewServeMux(spec.opts...), &runtime.JSONPb{}, resp, req, spec.resp, accepted)
+
+			if got, want := resp.Code, spec.status; got != want {
+				t.Errorf("resp.Code = %d; want %d", got, want)
+			}
+			if spec.body == "" {
+				return
+			}
+			if got, want := resp.Body.String(), spec.body; got != want {
+				t.Errorf("resp.Body = %q; want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestForwardResponseMessageBodyOption(t *testing.T) {
+	// rename stands for options which rewrite the message, e.g. runtime.RedactFields.
+	rename := func(_ context.Context, _ http.ResponseWriter, resp proto.Message) error {
+		resp.(*pb.SimpleMessage).Id = "renamed"
+		return nil
+	}
+	digest := func(_ context.Context, w http.ResponseWriter, resp proto.Message, body []byte) error {
+		if resp.(*pb.SimpleMessage).Id == "" {
+			return status.Error(codes.Internal, "empty id")
+		}
+		w.Header().Set("Digest", fmt.Sprintf("sha-256=%x", sha256.Sum256(body)))
+		w.WriteHeader(http.StatusCreated)
+		return nil
+	}
+	mux := runtime.NewServeMux(runtime.WithForwardResponseBodyOption(digest))
+	for _, spec := range []struct {
+		name   string
+		resp   *pb.SimpleMessage
+		status int
+		digest bool
+	}{
+		{
+			name:   "digest",
+			resp:   &pb.SimpleMessage{Id: "foo"},
+			status: http.StatusCreated,
+			digest: true,
+		},
+		{
+			name:   "error",
+			resp:   &pb.SimpleMessage{},
+			status: http.StatusInternalServerError,
+		},
+	} {
+		t.Run(spec.name, func(t *testing.T) {
+			ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+			req := httptest.NewRequest("POST", "http://example.com/foo", nil)
+			w := httptest.NewRecorder()
+			opts := []func(context.Context, http.ResponseWriter, proto.Message) error{}
+			if spec.digest {
+				opts = append(opts, rename)
+			}
+
+			runtime.ForwardResponseMessage(ctx, mux, &runtime.JSONPb{}, w, req, spec.resp, opts...)
+
+			if got, want := w.Code, spec.status; got != want {
+				t.Errorf("w.Code = %d; want %d", got, want)
+			}
+			if !spec.digest {
+				if got := w.Header().Get("Digest"); got != "" {
+					t.Errorf("Digest = %q; want no digest", got)
+				}
+				return
+			}
+			if got, want := w.Body.String(), `{"id":"renamed"}`; got != want {
+				t.Errorf("w.Body = %q; want %q", got, want)
+			}
+			if got, want := w.Header().Get("Digest"), fmt.Sprintf("sha-256=%x", sha256.Sum256(w.Body.Bytes())); got != want {
+				t.Errorf("Digest = %q; want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestForwardResponseMessageAnyUnwrapping(t *testing.T) {
+	packed, err := ptypes.MarshalAny(&pb.SimpleMessage{Id: "foo"})
+	if err != nil {
+		t.Fatalf("ptypes.MarshalAny(msg) failed with %v; want success", err)
+	}
+	unknown := &any.Any{TypeUrl: "type.googleapis.com/example.NoSuchMessage", Value: []byte("\x0a\x03foo")}
+	unwrapping := runtime.WithAnyResponseUnwrapping("X-Message-Type")
+	for _, spec := range []struct {
+		name       string
+		opts       []runtime.ServeMuxOption
+		resp       *any.Any
+		body       proto.Message
+		typeHeader string
+	}{
+		{
+			name: "envelope",
+			resp: packed,
+			body: packed,
+		},
+		{
+			name:       "unwrapped",
+			opts:       []runtime.ServeMuxOption{unwrapping},
+			resp:       packed,
+			body:       &pb.SimpleMessage{Id: "foo"},
+			typeHeader: packed.TypeUrl,
+		},
+		{
+			name: "unknown type",
+			opts: []runtime.ServeMuxOption{unwrapping},
+			resp: unknown,
+			body: unknown,
+		},
+	} {
+		t.Run(spec.name, func(t *testing.T) {
+			ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+			req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+			w := httptest.NewRecorder()
+			m := &runtime.ProtoMarshaller{}
+
+			runtime.ForwardResponseMessage(ctx, runtime.NewServeMux(spec.opts...), m, w, req, spec.resp)
+
+			if got, want := w.Code, http.StatusOK; got != want {
+				t.Errorf("w.Code = %d; want %d", got, want)
+			}
+			want, err := m.Marshal(spec.body)
+			if err != nil {
+				t.Fatalf("m.Marshal(%v) failed with %v; want success", spec.body, err)
+			}
+			if got := w.Body.Bytes(); !bytes.Equal(got, want) {
+				t.Errorf("w.Body = %q; want %q", got, want)
+			}
+			if got, want := w.Header().Get("X-Message-Type"), spec.typeHeader; got != want {
+				t.Errorf("X-Message-Type = %q; want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestForwardResponseMessageReturnPreference(t *testing.T) {
+	for _, spec := range []struct {
+		name    string
+		opts    []runtime.ServeMuxOption
+		prefer  string
+		body    string
+		applied string
+	}{
+		{
+			name:   "disabled",
+			prefer: "return=minimal",
+			body:   `{"id":"foo"}`,
+		},
+		{
+			name:    "minimal",
+			opts:    []runtime.ServeMuxOption{runtime.WithReturnPreference()},
+			prefer:  "respond-async, return=minimal; foo=bar",
+			applied: "return=minimal",
+		},
+		{
+			name:    "representation",
+			opts:    []runtime.ServeMuxOption{runtime.WithReturnPreference()},
+			prefer:  "return=representation",
+			body:    `{"id":"foo"}`,
+			applied: "return=representation",
+		},
+		{
+			name: "no preference",
+			opts: []runtime.ServeMuxOption{runtime.WithReturnPreference()},
+			body: `{"id":"foo"}`,
+		},
+	} {
+		t.Run(spec.name, func(t *testing.T) {
+			ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+			req := httptest.NewRequest("POST", "http://example.com/foo", nil)
+			if spec.prefer != "" {
+				req.Header.Set("Prefer", spec.prefer)
+			}
+			resp := httptest.NewRecorder()
+
+			runtime.ForwardResponseMessage(ctx, runtime.NewServeMux(spec.opts...), &runtime.JSONPb{}, resp, req, &pb.SimpleMessage{Id: "foo"})
+
+			if got, want := resp.Code, http.StatusOK; got != want {
+				t.Errorf("resp.Code = %d; want %d", got, want)
+			}
+			if got, want := resp.Body.String(), spec.body; got != want {
+				t.Errorf("resp.Body = %q; want %q", got, want)
+			}
+			if got, want := resp.Header().Get("Preference-Applied"), spec.applied; got != want {
+				t.Errorf("Preference-Applied = %q; want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestForwardResponseMarshalerFromContext(t *testing.T) {
+	msg := &pb.SimpleMessage{Id: "foo"}
+	want, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatalf("proto.Marshal(%v) failed with %v; want success", msg, err)
+	}
+	ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+	ctx = runtime.WithMarshalerContext(ctx, &runtime.ProtoMarshaller{})
+
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	resp := httptest.NewRecorder()
+	runtime.ForwardResponseMessage(ctx, runtime.NewServeMux(), &runtime.JSONPb{}, resp, req, msg)
+
+	if got, want := resp.Header().Get("Content-Type"), "application/octet-stream"; got != want {
+		t.Errorf("Content-Type = %q; want %q", got, want)
+	}
+	if got := resp.Body.Bytes(); string(got) != string(want) {
+		t.Errorf("resp.Body = %q; want %q", got, want)
+	}
+
+	var sent bool
+	recv := func() (proto.Message, error) {
+		if sent {
+			return nil, io.EOF
+		}
+		sent = true
+		return msg, nil
+	}
+	resp = httptest.NewRecorder()
+	runtime.ForwardResponseStream(ctx, runtime.NewServeMux(), &runtime.JSONPb{}, resp, req, recv)
+	if got, want := resp.Header().Get("Content-Type"), "application/octet-stream"; got != want {
+		t.Errorf("stream Content-Type = %q; want %q", got, want)
+	}
+}
+
+func TestForwardResponseMessageLocationHeader(t *testing.T) {
+	for _, spec := range []struct {
+		name     string
+		method   string
+		tmpl     string
+		created  bool
+		msg      *pb.ABitOfEverything
+		status   int
+		location string
+	}{
+		{
+			name:     "created",
+			method:   "POST",
+			tmpl:     "/v1/example/a_bit_of_everything/{uuid}",
+			created:  true,
+			msg:      &pb.ABitOfEverything{Uuid: "foo bar"},
+			status:   http.StatusCreated,
+			location: "/v1/example/a_bit_of_everything/foo%20bar",
+		},
+		{
+			name:     "nested field",
+			method:   "POST",
+			tmpl:     "/v1/{single_nested.name}/{int32_value}",
+			msg:      &pb.ABitOfEverything{SingleNested: &pb.ABitOfEverything_Nested{Name: "shelves/1"}, Int32Value: 2},
+			status:   http.StatusOK,
+			location: "/v1/shelves/1/2",
+		},
+		{
+			name:     "oneof",
+			method:   "POST",
+			tmpl:     "/v1/{oneof_string}",
+			created:  true,
+			msg:      &pb.ABitOfEverything{OneofValue: &pb.ABitOfEverything_OneofString{OneofString: "foo"}},
+			status:   http.StatusCreated,
+			location: "/v1/foo",
+		},
+		{
+			name:    "unset field",
+			method:  "POST",
+			tmpl:    "/v1/example/a_bit_of_everything/{uuid}",
+			created: true,
+			msg:     &pb.ABitOfEverything{},
+			status:  http.StatusOK,
+		},
+		{
+			name:    "not a creation",
+			method:  "GET",
+			tmpl:    "/v1/example/a_bit_of_everything/{uuid}",
+			created: true,
+			msg:     &pb.ABitOfEverything{Uuid: "foo"},
+			status:  http.StatusOK,
+		},
+	} {
+		t.Run(spec.name, func(t *testing.T) {
+			ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+			req := httptest.NewRequest(spec.method, "http://example.com/foo", nil)
+			resp := httptest.NewRecorder()
+
+			runtime.ForwardResponseMessage(ctx, runtime.NewServeMux(), &runtime.JSONPb{}, resp, req, spec.msg, runtime.LocationHeader(spec.tmpl, spec.created))
+
+			if got, want := resp.Code, spec.status; got != want {
+				t.Errorf("resp.Code = %d; want %d", got, want)
+			}
+			if got, want := resp.Header().Get("Location"), spec.location; got != want {
+				t.Errorf("Location = %q; want %q", got, want)
+			}
+			if resp.Body.Len() == 0 {
+				t.Errorf("resp.Body is empty; want the response message")
+			}
+		})
+	}
+}
+
+func TestForwardResponseMessageLastModifiedHeader(t *testing.T) {
+	modified := &timestamp.Timestamp{Seconds: 1500000000, Nanos: 500}
+	const lastModified = "Fri, 14 Jul 2017 02:40:00 GMT"
+	for _, spec := range []struct {
+		name         string
+		method       string
+		header       http.Header
+		msg          *proto3Message
+		status       int
+		lastModified string
+	}{
+		{
+			name:         "unconditional",
+			method:       "GET",
+			msg:          &proto3Message{TimestampValue: modified},
+			status:       http.StatusOK,
+			lastModified: lastModified,
+		},
+		{
+			name:         "not modified",
+			method:       "GET",
+			header:       http.Header{"If-Modified-Since": {lastModified}},
+			msg:          &proto3Message{TimestampValue: modified},
+			status:       http.StatusNotModified,
+			lastModified: lastModified,
+		},
+		{
+			name:         "modified",
+			method:       "GET",
+			header:       http.Header{"If-Modified-Since": {"Fri, 14 Jul 2017 02:39:59 GMT"}},
+			msg:          &proto3Message{TimestampValue: modified},
+			status:       http.StatusOK,
+			lastModified: lastModified,
+		},
+		{
+			name:         "If-None-Match takes precedence",
+			method:       "GET",
+			header:       http.Header{"If-Modified-Since": {lastModified}, "If-None-Match": {`"foo"`}},
+			msg:          &proto3Message{TimestampValue: modified},
+			status:       http.StatusOK,
+			lastModified: lastModified,
+		},
+		{
+			name:   "unset field",
+			method: "GET",
+			header: http.Header{"If-Modified-Since": {lastModified}},
+			msg:    &proto3Message{},
+			status: http.StatusOK,
+		},
+		{
+			name:   "not a GET",
+			method: "POST",
+			header: http.Header{"If-Modified-Since": {lastModified}},
+			msg:    &proto3Message{TimestampValue: modified},
+			status: http.StatusOK,
+		},
+	} {
+		t.Run(spec.name, func(t *testing.T) {
+			ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+			req := httptest.NewRequest(spec.method, "http://example.com/foo", nil)
+			for k, vs := range spec.header {
+				req.Header[k] = vs
+			}
+			resp := httptest.NewRecorder()
+
+			runtime.ForwardResponseMessage(ctx, runtime.NewServeMux(), &runtime.JSONBuiltin{}, resp, req, spec.msg, runtime.LastModifiedHeader("timestamp_value"))
+
+			if got, want := resp.Code, spec.status; got != want {
+				t.Errorf("resp.Code = %d; want %d", got, want)
+			}
+			if got, want := resp.Header().Get("Last-Modified"), spec.lastModified; got != want {
+				t.Errorf("Last-Modified = %q; want %q", got, want)
+			}
+			if got, want := resp.Body.Len() == 0, spec.status == http.StatusNotModified; got != want {
+				t.Errorf("resp.Body = %q; want a body only if the status is not 304", resp.Body)
+			}
+		})
+	}
+}
+
+func TestForwardResponseMessageLinkHeader(t *testing.T) {
+	links := []string{"</style.css>; rel=preload; as=style", "</app.js>; rel=preload; as=script"}
+	ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{
+		HeaderMD: metadata.MD{"x-preload": links},
+	})
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	resp := httptest.NewRecorder()
+	mux := runtime.NewServeMux(runtime.WithLinkHeaderMetadata("X-Preload"))
+
+	runtime.ForwardResponseMessage(ctx, mux, &runtime.JSONPb{}, resp, req, &pb.SimpleMessage{Id: "foo"})
+
+	if got, want := resp.Header()["Link"], links; !reflect.DeepEqual(got, want) {
+		t.Errorf("Link = %q; want %q", got, want)
+	}
+}
+
+func TestForwardResponseStreamEarlyHeaders(t *testing.T) {
+	for _, spec := range []struct {
+		name       string
+		err        error
+		statusCode int
+	}{
+		{name: "ok", err: io.EOF, statusCode: http.StatusOK},
+		{name: "first_error", err: grpc.Errorf(codes.OutOfRange, "400"), statusCode: http.StatusOK},
+	} {
+		t.Run(spec.name, func(t *testing.T) {
+			ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{
+				HeaderMD: metadata.Pairs("foo", "bar"),
+			})
+			req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+			resp := httptest.NewRecorder()
+			recv := func() (proto.Message, error) {
+				if !resp.Flushed {
+					t.Errorf("headers were not flushed before the first recv()")
+				}
+				if got, want := resp.Header().Get("Grpc-Metadata-Foo"), "bar"; got != want {
+					t.Errorf("Grpc-Metadata-Foo = %q; want %q", got, want)
+				}
+				return nil, spec.err
+			}
+
+			runtime.ForwardResponseStream(ctx, runtime.NewServeMux(), &runtime.JSONPb{}, resp, req, recv)
+
+			if got, want := resp.Code, spec.statusCode; got != want {
+				t.Errorf("resp.Code = %d; want %d", got, want)
+			}
+			if spec.err != io.EOF && !strings.Contains(resp.Body.String(), `"error"`) {
+				t.Errorf("resp.Body = %q; want an error chunk", resp.Body.String())
+			}
+		})
+	}
+}
+
+func TestForwardResponseMessageTrailerMetadata(t *testing.T) {
+	md := runtime.ServerMetadata{
+		HeaderMD:  metadata.Pairs("foo", "header"),
+		TrailerMD: metadata.Pairs("foo", "trailer", "ignored", "value"),
+	}
+	matcher := func(key string) (string, bool) {
+		if key == "ignored" {
+			return "", false
+		}
+		return runtime.MetadataTrailerPrefix + key, true
+	}
+	for _, spec := range []struct {
+		name        string
+		opts        []runtime.ServeMuxOption
+		wantHeader  string
+		wantTrailer string
+	}{
+		{name: "http_trailers", wantTrailer: "trailer"},
+		{name: "headers", opts: []runtime.ServeMuxOption{runtime.WithOutgoingTrailerMatcher(matcher)}, wantHeader: "trailer"},
+	} {
+		t.Run(spec.name, func(t *testing.T) {
+			ctx := runtime.NewServerMetadataContext(context.Background(), md)
+			req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+			resp := httptest.NewRecorder()
+
+			runtime.ForwardResponseMessage(ctx, runtime.NewServeMux(spec.opts...), &runtime.JSONPb{}, resp, req, &pb.SimpleMessage{Id: "foo"})
+
+			w := resp.Result()
+			if got, want := w.Header.Get("Grpc-Metadata-Foo"), "header"; got != want {
+				t.Errorf("Grpc-Metadata-Foo header = %q; want %q", got, want)
+			}
+			if got, want := w.Header.Get("Grpc-Trailer-Foo"), spec.wantHeader; got != want {
+				t.Errorf("Grpc-Trailer-Foo header = %q; want %q", got, want)
+			}
+			if got := w.Header.Get("Grpc-Trailer-Ignored"); got != "" {
+				t.Errorf("Grpc-Trailer-Ignored header = %q; want none", got)
+			}
+			if got, want := w.Trailer.Get("Grpc-Trailer-Foo"), spec.wantTrailer; got != want {
+				t.Errorf("Grpc-Trailer-Foo trailer = %q; want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestForwardResponseMessageHTTPBodyRange(t *testing.T) {
+	body := &httpbody.HttpBody{ContentType: "text/plain", Data: []byte("0123456789")}
+	for _, spec := range []struct {
+		method       string
+		rng          string
+		code         int
+		body         string
+		contentRange string
+	}{
+		{method: "GET", code: http.StatusOK, body: "0123456789"},
+		{method: "GET", rng: "bytes=2-5", code: http.StatusPartialContent, body: "2345", contentRange: "bytes 2-5/10"},
+		{method: "GET", rng: "bytes=7-", code: http.StatusPartialContent, body: "789", contentRange: "bytes 7-9/10"},
+		{method: "GET", rng: "bytes=-3", code: http.StatusPartialContent, body: "789", contentRange: "bytes 7-9/10"},
+		{method: "GET", rng: "bytes=8-20", code: http.StatusPartialContent, body: "89", contentRange: "bytes 8-9/10"},
+		{method: "GET", rng: "bytes=0-1,4-5", code: http.StatusOK, body: "0123456789"},
+		{method: "GET", rng: "bytes=10-", code: http.StatusRequestedRangeNotSatisfiable, contentRange: "bytes */10"},
+		{method: "GET", rng: "bytes=5-2", code: http.StatusRequestedRangeNotSatisfiable, contentRange: "bytes */10"},
+		{method: "GET", rng: "items=0-1", code: http.StatusRequestedRangeNotSatisfiable, contentRange: "bytes */10"},
+		{method: "POST", rng: "bytes=2-5", code: http.StatusOK, body: "0123456789"},
+	} {
+		ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+		req := httptest.NewRequest(spec.method, "http://example.com/file", nil)
+		if spec.rng != "" {
+			req.Header.Set("Range", spec.rng)
+		}
+		resp := httptest.NewRecorder()
+
+		runtime.ForwardResponseMessage(ctx, runtime.NewServeMux(), &runtime.JSONPb{}, resp, req, body)
+
+		if got, want := resp.Code, spec.code; got != want {
+			t.Errorf("resp.Code = %d; want %d; method=%s, range=%q", got, want, spec.method, spec.rng)
+		}
+		if got, want := resp.Header().Get("Content-Range"), spec.contentRange; got != want {
+			t.Errorf("Content-Range = %q; want %q; method=%s, range=%q", got, want, spec.method, spec.rng)
+		}
+		if spec.body == "" {
+			continue
+		}
+		if got, want := resp.Body.String(), spec.body; got != want {
+			t.Errorf("resp.Body = %q; want %q; method=%s, range=%q", got, want, spec.method, spec.rng)
+		}
+		if got, want := resp.Header().Get("Content-Type"), "text/plain"; got != want {
+			t.Errorf("Content-Type = %q; want %q", got, want)
+		}
+		if got, want := resp.Header().Get("Accept-Ranges"), "bytes"; got != want {
+			t.Errorf("Accept-Ranges = %q; want %q", got, want)
+		}
+	}
+}
+
+func TestForwardResponseStreamMaxMessages(t *testing.T) {
+	const max = 3
+	var count int
+	recv := func() (proto.Message, error) {
+		count++
+		return &pb.SimpleMessage{Id: "foo"}, nil
+	}
+	ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	resp := httptest.NewRecorder()
+	mux := runtime.NewServeMux(runtime.WithMaxStreamMessages(max))
+
+	runtime.ForwardResponseStream(ctx, mux, &runtime.JSONPb{}, resp, req, recv)
+
+	if got, want := count, max+1; got != want {
+		t.Errorf("recv() called %d times; want %d", got, want)
+	}
+	lines := strings.Split(strings.TrimSpace(resp.Body.String()), "\n")
+	if got, want := len(lines), max+1; got != want {
+		t.Fatalf("got %d chunks; want %d; body = %q", got, want, resp.Body.String())
+	}
+	for _, l := range lines[:max] {
+		if !strings.Contains(l, `"result"`) {
+			t.Errorf("chunk = %q; want a result", l)
+		}
+	}
+	if got, want := lines[max], fmt.Sprintf(`"grpcCode":%d`, codes.ResourceExhausted); !strings.Contains(got, want) {
+		t.Errorf("last chunk = %q; want it to contain %q", got, want)
+	}
+}
+
+func TestForwardResponseStreamProto(t *testing.T) {
+	ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	resp := httptest.NewRecorder()
+	msgs := []*pb.SimpleMessage{{Id: "One"}, {Id: "Two\nThree", Num: 10}}
+	var count int
+	recv := func() (proto.Message, error) {
+		if count == len(msgs) {
+			return nil, io.EOF
+		}
+		count++
+		return msgs[count-1], nil
+	}
+
+	runtime.ForwardResponseStream(ctx, runtime.NewServeMux(), &runtime.ProtoMarshaller{}, resp, req, recv)
+
+	if got, want := resp.Code, http.StatusOK; got != want {
+		t.Errorf("resp.Code = %d; want %d", got, want)
+	}
+	dec := runtime.NewProtoStreamDecoder(resp.Body)
+	for _, want := range msgs {
+		var got pb.SimpleMessage
+		if err := dec.Decode(&got); err != nil {
+			t.Fatalf("dec.Decode(&got) failed with %v; want success", err)
+		}
+		if !proto.Equal(&got, want) {
+			t.Errorf("dec.Decode(&got); got = %v; want %v", &got, want)
+		}
+	}
+	var msg pb.SimpleMessage
+	if err := dec.Decode(&msg); err != io.EOF {
+		t.Errorf("dec.Decode(&msg) = %v; want %v", err, io.EOF)
+	}
+
+	dec = runtime.NewProtoStreamDecoder(strings.NewReader("\x05ab"))
+	if err := dec.Decode(&msg); err != io.ErrUnexpectedEOF {
+		t.Errorf("dec.Decode(&msg) of a truncated stream = %v; want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestForwardResponseStreamProtoError(t *testing.T) {
+	ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	resp := httptest.NewRecorder()
+	recv := func() (proto.Message, error) {
+		return nil, grpc.Errorf(codes.OutOfRange, "out of range")
+	}
+
+	runtime.ForwardResponseStream(ctx, runtime.NewServeMux(), &runtime.ProtoMarshaller{}, resp, req, recv)
+
+	if got, want := resp.Code, http.StatusBadRequest; got != want {
+		t.Errorf("resp.Code = %d; want %d", got, want)
+	}
+}
+
+func TestForwardResponseStreamNDJSON(t *testing.T) {
+	ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	req.Header.Set("Accept", runtime.NDJSONContentType)
+	resp := httptest.NewRecorder()
+	mux := runtime.NewServeMux()
+	_, marshaler := runtime.MarshalerForRequest(mux, req)
+
+	runtime.ForwardResponseStream(ctx, mux, marshaler, resp, req, newStreamRecv(2))
+
+	if got, want := resp.Header().Get("Content-Type"), runtime.NDJSONContentType; got != want {
+		t.Errorf("Content-Type = %q; want %q", got, want)
+	}
+	if got, want := resp.Body.String(), "{\"result\":{\"id\":\"foo\"}}\n{\"result\":{\"id\":\"foo\"}}\n"; got != want {
+		t.Errorf("resp.Body = %q; want %q", got, want)
+	}
+
+	resp = httptest.NewRecorder()
+	runtime.ForwardResponseMessage(ctx, mux, marshaler, resp, req, &pb.SimpleMessage{Id: "foo"})
+	if got, want := resp.Header().Get("Content-Type"), "application/json"; got != want {
+		t.Errorf("Content-Type of a unary response = %q; want %q", got, want)
+	}
+}
+
+// countingResponseWriter counts the writes made to the underlying ResponseRecorder.
+type countingResponseWriter struct {
+	*httptest.ResponseRecorder
+	writes int
+}
+
+func (w *countingResponseWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.ResponseRecorder.Write(p)
+}
+
+func newStreamRecv(n int) func() (proto.Message, error) {
+	var count int
+	return func() (proto.Message, error) {
+		if count == n {
+			return nil, io.EOF
+		}
+		count++
+		return &pb.SimpleMessage{Id: "foo"}, nil
+	}
+}
+
+func forwardTestStream(mux *runtime.ServeMux, n int) *countingResponseWriter {
+	ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	w := &countingResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+	runtime.ForwardResponseStream(ctx, mux, &runtime.JSONPb{}, w, req, newStreamRecv(n))
+	return w
+}
+
+func TestForwardResponseStreamCoalescing(t *testing.T) {
+	const n = 100
+	plain := forwardTestStream(runtime.NewServeMux(), n)
+	coalesced := forwardTestStream(runtime.NewServeMux(runtime.WithStreamCoalescing(1024, time.Second)), n)
+
+	if got, want := coalesced.Body.String(), plain.Body.String(); got != want {
+		t.Errorf("coalesced body = %q; want %q", got, want)
+	}
+	if got, want := plain.writes, 2*n; got != want {
+		t.Errorf("writes without coalescing = %d; want %d", got, want)
+	}
+	if max := plain.Body.Len()/1024 + 1; coalesced.writes > max {
+		t.Errorf("writes with coalescing = %d; want at most %d", coalesced.writes, max)
+	}
+	if ct := coalesced.Header().Get("Content-Type"); ct != (&runtime.JSONPb{}).ContentType() {
+		t.Errorf("Content-Type = %q; want %q", ct, (&runtime.JSONPb{}).ContentType())
+	}
+}
+
+func TestForwardResponseStreamCoalescingDelay(t *testing.T) {
+	ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	w := &countingResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+	mux := runtime.NewServeMux(runtime.WithStreamCoalescing(1<<20, time.Millisecond))
+
+	var count int
+	recv := func() (proto.Message, error) {
+		if count == 2 {
+			return nil, io.EOF
+		}
+		if count == 1 {
+			// Leave time for the first message to be flushed by the timer.
+			time.Sleep(50 * time.Millisecond)
+		}
+		count++
+		return &pb.SimpleMessage{Id: "foo"}, nil
+	}
+	runtime.ForwardResponseStream(ctx, mux, &runtime.JSONPb{}, w, req, recv)
+
+	if got, want := w.writes, 2; got != want {
+		t.Errorf("writes = %d; want %d", got, want)
+	}
+}
+
+func TestForwardResponseStreamHeartbeat(t *testing.T) {
+	ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	w := httptest.NewRecorder()
+	mux := runtime.NewServeMux(runtime.WithStreamHeartbeat(10*time.Millisecond, []byte(":\n")))
+
+	var count int
+	recv := func() (proto.Message, error) {
+		if count == 2 {
+			return nil, io.EOF
+		}
+		if count == 1 {
+			// Stay idle for a few heartbeats between the messages.
+			time.Sleep(45 * time.Millisecond)
+		}
+		count++
+		return &pb.SimpleMessage{Id: "foo"}, nil
+	}
+	runtime.ForwardResponseStream(ctx, mux, &runtime.JSONPb{}, w, req, recv)
+	body := w.Body.String()
+
+	msg := "{\"result\":{\"id\":\"foo\"}}\n"
+	if !strings.HasPrefix(body, msg+":\n") || !strings.HasSuffix(body, ":\n"+msg) {
+		t.Errorf("w.Body = %q; want heartbeats between two messages %q", body, msg)
+	}
+	if got := strings.Count(body, ":\n"); got < 2 || got > 4 {
+		t.Errorf("heartbeats = %d; want 2 to 4 in %q", got, body)
+	}
+
+	// No heartbeat is sent once the stream is over.
+	time.Sleep(30 * time.Millisecond)
+	if got := w.Body.String(); got != body {
+		t.Errorf("w.Body = %q after the stream; want %q", got, body)
+	}
+}
+
+func TestForwardResponseStreamEmptyBody(t *testing.T) {
+	msg := "{\"result\":{\"id\":\"foo\"}}\n"
+	for _, spec := range []struct {
+		opts []runtime.ServeMuxOption
+		n    int
+		want string
+	}{
+		{
+			want: "",
+		},
+		{
+			opts: []runtime.ServeMuxOption{runtime.WithEmptyStreamBody([]byte("[]\n"))},
+			want: "[]\n",
+		},
+		{
+			opts: []runtime.ServeMuxOption{runtime.WithEmptyStreamBody([]byte("[]\n"))},
+			n:    2,
+			want: msg + msg,
+		},
+	} {
+		w := forwardTestStream(runtime.NewServeMux(spec.opts...), spec.n)
+		if got := w.Body.String(); got != spec.want {
+			t.Errorf("w.Body = %q; want %q; messages = %d", got, spec.want, spec.n)
+		}
+		if got, want := w.Code, http.StatusOK; got != want {
+			t.Errorf("w.Code = %d; want %d", got, want)
+		}
+	}
+}
+
+func benchmarkForwardResponseStream(b *testing.B, opts ...runtime.ServeMuxOption) {
+	mux := runtime.NewServeMux(opts...)
+	var writes int
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		writes += forwardTestStream(mux, 100).writes
+	}
+	b.StopTimer()
+	b.Logf("%d writes per stream of 100 messages", writes/b.N)
+}
+
+func BenchmarkForwardResponseStream(b *testing.B) {
+	benchmarkForwardResponseStream(b)
+}
+
+func BenchmarkForwardResponseStreamCoalesced(b *testing.B) {
+	benchmarkForwardResponseStream(b, runtime.WithStreamCoalescing(4096, 10*time.Millisecond))
+}
+
+func TestForwardResponseMessageRouteHeaders(t *testing.T) {
+	v1 := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0, int(utilities.OpLitPush), 1}, []string{"v1", "foo"}, ""))
+	v2 := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0, int(utilities.OpLitPush), 1}, []string{"v2", "foo"}, ""))
+	sunset := time.Date(2030, time.January, 2, 3, 4, 5, 0, time.UTC)
+	mux := runtime.NewServeMux(
+		runtime.WithDeprecatedRoute("GET", v1, sunset),
+		runtime.WithRouteHeaders("GET", v1, http.Header{"x-api-version": {"1"}}),
+	)
+	handler := func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		ctx := runtime.NewServerMetadataContext(r.Context(), runtime.ServerMetadata{})
+		runtime.ForwardResponseMessage(ctx, mux, &runtime.JSONPb{}, w, r, &pb.SimpleMessage{Id: "foo"})
+	}
+	mux.Handle("GET", v1, handler)
+	mux.Handle("POST", v1, handler)
+	mux.Handle("GET", v2, handler)
+
+	for _, spec := range []struct {
+		method string
+		path   string
+		want   http.Header
+	}{
+		{
+			method: "GET",
+			path:   "/v1/foo",
+			want: http.Header{
+				"Deprecation":   {"true"},
+				"Sunset":        {"Wed, 02 Jan 2030 03:04:05 GMT"},
+				"X-Api-Version": {"1"},
+			},
+		},
+		{method: "POST", path: "/v1/foo", want: http.Header{}},
+		{method: "GET", path: "/v2/foo", want: http.Header{}},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(spec.method, "http://example.com"+spec.path, nil))
+		got := make(http.Header)
+		for _, h := range []string{"Deprecation", "Sunset", "X-Api-Version"} {
+			if vs, ok := w.Header()[h]; ok {
+				got[h] = vs
+			}
+		}
+		if !reflect.DeepEqual(got, spec.want) {
+			t.Errorf("%s %s: headers = %q; want %q", spec.method, spec.path, got, spec.want)
+		}
+	}
+}
+
+func TestForwardResponseStreamFlushesMessages(t *testing.T) {
+	large := strings.Repeat("x", 64<<10)
+	next := make(chan struct{})
+	defer close(next)
+	var count int
+	recv := func() (proto.Message, error) {
+		if count == 2 {
+			return nil, io.EOF
+		}
+		if count == 1 {
+			// The client must have read the first message before the second one is produced.
+			select {
+			case <-next:
+			case <-time.After(5 * time.Second):
+				return nil, status.Error(codes.DeadlineExceeded, "first message not read")
+			}
+		}
+		count++
+		return &pb.SimpleMessage{Id: large}, nil
+	}
+	mux := runtime.NewServeMux()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := runtime.NewServerMetadataContext(r.Context(), runtime.ServerMetadata{})
+		runtime.ForwardResponseStream(ctx, mux, &runtime.JSONPb{}, w, r, recv)
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("http.Get(%q) failed with %v; want success", srv.URL, err)
+	}
+	defer resp.Body.Close()
+	body := bufio.NewReader(resp.Body)
+	want := fmt.Sprintf("{\"result\":{\"id\":%q}}\n", large)
+	for i := 0; i < 2; i++ {
+		line, err := body.ReadString('\n')
+		if err != nil {
+			t.Fatalf("body.ReadString('\\n') failed with %v; want message %d", err, i)
+		}
+		if line != want {
+			t.Errorf("message %d = %.40q...; want %.40q...", i, line, want)
+		}
+		if i == 0 {
+			next <- struct{}{}
+		}
+	}
+}
+
+func TestForwardResponseMessageRouteContentType(t *testing.T) {
+	const vendor = "application/vnd.myapi.v1+json"
+	v1 := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0, int(utilities.OpLitPush), 1}, []string{"v1", "foo"}, ""))
+	v2 := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0, int(utilities.OpLitPush), 1}, []string{"v2", "foo"}, ""))
+	protoMarshaler := &runtime.ProtoMarshaller{}
+	mux := runtime.NewServeMux(
+		runtime.WithRouteContentType("GET", v1, vendor),
+		runtime.WithMarshalerOption("application/octet-stream", protoMarshaler),
+	)
+	handler := func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		ctx := runtime.NewServerMetadataContext(r.Context(), runtime.ServerMetadata{})
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+		runtime.ForwardResponseMessage(ctx, mux, outbound, w, r, &pb.SimpleMessage{Id: "foo"})
+	}
+	mux.Handle("GET", v1, handler)
+	mux.Handle("GET", v2, handler)
+
+	defaultContentType := (&runtime.JSONPb{}).ContentType()
+	for _, spec := range []struct {
+		path   string
+		accept string
+		want   string
+	}{
+		{path: "/v1/foo", want: vendor},
+		{path: "/v1/foo", accept: vendor, want: vendor},
+		{path: "/v1/foo", accept: "application/octet-stream", want: protoMarshaler.ContentType()},
+		{path: "/v2/foo", want: defaultContentType},
+		{path: "/v2/foo", accept: vendor, want: defaultContentType},
+	} {
+		r := httptest.NewRequest("GET", "http://example.com"+spec.path, nil)
+		if spec.accept != "" {
+			r.Header.Set("Accept", spec.accept)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if got := w.Header().Get("Content-Type"); got != spec.want {
+			t.Errorf("Content-Type = %q; want %q; path=%q, accept=%q", got, spec.want, spec.path, spec.accept)
+		}
+	}
+}
+
+func TestForwardResponseMessageHeaderPrecedence(t *testing.T) {
+	md := runtime.ServerMetadata{
+		HeaderMD:  metadata.Pairs("cache-control", "no-store", "x-backend", "header"),
+		TrailerMD: metadata.Pairs("x-trailer", "trailer"),
+	}
+	matcher := func(key string) (string, bool) {
+		return key, true
+	}
+	for _, spec := range []struct {
+		name string
+		opts []runtime.ServeMuxOption
+		want http.Header
+	}{
+		{
+			name: "default",
+			want: http.Header{
+				"Cache-Control": {"max-age=60", "no-store"},
+				"X-Backend":     {"header"},
+				"X-Trailer":     {"default", "trailer"},
+			},
+		},
+		{
+			name: "metadata overrides headers",
+			opts: []runtime.ServeMuxOption{runtime.WithHeaderPrecedence(runtime.MetadataOverridesHeaders)},
+			want: http.Header{
+				"Cache-Control": {"no-store"},
+				"X-Backend":     {"header"},
+				"X-Trailer":     {"trailer"},
+			},
+		},
+		{
+			name: "headers override metadata",
+			opts: []runtime.ServeMuxOption{runtime.WithHeaderPrecedence(runtime.HeadersOverrideMetadata)},
+			want: http.Header{
+				"Cache-Control": {"max-age=60"},
+				"X-Backend":     {"header"},
+				"X-Trailer":     {"default"},
+			},
+		},
+	} {
+		opts := append([]runtime.ServeMuxOption{
+			runtime.WithOutgoingHeaderMatcher(matcher),
+			runtime.WithOutgoingTrailerMatcher(matcher),
+		}, spec.opts...)
+		mux := runtime.NewServeMux(opts...)
+		ctx := runtime.NewServerMetadataContext(context.Background(), md)
+		req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+		w := httptest.NewRecorder()
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("X-Trailer", "default")
+		runtime.ForwardResponseMessage(ctx, mux, &runtime.JSONPb{}, w, req, &pb.SimpleMessage{Id: "foo"})
+
+		got := make(http.Header)
+		for _, h := range []string{"Cache-Control", "X-Backend", "X-Trailer"} {
+			got[h] = w.Header()[h]
+		}
+		if !reflect.DeepEqual(got, spec.want) {
+			t.Errorf("%s: headers = %q; want %q", spec.name, got, spec.want)
+		}
+	}
+}
+
+func TestStatusTrailers(t *testing.T) {
+	mux := runtime.NewServeMux(runtime.WithStatusTrailers())
+	for _, spec := range []struct {
+		name    string
+		err     error
+		status  string
+		message string
+	}{
+		{name: "ok", status: "0", message: ""},
+		{name: "error", err: status.Error(codes.NotFound, "no 100% match"), status: "5", message: "no 100%25 match"},
+	} {
+		req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+		w := httptest.NewRecorder()
+		if spec.err != nil {
+			runtime.HTTPError(context.Background(), mux, &runtime.JSONPb{}, w, req, spec.err)
+		} else {
+			runtime.ForwardResponseMessage(context.Background(), mux, &runtime.JSONPb{}, w, req, &pb.SimpleMessage{Id: "foo"})
+		}
+
+		resp := w.Result()
+		if got, want := resp.Header["Trailer"], []string{"Grpc-Status", "Grpc-Message"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Trailer = %q; want %q", spec.name, got, want)
+		}
+		if got := resp.Trailer.Get("Grpc-Status"); got != spec.status {
+			t.Errorf("%s: Grpc-Status = %q; want %q", spec.name, got, spec.status)
+		}
+		if got := resp.Trailer.Get("Grpc-Message"); got != spec.message {
+			t.Errorf("%s: Grpc-Message = %q; want %q", spec.name, got, spec.message)
+		}
+	}
+}
+
+func TestForwardResponseStreamErrorMessageSanitizer(t *testing.T) {
+	sanitizer := runtime.WithErrorMessageSanitizer(func(_ context.Context, code codes.Code, msg string) string {
+		if code == codes.Internal {
+			return "internal error"
+		}
+		return msg
+	})
+	for _, spec := range []struct {
+		name string
+		opts []runtime.ServeMuxOption
+	}{
+		{name: "chunks"},
+		{name: "frames", opts: []runtime.ServeMuxOption{runtime.WithStreamFraming()}},
+	} {
+		var sent bool
+		recv := func() (proto.Message, error) {
+			if !sent {
+				sent = true
+				return &pb.SimpleMessage{Id: "foo"}, nil
+			}
+			return nil, status.Error(codes.Internal, "dial tcp 10.0.0.1:5432: connection refused")
+		}
+		mux := runtime.NewServeMux(append([]runtime.ServeMuxOption{sanitizer}, spec.opts...)...)
+		ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+		req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+		w := httptest.NewRecorder()
+		runtime.ForwardResponseStream(ctx, mux, &runtime.JSONPb{}, w, req, recv)
+
+		body := w.Body.String()
+		if strings.Contains(body, "10.0.0.1") {
+			t.Errorf("%s: w.Body = %q; want the message of the error sanitized", spec.name, body)
+		}
+		if !strings.Contains(body, "internal error") {
+			t.Errorf("%s: w.Body = %q; want it to contain %q", spec.name, body, "internal error")
+		}
+	}
+}
+
+// countingStreamMarshaler is a runtime.StreamMarshaler counting the values marshaled and encoded.
+type countingStreamMarshaler struct {
+	runtime.JSONPb
+	marshaled, encoded int
+}
+
+func (m *countingStreamMarshaler) Marshal(v interface{}) ([]byte, error) {
+	m.marshaled++
+	return m.JSONPb.Marshal(v)
+}
+
+func (m *countingStreamMarshaler) NewStreamEncoder(w io.Writer) runtime.Encoder {
+	enc := m.JSONPb.NewEncoder(w)
+	return runtime.EncoderFunc(func(v interface{}) error {
+		m.encoded++
+		return enc.Encode(v)
+	})
+}
+
+func TestForwardResponseStreamEncoder(t *testing.T) {
+	ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	msgs := []proto.Message{&pb.SimpleMessage{Id: "One"}, &pb.SimpleMessage{Id: "Two"}}
+	newRecv := func() func() (proto.Message, error) {
+		var count int
+		return func() (proto.Message, error) {
+			if count == len(msgs) {
+				return nil, io.EOF
+			}
+			count++
+			return msgs[count-1], nil
+		}
+	}
+
+	marshaler := &countingStreamMarshaler{}
+	w := httptest.NewRecorder()
+	runtime.ForwardResponseStream(ctx, runtime.NewServeMux(), marshaler, w, req, newRecv())
+	if got, want := marshaler.encoded, len(msgs); got != want {
+		t.Errorf("encoded = %d; want %d", got, want)
+	}
+	if got := marshaler.marshaled; got != 0 {
+		t.Errorf("marshaled = %d; want 0", got)
+	}
+	want := "{\"result\":{\"id\":\"One\"}}\n{\"result\":{\"id\":\"Two\"}}\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("w.Body = %q; want %q", got, want)
+	}
+}
+
+func TestForwardResponseStreamJSONPbEncoder(t *testing.T) {
+	ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	for _, marshaler := range []runtime.Marshaler{&runtime.JSONPb{}, &runtime.NDJSONMarshaler{}} {
+		var done bool
+		recv := func() (proto.Message, error) {
+			if done {
+				return nil, io.EOF
+			}
+			done = true
+			return &pb.SimpleMessage{Id: "One"}, nil
+		}
+		w := httptest.NewRecorder()
+		runtime.ForwardResponseStream(ctx, runtime.NewServeMux(), marshaler, w, req, recv)
+		if got, want := w.Body.String(), "{\"result\":{\"id\":\"One\"}}\n"; got != want {
+			t.Errorf("w.Body = %q; want %q; marshaler=%T", got, want, marshaler)
+		}
+	}
+}
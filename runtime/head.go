@@ -0,0 +1,77 @@
+package runtime
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// WithHeadForGet returns a ServeMuxOption which makes the ServeMux serve HEAD requests which match
+// no HEAD handler with the GET handler of their path. The response has the status and the headers
+// of the GET response, with the Content-Length of the body which would have been written, but no body.
+// Streamed responses are sent without a Content-Length as soon as they are flushed.
+func WithHeadForGet() ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.headForGet = true
+	}
+}
+
+// headResponseWriter discards the body of the response written through it, and delays the header until
+// finish so that the Content-Length of the discarded body is known.
+type headResponseWriter struct {
+	http.ResponseWriter
+	status    int
+	length    int
+	committed bool
+}
+
+func (w *headResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.length += len(b)
+	return len(b), nil
+}
+
+// Flush writes the header without a Content-Length, as for a streamed response.
+func (w *headResponseWriter) Flush() {
+	w.commit()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *headResponseWriter) CloseNotify() <-chan bool {
+	if cn, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+// finish writes the header with the Content-Length of the discarded body unless it was already written.
+func (w *headResponseWriter) finish() {
+	if w.committed {
+		return
+	}
+	bodyAllowed := w.status != http.StatusNoContent && w.status != http.StatusNotModified
+	if bodyAllowed && w.Header().Get("Content-Length") == "" && w.Header().Get("Transfer-Encoding") == "" {
+		w.Header().Set("Content-Length", strconv.Itoa(w.length))
+	}
+	w.commit()
+}
+
+func (w *headResponseWriter) commit() {
+	if w.committed {
+		return
+	}
+	w.committed = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
@@ -0,0 +1,46 @@
+package runtime
+
+import (
+	"net/http"
+)
+
+// HeaderPrecedence is how the headers forwarded from the metadata of the backend are combined with the headers
+// already set in the response, e.g. by an http.Handler wrapping the ServeMux or with WithRouteHeaders.
+type HeaderPrecedence int
+
+const (
+	// HeadersAppend adds the forwarded values to those already set, which may duplicate a header.
+	// This is the default.
+	HeadersAppend HeaderPrecedence = iota
+	// MetadataOverridesHeaders replaces the values already set by the forwarded values of the same header.
+	MetadataOverridesHeaders
+	// HeadersOverrideMetadata drops the forwarded values of the headers which are already set.
+	HeadersOverrideMetadata
+)
+
+// WithHeaderPrecedence returns a ServeMuxOption which combines the headers forwarded from the header metadata,
+// and from the trailer metadata forwarded in the headers of unary responses, with the headers already set in
+// the response as configured by "precedence", e.g. so that a Cache-Control header is not duplicated.
+// Headers set by the gateway itself, e.g. Content-Type or the request ID header, are not affected.
+func WithHeaderPrecedence(precedence HeaderPrecedence) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.headerPrecedence = precedence
+	}
+}
+
+// mergeForwardedHeaders sets the headers forwarded from metadata, "forwarded", into the response as configured
+// by WithHeaderPrecedence.
+func mergeForwardedHeaders(w http.ResponseWriter, mux *ServeMux, forwarded http.Header) {
+	for h, vs := range forwarded {
+		switch mux.headerPrecedence {
+		case MetadataOverridesHeaders:
+			w.Header()[h] = vs
+		case HeadersOverrideMetadata:
+			if _, ok := w.Header()[h]; !ok {
+				w.Header()[h] = vs
+			}
+		default:
+			w.Header()[h] = append(w.Header()[h], vs...)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package runtime
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/grpc/grpclog"
+)
+
+// forwardHTTPBody writes the raw content of "body" as the response to "req" instead of marshaling it.
+// A single byte range requested by the Range header of a GET request is answered with
+// http.StatusPartialContent and the corresponding slice of the content, so that downloads can be resumed.
+// Requests for several ranges are answered with the full content.
+// "code" is the status of a response with the full content.
+func forwardHTTPBody(w http.ResponseWriter, req *http.Request, body *httpbody.HttpBody, code int) {
+	data := body.GetData()
+	if body.GetContentType() != "" {
+		w.Header().Set("Content-Type", body.GetContentType())
+	}
+	w.Header().Set("Accept-Ranges", "bytes")
+
+	if rng := req.Header.Get("Range"); rng != "" && req.Method == "GET" && code == http.StatusOK {
+		start, end, ok, err := parseByteRange(rng, int64(len(data)))
+		if err != nil {
+			w.Header().Del("Content-Type")
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", len(data)))
+			http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		if ok {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end-1, len(data)))
+			data = data[start:end]
+			code = http.StatusPartialContent
+		}
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(code)
+	if _, err := w.Write(data); err != nil {
+		grpclog.Printf("Failed to write response: %v", err)
+	}
+}
+
+// parseByteRange parses the value of a Range header for content of "size" bytes.
+// It returns the half-open interval [start, end) of the range and true if the header requests a single range,
+// or false if it requests several ranges.
+// An error is returned if the header is malformed or the range cannot be satisfied.
+func parseByteRange(s string, size int64) (start, end int64, ok bool, err error) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(s, prefix) {
+		return 0, 0, false, fmt.Errorf("invalid range: %q", s)
+	}
+	spec := strings.TrimSpace(s[len(prefix):])
+	if strings.Contains(spec, ",") {
+		return 0, 0, false, nil
+	}
+	i := strings.Index(spec, "-")
+	if i < 0 {
+		return 0, 0, false, fmt.Errorf("invalid range: %q", s)
+	}
+	first, last := strings.TrimSpace(spec[:i]), strings.TrimSpace(spec[i+1:])
+
+	if first == "" {
+		// A suffix range such as "-500" requests the last bytes of the content.
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 || size == 0 {
+			return 0, 0, false, fmt.Errorf("invalid range: %q", s)
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size, true, nil
+	}
+
+	start, err = strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false, fmt.Errorf("invalid range: %q", s)
+	}
+	end = size
+	if last != "" {
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < start {
+			return 0, 0, false, fmt.Errorf("invalid range: %q", s)
+		}
+		if n+1 < size {
+			end = n + 1
+		}
+	}
+	return start, end, true, nil
+}
@@ -0,0 +1,131 @@
+package runtime
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"google.golang.org/grpc/grpclog"
+)
+
+// IdempotencyKeyHeader is the HTTP header carrying the idempotency key of a request for WithIdempotency.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// CachedResponse is a response stored by WithIdempotency to be replayed to repeated requests.
+type CachedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// IdempotencyCache stores the responses replayed by WithIdempotency.
+// It must be safe for concurrent use.
+type IdempotencyCache interface {
+	// Get returns the response stored for "key", if any.
+	Get(key string) (*CachedResponse, bool)
+	// Set stores "resp" for "key" for the duration "ttl".
+	Set(key string, resp *CachedResponse, ttl time.Duration)
+}
+
+type idempotencyConfig struct {
+	cache   IdempotencyCache
+	window  time.Duration
+	methods map[string]bool
+}
+
+// WithIdempotency returns a ServeMuxOption which deduplicates the requests using one of the HTTP "methods",
+// e.g. "POST", which carry an IdempotencyKeyHeader. The successful response to such a request is stored
+// in "cache" for the duration "window", keyed by the idempotency key, the matched route and its path parameters,
+// and replayed to the requests repeating the key on the same resource instead of calling the backend again.
+// Requests carrying the same key concurrently are not deduplicated until one of them completes.
+func WithIdempotency(cache IdempotencyCache, window time.Duration, methods ...string) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		config := &idempotencyConfig{cache: cache, window: window, methods: make(map[string]bool)}
+		for _, m := range methods {
+			config.methods[m] = true
+		}
+		serveMux.idempotency = config
+	}
+}
+
+// serveIdempotent replays the response cached for "key" on the route of "h" with "pathParams", or calls "h" and
+// caches its response if successful.
+func (s *ServeMux) serveIdempotent(h handler, meth, key string, w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	params := make(url.Values, len(pathParams))
+	for k, v := range pathParams {
+		params.Set(k, v)
+	}
+	// Encode sorts the parameters by name and escapes them, so that the key is unambiguous.
+	cacheKey := fmt.Sprintf("%s %s?%s %s", meth, h.pat, params.Encode(), key)
+	if resp, ok := s.idempotency.cache.Get(cacheKey); ok {
+		for k, vs := range resp.Header {
+			w.Header()[k] = append([]string(nil), vs...)
+		}
+		w.WriteHeader(resp.StatusCode)
+		if _, err := w.Write(resp.Body); err != nil {
+			grpclog.Printf("Failed to write response: %v", err)
+		}
+		return
+	}
+
+	rw := &recordingResponseWriter{ResponseWriter: w}
+	h.h(rw, r, pathParams)
+	if rw.status == 0 {
+		rw.writeHeader(http.StatusOK)
+	}
+	if rw.status < 200 || rw.status >= 300 {
+		return
+	}
+	s.idempotency.cache.Set(cacheKey, &CachedResponse{
+		StatusCode: rw.status,
+		Header:     rw.header,
+		Body:       rw.body,
+	}, s.idempotency.window)
+}
+
+// recordingResponseWriter records the response written through it.
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   []byte
+}
+
+func (w *recordingResponseWriter) writeHeader(code int) {
+	w.status = code
+	w.header = make(http.Header)
+	for k, vs := range w.Header() {
+		w.header[k] = append([]string(nil), vs...)
+	}
+	// Trailers are not recorded, so they must not be announced when the response is replayed.
+	w.header.Del("Trailer")
+}
+
+func (w *recordingResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.writeHeader(code)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recordingResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.writeHeader(http.StatusOK)
+	}
+	w.body = append(w.body, b...)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *recordingResponseWriter) CloseNotify() <-chan bool {
+	if cn, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return make(chan bool)
+}
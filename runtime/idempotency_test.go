@@ -0,0 +1,130 @@
+package runtime_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/utilities"
+)
+
+type mapIdempotencyCache struct {
+	mu        sync.Mutex
+	responses map[string]*runtime.CachedResponse
+}
+
+func (c *mapIdempotencyCache) Get(key string) (*runtime.CachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	resp, ok := c.responses[key]
+	return resp, ok
+}
+
+func (c *mapIdempotencyCache) Set(key string, resp *runtime.CachedResponse, _ time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.responses[key] = resp
+}
+
+func TestMuxServeHTTPIdempotency(t *testing.T) {
+	cache := &mapIdempotencyCache{responses: make(map[string]*runtime.CachedResponse)}
+	mux := runtime.NewServeMux(runtime.WithIdempotency(cache, time.Minute, "POST"))
+	var calls int
+	status := http.StatusOK
+	pat := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0}, []string{"payments"}, ""))
+	handle := func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		calls++
+		w.Header().Set("X-Call", fmt.Sprint(calls))
+		w.WriteHeader(status)
+		fmt.Fprintf(w, "call %d", calls)
+	}
+	mux.Handle("POST", pat, handle)
+	mux.Handle("PUT", pat, handle)
+
+	serve := func(method, key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "http://host.example/payments", nil)
+		if key != "" {
+			r.Header.Set(runtime.IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	for _, spec := range []struct {
+		method, key string
+		status      int
+		wantBody    string
+		wantCalls   int
+	}{
+		{method: "POST", key: "a", status: http.StatusOK, wantBody: "call 1", wantCalls: 1},
+		// A repeated key replays the response.
+		{method: "POST", key: "a", status: http.StatusOK, wantBody: "call 1", wantCalls: 1},
+		{method: "POST", key: "b", status: http.StatusOK, wantBody: "call 2", wantCalls: 2},
+		// Requests without a key or with a method not opted in are not deduplicated.
+		{method: "POST", status: http.StatusOK, wantBody: "call 3", wantCalls: 3},
+		{method: "PUT", key: "a", status: http.StatusOK, wantBody: "call 4", wantCalls: 4},
+		{method: "PUT", key: "a", status: http.StatusOK, wantBody: "call 5", wantCalls: 5},
+		// Failed responses are not stored.
+		{method: "POST", key: "c", status: http.StatusInternalServerError, wantBody: "call 6", wantCalls: 6},
+		{method: "POST", key: "c", status: http.StatusOK, wantBody: "call 7", wantCalls: 7},
+		{method: "POST", key: "c", status: http.StatusOK, wantBody: "call 7", wantCalls: 7},
+	} {
+		status = spec.status
+		w := serve(spec.method, spec.key)
+		if got, want := w.Body.String(), spec.wantBody; got != want {
+			t.Errorf("%s with key %q: w.Body = %q; want %q", spec.method, spec.key, got, want)
+		}
+		if got, want := w.Header().Get("X-Call"), spec.wantBody[len("call "):]; got != want {
+			t.Errorf("%s with key %q: X-Call = %q; want %q", spec.method, spec.key, got, want)
+		}
+		if got, want := w.Code, spec.status; got != want {
+			t.Errorf("%s with key %q: w.Code = %d; want %d", spec.method, spec.key, got, want)
+		}
+		if calls != spec.wantCalls {
+			t.Errorf("%s with key %q: calls = %d; want %d", spec.method, spec.key, calls, spec.wantCalls)
+		}
+	}
+}
+
+func TestMuxServeHTTPIdempotencyPathParameters(t *testing.T) {
+	cache := &mapIdempotencyCache{responses: make(map[string]*runtime.CachedResponse)}
+	mux := runtime.NewServeMux(runtime.WithIdempotency(cache, time.Minute, "POST"))
+	var calls int
+	pat := runtime.MustPattern(runtime.NewPattern(1, []int{
+		int(utilities.OpLitPush), 0, int(utilities.OpPush), 0, int(utilities.OpConcatN), 1, int(utilities.OpCapture), 1,
+		int(utilities.OpLitPush), 2,
+	}, []string{"accounts", "id", "charge"}, ""))
+	mux.Handle("POST", pat, func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		calls++
+		fmt.Fprintf(w, "charged %s", pathParams["id"])
+	})
+
+	for _, spec := range []struct {
+		path      string
+		wantBody  string
+		wantCalls int
+	}{
+		{path: "/accounts/A/charge", wantBody: "charged A", wantCalls: 1},
+		// The same key on another resource is another request.
+		{path: "/accounts/B/charge", wantBody: "charged B", wantCalls: 2},
+		{path: "/accounts/A/charge", wantBody: "charged A", wantCalls: 2},
+		{path: "/accounts/B/charge", wantBody: "charged B", wantCalls: 2},
+	} {
+		r := httptest.NewRequest("POST", "http://host.example"+spec.path, nil)
+		r.Header.Set(runtime.IdempotencyKeyHeader, "a")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		if got, want := w.Body.String(), spec.wantBody; got != want {
+			t.Errorf("POST %s: w.Body = %q; want %q", spec.path, got, want)
+		}
+		if calls != spec.wantCalls {
+			t.Errorf("POST %s: calls = %d; want %d", spec.path, calls, spec.wantCalls)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package runtime
+
+import (
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+)
+
+// UnaryInfo describes the unary call seen by a UnaryInterceptor.
+type UnaryInfo struct {
+	// FullMethod is the full name of the gRPC method, e.g. "/package.Service/Method".
+	FullMethod string
+}
+
+// UnaryInvoker performs the gRPC call with the request message "req" and returns its response message.
+type UnaryInvoker func(ctx context.Context, req proto.Message) (proto.Message, error)
+
+// UnaryInterceptor intercepts the gRPC calls of unary methods made by the generated handlers,
+// once the request message is decoded from the HTTP request. It sees both the request and the
+// response message, and must call "invoker" to perform the call, possibly with a modified "ctx" or a "req"
+// of the same type.
+type UnaryInterceptor func(ctx context.Context, info *UnaryInfo, req proto.Message, invoker UnaryInvoker) (proto.Message, error)
+
+// WithUnaryInterceptor returns a ServeMuxOption which adds "interceptors" to the interceptors of unary calls,
+// e.g. for audit logs of the request and response messages. Interceptors are applied in the order they
+// are given, the first one being the outermost. See InvokeUnary.
+func WithUnaryInterceptor(interceptors ...UnaryInterceptor) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.unaryInterceptors = append(serveMux.unaryInterceptors, interceptors...)
+	}
+}
+
+type unaryInterceptorsKey struct{}
+
+// InvokeUnary calls "invoker" with "req" through the interceptors given with WithUnaryInterceptor
+// to the ServeMux in "ctx", retrying it as configured by WithRetry. "fullMethod" is the full name of the gRPC method.
+// Generated handlers of unary methods use it to make the gRPC call.
+func InvokeUnary(ctx context.Context, fullMethod string, req proto.Message, invoker UnaryInvoker) (proto.Message, error) {
+	invoker = timeUnary(ctx, retryUnary(ctx, fullMethod, invoker))
+	interceptors, _ := ctx.Value(unaryInterceptorsKey{}).([]UnaryInterceptor)
+	if len(interceptors) == 0 {
+		return invoker(ctx, req)
+	}
+	info := &UnaryInfo{FullMethod: fullMethod}
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+		invoker = func(ctx context.Context, req proto.Message) (proto.Message, error) {
+			return interceptor(ctx, info, req, next)
+		}
+	}
+	return invoker(ctx, req)
+}
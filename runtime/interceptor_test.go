@@ -0,0 +1,55 @@
+package runtime_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"golang.org/x/net/context"
+)
+
+func TestInvokeUnary(t *testing.T) {
+	const fullMethod = "/grpc.gateway.examples.examplepb.EchoService/Echo"
+	var calls []string
+	interceptor := func(name string) runtime.UnaryInterceptor {
+		return func(ctx context.Context, info *runtime.UnaryInfo, req proto.Message, invoker runtime.UnaryInvoker) (proto.Message, error) {
+			if info.FullMethod != fullMethod {
+				t.Errorf("info.FullMethod = %q; want %q", info.FullMethod, fullMethod)
+			}
+			calls = append(calls, name+" "+req.(*pb.SimpleMessage).Id)
+			resp, err := invoker(ctx, &pb.SimpleMessage{Id: req.(*pb.SimpleMessage).Id + "+" + name})
+			if err != nil {
+				return nil, err
+			}
+			calls = append(calls, name+" "+resp.(*pb.SimpleMessage).Id)
+			return resp, nil
+		}
+	}
+	mux := runtime.NewServeMux(runtime.WithUnaryInterceptor(interceptor("a")), runtime.WithUnaryInterceptor(interceptor("b")))
+	req, err := http.NewRequest("POST", "http://example.com/v1/example/echo", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest failed with %v; want success", err)
+	}
+	ctx, err := runtime.AnnotateContext(context.Background(), mux, req)
+	if err != nil {
+		t.Fatalf("runtime.AnnotateContext(ctx, mux, req) failed with %v; want success", err)
+	}
+
+	resp, err := runtime.InvokeUnary(ctx, fullMethod, &pb.SimpleMessage{Id: "foo"}, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		calls = append(calls, "invoker "+req.(*pb.SimpleMessage).Id)
+		return &pb.SimpleMessage{Id: "bar"}, nil
+	})
+	if err != nil {
+		t.Fatalf("runtime.InvokeUnary(ctx, %q, req, invoker) failed with %v; want success", fullMethod, err)
+	}
+	if got, want := resp.(*pb.SimpleMessage).Id, "bar"; got != want {
+		t.Errorf("resp.Id = %q; want %q", got, want)
+	}
+	want := []string{"a foo", "b foo+a", "invoker foo+a+b", "b bar", "a bar"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %q; want %q", calls, want)
+	}
+}
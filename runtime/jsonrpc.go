@@ -0,0 +1,229 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/status"
+)
+
+// Error codes defined by the JSON-RPC 2.0 specification.
+const (
+	JSONRPCParseError     = -32700
+	JSONRPCInvalidRequest = -32600
+	JSONRPCMethodNotFound = -32601
+	JSONRPCInvalidParams  = -32602
+	JSONRPCInternalError  = -32603
+	// JSONRPCServerError is the upper bound of the range reserved for implementation-defined server errors.
+	// Other gRPC status codes are reported as JSONRPCServerError minus the code.
+	JSONRPCServerError = -32000
+)
+
+const jsonRPCVersion = "2.0"
+
+// JSONRPCInvokeFunc invokes a unary gRPC method with a decoded request message.
+type JSONRPCInvokeFunc func(ctx context.Context, req proto.Message) (proto.Message, error)
+
+// JSONRPCHandler is an http.Handler which serves JSON-RPC 2.0 requests by dispatching them to gRPC methods.
+// Params and results are converted with the marshalers registered on the associated ServeMux,
+// so the negotiated marshalers must produce JSON.
+type JSONRPCHandler struct {
+	mux     *ServeMux
+	methods map[string]jsonRPCMethod
+}
+
+type jsonRPCMethod struct {
+	newRequest func() proto.Message
+	invoke     JSONRPCInvokeFunc
+}
+
+// NewJSONRPCHandler returns a new JSONRPCHandler which negotiates marshalers and annotates contexts with "mux".
+func NewJSONRPCHandler(mux *ServeMux) *JSONRPCHandler {
+	return &JSONRPCHandler{
+		mux:     mux,
+		methods: make(map[string]jsonRPCMethod),
+	}
+}
+
+// Handle associates the JSON-RPC method name "method", e.g. "Service.Method", with "invoke".
+// "newRequest" must return an empty request message which "params" are unmarshaled into.
+func (h *JSONRPCHandler) Handle(method string, newRequest func() proto.Message, invoke JSONRPCInvokeFunc) {
+	h.methods[method] = jsonRPCMethod{newRequest: newRequest, invoke: invoke}
+}
+
+// JSONRPCInvoker returns a JSONRPCInvokeFunc which calls the gRPC method "fullMethod", e.g. "/pkg.Service/Method", over "conn".
+// "newResponse" must return an empty response message.
+func JSONRPCInvoker(conn *grpc.ClientConn, fullMethod string, newResponse func() proto.Message) JSONRPCInvokeFunc {
+	return func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		resp := newResponse()
+		if err := grpc.Invoke(ctx, fullMethod, req, resp, conn, CallOptionsFromContext(ctx)...); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+}
+
+// jsonRPCRequest is a JSON-RPC 2.0 request. Its ID is empty if the member is absent, i.e. for notifications,
+// and "null" if the id is null.
+type jsonRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+type jsonRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *jsonRPCError   `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type jsonRPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// ServeHTTP decodes a single or batched JSON-RPC request from the body of "r" and writes the corresponding response.
+func (h *JSONRPCHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		OtherErrorHandler(w, r, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	inbound, outbound := MarshalerForRequest(h.mux, r)
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		OtherErrorHandler(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var result interface{}
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(trimmed, &batch); err != nil {
+			result = newJSONRPCErrorResponse(nil, JSONRPCParseError, err.Error())
+		} else if len(batch) == 0 {
+			result = newJSONRPCErrorResponse(nil, JSONRPCInvalidRequest, "empty batch")
+		} else {
+			var resps []*jsonRPCResponse
+			for _, raw := range batch {
+				if resp := h.serveOne(r, inbound, outbound, raw); resp != nil {
+					resps = append(resps, resp)
+				}
+			}
+			if len(resps) > 0 {
+				result = resps
+			}
+		}
+	} else if resp := h.serveOne(r, inbound, outbound, body); resp != nil {
+		result = resp
+	}
+
+	if result == nil {
+		// Only notifications were received.
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	buf, err := json.Marshal(result)
+	if err != nil {
+		grpclog.Printf("Failed to marshal JSON-RPC response: %v", err)
+		OtherErrorHandler(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", outbound.ContentType())
+	if _, err := w.Write(buf); err != nil {
+		grpclog.Printf("Failed to write response: %v", err)
+	}
+}
+
+// serveOne handles a single JSON-RPC request. It returns nil if the request is a notification,
+// i.e. it has no "id" member. A request whose id is null is not a notification.
+func (h *JSONRPCHandler) serveOne(r *http.Request, inbound, outbound Marshaler, raw []byte) *jsonRPCResponse {
+	var req jsonRPCRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return newJSONRPCErrorResponse(nil, JSONRPCParseError, err.Error())
+	}
+	if req.JSONRPC != jsonRPCVersion || req.Method == "" {
+		return newJSONRPCErrorResponse(req.ID, JSONRPCInvalidRequest, "invalid JSON-RPC 2.0 request")
+	}
+
+	resp := h.call(r, inbound, outbound, &req)
+	if len(req.ID) == 0 {
+		return nil
+	}
+	return resp
+}
+
+func (h *JSONRPCHandler) call(r *http.Request, inbound, outbound Marshaler, req *jsonRPCRequest) *jsonRPCResponse {
+	m, ok := h.methods[req.Method]
+	if !ok {
+		return newJSONRPCErrorResponse(req.ID, JSONRPCMethodNotFound, "method not found: "+req.Method)
+	}
+
+	protoReq := m.newRequest()
+	if len(req.Params) > 0 {
+		if err := inbound.Unmarshal(req.Params, protoReq); err != nil {
+			return newJSONRPCErrorResponse(req.ID, JSONRPCInvalidParams, err.Error())
+		}
+	}
+
+	ctx, err := AnnotateContext(r.Context(), h.mux, r)
+	if err != nil {
+		return h.statusErrorResponse(req.ID, outbound, err)
+	}
+	protoResp, err := m.invoke(ctx, protoReq)
+	if err != nil {
+		return h.statusErrorResponse(req.ID, outbound, err)
+	}
+
+	buf, err := outbound.Marshal(protoResp)
+	if err != nil {
+		grpclog.Printf("Marshal error: %v", err)
+		return newJSONRPCErrorResponse(req.ID, JSONRPCInternalError, err.Error())
+	}
+	return &jsonRPCResponse{JSONRPC: jsonRPCVersion, Result: buf, ID: req.ID}
+}
+
+func (h *JSONRPCHandler) statusErrorResponse(id json.RawMessage, outbound Marshaler, err error) *jsonRPCResponse {
+	s, ok := status.FromError(err)
+	if !ok {
+		s = status.New(codes.Unknown, err.Error())
+	}
+	resp := newJSONRPCErrorResponse(id, JSONRPCCodeFromStatus(s.Code()), s.Message())
+	if buf, merr := outbound.Marshal(s.Proto()); merr == nil {
+		resp.Error.Data = buf
+	} else {
+		grpclog.Printf("Failed to marshal error details %q: %v", s.Proto(), merr)
+	}
+	return resp
+}
+
+// JSONRPCCodeFromStatus converts a gRPC error code into the corresponding JSON-RPC 2.0 error code.
+func JSONRPCCodeFromStatus(code codes.Code) int {
+	switch code {
+	case codes.InvalidArgument:
+		return JSONRPCInvalidParams
+	case codes.Unimplemented:
+		return JSONRPCMethodNotFound
+	case codes.Unknown, codes.Internal, codes.DataLoss:
+		return JSONRPCInternalError
+	}
+	return JSONRPCServerError - int(code)
+}
+
+func newJSONRPCErrorResponse(id json.RawMessage, code int, msg string) *jsonRPCResponse {
+	return &jsonRPCResponse{
+		JSONRPC: jsonRPCVersion,
+		Error:   &jsonRPCError{Code: code, Message: msg},
+		ID:      id,
+	}
+}
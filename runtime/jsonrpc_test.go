@@ -0,0 +1,149 @@
+package runtime_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestJSONRPCHandler(t *testing.T) {
+	h := runtime.NewJSONRPCHandler(runtime.NewServeMux())
+	h.Handle("EchoService.Echo", func() proto.Message { return new(pb.SimpleMessage) }, func(_ context.Context, req proto.Message) (proto.Message, error) {
+		msg := req.(*pb.SimpleMessage)
+		if msg.Id == "" {
+			return nil, status.Error(codes.InvalidArgument, "missing id")
+		}
+		return msg, nil
+	})
+
+	for _, spec := range []struct {
+		body string
+
+		respStatus int
+		wantResult string
+		wantCode   int
+		wantID     string
+	}{
+		{
+			body:       `{"jsonrpc":"2.0","method":"EchoService.Echo","params":{"id":"foo"},"id":1}`,
+			respStatus: http.StatusOK,
+			wantResult: `{"id":"foo"}`,
+			wantID:     "1",
+		},
+		{
+			body:       `{"jsonrpc":"2.0","method":"EchoService.Echo","params":{},"id":"a"}`,
+			respStatus: http.StatusOK,
+			wantCode:   runtime.JSONRPCInvalidParams,
+			wantID:     `"a"`,
+		},
+		{
+			body:       `{"jsonrpc":"2.0","method":"EchoService.Unknown","id":2}`,
+			respStatus: http.StatusOK,
+			wantCode:   runtime.JSONRPCMethodNotFound,
+			wantID:     "2",
+		},
+		{
+			body:       `{"jsonrpc":"1.0","method":"EchoService.Echo","id":3}`,
+			respStatus: http.StatusOK,
+			wantCode:   runtime.JSONRPCInvalidRequest,
+			wantID:     "3",
+		},
+		{
+			body:       `{"jsonrpc":`,
+			respStatus: http.StatusOK,
+			wantCode:   runtime.JSONRPCParseError,
+			wantID:     "null",
+		},
+		{
+			body:       `{"jsonrpc":"2.0","method":"EchoService.Echo","params":{"id":"foo"}}`,
+			respStatus: http.StatusNoContent,
+		},
+		// A null id is not a notification.
+		{
+			body:       `{"jsonrpc":"2.0","method":"EchoService.Echo","params":{"id":"foo"},"id":null}`,
+			respStatus: http.StatusOK,
+			wantResult: `{"id":"foo"}`,
+			wantID:     "null",
+		},
+	} {
+		req := httptest.NewRequest("POST", "http://example.com/rpc", strings.NewReader(spec.body))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		if got, want := w.Code, spec.respStatus; got != want {
+			t.Errorf("w.Code = %d; want %d; body = %s", got, want, spec.body)
+			continue
+		}
+		if spec.respStatus == http.StatusNoContent {
+			continue
+		}
+
+		var resp struct {
+			JSONRPC string          `json:"jsonrpc"`
+			Result  json.RawMessage `json:"result"`
+			Error   *struct {
+				Code int `json:"code"`
+			} `json:"error"`
+			ID json.RawMessage `json:"id"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Errorf("json.Unmarshal(%q, &resp) failed with %v; want success", w.Body.Bytes(), err)
+			continue
+		}
+		if got, want := resp.JSONRPC, "2.0"; got != want {
+			t.Errorf("resp.JSONRPC = %q; want %q", got, want)
+		}
+		if got, want := string(resp.ID), spec.wantID; got != want {
+			t.Errorf("resp.ID = %s; want %s; body = %s", got, want, spec.body)
+		}
+		if spec.wantCode != 0 {
+			if resp.Error == nil {
+				t.Errorf("resp.Error = nil; want code %d; body = %s", spec.wantCode, spec.body)
+			} else if got, want := resp.Error.Code, spec.wantCode; got != want {
+				t.Errorf("resp.Error.Code = %d; want %d; body = %s", got, want, spec.body)
+			}
+			continue
+		}
+		if got, want := string(resp.Result), spec.wantResult; got != want {
+			t.Errorf("resp.Result = %s; want %s", got, want)
+		}
+	}
+}
+
+func TestJSONRPCHandlerBatch(t *testing.T) {
+	h := runtime.NewJSONRPCHandler(runtime.NewServeMux())
+	h.Handle("EchoService.Echo", func() proto.Message { return new(pb.SimpleMessage) }, func(_ context.Context, req proto.Message) (proto.Message, error) {
+		return req, nil
+	})
+
+	body := `[
+		{"jsonrpc":"2.0","method":"EchoService.Echo","params":{"id":"foo"},"id":1},
+		{"jsonrpc":"2.0","method":"EchoService.Echo","params":{"id":"bar"}},
+		{"jsonrpc":"2.0","method":"EchoService.Echo","params":{"id":"baz"},"id":2}
+	]`
+	req := httptest.NewRequest("POST", "http://example.com/rpc", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	var resps []map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resps); err != nil {
+		t.Fatalf("json.Unmarshal(%q, &resps) failed with %v; want success", w.Body.Bytes(), err)
+	}
+	if got, want := len(resps), 2; got != want {
+		t.Fatalf("len(resps) = %d; want %d", got, want)
+	}
+	for i, id := range []float64{1, 2} {
+		if got := resps[i]["id"]; got != id {
+			t.Errorf("resps[%d][\"id\"] = %v; want %v", i, got, id)
+		}
+	}
+}
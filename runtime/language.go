@@ -0,0 +1,52 @@
+package runtime
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// WithAcceptLanguageMetadata returns a ServeMuxOption which forwards the language preferred by the
+// Accept-Language header of requests to the gRPC metadata "key", e.g. "x-language". The preferred
+// language is the tag with the highest quality value, or the first of them in case of a tie, e.g.
+// "de-CH" for "fr;q=0.8, de-CH, en;q=0.9". The wildcard "*" and tags with a quality of 0 are ignored.
+//
+// The header itself is not forwarded unless the incoming header matcher does so.
+func WithAcceptLanguageMetadata(key string) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.acceptLanguageKey = strings.ToLower(key)
+	}
+}
+
+// preferredLanguage returns the language tag with the highest quality in the Accept-Language header of "req".
+func preferredLanguage(req *http.Request) (string, bool) {
+	var (
+		best  string
+		bestQ float64
+	)
+	for _, v := range req.Header[http.CanonicalHeaderKey("Accept-Language")] {
+		for _, lang := range strings.Split(v, ",") {
+			q := 1.0
+			parts := strings.Split(lang, ";")
+			for _, param := range parts[1:] {
+				kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+				if len(kv) != 2 || !strings.EqualFold(strings.TrimSpace(kv[0]), "q") {
+					continue
+				}
+				f, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+				if err != nil || f < 0 || f > 1 {
+					// Ignore tags with an invalid quality rather than guessing it.
+					q = 0
+					break
+				}
+				q = f
+			}
+			tag := strings.TrimSpace(parts[0])
+			if tag == "" || tag == "*" || q <= bestQ {
+				continue
+			}
+			best, bestQ = tag, q
+		}
+	}
+	return best, best != ""
+}
@@ -0,0 +1,56 @@
+package runtime_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestAcceptLanguageMetadata(t *testing.T) {
+	mux := runtime.NewServeMux(runtime.WithAcceptLanguageMetadata("X-Language"))
+	for _, spec := range []struct {
+		header []string
+		want   []string
+	}{
+		{},
+		{
+			header: []string{"en-US"},
+			want:   []string{"en-US"},
+		},
+		{
+			header: []string{"fr;q=0.8, de-CH, en;q=0.9"},
+			want:   []string{"de-CH"},
+		},
+		{
+			header: []string{"fr;q=0.8", "en;q=0.9, de;q=0.9"},
+			want:   []string{"en"},
+		},
+		{
+			header: []string{"*, ja;q=0.5"},
+			want:   []string{"ja"},
+		},
+		{
+			header: []string{"en;q=0, fr;q=abc"},
+		},
+	} {
+		req, err := http.NewRequest("GET", "http://example.com/foo", nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest(%q, %q, nil) failed with %v; want success", "GET", "http://example.com/foo", err)
+		}
+		for _, v := range spec.header {
+			req.Header.Add("Accept-Language", v)
+		}
+		ctx, err := runtime.AnnotateContext(context.Background(), mux, req)
+		if err != nil {
+			t.Fatalf("runtime.AnnotateContext(ctx, mux, req) failed with %v; want success", err)
+		}
+		md, _ := metadata.FromOutgoingContext(ctx)
+		if got, want := md["x-language"], spec.want; !reflect.DeepEqual(got, want) {
+			t.Errorf(`md["x-language"] = %q; want %q; Accept-Language = %q`, got, want, spec.header)
+		}
+	}
+}
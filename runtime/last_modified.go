@@ -0,0 +1,84 @@
+package runtime
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"golang.org/x/net/context"
+)
+
+type requestHeaderKey struct{}
+
+// LastModifiedHeader returns a forward response option which sets the Last-Modified header of the responses to
+// GET and HEAD requests to the google.protobuf.Timestamp at the dot-separated path of proto field names "field"
+// in the response message, e.g. "update_time". Register it with WithForwardResponseOption.
+//
+// If the request has an If-Modified-Since header and the resource has not been modified since, the response is
+// 304 Not Modified without a body. As HTTP dates have a precision of a second, the timestamp is truncated to
+// the second. If-Modified-Since is ignored if the request also has an If-None-Match header.
+// Responses whose timestamp is unset are left as they are.
+func LastModifiedHeader(field string) func(context.Context, http.ResponseWriter, proto.Message) error {
+	fieldPath := strings.Split(field, ".")
+	return func(ctx context.Context, w http.ResponseWriter, msg proto.Message) error {
+		if meth, _ := ctx.Value(httpMethodKey{}).(string); (meth != "GET" && meth != "HEAD") || msg == nil {
+			return nil
+		}
+		modified, ok := timestampByPath(reflect.ValueOf(msg), fieldPath)
+		if !ok {
+			return nil
+		}
+		modified = modified.Truncate(time.Second)
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+
+		header, _ := ctx.Value(requestHeaderKey{}).(http.Header)
+		if header.Get("If-None-Match") != "" {
+			return nil
+		}
+		if since, err := http.ParseTime(header.Get("If-Modified-Since")); err == nil && !modified.After(since) {
+			w.WriteHeader(http.StatusNotModified)
+		}
+		return nil
+	}
+}
+
+// timestampByPath returns the time of the google.protobuf.Timestamp field at "fieldPath" in the message "v".
+func timestampByPath(v reflect.Value, fieldPath []string) (time.Time, bool) {
+	for _, name := range fieldPath {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return time.Time{}, false
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return time.Time{}, false
+		}
+		l := lookupField(v.Type(), name)
+		switch {
+		case l.oneof != nil:
+			f := v.Field(l.oneof.Field)
+			if f.IsNil() || f.Elem().Type() != l.oneof.Type {
+				return time.Time{}, false
+			}
+			v = f.Elem().Elem().Field(0)
+		case l.props != nil:
+			v = v.FieldByIndex(l.index)
+		default:
+			return time.Time{}, false
+		}
+	}
+	ts, ok := v.Interface().(*timestamp.Timestamp)
+	if !ok || ts == nil {
+		return time.Time{}, false
+	}
+	t, err := ptypes.Timestamp(ts)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,110 @@
+package runtime
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+)
+
+type httpMethodKey struct{}
+
+// locationFieldPattern matches the field paths in a Location template, e.g. "{user.id}".
+var locationFieldPattern = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// LocationHeader returns a forward response option which sets the Location header of the responses to POST
+// requests, e.g. of methods creating a resource, to the URL of the resource. Register it with
+// WithForwardResponseOption. If "created" is true, the response status is also set to 201 Created.
+//
+// "tmpl" is the URL of the resource in which each dot-separated path of proto field names in braces is
+// replaced with the value of that field in the response message, e.g. "/v1/users/{user.id}" or "/v1/{name}".
+// Values are escaped segment by segment so that a resource name such as "shelves/1/books/2" keeps its slashes.
+// Responses which do not have all the fields of "tmpl" set are left as they are.
+func LocationHeader(tmpl string, created bool) func(context.Context, http.ResponseWriter, proto.Message) error {
+	return func(ctx context.Context, w http.ResponseWriter, msg proto.Message) error {
+		if meth, _ := ctx.Value(httpMethodKey{}).(string); meth != "POST" || msg == nil {
+			return nil
+		}
+		loc, ok := expandLocation(tmpl, msg)
+		if !ok {
+			return nil
+		}
+		w.Header().Set("Location", loc)
+		if created {
+			w.WriteHeader(http.StatusCreated)
+		}
+		return nil
+	}
+}
+
+// expandLocation replaces the field paths in "tmpl" with the values of the fields of "msg".
+// It returns false if any of the fields does not exist or is unset.
+func expandLocation(tmpl string, msg proto.Message) (string, bool) {
+	ok := true
+	loc := locationFieldPattern.ReplaceAllStringFunc(tmpl, func(m string) string {
+		val, found := fieldValueByPath(reflect.ValueOf(msg), strings.Split(m[1:len(m)-1], "."))
+		if !found || val == "" {
+			ok = false
+			return ""
+		}
+		segs := strings.Split(val, "/")
+		for i, seg := range segs {
+			segs[i] = url.PathEscape(seg)
+		}
+		return strings.Join(segs, "/")
+	})
+	return loc, ok
+}
+
+// fieldValueByPath returns the string representation of the scalar field at "fieldPath" in the message "v".
+func fieldValueByPath(v reflect.Value, fieldPath []string) (string, bool) {
+	for _, name := range fieldPath {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return "", false
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return "", false
+		}
+		l := lookupField(v.Type(), name)
+		switch {
+		case l.oneof != nil:
+			f := v.Field(l.oneof.Field)
+			if f.IsNil() || f.Elem().Type() != l.oneof.Type {
+				return "", false
+			}
+			v = f.Elem().Elem().Field(0)
+		case l.props != nil:
+			v = v.FieldByIndex(l.index)
+		default:
+			return "", false
+		}
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true
+	case reflect.Int32, reflect.Int64, reflect.Uint32, reflect.Uint64:
+		if v.Interface() == reflect.Zero(v.Type()).Interface() {
+			return "", false
+		}
+		if s, ok := v.Interface().(fmt.Stringer); ok {
+			// An enum.
+			return s.String(), true
+		}
+		return fmt.Sprint(v.Interface()), true
+	}
+	return "", false
+}
@@ -0,0 +1,198 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// CSVMarshaler is a Marshaler which writes responses of tabular list methods as CSV (RFC 4180),
+// e.g. for spreadsheets. Register it for "text/csv" with WithMarshalerOption.
+//
+// A response must have exactly one repeated message field, whose elements are written one per row.
+// Other fields of the response, e.g. a page token, are left out. The header row consists of the names of
+// the scalar fields of the element type, or of their JSON names if OrigName is false. Fields of other kinds,
+// i.e. messages, repeated fields, maps and oneofs, are skipped. Enums are written by name and bytes in base64.
+//
+// Error responses are written as a single row of their error, code and status.
+// CSVMarshaler does not unmarshal request bodies.
+type CSVMarshaler struct {
+	// OrigName specifies whether the header uses the original protobuf field names.
+	OrigName bool
+}
+
+// ContentType always returns "text/csv".
+func (*CSVMarshaler) ContentType() string {
+	return "text/csv"
+}
+
+// Marshal marshals the elements of the repeated message field of "v" into CSV.
+func (c *CSVMarshaler) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.marshalTo(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *CSVMarshaler) marshalTo(w io.Writer, v interface{}) error {
+	if e, ok := v.(*errorBody); ok {
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"error", "code", "status"})
+		cw.Write([]string{e.Error, strconv.Itoa(int(e.Code)), e.Status})
+		cw.Flush()
+		return cw.Error()
+	}
+
+	rows, err := csvRows(v)
+	if err != nil {
+		return err
+	}
+	cols := c.columns(rows.Type().Elem())
+	cw := csv.NewWriter(w)
+	header := make([]string, len(cols))
+	for i, col := range cols {
+		header[i] = col.name
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for i := 0; i < rows.Len(); i++ {
+		row := rows.Index(i)
+		for row.Kind() == reflect.Ptr && !row.IsNil() {
+			row = row.Elem()
+		}
+		record := make([]string, len(cols))
+		if row.Kind() == reflect.Struct {
+			for j, col := range cols {
+				record[j] = csvValue(row.FieldByIndex(col.index), col.props)
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvRows returns the only repeated message field of the message "v".
+func csvRows(v interface{}) (reflect.Value, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("cannot marshal %T into CSV: not a proto message", v)
+	}
+	rv := reflect.ValueOf(msg)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("cannot marshal %T into CSV: not a message struct", v)
+	}
+	rv = rv.Elem()
+	var rows []reflect.Value
+	for _, p := range proto.GetProperties(rv.Type()).Prop {
+		f, ok := rv.Type().FieldByName(p.Name)
+		if !ok || !p.Repeated || f.Type.Kind() != reflect.Slice {
+			continue
+		}
+		if elem := f.Type.Elem(); elem.Kind() == reflect.Ptr && elem.Elem().Kind() == reflect.Struct {
+			rows = append(rows, rv.FieldByIndex(f.Index))
+		}
+	}
+	if len(rows) != 1 {
+		return reflect.Value{}, fmt.Errorf("cannot marshal %s into CSV: it has %d repeated message fields; want exactly 1", proto.MessageName(msg), len(rows))
+	}
+	return rows[0], nil
+}
+
+type csvColumn struct {
+	name  string
+	index []int
+	props *proto.Properties
+}
+
+// columns returns the scalar fields of the message element type "t".
+func (c *CSVMarshaler) columns(t reflect.Type) []csvColumn {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var cols []csvColumn
+	for _, p := range proto.GetProperties(t).Prop {
+		f, ok := t.FieldByName(p.Name)
+		if !ok || p.Tag == 0 || !isCSVScalar(f.Type) {
+			continue
+		}
+		name := p.OrigName
+		if !c.OrigName && p.JSONName != "" {
+			name = p.JSONName
+		}
+		cols = append(cols, csvColumn{name: name, index: f.Index, props: p})
+	}
+	return cols
+}
+
+func isCSVScalar(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		// A scalar field with explicit presence.
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int32, reflect.Int64, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64, reflect.String:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	}
+	return false
+}
+
+// csvValue formats the scalar field "f" for a CSV record.
+func csvValue(f reflect.Value, props *proto.Properties) string {
+	if f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			return ""
+		}
+		f = f.Elem()
+	}
+	switch f.Kind() {
+	case reflect.Int32:
+		if props.Enum != "" {
+			if s, ok := f.Interface().(fmt.Stringer); ok {
+				return s.String()
+			}
+		}
+		return strconv.FormatInt(f.Int(), 10)
+	case reflect.Int64:
+		return strconv.FormatInt(f.Int(), 10)
+	case reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(f.Uint(), 10)
+	case reflect.Float32:
+		return strconv.FormatFloat(f.Float(), 'g', -1, 32)
+	case reflect.Float64:
+		return strconv.FormatFloat(f.Float(), 'g', -1, 64)
+	case reflect.Bool:
+		return strconv.FormatBool(f.Bool())
+	case reflect.Slice:
+		return base64.StdEncoding.EncodeToString(f.Bytes())
+	}
+	return f.String()
+}
+
+// Unmarshal always fails since CSVMarshaler does not support request bodies.
+func (*CSVMarshaler) Unmarshal(data []byte, v interface{}) error {
+	return errors.New("CSV request bodies are not supported")
+}
+
+// NewDecoder returns a Decoder which always fails since CSVMarshaler does not support request bodies.
+func (c *CSVMarshaler) NewDecoder(r io.Reader) Decoder {
+	return DecoderFunc(func(v interface{}) error { return c.Unmarshal(nil, v) })
+}
+
+// NewEncoder returns an Encoder which writes CSV into "w".
+func (c *CSVMarshaler) NewEncoder(w io.Writer) Encoder {
+	return EncoderFunc(func(v interface{}) error { return c.marshalTo(w, v) })
+}
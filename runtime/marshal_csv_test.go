@@ -0,0 +1,77 @@
+package runtime_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCSVMarshalerMarshal(t *testing.T) {
+	msg := &examplepb.ABitOfEverything{
+		Uuid: "ignored",
+		Nested: []*examplepb.ABitOfEverything_Nested{
+			{Name: "foo", Amount: 10, Ok: examplepb.ABitOfEverything_Nested_TRUE},
+			{Name: "bar, \"baz\"", Amount: 20},
+		},
+	}
+	m := &runtime.CSVMarshaler{OrigName: true}
+	buf, err := m.Marshal(msg)
+	if err != nil {
+		t.Fatalf("m.Marshal(%v) failed with %v; want success", msg, err)
+	}
+	want := "name,amount,ok\nfoo,10,TRUE\n\"bar, \"\"baz\"\"\",20,FALSE\n"
+	if got := string(buf); got != want {
+		t.Errorf("m.Marshal(%v) = %q; want %q", msg, got, want)
+	}
+}
+
+func TestCSVMarshalerMarshalNonTabular(t *testing.T) {
+	m := &runtime.CSVMarshaler{}
+	for _, v := range []interface{}{
+		&examplepb.SimpleMessage{Id: "foo"},
+		map[string]string{"foo": "bar"},
+	} {
+		if buf, err := m.Marshal(v); err == nil {
+			t.Errorf("m.Marshal(%v) = %q; want an error", v, buf)
+		}
+	}
+}
+
+func TestCSVMarshalerUnmarshal(t *testing.T) {
+	m := &runtime.CSVMarshaler{}
+	var msg examplepb.ABitOfEverything
+	if err := m.Unmarshal([]byte("name\nfoo\n"), &msg); err == nil {
+		t.Errorf("m.Unmarshal(%q, &msg) succeeded; want an error", "name\nfoo\n")
+	}
+}
+
+func TestCSVMarshalerHTTPError(t *testing.T) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "", nil)
+	m := &runtime.CSVMarshaler{}
+	err := status.Error(codes.NotFound, "not found")
+	runtime.DefaultHTTPError(context.Background(), runtime.NewServeMux(), m, w, req, err)
+
+	if got, want := w.Code, http.StatusNotFound; got != want {
+		t.Errorf("w.Code = %d; want %d", got, want)
+	}
+	if got, want := w.HeaderMap.Get("Content-Type"), "text/csv"; got != want {
+		t.Errorf("Content-Type = %q; want %q", got, want)
+	}
+	if got, want := w.Body.String(), "error,code,status\nnot found,5,NOT_FOUND\n"; got != want {
+		t.Errorf("w.Body = %q; want %q", got, want)
+	}
+
+	w = httptest.NewRecorder()
+	runtime.DefaultHTTPError(context.Background(), runtime.NewServeMux(), m, w, req, errors.New("boom"))
+	if got, want := w.Body.String(), "error,code,status\nboom,2,UNKNOWN\n"; got != want {
+		t.Errorf("w.Body = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,68 @@
+package runtime
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/url"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/grpc-ecosystem/grpc-gateway/utilities"
+)
+
+// FormMarshaler is a Marshaler which unmarshals "application/x-www-form-urlencoded" request bodies
+// into proto messages with the same rules as query parameters, so repeated fields are populated
+// from repeated keys, e.g. "tag=a&tag=b".
+// Responses are marshaled with the embedded Marshaler, or with the default JSONPb marshaler if it is nil.
+type FormMarshaler struct {
+	Marshaler
+}
+
+var emptyFilter = utilities.NewDoubleArray(nil)
+
+// ContentType returns the Content-Type of the embedded Marshaler since responses are written with it.
+func (f *FormMarshaler) ContentType() string {
+	return f.outbound().ContentType()
+}
+
+// Marshal marshals "v" with the embedded Marshaler.
+func (f *FormMarshaler) Marshal(v interface{}) ([]byte, error) {
+	return f.outbound().Marshal(v)
+}
+
+// Unmarshal unmarshals form encoded "data" into "v".
+// "v" must be a proto.Message.
+func (f *FormMarshaler) Unmarshal(data []byte, v interface{}) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return errors.New("unable to unmarshal form into non proto field")
+	}
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+	return PopulateQueryParameters(msg, values, emptyFilter)
+}
+
+// NewDecoder returns a Decoder which reads a form encoded body from "r".
+func (f *FormMarshaler) NewDecoder(r io.Reader) Decoder {
+	return DecoderFunc(func(v interface{}) error {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return f.Unmarshal(data, v)
+	})
+}
+
+// NewEncoder returns an Encoder of the embedded Marshaler.
+func (f *FormMarshaler) NewEncoder(w io.Writer) Encoder {
+	return f.outbound().NewEncoder(w)
+}
+
+func (f *FormMarshaler) outbound() Marshaler {
+	if f.Marshaler == nil {
+		return defaultMarshaler
+	}
+	return f.Marshaler
+}
@@ -0,0 +1,40 @@
+package runtime_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+)
+
+func TestFormMarshalerRepeatedKeys(t *testing.T) {
+	m := &runtime.FormMarshaler{}
+	body := "uuid=foo&repeated_string_value=a&repeated_string_value=b&repeatedEnumValue=ONE&repeatedEnumValue=ZERO"
+
+	var got examplepb.ABitOfEverything
+	if err := m.NewDecoder(strings.NewReader(body)).Decode(&got); err != nil {
+		t.Fatalf("m.NewDecoder(%q).Decode(&got) failed with %v; want success", body, err)
+	}
+	if got, want := got.Uuid, "foo"; got != want {
+		t.Errorf("got.Uuid = %q; want %q", got, want)
+	}
+	if got, want := got.RepeatedStringValue, []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got.RepeatedStringValue = %q; want %q", got, want)
+	}
+	want := []examplepb.NumericEnum{examplepb.NumericEnum_ONE, examplepb.NumericEnum_ZERO}
+	if got := got.RepeatedEnumValue; !reflect.DeepEqual(got, want) {
+		t.Errorf("got.RepeatedEnumValue = %v; want %v", got, want)
+	}
+}
+
+func TestFormMarshalerContentType(t *testing.T) {
+	if got, want := (&runtime.FormMarshaler{}).ContentType(), "application/json"; got != want {
+		t.Errorf("(&runtime.FormMarshaler{}).ContentType() = %q; want %q", got, want)
+	}
+	m := &runtime.FormMarshaler{Marshaler: &runtime.ProtoMarshaller{}}
+	if got, want := m.ContentType(), "application/octet-stream"; got != want {
+		t.Errorf("m.ContentType() = %q; want %q", got, want)
+	}
+}
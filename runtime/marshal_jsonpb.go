@@ -157,6 +157,25 @@ func decodeNonProtoField(d *json.Decoder, v interface{}) error {
 		}
 		return nil
 	}
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+		// A repeated field, e.g. the body bound to it, whose elements are decoded as JSONPb values.
+		var elems []json.RawMessage
+		if err := d.Decode(&elems); err != nil {
+			return err
+		}
+		if elems == nil {
+			rv.Set(reflect.Zero(rv.Type()))
+			return nil
+		}
+		s := reflect.MakeSlice(rv.Type(), len(elems), len(elems))
+		for i, elem := range elems {
+			if err := unmarshalJSONPb([]byte(elem), s.Index(i).Addr().Interface()); err != nil {
+				return err
+			}
+		}
+		rv.Set(s)
+		return nil
+	}
 	if _, ok := rv.Interface().(protoEnum); ok {
 		var repr interface{}
 		if err := d.Decode(&repr); err != nil {
@@ -173,9 +192,34 @@ func decodeNonProtoField(d *json.Decoder, v interface{}) error {
 			return fmt.Errorf("cannot assign %#v into Go type %T", repr, rv.Interface())
 		}
 	}
+	switch rv.Kind() {
+	case reflect.Int32, reflect.Int64, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return decodeNumber(d, rv)
+	}
 	return d.Decode(v)
 }
 
+// decodeNumber decodes a bare JSON number into "rv", e.g. the body bound to a numeric field.
+// As in the JSON mapping of proto3, the number may also be given as a string,
+// which is how 64-bit integers and special float values such as "NaN" are represented.
+func decodeNumber(d *json.Decoder, rv reflect.Value) error {
+	var raw json.RawMessage
+	if err := d.Decode(&raw); err != nil {
+		return err
+	}
+	repr := string(raw)
+	var quoted string
+	if err := json.Unmarshal(raw, &quoted); err == nil {
+		repr = quoted
+	}
+	result := convFromType[rv.Kind()].Call([]reflect.Value{reflect.ValueOf(repr)})
+	if err := result[1].Interface(); err != nil {
+		return fmt.Errorf("cannot assign %s into Go type %s: %v", raw, rv.Type(), err)
+	}
+	rv.Set(result[0].Convert(rv.Type()))
+	return nil
+}
+
 type protoEnum interface {
 	fmt.Stringer
 	EnumDescriptor() ([]byte, []int)
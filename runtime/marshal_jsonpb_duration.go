@@ -0,0 +1,179 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/duration"
+)
+
+// NumericDurationJSONPb is a Marshaler which behaves like JSONPb except that the values of
+// google.protobuf.Duration fields, including repeated fields, map values and oneofs, are marshaled as numbers
+// of Unit, e.g. 3500 for "3.5s" if Unit is time.Millisecond, for clients which cannot parse the canonical
+// string form. Both numbers of Unit and the canonical string form are accepted on Unmarshal.
+//
+// If Unit is zero, durations are marshaled in the canonical string form as in JSONPb. Durations which do not
+// fit in a time.Duration, i.e. beyond about 290 years, are always marshaled in the string form, and numbers
+// which are not whole nanoseconds are rounded. Durations in google.protobuf.Any and Struct values are not affected.
+//
+// It is a TransformingJSONPb with NumericDurations only.
+type NumericDurationJSONPb struct {
+	JSONPb
+	// Unit is the unit of the numeric durations, e.g. time.Second or time.Millisecond.
+	Unit time.Duration
+}
+
+var durationType = reflect.TypeOf(&duration.Duration{})
+
+func (j *NumericDurationJSONPb) transformer() jsonTransformer {
+	if j.Unit <= 0 {
+		return newJSONPbTransformer(&j.JSONPb)
+	}
+	return newJSONPbTransformer(&j.JSONPb, NumericDurations{Unit: j.Unit})
+}
+
+// Marshal marshals "v" into JSON with numeric durations.
+func (j *NumericDurationJSONPb) Marshal(v interface{}) ([]byte, error) {
+	return j.transformer().Marshal(v)
+}
+
+// Unmarshal unmarshals JSON "data" with numeric or string durations into "v".
+func (j *NumericDurationJSONPb) Unmarshal(data []byte, v interface{}) error {
+	return j.transformer().Unmarshal(data, v)
+}
+
+// NewDecoder returns a Decoder which reads JSON stream with numeric or string durations from "r".
+func (j *NumericDurationJSONPb) NewDecoder(r io.Reader) Decoder {
+	return j.transformer().NewDecoder(r)
+}
+
+// NewEncoder returns an Encoder which writes JSON stream with numeric durations into "w".
+func (j *NumericDurationJSONPb) NewEncoder(w io.Writer) Encoder {
+	return j.transformer().NewEncoder(w)
+}
+
+// NumericDurations is a JSONTransform which represents the values of google.protobuf.Duration fields as
+// numbers of Unit, as NumericDurationJSONPb does. Durations are left in the string form if Unit is zero.
+type NumericDurations struct {
+	// Unit is the unit of the numeric durations, e.g. time.Second or time.Millisecond.
+	Unit time.Duration
+}
+
+// MarshalRepr converts the durations in "repr" into numbers of Unit.
+func (u NumericDurations) MarshalRepr(_ *JSONPb, v, repr interface{}) (interface{}, error) {
+	if u.Unit <= 0 {
+		return repr, nil
+	}
+	if reflect.TypeOf(v) == durationType {
+		return u.numericDuration(repr), nil
+	}
+	return repr, walkJSONRepr(reflect.TypeOf(v), reflect.ValueOf(v), repr, true, func(_ string, obj map[string]interface{}, fields []jsonReprField) error {
+		return convertDurations(obj, fields, func(repr interface{}) (interface{}, error) {
+			return u.numericDuration(repr), nil
+		})
+	})
+}
+
+// UnmarshalRepr converts the numeric durations in "repr" back into the string form.
+func (u NumericDurations) UnmarshalRepr(_ *JSONPb, v, repr interface{}) (interface{}, error) {
+	if u.Unit <= 0 {
+		return repr, nil
+	}
+	if reflect.TypeOf(v) == durationType {
+		return u.stringDuration(repr)
+	}
+	return repr, walkJSONRepr(reflect.TypeOf(v), reflect.ValueOf(v), repr, false, func(_ string, obj map[string]interface{}, fields []jsonReprField) error {
+		return convertDurations(obj, fields, u.stringDuration)
+	})
+}
+
+// convertDurations replaces with "conv" the values of the google.protobuf.Duration fields in "obj".
+func convertDurations(obj map[string]interface{}, fields []jsonReprField, conv func(interface{}) (interface{}, error)) error {
+	for _, f := range fields {
+		if f.key == "" {
+			continue
+		}
+		var err error
+		switch {
+		case f.typ == durationType:
+			obj[f.key], err = conv(obj[f.key])
+		case f.typ.Kind() == reflect.Slice && f.typ.Elem() == durationType:
+			list, _ := obj[f.key].([]interface{})
+			for i := 0; i < len(list) && err == nil; i++ {
+				list[i], err = conv(list[i])
+			}
+		case f.typ.Kind() == reflect.Map && f.typ.Elem() == durationType:
+			m, _ := obj[f.key].(map[string]interface{})
+			for k, e := range m {
+				if m[k], err = conv(e); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// numericDuration converts the canonical string form of a duration, e.g. "3.5s", into a number of Unit.
+func (u NumericDurations) numericDuration(repr interface{}) interface{} {
+	s, ok := repr.(string)
+	if !ok {
+		return repr
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || !strings.HasSuffix(s, "s") {
+		return repr
+	}
+	if d%u.Unit == 0 {
+		return json.Number(strconv.FormatInt(int64(d/u.Unit), 10))
+	}
+	return json.Number(strconv.FormatFloat(float64(d)/float64(u.Unit), 'f', -1, 64))
+}
+
+// stringDuration converts a number of Unit into the canonical string form of a duration.
+// Strings are left as they are.
+func (u NumericDurations) stringDuration(repr interface{}) (interface{}, error) {
+	n, ok := repr.(json.Number)
+	if !ok {
+		return repr, nil
+	}
+	var d time.Duration
+	if i, err := n.Int64(); err == nil && i <= math.MaxInt64/int64(u.Unit) && i >= math.MinInt64/int64(u.Unit) {
+		d = time.Duration(i) * u.Unit
+	} else {
+		f, err := n.Float64()
+		if err != nil || math.Abs(f*float64(u.Unit)) >= math.MaxInt64 {
+			return nil, fmt.Errorf("bad duration: %s", n)
+		}
+		d = time.Duration(math.Round(f * float64(u.Unit)))
+	}
+	return formatDurationSeconds(d), nil
+}
+
+// formatDurationSeconds formats "d" in the canonical string form of google.protobuf.Duration, e.g. "3.5s".
+func formatDurationSeconds(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+	}
+	secs, nanos := int64(d/time.Second), int64(d%time.Second)
+	if secs < 0 {
+		secs = -secs
+	}
+	if nanos < 0 {
+		nanos = -nanos
+	}
+	if nanos == 0 {
+		return fmt.Sprintf("%s%ds", sign, secs)
+	}
+	return fmt.Sprintf("%s%d.%ss", sign, secs, strings.TrimRight(fmt.Sprintf("%09d", nanos), "0"))
+}
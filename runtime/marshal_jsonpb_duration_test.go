@@ -0,0 +1,84 @@
+package runtime_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+)
+
+// durationMessage has duration fields of all the kinds handled by NumericDurationJSONPb.
+type durationMessage struct {
+	Timeout  *duration.Duration            `protobuf:"bytes,1,opt,name=timeout" json:"timeout,omitempty"`
+	Retries  []*duration.Duration          `protobuf:"bytes,2,rep,name=retries" json:"retries,omitempty"`
+	Deadline map[string]*duration.Duration `protobuf:"bytes,3,rep,name=deadline" json:"deadline,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Nested   *durationMessage              `protobuf:"bytes,4,opt,name=nested" json:"nested,omitempty"`
+	Name     string                        `protobuf:"bytes,5,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *durationMessage) Reset()         { *m = durationMessage{} }
+func (m *durationMessage) String() string { return proto.CompactTextString(m) }
+func (*durationMessage) ProtoMessage()    {}
+
+func newDurationMessage() *durationMessage {
+	return &durationMessage{
+		Timeout:  &duration.Duration{Seconds: 3, Nanos: 500000000},
+		Retries:  []*duration.Duration{{Nanos: 1500000}, {Seconds: -2}},
+		Deadline: map[string]*duration.Duration{"a": {Seconds: 60}},
+		Nested:   &durationMessage{Timeout: &duration.Duration{Seconds: 1}},
+		Name:     "3s",
+	}
+}
+
+func TestNumericDurationJSONPbMarshal(t *testing.T) {
+	for _, spec := range []struct {
+		m    *runtime.NumericDurationJSONPb
+		want string
+	}{
+		{
+			m:    &runtime.NumericDurationJSONPb{Unit: time.Millisecond},
+			want: `{"deadline":{"a":60000},"name":"3s","nested":{"timeout":1000},"retries":[1.5,-2000],"timeout":3500}`,
+		},
+		{
+			m:    &runtime.NumericDurationJSONPb{Unit: time.Second},
+			want: `{"deadline":{"a":60},"name":"3s","nested":{"timeout":1},"retries":[0.0015,-2],"timeout":3.5}`,
+		},
+		{
+			m:    &runtime.NumericDurationJSONPb{},
+			want: `{"timeout":"3.500s","retries":["0.001500s","-2s"],"deadline":{"a":"60s"},"nested":{"timeout":"1s"},"name":"3s"}`,
+		},
+	} {
+		buf, err := spec.m.Marshal(newDurationMessage())
+		if err != nil {
+			t.Errorf("m.Marshal(msg) failed with %v; want success; unit=%v", err, spec.m.Unit)
+			continue
+		}
+		if got := string(buf); got != spec.want {
+			t.Errorf("m.Marshal(msg) = %s; want %s; unit=%v", got, spec.want, spec.m.Unit)
+		}
+	}
+}
+
+func TestNumericDurationJSONPbUnmarshal(t *testing.T) {
+	m := &runtime.NumericDurationJSONPb{Unit: time.Millisecond}
+	for _, data := range []string{
+		`{"deadline":{"a":60000},"name":"3s","nested":{"timeout":1000},"retries":[1.5,-2000],"timeout":3500}`,
+		`{"deadline":{"a":"60s"},"name":"3s","nested":{"timeout":"1s"},"retries":["0.0015s","-2s"],"timeout":"3.5s"}`,
+	} {
+		var got durationMessage
+		if err := m.Unmarshal([]byte(data), &got); err != nil {
+			t.Errorf("m.Unmarshal(%s, &got) failed with %v; want success", data, err)
+			continue
+		}
+		if want := newDurationMessage(); !proto.Equal(&got, want) {
+			t.Errorf("m.Unmarshal(%s, &got); got = %v; want %v", data, &got, want)
+		}
+	}
+
+	var got durationMessage
+	if err := m.Unmarshal([]byte(`{"timeout":1e30}`), &got); err == nil {
+		t.Errorf("m.Unmarshal(%s, &got) succeeded; want an error", `{"timeout":1e30}`)
+	}
+}
@@ -0,0 +1,133 @@
+package runtime
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"regexp"
+)
+
+// CamelCaseMapKeysJSONPb is a Marshaler which behaves like JSONPb except that the keys of map<string, ...>
+// fields are converted from snake_case into lowerCamelCase on Marshal, e.g. {"labels": {"foo_bar": "x"}}
+// into {"labels": {"fooBar": "x"}}, and back on Unmarshal.
+//
+// Only lower snake_case keys are converted on Marshal, and only lowerCamelCase keys on Unmarshal, so that
+// round trips are lossless. Other keys, such as "Foo" or "foo_2", are passed through as they are, and so are
+// the keys of a map in which converting would merge keys, e.g. "foo_bar" and "fooBar". Note that keys which
+// are already in lowerCamelCase in the backend come back in snake_case.
+// Keys of other map types and field names are not affected.
+//
+// It is a TransformingJSONPb with CamelCaseMapKeys only.
+type CamelCaseMapKeysJSONPb struct {
+	JSONPb
+}
+
+func (j *CamelCaseMapKeysJSONPb) transformer() jsonTransformer {
+	return newJSONPbTransformer(&j.JSONPb, CamelCaseMapKeys{})
+}
+
+// Marshal marshals "v" into JSON with lowerCamelCase map keys.
+func (j *CamelCaseMapKeysJSONPb) Marshal(v interface{}) ([]byte, error) {
+	return j.transformer().Marshal(v)
+}
+
+// Unmarshal unmarshals JSON "data" with lowerCamelCase map keys into "v".
+func (j *CamelCaseMapKeysJSONPb) Unmarshal(data []byte, v interface{}) error {
+	return j.transformer().Unmarshal(data, v)
+}
+
+// NewDecoder returns a Decoder which reads JSON stream with lowerCamelCase map keys from "r".
+func (j *CamelCaseMapKeysJSONPb) NewDecoder(r io.Reader) Decoder {
+	return j.transformer().NewDecoder(r)
+}
+
+// NewEncoder returns an Encoder which writes JSON stream with lowerCamelCase map keys into "w".
+func (j *CamelCaseMapKeysJSONPb) NewEncoder(w io.Writer) Encoder {
+	return j.transformer().NewEncoder(w)
+}
+
+// CamelCaseMapKeys is a JSONTransform which converts the keys of map<string, ...> fields from snake_case
+// into lowerCamelCase, as CamelCaseMapKeysJSONPb does.
+type CamelCaseMapKeys struct{}
+
+// MarshalRepr converts the keys of the map<string, ...> fields in "repr" into lowerCamelCase.
+func (CamelCaseMapKeys) MarshalRepr(_ *JSONPb, v, repr interface{}) (interface{}, error) {
+	return repr, walkJSONRepr(reflect.TypeOf(v), reflect.ValueOf(v), repr, true, func(_ string, obj map[string]interface{}, fields []jsonReprField) error {
+		convertMapKeys(obj, fields, snakeToCamelMapKey)
+		return nil
+	})
+}
+
+// UnmarshalRepr converts the keys of the map<string, ...> fields in "repr" back into snake_case.
+func (CamelCaseMapKeys) UnmarshalRepr(_ *JSONPb, v, repr interface{}) (interface{}, error) {
+	return repr, walkJSONRepr(reflect.TypeOf(v), reflect.ValueOf(v), repr, false, func(_ string, obj map[string]interface{}, fields []jsonReprField) error {
+		convertMapKeys(obj, fields, camelToSnakeMapKey)
+		return nil
+	})
+}
+
+// convertMapKeys rewrites with "conv" the keys of the map<string, ...> fields in "obj".
+func convertMapKeys(obj map[string]interface{}, fields []jsonReprField, conv func(string) (string, bool)) {
+	for _, f := range fields {
+		if f.key == "" || f.typ.Kind() != reflect.Map || f.typ.Key().Kind() != reflect.String {
+			continue
+		}
+		m, _ := obj[f.key].(map[string]interface{})
+		converted := make(map[string]interface{}, len(m))
+		for k, e := range m {
+			if c, ok := conv(k); ok {
+				k = c
+			}
+			converted[k] = e
+		}
+		if len(converted) != len(m) {
+			// Converting would merge keys, e.g. "foo_bar" and "fooBar".
+			continue
+		}
+		for k := range m {
+			delete(m, k)
+		}
+		for k, e := range converted {
+			m[k] = e
+		}
+	}
+}
+
+var (
+	snakeCaseMapKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z][a-z0-9]*)*$`)
+	camelCaseMapKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9]*([A-Z][a-z0-9]*)*$`)
+)
+
+// snakeToCamelMapKey converts "key" into lowerCamelCase if it is in lower snake_case.
+func snakeToCamelMapKey(key string) (string, bool) {
+	if !snakeCaseMapKeyPattern.MatchString(key) {
+		return "", false
+	}
+	var b bytes.Buffer
+	for i := 0; i < len(key); i++ {
+		if c := key[i]; c == '_' {
+			i++
+			b.WriteByte(key[i] - 'a' + 'A')
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), true
+}
+
+// camelToSnakeMapKey converts "key" into lower snake_case if it is in lowerCamelCase.
+func camelToSnakeMapKey(key string) (string, bool) {
+	if !camelCaseMapKeyPattern.MatchString(key) {
+		return "", false
+	}
+	var b bytes.Buffer
+	for i := 0; i < len(key); i++ {
+		if c := key[i]; 'A' <= c && c <= 'Z' {
+			b.WriteByte('_')
+			b.WriteByte(c - 'A' + 'a')
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), true
+}
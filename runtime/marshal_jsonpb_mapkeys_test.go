@@ -0,0 +1,62 @@
+package runtime_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+)
+
+func TestCamelCaseMapKeysJSONPbMarshal(t *testing.T) {
+	m := &runtime.CamelCaseMapKeysJSONPb{JSONPb: runtime.JSONPb{OrigName: true}}
+	msg := &examplepb.ABitOfEverything{
+		Uuid:              "foo",
+		MappedStringValue: map[string]string{"foo_bar": "a", "Baz": "b", "qux_2": "c"},
+		MappedNestedValue: map[string]*examplepb.ABitOfEverything_Nested{"nested_key": {Name: "nested_name"}},
+	}
+	buf, err := m.Marshal(msg)
+	if err != nil {
+		t.Fatalf("m.Marshal(%v) failed with %v; want success", msg, err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf, &got); err != nil {
+		t.Fatalf("json.Unmarshal(%s, &got) failed with %v; want success", buf, err)
+	}
+	if want := map[string]interface{}{"fooBar": "a", "Baz": "b", "qux_2": "c"}; !reflect.DeepEqual(got["mapped_string_value"], want) {
+		t.Errorf("mapped_string_value = %v; want %v", got["mapped_string_value"], want)
+	}
+	if want := map[string]interface{}{"nestedKey": map[string]interface{}{"name": "nested_name"}}; !reflect.DeepEqual(got["mapped_nested_value"], want) {
+		t.Errorf("mapped_nested_value = %v; want %v", got["mapped_nested_value"], want)
+	}
+
+	var back examplepb.ABitOfEverything
+	if err := m.Unmarshal(buf, &back); err != nil {
+		t.Fatalf("m.Unmarshal(%s, &back) failed with %v; want success", buf, err)
+	}
+	if !reflect.DeepEqual(back.MappedStringValue, msg.MappedStringValue) {
+		t.Errorf("back.MappedStringValue = %v; want %v", back.MappedStringValue, msg.MappedStringValue)
+	}
+	if _, ok := back.MappedNestedValue["nested_key"]; !ok {
+		t.Errorf("back.MappedNestedValue = %v; want key %q", back.MappedNestedValue, "nested_key")
+	}
+}
+
+func TestCamelCaseMapKeysJSONPbCollision(t *testing.T) {
+	m := &runtime.CamelCaseMapKeysJSONPb{}
+	msg := &examplepb.ABitOfEverything{
+		MappedStringValue: map[string]string{"foo_bar": "a", "fooBar": "b"},
+	}
+	buf, err := m.Marshal(msg)
+	if err != nil {
+		t.Fatalf("m.Marshal(%v) failed with %v; want success", msg, err)
+	}
+	var back examplepb.ABitOfEverything
+	if err := m.Unmarshal(buf, &back); err != nil {
+		t.Fatalf("m.Unmarshal(%s, &back) failed with %v; want success", buf, err)
+	}
+	if !reflect.DeepEqual(back.MappedStringValue, msg.MappedStringValue) {
+		t.Errorf("back.MappedStringValue = %v; want %v", back.MappedStringValue, msg.MappedStringValue)
+	}
+}
@@ -0,0 +1,206 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// NonFiniteFloats is how NonFiniteJSONPb marshals NaN and infinite float values.
+type NonFiniteFloats int
+
+const (
+	// NonFiniteAsStrings marshals NaN and infinite values as the strings "NaN", "Infinity" and "-Infinity",
+	// as in the JSON mapping of proto3, which JSONPb unmarshals back. This is the default.
+	NonFiniteAsStrings NonFiniteFloats = iota
+	// NonFiniteAsNull marshals NaN and infinite values as null.
+	NonFiniteAsNull
+	// NonFiniteAsError fails to marshal values which contain NaN or infinite values, for clients which
+	// want strict behavior.
+	NonFiniteAsError
+)
+
+// NonFiniteJSONPb is a Marshaler which behaves like JSONPb except that NaN and infinite values of float and
+// double fields, including repeated fields, map values, oneofs and google.protobuf.FloatValue and DoubleValue
+// wrappers, are marshaled as configured by NonFinite instead of depending on the version of jsonpb, which
+// may fail to marshal the whole response. The values of fields of google.protobuf.Struct are not affected.
+//
+// It is a TransformingJSONPb with NonFinite only.
+type NonFiniteJSONPb struct {
+	JSONPb
+	// NonFinite is how NaN and infinite values are marshaled. It defaults to NonFiniteAsStrings.
+	NonFinite NonFiniteFloats
+}
+
+// Marshal marshals "v" into JSON with NaN and infinite values as configured by NonFinite.
+func (j *NonFiniteJSONPb) Marshal(v interface{}) ([]byte, error) {
+	return newJSONPbTransformer(&j.JSONPb, j.NonFinite).Marshal(v)
+}
+
+// NewEncoder returns an Encoder which writes JSON stream with NaN and infinite values as configured by NonFinite into "w".
+func (j *NonFiniteJSONPb) NewEncoder(w io.Writer) Encoder {
+	return newJSONPbTransformer(&j.JSONPb, j.NonFinite).NewEncoder(w)
+}
+
+// prepareValue returns a copy of "v" whose NaN and infinite values are zeroed, so that jsonpb marshals it whatever its version.
+func (n NonFiniteFloats) prepareValue(_ *JSONPb, v interface{}) (interface{}, bool, error) {
+	if x, ok := floatValue(v); ok {
+		if !isNonFinite(x) {
+			return v, false, nil
+		}
+		if n == NonFiniteAsError {
+			return nil, false, fmt.Errorf("non-finite float value %v", x)
+		}
+		return float64(0), true, nil
+	}
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return v, false, nil
+	}
+
+	clone := proto.Clone(msg)
+	var found bool
+	walkJSONRepr(reflect.TypeOf(clone), reflect.ValueOf(clone), nil, true, func(_ string, obj map[string]interface{}, fields []jsonReprField) error {
+		visitFloatFields(nil, obj, fields, func(x float64, setGo func(float64), _ func(interface{})) {
+			if isNonFinite(x) {
+				found = true
+				setGo(0)
+			}
+		})
+		return nil
+	})
+	if !found {
+		return v, false, nil
+	}
+	if n == NonFiniteAsError {
+		return nil, false, fmt.Errorf("non-finite float value in %s", proto.MessageName(msg))
+	}
+	return clone, true, nil
+}
+
+// MarshalRepr puts the representation of the NaN and infinite values of "v" into "repr".
+func (n NonFiniteFloats) MarshalRepr(j *JSONPb, v, repr interface{}) (interface{}, error) {
+	if x, ok := floatValue(v); ok {
+		if isNonFinite(x) {
+			return n.nonFiniteRepr(x), nil
+		}
+		return repr, nil
+	}
+	return repr, walkJSONRepr(reflect.TypeOf(v), reflect.ValueOf(v), repr, true, func(_ string, obj map[string]interface{}, fields []jsonReprField) error {
+		visitFloatFields(j, obj, fields, func(x float64, _ func(float64), setJSON func(interface{})) {
+			if isNonFinite(x) {
+				setJSON(n.nonFiniteRepr(x))
+			}
+		})
+		return nil
+	})
+}
+
+// UnmarshalRepr returns "repr" as it is since jsonpb unmarshals the string representations of non-finite values.
+func (n NonFiniteFloats) UnmarshalRepr(_ *JSONPb, _, repr interface{}) (interface{}, error) {
+	return repr, nil
+}
+
+// nonFiniteRepr returns the JSON representation of the non-finite value "x".
+func (n NonFiniteFloats) nonFiniteRepr(x float64) interface{} {
+	switch {
+	case n == NonFiniteAsNull:
+		return nil
+	case math.IsNaN(x):
+		return "NaN"
+	case x > 0:
+		return "Infinity"
+	default:
+		return "-Infinity"
+	}
+}
+
+// floatValue returns the value of "v" if it is a float, or a pointer to one.
+func floatValue(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+func isNonFinite(x float64) bool {
+	return math.IsNaN(x) || math.IsInf(x, 0)
+}
+
+// floatVisitor is called with each float value of a message, along with functions which set the value in the
+// message and in its decoded JSON.
+type floatVisitor func(x float64, setGo func(float64), setJSON func(interface{}))
+
+// visitFloatFields calls "visit" with the float values of "fields", the fields of a message whose JSON object
+// is "obj". Fields missing from "obj", e.g. as the zero value of a proto3 scalar field, are set with their key
+// in the JSON marshaled with the options "j".
+func visitFloatFields(j *JSONPb, obj map[string]interface{}, fields []jsonReprField, visit floatVisitor) {
+	for _, f := range fields {
+		fv := f.value
+		if !fv.IsValid() {
+			continue
+		}
+		key := f.key
+		if key == "" && j != nil {
+			key = jsonFieldName(j, f.props)
+		}
+		setKey := func(v interface{}) {
+			if obj != nil {
+				obj[key] = v
+			}
+		}
+
+		switch fv.Kind() {
+		case reflect.Float32, reflect.Float64:
+			visit(fv.Float(), fv.SetFloat, setKey)
+		case reflect.Ptr:
+			if fv.IsNil() {
+				continue
+			}
+			if msg, ok := fv.Interface().(proto.Message); ok {
+				switch proto.MessageName(msg) {
+				case "google.protobuf.DoubleValue", "google.protobuf.FloatValue":
+					// Wrappers are represented by their bare value.
+					value := fv.Elem().FieldByName("Value")
+					visit(value.Float(), value.SetFloat, setKey)
+				}
+			}
+		case reflect.Slice:
+			list, _ := obj[key].([]interface{})
+			for i := 0; i < fv.Len(); i++ {
+				i, ev := i, fv.Index(i)
+				switch ev.Kind() {
+				case reflect.Float32, reflect.Float64:
+					visit(ev.Float(), ev.SetFloat, func(v interface{}) {
+						if i < len(list) {
+							list[i] = v
+						}
+					})
+				}
+			}
+		case reflect.Map:
+			m, _ := obj[key].(map[string]interface{})
+			for _, k := range fv.MapKeys() {
+				k, ev := k, fv.MapIndex(k)
+				switch ev.Kind() {
+				case reflect.Float32, reflect.Float64:
+					mkey := fmt.Sprintf("%v", k.Interface())
+					setGo := func(x float64) { fv.SetMapIndex(k, reflect.ValueOf(x).Convert(ev.Type())) }
+					visit(ev.Float(), setGo, func(v interface{}) {
+						if m != nil {
+							m[mkey] = v
+						}
+					})
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package runtime_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+)
+
+// floatMessage has float fields of all the kinds handled by NonFiniteJSONPb.
+type floatMessage struct {
+	Value   float64               `protobuf:"fixed64,1,opt,name=value" json:"value,omitempty"`
+	Ratio   float32               `protobuf:"fixed32,2,opt,name=ratio" json:"ratio,omitempty"`
+	Samples []float64             `protobuf:"fixed64,3,rep,packed,name=samples" json:"samples,omitempty"`
+	Scores  map[string]float64    `protobuf:"bytes,4,rep,name=scores" json:"scores,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
+	Limit   *wrappers.DoubleValue `protobuf:"bytes,5,opt,name=limit" json:"limit,omitempty"`
+	Nested  *floatMessage         `protobuf:"bytes,6,opt,name=nested" json:"nested,omitempty"`
+}
+
+func (m *floatMessage) Reset()         { *m = floatMessage{} }
+func (m *floatMessage) String() string { return proto.CompactTextString(m) }
+func (*floatMessage) ProtoMessage()    {}
+
+func newNonFiniteMessage() *floatMessage {
+	return &floatMessage{
+		Value:   math.NaN(),
+		Ratio:   1.5,
+		Samples: []float64{1, math.Inf(1)},
+		Scores:  map[string]float64{"a": math.Inf(-1)},
+		Limit:   &wrappers.DoubleValue{Value: math.NaN()},
+		Nested:  &floatMessage{Value: math.Inf(1)},
+	}
+}
+
+func TestNonFiniteJSONPbMarshal(t *testing.T) {
+	for _, spec := range []struct {
+		m       *runtime.NonFiniteJSONPb
+		v       interface{}
+		want    string
+		wantErr bool
+	}{
+		{
+			m:    &runtime.NonFiniteJSONPb{},
+			v:    newNonFiniteMessage(),
+			want: `{"limit":"NaN","nested":{"value":"Infinity"},"ratio":1.5,"samples":[1,"Infinity"],"scores":{"a":"-Infinity"},"value":"NaN"}`,
+		},
+		{
+			m:    &runtime.NonFiniteJSONPb{NonFinite: runtime.NonFiniteAsNull},
+			v:    newNonFiniteMessage(),
+			want: `{"limit":null,"nested":{"value":null},"ratio":1.5,"samples":[1,null],"scores":{"a":null},"value":null}`,
+		},
+		{
+			m:       &runtime.NonFiniteJSONPb{NonFinite: runtime.NonFiniteAsError},
+			v:       newNonFiniteMessage(),
+			wantErr: true,
+		},
+		{
+			m:    &runtime.NonFiniteJSONPb{NonFinite: runtime.NonFiniteAsError},
+			v:    &floatMessage{Value: 2.5, Samples: []float64{0}},
+			want: `{"value":2.5,"samples":[0]}`,
+		},
+		{
+			m:    &runtime.NonFiniteJSONPb{NonFinite: runtime.NonFiniteAsNull},
+			v:    map[string]proto.Message{"result": &floatMessage{Value: math.NaN()}},
+			want: `{"result":{"value":null}}`,
+		},
+		{
+			m:    &runtime.NonFiniteJSONPb{},
+			v:    math.Inf(-1),
+			want: `"-Infinity"`,
+		},
+	} {
+		buf, err := spec.m.Marshal(spec.v)
+		if spec.wantErr {
+			if err == nil {
+				t.Errorf("m.Marshal(%v) = %q; want an error; NonFinite = %d", spec.v, buf, spec.m.NonFinite)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("m.Marshal(%v) failed with %v; want success; NonFinite = %d", spec.v, err, spec.m.NonFinite)
+			continue
+		}
+		if got, want := string(buf), spec.want; got != want {
+			t.Errorf("m.Marshal(%v) = %s; want %s; NonFinite = %d", spec.v, got, want, spec.m.NonFinite)
+		}
+	}
+
+	// The response message is left as it is.
+	msg := newNonFiniteMessage()
+	if _, err := (&runtime.NonFiniteJSONPb{}).Marshal(msg); err != nil {
+		t.Fatalf("m.Marshal(%v) failed with %v; want success", msg, err)
+	}
+	if !math.IsNaN(msg.Value) || !math.IsInf(msg.Scores["a"], -1) || !math.IsNaN(msg.Limit.Value) {
+		t.Errorf("msg = %v after m.Marshal(msg); want its non-finite values kept", msg)
+	}
+}
@@ -0,0 +1,103 @@
+package runtime
+
+import (
+	"io"
+	"reflect"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// wrapperMessageNames are the names of the google.protobuf.*Value wrapper messages.
+var wrapperMessageNames = map[string]bool{
+	"google.protobuf.DoubleValue": true,
+	"google.protobuf.FloatValue":  true,
+	"google.protobuf.Int64Value":  true,
+	"google.protobuf.UInt64Value": true,
+	"google.protobuf.Int32Value":  true,
+	"google.protobuf.UInt32Value": true,
+	"google.protobuf.BoolValue":   true,
+	"google.protobuf.StringValue": true,
+	"google.protobuf.BytesValue":  true,
+}
+
+// NullableJSONPb is a Marshaler which behaves like JSONPb except that fields with explicit presence,
+// i.e. optional scalar fields and fields of the google.protobuf.*Value wrapper types, are marshaled
+// as JSON null when unset instead of being omitted. Conversely, null unmarshaled into such a field
+// clears it even if "v" already had it set.
+//
+// A set wrapper field is marshaled as its bare value as in jsonpb, e.g. {"name": "foo"} for a
+// google.protobuf.StringValue, so that a wrapper set to its zero value ({"name": ""}) stays
+// distinguishable from an unset one ({"name": null}).
+//
+// Unlike EmitDefaults, fields without presence are left as they are: an empty proto3 string field
+// is still omitted unless EmitDefaults is also set.
+//
+// It is a TransformingJSONPb with NullableFields only.
+type NullableJSONPb struct {
+	JSONPb
+}
+
+func (j *NullableJSONPb) transformer() jsonTransformer {
+	return newJSONPbTransformer(&j.JSONPb, NullableFields{})
+}
+
+// Marshal marshals "v" into JSON with unset nullable fields as null.
+func (j *NullableJSONPb) Marshal(v interface{}) ([]byte, error) {
+	return j.transformer().Marshal(v)
+}
+
+// Unmarshal unmarshals JSON "data" into "v", clearing the nullable fields which are null in "data".
+func (j *NullableJSONPb) Unmarshal(data []byte, v interface{}) error {
+	return j.transformer().Unmarshal(data, v)
+}
+
+// NewDecoder returns a Decoder which reads JSON stream from "r" and clears the nullable fields which are null.
+func (j *NullableJSONPb) NewDecoder(r io.Reader) Decoder {
+	return j.transformer().NewDecoder(r)
+}
+
+// NewEncoder returns an Encoder which writes JSON stream with unset nullable fields as null into "w".
+func (j *NullableJSONPb) NewEncoder(w io.Writer) Encoder {
+	return j.transformer().NewEncoder(w)
+}
+
+// NullableFields is a JSONTransform which represents unset fields with explicit presence as null,
+// as NullableJSONPb does.
+type NullableFields struct{}
+
+// MarshalRepr adds null to "repr" for each unset nullable field of "v".
+func (NullableFields) MarshalRepr(j *JSONPb, v, repr interface{}) (interface{}, error) {
+	return repr, walkJSONRepr(reflect.TypeOf(v), reflect.ValueOf(v), repr, true, func(_ string, obj map[string]interface{}, fields []jsonReprField) error {
+		for _, f := range fields {
+			if obj != nil && f.oneof == nil && f.value.IsValid() && isNullableField(f.typ) && f.value.IsNil() {
+				obj[jsonFieldName(j, f.props)] = nil
+			}
+		}
+		return nil
+	})
+}
+
+// UnmarshalRepr clears each nullable field of "v" which is null in "repr", since jsonpb leaves such fields as they are.
+func (NullableFields) UnmarshalRepr(_ *JSONPb, v, repr interface{}) (interface{}, error) {
+	return repr, walkJSONRepr(reflect.TypeOf(v), reflect.ValueOf(v), repr, false, func(_ string, obj map[string]interface{}, fields []jsonReprField) error {
+		for _, f := range fields {
+			if f.key != "" && obj[f.key] == nil && f.oneof == nil && f.value.IsValid() && isNullableField(f.typ) {
+				f.value.Set(reflect.Zero(f.typ))
+			}
+		}
+		return nil
+	})
+}
+
+// isNullableField returns whether fields of type "t" have explicit presence,
+// i.e. they are optional scalar fields or google.protobuf.*Value wrappers.
+func isNullableField(t reflect.Type) bool {
+	if t.Kind() != reflect.Ptr {
+		return false
+	}
+	if t.Elem().Kind() != reflect.Struct {
+		return true
+	}
+	msg, ok := reflect.New(t.Elem()).Interface().(proto.Message)
+	return ok && wrapperMessageNames[proto.MessageName(msg)]
+}
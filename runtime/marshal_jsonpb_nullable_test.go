@@ -0,0 +1,91 @@
+package runtime_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+)
+
+// nullableMessage has optional scalar fields and fields of the google.protobuf.*Value wrapper types.
+type nullableMessage struct {
+	Name    *string               `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Label   *wrappers.StringValue `protobuf:"bytes,2,opt,name=label" json:"label,omitempty"`
+	Enabled *wrappers.BoolValue   `protobuf:"bytes,3,opt,name=enabled" json:"enabled,omitempty"`
+	Count   *wrappers.Int64Value  `protobuf:"bytes,4,opt,name=count" json:"count,omitempty"`
+	Nested  *nullableMessage      `protobuf:"bytes,5,opt,name=nested" json:"nested,omitempty"`
+	Size    *int32                `protobuf:"varint,6,opt,name=size_hint,json=sizeHint" json:"size_hint,omitempty"`
+	Tags    []string              `protobuf:"bytes,7,rep,name=tags" json:"tags,omitempty"`
+}
+
+func (m *nullableMessage) Reset()         { *m = nullableMessage{} }
+func (m *nullableMessage) String() string { return proto.CompactTextString(m) }
+func (*nullableMessage) ProtoMessage()    {}
+
+func TestNullableJSONPbMarshal(t *testing.T) {
+	for _, spec := range []struct {
+		m    *runtime.NullableJSONPb
+		msg  proto.Message
+		want string
+	}{
+		{
+			m:    &runtime.NullableJSONPb{},
+			msg:  &nullableMessage{Label: &wrappers.StringValue{}},
+			want: `{"count":null,"enabled":null,"label":"","name":null,"sizeHint":null}`,
+		},
+		{
+			m: &runtime.NullableJSONPb{},
+			msg: &nullableMessage{
+				Name:    proto.String("foo"),
+				Enabled: &wrappers.BoolValue{Value: true},
+				Nested:  &nullableMessage{Count: &wrappers.Int64Value{Value: 1}},
+			},
+			want: `{"count":null,"enabled":true,"label":null,"name":"foo","nested":{"count":"1","enabled":null,"label":null,"name":null,"sizeHint":null},"sizeHint":null}`,
+		},
+		{
+			m:    &runtime.NullableJSONPb{JSONPb: runtime.JSONPb{OrigName: true}},
+			msg:  &nullableMessage{Size: proto.Int32(0)},
+			want: `{"count":null,"enabled":null,"label":null,"name":null,"size_hint":0}`,
+		},
+	} {
+		buf, err := spec.m.Marshal(spec.msg)
+		if err != nil {
+			t.Errorf("m.Marshal(%v) failed with %v; want success", spec.msg, err)
+			continue
+		}
+		if got := string(buf); got != spec.want {
+			t.Errorf("m.Marshal(%v) = %s; want %s", spec.msg, got, spec.want)
+		}
+	}
+}
+
+func TestNullableJSONPbUnmarshalClears(t *testing.T) {
+	m := &runtime.NullableJSONPb{}
+	msg := &nullableMessage{
+		Label:   &wrappers.StringValue{Value: "foo"},
+		Enabled: &wrappers.BoolValue{Value: true},
+		Nested:  &nullableMessage{Count: &wrappers.Int64Value{Value: 1}},
+		Size:    proto.Int32(3),
+	}
+	data := `{"label": null, "count": "2", "nested": {"count": null}, "size_hint": null}`
+	if err := m.NewDecoder(strings.NewReader(data)).Decode(msg); err != nil {
+		t.Fatalf("m.NewDecoder(%q).Decode(msg) failed with %v; want success", data, err)
+	}
+	if msg.Label != nil {
+		t.Errorf("msg.Label = %v; want nil", msg.Label)
+	}
+	if msg.Enabled == nil || !msg.Enabled.Value {
+		t.Errorf("msg.Enabled = %v; want true", msg.Enabled)
+	}
+	if msg.Count == nil || msg.Count.Value != 2 {
+		t.Errorf("msg.Count = %v; want 2", msg.Count)
+	}
+	if msg.Size != nil {
+		t.Errorf("msg.Size = %d; want nil", *msg.Size)
+	}
+	if msg.Nested == nil || msg.Nested.Count != nil {
+		t.Errorf("msg.Nested = %v; want a message without count", msg.Nested)
+	}
+}
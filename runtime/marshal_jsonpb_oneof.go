@@ -0,0 +1,158 @@
+package runtime
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// TaggedOneofJSONPb is a Marshaler which behaves like JSONPb except that each oneof is represented
+// as a tagged union keyed by the oneof name, e.g.
+//
+//	{"oneof_value": {"type": "oneof_string", "value": "bar"}}
+//
+// instead of the flattened {"oneof_string": "bar"} of jsonpb.
+//
+// It is a TransformingJSONPb with TaggedOneofs only.
+type TaggedOneofJSONPb struct {
+	JSONPb
+	// TypeKey is the key of the name of the set field in a tagged union. Defaults to "type".
+	TypeKey string
+	// ValueKey is the key of the value of the set field in a tagged union. Defaults to "value".
+	ValueKey string
+}
+
+func (j *TaggedOneofJSONPb) transformer() jsonTransformer {
+	return newJSONPbTransformer(&j.JSONPb, TaggedOneofs{TypeKey: j.TypeKey, ValueKey: j.ValueKey})
+}
+
+// Marshal marshals "v" into JSON with tagged oneofs.
+func (j *TaggedOneofJSONPb) Marshal(v interface{}) ([]byte, error) {
+	return j.transformer().Marshal(v)
+}
+
+// Unmarshal unmarshals JSON "data" with tagged oneofs into "v".
+func (j *TaggedOneofJSONPb) Unmarshal(data []byte, v interface{}) error {
+	return j.transformer().Unmarshal(data, v)
+}
+
+// NewDecoder returns a Decoder which reads JSON stream with tagged oneofs from "r".
+func (j *TaggedOneofJSONPb) NewDecoder(r io.Reader) Decoder {
+	return j.transformer().NewDecoder(r)
+}
+
+// NewEncoder returns an Encoder which writes JSON stream with tagged oneofs into "w".
+func (j *TaggedOneofJSONPb) NewEncoder(w io.Writer) Encoder {
+	return j.transformer().NewEncoder(w)
+}
+
+// TaggedOneofs is a JSONTransform which represents each oneof as a tagged union keyed by the oneof name,
+// as TaggedOneofJSONPb does.
+type TaggedOneofs struct {
+	// TypeKey is the key of the name of the set field in a tagged union. Defaults to "type".
+	TypeKey string
+	// ValueKey is the key of the value of the set field in a tagged union. Defaults to "value".
+	ValueKey string
+}
+
+// MarshalRepr rewrites the set fields of oneofs in "repr" into tagged unions.
+func (o TaggedOneofs) MarshalRepr(j *JSONPb, v, repr interface{}) (interface{}, error) {
+	return repr, walkJSONRepr(reflect.TypeOf(v), reflect.ValueOf(v), repr, true, func(_ string, obj map[string]interface{}, fields []jsonReprField) error {
+		for _, f := range fields {
+			if f.oneof == nil || f.key == "" {
+				continue
+			}
+			val := obj[f.key]
+			delete(obj, f.key)
+			obj[o.oneofKey(j, f.oneofName)] = map[string]interface{}{
+				o.typeKey():  f.key,
+				o.valueKey(): val,
+			}
+		}
+		return nil
+	})
+}
+
+// UnmarshalRepr rewrites the tagged unions in "repr" back into the set fields of oneofs.
+func (o TaggedOneofs) UnmarshalRepr(_ *JSONPb, v, repr interface{}) (interface{}, error) {
+	return repr, walkJSONRepr(reflect.TypeOf(v), reflect.ValueOf(v), repr, false, func(_ string, obj map[string]interface{}, fields []jsonReprField) error {
+		untagged := make(map[string]bool)
+		for _, f := range fields {
+			if f.oneof == nil || untagged[f.oneofName] {
+				continue
+			}
+			untagged[f.oneofName] = true
+			if err := o.untag(obj, f.oneofName, fields); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// untag rewrites the tagged union of the oneof named "oneof" in "obj" back into its set field.
+func (o TaggedOneofs) untag(obj map[string]interface{}, oneof string, fields []jsonReprField) error {
+	for _, key := range []string{oneof, jsonCamelCase(oneof)} {
+		repr, ok := obj[key]
+		if !ok {
+			continue
+		}
+		union, ok := repr.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("oneof %s is not a tagged union: %v", key, repr)
+		}
+		typ, _ := union[o.typeKey()].(string)
+		for _, f := range fields {
+			if f.oneofName != oneof || typ == "" || (f.props.OrigName != typ && f.props.JSONName != typ) {
+				continue
+			}
+			delete(obj, key)
+			obj[typ] = union[o.valueKey()]
+			return nil
+		}
+		return fmt.Errorf("unknown type %q of oneof %s", typ, key)
+	}
+	return nil
+}
+
+func (o TaggedOneofs) oneofKey(j *JSONPb, oneof string) string {
+	if j.OrigName {
+		return oneof
+	}
+	return jsonCamelCase(oneof)
+}
+
+func (o TaggedOneofs) typeKey() string {
+	if o.TypeKey == "" {
+		return "type"
+	}
+	return o.TypeKey
+}
+
+func (o TaggedOneofs) valueKey() string {
+	if o.ValueKey == "" {
+		return "value"
+	}
+	return o.ValueKey
+}
+
+// jsonCamelCase converts a snake_case identifier into lowerCamelCase in the same way as protoc does for JSON names.
+func jsonCamelCase(s string) string {
+	var b bytes.Buffer
+	var upper bool
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_':
+			upper = true
+		case upper && 'a' <= c && c <= 'z':
+			b.WriteByte(c - 'a' + 'A')
+			upper = false
+		default:
+			b.WriteByte(c)
+			upper = false
+		}
+	}
+	return b.String()
+}
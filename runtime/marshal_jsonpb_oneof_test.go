@@ -0,0 +1,101 @@
+package runtime_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+)
+
+func TestTaggedOneofJSONPbRoundTrip(t *testing.T) {
+	for _, spec := range []struct {
+		m        *runtime.TaggedOneofJSONPb
+		msg      *examplepb.ABitOfEverything
+		oneofKey string
+		wantType string
+		typeKey  string
+	}{
+		{
+			m: &runtime.TaggedOneofJSONPb{JSONPb: runtime.JSONPb{OrigName: true}},
+			msg: &examplepb.ABitOfEverything{
+				Uuid:       "foo",
+				OneofValue: &examplepb.ABitOfEverything_OneofString{OneofString: "bar"},
+			},
+			oneofKey: "oneof_value",
+			wantType: "oneof_string",
+			typeKey:  "type",
+		},
+		{
+			m: &runtime.TaggedOneofJSONPb{},
+			msg: &examplepb.ABitOfEverything{
+				Uuid:       "foo",
+				OneofValue: &examplepb.ABitOfEverything_OneofEmpty{OneofEmpty: &empty.Empty{}},
+			},
+			oneofKey: "oneofValue",
+			wantType: "oneofEmpty",
+			typeKey:  "type",
+		},
+		{
+			m: &runtime.TaggedOneofJSONPb{TypeKey: "kind", ValueKey: "data"},
+			msg: &examplepb.ABitOfEverything{
+				Uuid:       "foo",
+				OneofValue: &examplepb.ABitOfEverything_OneofString{OneofString: "bar"},
+				Nested:     []*examplepb.ABitOfEverything_Nested{{Name: "nested"}},
+			},
+			oneofKey: "oneofValue",
+			wantType: "oneofString",
+			typeKey:  "kind",
+		},
+		{
+			m:   &runtime.TaggedOneofJSONPb{},
+			msg: &examplepb.ABitOfEverything{Uuid: "foo"},
+		},
+	} {
+		buf, err := spec.m.Marshal(spec.msg)
+		if err != nil {
+			t.Errorf("m.Marshal(%v) failed with %v; want success", spec.msg, err)
+			continue
+		}
+
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(buf, &obj); err != nil {
+			t.Errorf("json.Unmarshal(%q, &obj) failed with %v; want success", buf, err)
+			continue
+		}
+		if spec.oneofKey != "" {
+			var union map[string]json.RawMessage
+			if err := json.Unmarshal(obj[spec.oneofKey], &union); err != nil {
+				t.Errorf("json.Unmarshal(%q, &union) failed with %v; want success; buf = %s", obj[spec.oneofKey], err, buf)
+				continue
+			}
+			if got, want := string(union[spec.typeKey]), `"`+spec.wantType+`"`; got != want {
+				t.Errorf("union[%q] = %s; want %s", spec.typeKey, got, want)
+			}
+			if _, ok := obj[spec.wantType]; ok {
+				t.Errorf("m.Marshal(%v) = %s; want no flattened oneof key %q", spec.msg, buf, spec.wantType)
+			}
+		}
+
+		var got examplepb.ABitOfEverything
+		if err := spec.m.NewDecoder(bytes.NewReader(buf)).Decode(&got); err != nil {
+			t.Errorf("m.NewDecoder(%q).Decode(&got) failed with %v; want success", buf, err)
+			continue
+		}
+		if !proto.Equal(&got, spec.msg) {
+			t.Errorf("got = %v; want %v; buf = %s", &got, spec.msg, buf)
+		}
+	}
+}
+
+func TestTaggedOneofJSONPbUnknownType(t *testing.T) {
+	m := &runtime.TaggedOneofJSONPb{}
+	data := `{"oneofValue": {"type": "noSuchField", "value": 1}}`
+	var got examplepb.ABitOfEverything
+	if err := m.Unmarshal([]byte(data), &got); err == nil {
+		t.Errorf("m.Unmarshal(%q, &got) succeeded; want an error", data)
+	}
+}
@@ -2,6 +2,8 @@ package runtime_test
 
 import (
 	"bytes"
+	"fmt"
+	"io"
 	"reflect"
 	"strings"
 	"testing"
@@ -438,6 +440,60 @@ func TestJSONPbDecoder(t *testing.T) {
 	}
 }
 
+// messageStream is an io.Reader which generates a stream of "n" JSON messages on the fly.
+type messageStream struct {
+	n, generated int
+	pending      []byte
+	// read is the number of bytes read from the stream so far.
+	read int
+}
+
+func (s *messageStream) Read(p []byte) (int, error) {
+	if len(s.pending) == 0 {
+		if s.generated == s.n {
+			return 0, io.EOF
+		}
+		s.pending = []byte(fmt.Sprintf(`{"id":"%d","num":%d}`, s.generated, s.generated))
+		s.generated++
+	}
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	s.read += n
+	return n, nil
+}
+
+func TestJSONPbDecoderStreamsBody(t *testing.T) {
+	const (
+		count = 100000
+		// maxReadAhead bounds the bytes read ahead of the decoded messages.
+		maxReadAhead = 64 << 10
+	)
+	body := &messageStream{n: count}
+	dec := (&runtime.JSONPb{}).NewDecoder(body)
+
+	var decodedBytes int
+	for i := 0; ; i++ {
+		var msg examplepb.SimpleMessage
+		err := dec.Decode(&msg)
+		if err == io.EOF {
+			if i != count {
+				t.Errorf("decoded %d messages; want %d", i, count)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatalf("dec.Decode(&msg) failed with %v; want success", err)
+		}
+		if got, want := msg.Id, fmt.Sprint(i); got != want {
+			t.Fatalf("msg.Id = %q; want %q", got, want)
+		}
+		decodedBytes += len(fmt.Sprintf(`{"id":"%d","num":%d}`, i, i))
+		if ahead := body.read - decodedBytes; ahead > maxReadAhead {
+			t.Fatalf("read %d bytes ahead of message %d; want at most %d", ahead, i, maxReadAhead)
+		}
+	}
+}
+
 func TestJSONPbDecoderFields(t *testing.T) {
 	var m runtime.JSONPb
 	for _, fixt := range fieldFixtures {
@@ -456,6 +512,58 @@ func TestJSONPbDecoderFields(t *testing.T) {
 	}
 }
 
+func TestJSONPbDecoderBareScalars(t *testing.T) {
+	var m runtime.JSONPb
+	for _, spec := range []struct {
+		json string
+		want interface{}
+	}{
+		{json: `"hello"`, want: "hello"},
+		{json: `42`, want: int32(42)},
+		{json: `"42"`, want: int32(42)},
+		{json: `-9007199254740993`, want: int64(-9007199254740993)},
+		{json: `"18446744073709551615"`, want: uint64(18446744073709551615)},
+		{json: `1.5`, want: float64(1.5)},
+		{json: `"1.5"`, want: float32(1.5)},
+		{json: `true`, want: true},
+		{json: `false`, want: false},
+	} {
+		dest := reflect.New(reflect.TypeOf(spec.want))
+		if err := m.NewDecoder(strings.NewReader(spec.json)).Decode(dest.Interface()); err != nil {
+			t.Errorf("dec.Decode(%T) failed with %v; want success; input = %q", dest.Interface(), err, spec.json)
+			continue
+		}
+		if got := dest.Elem().Interface(); got != spec.want {
+			t.Errorf("dest = %#v; want %#v; input = %q", got, spec.want, spec.json)
+		}
+	}
+
+	var msg examplepb.ABitOfEverything
+	if err := m.NewDecoder(strings.NewReader(`"hello"`)).Decode(&msg.StringValue); err != nil {
+		t.Errorf("dec.Decode(&msg.StringValue) failed with %v; want success", err)
+	}
+	if got, want := msg.StringValue, "hello"; got != want {
+		t.Errorf("msg.StringValue = %q; want %q", got, want)
+	}
+
+	body := `[{"name": "foo", "amount": 10}, {"name": "bar", "ok": "TRUE"}]`
+	if err := m.NewDecoder(strings.NewReader(body)).Decode(&msg.Nested); err != nil {
+		t.Errorf("dec.Decode(&msg.Nested) failed with %v; want success; input = %q", err, body)
+	}
+	wantNested := []*examplepb.ABitOfEverything_Nested{
+		{Name: "foo", Amount: 10},
+		{Name: "bar", Ok: examplepb.ABitOfEverything_Nested_TRUE},
+	}
+	if got := msg.Nested; len(got) != len(wantNested) || !proto.Equal(got[0], wantNested[0]) || !proto.Equal(got[1], wantNested[1]) {
+		t.Errorf("msg.Nested = %v; want %v", got, wantNested)
+	}
+
+	var i int32
+	if err := m.NewDecoder(strings.NewReader(`"foo"`)).Decode(&i); err == nil {
+		t.Errorf("dec.Decode(&i) succeeded with %q; want failure", `"foo"`)
+	}
+}
+
 var (
 	fieldFixtures = []struct {
 		data          interface{}
@@ -0,0 +1,336 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// JSONTransform rewrites the JSON of the values marshaled and unmarshaled by TransformingJSONPb.
+//
+// The JSON is passed decoded into map[string]interface{}, []interface{}, string, json.Number, bool and nil
+// values, so that transforms can be chained without encoding it again in between.
+type JSONTransform interface {
+	// MarshalRepr rewrites "repr", the JSON of "v" marshaled with the options "j", and returns the result.
+	MarshalRepr(j *JSONPb, v, repr interface{}) (interface{}, error)
+	// UnmarshalRepr rewrites "repr", the JSON to be unmarshaled with the options "j" into "v", and returns the
+	// result. It may also update "v" before the result is unmarshaled into it.
+	UnmarshalRepr(j *JSONPb, v, repr interface{}) (interface{}, error)
+}
+
+// jsonValuePreparer is implemented by JSONTransforms which rewrite values before they are marshaled,
+// e.g. because jsonpb cannot marshal some of them. It returns whether it rewrote "v", and MarshalRepr
+// is then called with the original value. Otherwise, the JSON of "v" is left as it is.
+type jsonValuePreparer interface {
+	prepareValue(j *JSONPb, v interface{}) (interface{}, bool, error)
+}
+
+// TransformingJSONPb is a Marshaler which behaves like JSONPb except that the JSON of values is rewritten by
+// Transforms, e.g. NullableFields and TaggedOneofs, in order on Marshal and in reverse order on Unmarshal.
+// The JSON is decoded and encoded once whatever the number of Transforms.
+//
+// Transforms which move the keys of fields, i.e. TaggedOneofs and FieldRenames, should come last so that
+// the others still find the fields by their names.
+type TransformingJSONPb struct {
+	JSONPb
+	// Transforms rewrite the JSON of values.
+	Transforms []JSONTransform
+}
+
+// Marshal marshals "v" into JSON rewritten by Transforms.
+func (j *TransformingJSONPb) Marshal(v interface{}) ([]byte, error) {
+	return newJSONPbTransformer(&j.JSONPb, j.Transforms...).Marshal(v)
+}
+
+// Unmarshal unmarshals JSON "data" rewritten back by Transforms into "v".
+func (j *TransformingJSONPb) Unmarshal(data []byte, v interface{}) error {
+	return newJSONPbTransformer(&j.JSONPb, j.Transforms...).Unmarshal(data, v)
+}
+
+// NewDecoder returns a Decoder which reads JSON stream rewritten back by Transforms from "r".
+func (j *TransformingJSONPb) NewDecoder(r io.Reader) Decoder {
+	return newJSONPbTransformer(&j.JSONPb, j.Transforms...).NewDecoder(r)
+}
+
+// NewEncoder returns an Encoder which writes JSON stream rewritten by Transforms into "w".
+func (j *TransformingJSONPb) NewEncoder(w io.Writer) Encoder {
+	return newJSONPbTransformer(&j.JSONPb, j.Transforms...).NewEncoder(w)
+}
+
+// jsonTransformer marshals and unmarshals values with "base", rewriting their JSON with "transforms".
+type jsonTransformer struct {
+	base Marshaler
+	// opts are the options passed to the transforms, whose Indent is also used to encode the rewritten JSON.
+	opts       *JSONPb
+	transforms []JSONTransform
+}
+
+func newJSONPbTransformer(j *JSONPb, transforms ...JSONTransform) jsonTransformer {
+	return jsonTransformer{base: j, opts: j, transforms: transforms}
+}
+
+func (c jsonTransformer) Marshal(v interface{}) ([]byte, error) {
+	if v == nil || len(c.transforms) == 0 {
+		return c.base.Marshal(v)
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Map {
+		return c.marshalMap(rv)
+	}
+
+	marshaled := v
+	transforms := make([]JSONTransform, 0, len(c.transforms))
+	for _, t := range c.transforms {
+		p, ok := t.(jsonValuePreparer)
+		if !ok {
+			transforms = append(transforms, t)
+			continue
+		}
+		prepared, rewritten, err := p.prepareValue(c.opts, marshaled)
+		if err != nil {
+			return nil, err
+		}
+		if rewritten {
+			marshaled = prepared
+			transforms = append(transforms, t)
+		}
+	}
+	if len(transforms) == 0 {
+		return c.base.Marshal(v)
+	}
+
+	buf, err := c.base.Marshal(marshaled)
+	if err != nil {
+		return nil, err
+	}
+	repr, err := decodeJSONRepr(buf)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range transforms {
+		if repr, err = t.MarshalRepr(c.opts, v, repr); err != nil {
+			return nil, err
+		}
+	}
+	return c.encode(repr)
+}
+
+// marshalMap marshals maps of values, e.g. stream chunks, value by value
+// so that the JSON of the messages in them is also rewritten.
+func (c jsonTransformer) marshalMap(rv reflect.Value) ([]byte, error) {
+	m := make(map[string]*json.RawMessage)
+	for _, k := range rv.MapKeys() {
+		buf, err := c.Marshal(rv.MapIndex(k).Interface())
+		if err != nil {
+			return nil, err
+		}
+		m[fmt.Sprintf("%v", k.Interface())] = (*json.RawMessage)(&buf)
+	}
+	return c.encode(m)
+}
+
+func (c jsonTransformer) encode(repr interface{}) ([]byte, error) {
+	if c.opts.Indent != "" {
+		return json.MarshalIndent(repr, "", c.opts.Indent)
+	}
+	return json.Marshal(repr)
+}
+
+func (c jsonTransformer) Unmarshal(data []byte, v interface{}) error {
+	if _, ok := v.(proto.Message); !ok || len(c.transforms) == 0 {
+		return c.base.Unmarshal(data, v)
+	}
+	repr, err := decodeJSONRepr(data)
+	if err != nil {
+		return err
+	}
+	for i := len(c.transforms) - 1; i >= 0; i-- {
+		if repr, err = c.transforms[i].UnmarshalRepr(c.opts, v, repr); err != nil {
+			return err
+		}
+	}
+	buf, err := json.Marshal(repr)
+	if err != nil {
+		return err
+	}
+	return c.base.Unmarshal(buf, v)
+}
+
+func (c jsonTransformer) NewDecoder(r io.Reader) Decoder {
+	d := json.NewDecoder(r)
+	return DecoderFunc(func(v interface{}) error {
+		var raw json.RawMessage
+		if err := d.Decode(&raw); err != nil {
+			return err
+		}
+		return c.Unmarshal(raw, v)
+	})
+}
+
+func (c jsonTransformer) NewEncoder(w io.Writer) Encoder {
+	return EncoderFunc(func(v interface{}) error {
+		buf, err := c.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(buf)
+		return err
+	})
+}
+
+func decodeJSONRepr(data []byte) (interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	// Keep numbers verbatim, e.g. large uint64 values.
+	d.UseNumber()
+	var repr interface{}
+	if err := d.Decode(&repr); err != nil {
+		return nil, err
+	}
+	return repr, nil
+}
+
+// jsonReprField is a field of a message visited by walkJSONRepr.
+type jsonReprField struct {
+	fieldLookup
+	// key is the key of the field in the JSON object of the message, or "" if the field is missing from it.
+	key string
+	// value is the value of the field. It is invalid if the value of the message is unknown, or if the field
+	// is a member of a oneof and another member is set.
+	value reflect.Value
+}
+
+// jsonReprVisitor rewrites "obj", the JSON object of the message named "name", given the fields of the message.
+// "obj" is nil if the values of the message are walked without JSON.
+type jsonReprVisitor func(name string, obj map[string]interface{}, fields []jsonReprField) error
+
+// walkJSONRepr calls "visit" with each message in "repr", the decoded JSON of the value "v" of type "t", which
+// is a message, or a list or a map of messages. "v" is invalid if only the type is known. If "repr" is nil,
+// the values of "v" are walked without JSON.
+//
+// Nested messages are visited before the messages which contain them if "nestedFirst" is true, so that they
+// are found by the keys which the visitor may then rewrite, and after them otherwise, so that they are found
+// by the keys which the visitor has rewritten back. Well known types are not visited since they have their
+// own JSON representation.
+func walkJSONRepr(t reflect.Type, v reflect.Value, repr interface{}, nestedFirst bool, visit jsonReprVisitor) error {
+	switch t.Kind() {
+	case reflect.Ptr:
+		if v.IsValid() {
+			if v.IsNil() {
+				v = reflect.Value{}
+			} else {
+				v = v.Elem()
+			}
+		}
+		return walkJSONRepr(t.Elem(), v, repr, nestedFirst, visit)
+	case reflect.Struct:
+		return walkJSONReprMessage(t, v, repr, nestedFirst, visit)
+	case reflect.Slice:
+		if t.Elem().Kind() != reflect.Ptr {
+			return nil
+		}
+		list, _ := repr.([]interface{})
+		n := len(list)
+		if repr == nil && v.IsValid() {
+			n = v.Len()
+		}
+		for i := 0; i < n; i++ {
+			var e interface{}
+			if i < len(list) {
+				e = list[i]
+			}
+			var ev reflect.Value
+			if v.IsValid() && i < v.Len() {
+				ev = v.Index(i)
+			}
+			if err := walkJSONRepr(t.Elem(), ev, e, nestedFirst, visit); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if t.Elem().Kind() != reflect.Ptr {
+			return nil
+		}
+		values := make(map[string]reflect.Value)
+		if v.IsValid() {
+			for _, k := range v.MapKeys() {
+				values[fmt.Sprintf("%v", k.Interface())] = v.MapIndex(k)
+			}
+		}
+		if repr == nil {
+			for _, ev := range values {
+				if err := walkJSONRepr(t.Elem(), ev, nil, nestedFirst, visit); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		m, _ := repr.(map[string]interface{})
+		for k, e := range m {
+			if err := walkJSONRepr(t.Elem(), values[k], e, nestedFirst, visit); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func walkJSONReprMessage(t reflect.Type, v reflect.Value, repr interface{}, nestedFirst bool, visit jsonReprVisitor) error {
+	obj, _ := repr.(map[string]interface{})
+	if obj == nil && (repr != nil || !v.IsValid()) {
+		return nil
+	}
+	idx := messageFieldIndex(t)
+	if idx.name == "" || strings.HasPrefix(idx.name, "google.protobuf.") {
+		return nil
+	}
+
+	if !nestedFirst {
+		if err := visit(idx.name, obj, jsonReprFields(idx, v, obj)); err != nil {
+			return err
+		}
+	}
+	fields := jsonReprFields(idx, v, obj)
+	for _, f := range fields {
+		if obj != nil && f.key == "" {
+			continue
+		}
+		if err := walkJSONRepr(f.typ, f.value, obj[f.key], nestedFirst, visit); err != nil {
+			return err
+		}
+	}
+	if nestedFirst {
+		return visit(idx.name, obj, fields)
+	}
+	return nil
+}
+
+// jsonReprFields returns the fields of the message "v", which may be invalid, along with their keys in "obj".
+func jsonReprFields(idx *fieldIndex, v reflect.Value, obj map[string]interface{}) []jsonReprField {
+	fields := make([]jsonReprField, 0, len(idx.fields))
+	for _, l := range idx.fields {
+		f := jsonReprField{fieldLookup: l}
+		for _, key := range []string{l.props.OrigName, l.props.JSONName} {
+			if _, ok := obj[key]; ok && key != "" {
+				f.key = key
+				break
+			}
+		}
+		if v.IsValid() {
+			f.value = l.value(v)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// jsonFieldName returns the key of the field "p" in the JSON marshaled with the options "j".
+func jsonFieldName(j *JSONPb, p *proto.Properties) string {
+	if j.OrigName || p.JSONName == "" {
+		return p.OrigName
+	}
+	return p.JSONName
+}
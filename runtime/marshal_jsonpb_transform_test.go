@@ -0,0 +1,46 @@
+package runtime_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	pb "github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+)
+
+func TestTransformingJSONPbChain(t *testing.T) {
+	m := &runtime.TransformingJSONPb{
+		JSONPb: runtime.JSONPb{OrigName: true},
+		Transforms: []runtime.JSONTransform{
+			runtime.CamelCaseMapKeys{},
+			runtime.NonFiniteAsNull,
+			runtime.FieldRenames{"grpc.gateway.examples.examplepb.ABitOfEverything": {"uuid": "id"}},
+			runtime.TaggedOneofs{},
+		},
+	}
+	msg := &pb.ABitOfEverything{
+		Uuid:              "foo",
+		DoubleValue:       math.Inf(1),
+		MappedStringValue: map[string]string{"foo_bar": "x"},
+		OneofValue:        &pb.ABitOfEverything_OneofString{OneofString: "bar"},
+	}
+
+	buf, err := m.Marshal(msg)
+	if err != nil {
+		t.Fatalf("m.Marshal(%v) failed with %v; want success", msg, err)
+	}
+	want := `{"double_value":null,"id":"foo","mapped_string_value":{"fooBar":"x"},"oneof_value":{"type":"oneof_string","value":"bar"}}`
+	if got := string(buf); got != want {
+		t.Errorf("m.Marshal(%v) = %s; want %s", msg, got, want)
+	}
+
+	var got pb.ABitOfEverything
+	if err := m.Unmarshal(buf, &got); err != nil {
+		t.Fatalf("m.Unmarshal(%s, &got) failed with %v; want success", buf, err)
+	}
+	msg.DoubleValue = 0
+	if !reflect.DeepEqual(&got, msg) {
+		t.Errorf("m.Unmarshal(%s, &got); got = %v; want %v", buf, &got, msg)
+	}
+}
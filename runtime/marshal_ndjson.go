@@ -0,0 +1,22 @@
+package runtime
+
+// NDJSONContentType is the content type of newline-delimited JSON streams (http://ndjson.org).
+const NDJSONContentType = "application/x-ndjson"
+
+// NDJSONMarshaler is a Marshaler which behaves like JSONPb except that it declares streamed responses
+// as NDJSONContentType, i.e. JSON values each followed by a newline, whereas unary responses remain
+// "application/json". It is registered for NDJSONContentType by default, and Indent must not be set
+// since each value has to fit on a single line.
+type NDJSONMarshaler struct {
+	JSONPb
+}
+
+// ContentType always returns "application/json".
+func (*NDJSONMarshaler) ContentType() string {
+	return "application/json"
+}
+
+// StreamContentType always returns NDJSONContentType.
+func (*NDJSONMarshaler) StreamContentType() string {
+	return NDJSONContentType
+}
@@ -0,0 +1,106 @@
+package runtime
+
+import (
+	"io"
+	"reflect"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// RenamingMarshaler is a Marshaler which decorates a JSON Marshaler, e.g. JSONPb, renaming the keys of
+// message fields in the JSON it marshals as configured by Renames, and back in the JSON it unmarshals, e.g.
+// to keep the published JSON key of a field renamed in the proto definition.
+//
+// Fields are renamed in nested messages, including repeated fields, map values and oneofs, but not in
+// google.protobuf.Any and Struct values. A public key must not be the name of another field of the message.
+// The renamed JSON is written without the indentation of the decorated Marshaler.
+type RenamingMarshaler struct {
+	Marshaler
+	// Renames maps the full name of a message, e.g. "foo.v1.Bar", to the renames of its fields, from the proto
+	// or JSON name of a field, e.g. "display_name" or "displayName", to its public JSON key, e.g. "title".
+	Renames map[string]map[string]string
+}
+
+func (m *RenamingMarshaler) transformer() jsonTransformer {
+	t := jsonTransformer{base: m.Marshaler, opts: &JSONPb{}}
+	if len(m.Renames) > 0 {
+		t.transforms = []JSONTransform{FieldRenames(m.Renames)}
+	}
+	return t
+}
+
+// Marshal marshals "v" into JSON with the public keys of the renamed fields.
+func (m *RenamingMarshaler) Marshal(v interface{}) ([]byte, error) {
+	return m.transformer().Marshal(v)
+}
+
+// Unmarshal unmarshals JSON "data" with the public keys of the renamed fields into "v".
+func (m *RenamingMarshaler) Unmarshal(data []byte, v interface{}) error {
+	return m.transformer().Unmarshal(data, v)
+}
+
+// NewDecoder returns a Decoder which reads JSON stream with the public keys of the renamed fields from "r".
+func (m *RenamingMarshaler) NewDecoder(r io.Reader) Decoder {
+	return m.transformer().NewDecoder(r)
+}
+
+// NewEncoder returns an Encoder which writes JSON stream with the public keys of the renamed fields into "w".
+func (m *RenamingMarshaler) NewEncoder(w io.Writer) Encoder {
+	return m.transformer().NewEncoder(w)
+}
+
+// Delimiter returns the delimiter of the decorated Marshaler if it is Delimited, or else a newline.
+func (m *RenamingMarshaler) Delimiter() []byte {
+	if d, ok := m.Marshaler.(Delimited); ok {
+		return d.Delimiter()
+	}
+	return []byte("\n")
+}
+
+// FieldRenames is a JSONTransform which renames the keys of message fields, as RenamingMarshaler does.
+// It maps the full name of a message, e.g. "foo.v1.Bar", to the renames of its fields, from the proto
+// or JSON name of a field, e.g. "display_name" or "displayName", to its public JSON key, e.g. "title".
+type FieldRenames map[string]map[string]string
+
+// MarshalRepr renames the keys of the renamed fields in "repr" into their public keys.
+func (r FieldRenames) MarshalRepr(_ *JSONPb, v, repr interface{}) (interface{}, error) {
+	return repr, walkJSONRepr(reflect.TypeOf(v), reflect.ValueOf(v), repr, true, func(name string, obj map[string]interface{}, fields []jsonReprField) error {
+		for _, f := range fields {
+			public, ok := r.publicKey(name, f.props)
+			if !ok || f.key == "" {
+				continue
+			}
+			child := obj[f.key]
+			delete(obj, f.key)
+			obj[public] = child
+		}
+		return nil
+	})
+}
+
+// UnmarshalRepr renames the public keys of the renamed fields in "repr" back into their proto names.
+func (r FieldRenames) UnmarshalRepr(_ *JSONPb, v, repr interface{}) (interface{}, error) {
+	return repr, walkJSONRepr(reflect.TypeOf(v), reflect.ValueOf(v), repr, false, func(name string, obj map[string]interface{}, fields []jsonReprField) error {
+		for _, f := range fields {
+			public, ok := r.publicKey(name, f.props)
+			if !ok {
+				continue
+			}
+			if child, found := obj[public]; found {
+				delete(obj, public)
+				obj[f.props.OrigName] = child
+			}
+		}
+		return nil
+	})
+}
+
+// publicKey returns the public key of the field "p" of the message named "name" if it is renamed.
+func (r FieldRenames) publicKey(name string, p *proto.Properties) (string, bool) {
+	renames := r[name]
+	if public, ok := renames[p.OrigName]; ok {
+		return public, true
+	}
+	public, ok := renames[p.JSONName]
+	return public, ok
+}
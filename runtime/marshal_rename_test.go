@@ -0,0 +1,75 @@
+package runtime_test
+
+import (
+	"reflect"
+	"testing"
+
+	pb "github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+)
+
+func newRenamingMarshaler(m runtime.Marshaler) *runtime.RenamingMarshaler {
+	return &runtime.RenamingMarshaler{
+		Marshaler: m,
+		Renames: map[string]map[string]string{
+			"grpc.gateway.examples.examplepb.ABitOfEverything":        {"uuid": "id", "singleNested": "main"},
+			"grpc.gateway.examples.examplepb.ABitOfEverything.Nested": {"amount": "qty"},
+		},
+	}
+}
+
+func newRenamedMessage() *pb.ABitOfEverything {
+	return &pb.ABitOfEverything{
+		Uuid:              "foo",
+		SingleNested:      &pb.ABitOfEverything_Nested{Name: "a", Amount: 1},
+		Nested:            []*pb.ABitOfEverything_Nested{{Name: "b", Amount: 2}},
+		MappedNestedValue: map[string]*pb.ABitOfEverything_Nested{"c": {Amount: 3}},
+		StringValue:       "bar",
+	}
+}
+
+func TestRenamingMarshalerMarshal(t *testing.T) {
+	for _, spec := range []struct {
+		m    runtime.Marshaler
+		want string
+	}{
+		{
+			m:    &runtime.JSONPb{OrigName: true},
+			want: `{"id":"foo","main":{"name":"a","qty":1},"mapped_nested_value":{"c":{"qty":3}},"nested":[{"name":"b","qty":2}],"string_value":"bar"}`,
+		},
+		{
+			m:    &runtime.JSONPb{},
+			want: `{"id":"foo","main":{"name":"a","qty":1},"mappedNestedValue":{"c":{"qty":3}},"nested":[{"name":"b","qty":2}],"stringValue":"bar"}`,
+		},
+	} {
+		buf, err := newRenamingMarshaler(spec.m).Marshal(newRenamedMessage())
+		if err != nil {
+			t.Errorf("m.Marshal(msg) failed with %v; want success; marshaler=%#v", err, spec.m)
+			continue
+		}
+		if got := string(buf); got != spec.want {
+			t.Errorf("m.Marshal(msg) = %s; want %s; marshaler=%#v", got, spec.want, spec.m)
+		}
+	}
+}
+
+func TestRenamingMarshalerUnmarshal(t *testing.T) {
+	m := newRenamingMarshaler(&runtime.JSONPb{})
+	data := `{"id":"foo","main":{"name":"a","qty":1},"mappedNestedValue":{"c":{"qty":3}},"nested":[{"name":"b","qty":2}],"stringValue":"bar"}`
+	var got pb.ABitOfEverything
+	if err := m.Unmarshal([]byte(data), &got); err != nil {
+		t.Fatalf("m.Unmarshal(%s, &got) failed with %v; want success", data, err)
+	}
+	if want := newRenamedMessage(); !reflect.DeepEqual(&got, want) {
+		t.Errorf("m.Unmarshal(%s, &got); got = %v; want %v", data, &got, want)
+	}
+
+	// Stream chunks are renamed value by value.
+	buf, err := m.Marshal(map[string]interface{}{"result": &pb.ABitOfEverything_Nested{Amount: 4}})
+	if err != nil {
+		t.Fatalf("m.Marshal(chunk) failed with %v; want success", err)
+	}
+	if got, want := string(buf), `{"result":{"qty":4}}`; got != want {
+		t.Errorf("m.Marshal(chunk) = %s; want %s", got, want)
+	}
+}
@@ -12,6 +12,8 @@ type Marshaler interface {
 	// "v" must be a pointer value.
 	Unmarshal(data []byte, v interface{}) error
 	// NewDecoder returns a Decoder which reads byte sequence from "r".
+	// Each call of Decode should only read as much of "r" as the next value requires,
+	// so that streamed request bodies are forwarded message by message without being buffered.
 	NewDecoder(r io.Reader) Decoder
 	// NewEncoder returns an Encoder which writes bytes sequence into "w".
 	NewEncoder(w io.Writer) Encoder
@@ -46,3 +48,26 @@ type Delimited interface {
 	// Delimiter returns the record seperator for the stream.
 	Delimiter() []byte
 }
+
+// StreamMarshaler is implemented by marshalers which encode the messages of streamed responses directly
+// into the response, without the intermediate byte slice returned by Marshal.
+type StreamMarshaler interface {
+	// NewStreamEncoder returns an Encoder which writes each value into "w" exactly as Marshal returns it,
+	// without a delimiter, and writes nothing if the value fails to encode.
+	NewStreamEncoder(w io.Writer) Encoder
+}
+
+// StreamContentTyper defines the content type of streamed responses
+// if it differs from the one of unary responses.
+type StreamContentTyper interface {
+	// StreamContentType returns the Content-Type of streamed responses.
+	StreamContentType() string
+}
+
+// streamContentType returns the Content-Type of responses streamed with "marshaler".
+func streamContentType(marshaler Marshaler) string {
+	if t, ok := marshaler.(StreamContentTyper); ok {
+		return t.StreamContentType()
+	}
+	return marshaler.ContentType()
+}
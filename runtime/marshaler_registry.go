@@ -2,7 +2,10 @@ package runtime
 
 import (
 	"errors"
+	"mime"
 	"net/http"
+
+	"golang.org/x/net/context"
 )
 
 // MIMEWildcard is the fallback MIME type used for requests which do not match
@@ -20,22 +23,14 @@ var (
 // It checks the registry on the ServeMux for the MIME type set by the Content-Type header.
 // If it isn't set (or the request Content-Type is empty), checks for "*".
 // If there are multiple Content-Type headers set, choose the first one that it can
-// exactly match in the registry.
+// exactly match in the registry, or else the first one whose media type without
+// parameters such as "charset" matches.
 // Otherwise, it follows the above logic for "*"/InboundMarshaler/OutboundMarshaler.
+// The inbound marshaler rejects request bodies in charsets other than UTF-8 unless it is a CharsetMarshaler.
+// The inbound marshaler records unknown fields of the request if WithUnknownFieldPassthrough applies to it.
 func MarshalerForRequest(mux *ServeMux, r *http.Request) (inbound Marshaler, outbound Marshaler) {
-	for _, acceptVal := range r.Header[acceptHeader] {
-		if m, ok := mux.marshalers.mimeMap[acceptVal]; ok {
-			outbound = m
-			break
-		}
-	}
-
-	for _, contentTypeVal := range r.Header[contentTypeHeader] {
-		if m, ok := mux.marshalers.mimeMap[contentTypeVal]; ok {
-			inbound = m
-			break
-		}
-	}
+	outbound, _ = mux.marshalers.lookup(r.Header[acceptHeader])
+	inbound, _ = mux.marshalers.lookup(r.Header[contentTypeHeader])
 
 	if inbound == nil {
 		inbound = mux.marshalers.mimeMap[MIMEWildcard]
@@ -43,6 +38,10 @@ func MarshalerForRequest(mux *ServeMux, r *http.Request) (inbound Marshaler, out
 	if outbound == nil {
 		outbound = inbound
 	}
+	inbound = charsetMarshaler(inbound, RequestCharset(r))
+	if fields, ok := unknownFieldsFromRequest(r); ok {
+		inbound = &unknownFieldsMarshaler{Marshaler: inbound, fields: fields}
+	}
 
 	return inbound, outbound
 }
@@ -52,6 +51,26 @@ type marshalerRegistry struct {
 	mimeMap map[string]Marshaler
 }
 
+// lookup returns the marshaler for the first of the header values "vals" which exactly matches a
+// registered MIME type, or else for the first one whose media type without parameters does.
+func (m marshalerRegistry) lookup(vals []string) (Marshaler, bool) {
+	for _, val := range vals {
+		if marshaler, ok := m.mimeMap[val]; ok {
+			return marshaler, true
+		}
+	}
+	for _, val := range vals {
+		mediaType, _, err := mime.ParseMediaType(val)
+		if err != nil {
+			continue
+		}
+		if marshaler, ok := m.mimeMap[mediaType]; ok {
+			return marshaler, true
+		}
+	}
+	return nil, false
+}
+
 // add adds a marshaler for a case-sensitive MIME type string ("*" to match any
 // MIME type).
 func (m marshalerRegistry) add(mime string, marshaler Marshaler) error {
@@ -71,11 +90,13 @@ func (m marshalerRegistry) add(mime string, marshaler Marshaler) error {
 // with a "applicaton/jsonpb" Content-Type and the use of the runtime.JSONBuiltin marshaler
 // with a "application/json" Content-Type.
 // "*" can be used to match any Content-Type.
+// NDJSONContentType is registered by default so that clients can ask for newline-delimited JSON streams.
 // This can be attached to a ServerMux with the marshaler option.
 func makeMarshalerMIMERegistry() marshalerRegistry {
 	return marshalerRegistry{
 		mimeMap: map[string]Marshaler{
-			MIMEWildcard: defaultMarshaler,
+			MIMEWildcard:      defaultMarshaler,
+			NDJSONContentType: &NDJSONMarshaler{JSONPb: JSONPb{OrigName: true}},
 		},
 	}
 }
@@ -89,3 +110,20 @@ func WithMarshalerOption(mime string, marshaler Marshaler) ServeMuxOption {
 		}
 	}
 }
+
+type marshalerContextKey struct{}
+
+// WithMarshalerContext returns a copy of ctx carrying "marshaler".
+//
+// ForwardResponseMessage and ForwardResponseStream prefer a Marshaler found in their context over the one
+// negotiated from the request headers. This lets interceptors, e.g. an http.Handler wrapping the ServeMux,
+// choose the response format from business logic.
+func WithMarshalerContext(ctx context.Context, marshaler Marshaler) context.Context {
+	return context.WithValue(ctx, marshalerContextKey{}, marshaler)
+}
+
+// MarshalerFromContext returns the Marshaler stored in ctx by WithMarshalerContext.
+func MarshalerFromContext(ctx context.Context) (marshaler Marshaler, ok bool) {
+	marshaler, ok = ctx.Value(marshalerContextKey{}).(Marshaler)
+	return
+}
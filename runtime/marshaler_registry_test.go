@@ -4,8 +4,11 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	pb "github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
 )
 
@@ -76,6 +79,38 @@ func TestMarshalerForRequest(t *testing.T) {
 	}
 }
 
+func TestMarshalerForRequestMediaTypeParameters(t *testing.T) {
+	jsonMarshaler := &runtime.JSONPb{OrigName: true}
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.ProtoMarshaller{}),
+		runtime.WithMarshalerOption("application/json", jsonMarshaler),
+	)
+	for _, contentType := range []string{"application/json", "application/json; charset=utf-8", "application/json;charset=UTF-8"} {
+		r := httptest.NewRequest("POST", "http://example.com", strings.NewReader(`{"id": "foo"}`))
+		r.Header.Set("Content-Type", contentType)
+
+		in, out := runtime.MarshalerForRequest(mux, r)
+		if got, want := in, runtime.Marshaler(jsonMarshaler); got != want {
+			t.Errorf("in = %#v; want %#v; Content-Type=%q", got, want, contentType)
+		}
+		if got, want := out, runtime.Marshaler(jsonMarshaler); got != want {
+			t.Errorf("out = %#v; want %#v; Content-Type=%q", got, want, contentType)
+		}
+	}
+
+	r := httptest.NewRequest("POST", "http://example.com", strings.NewReader(`{"id": "foo"}`))
+	r.Header.Set("Content-Type", "application/json; charset=ISO-8859-1")
+	if got, want := runtime.RequestCharset(r), "iso-8859-1"; got != want {
+		t.Errorf("runtime.RequestCharset(r) = %q; want %q", got, want)
+	}
+	in, _ := runtime.MarshalerForRequest(mux, r)
+	var msg pb.SimpleMessage
+	err := in.NewDecoder(r.Body).Decode(&msg)
+	if err == nil || !strings.Contains(err.Error(), "iso-8859-1") {
+		t.Errorf("in.NewDecoder(r.Body).Decode(&msg) = %v; want an unsupported charset error", err)
+	}
+}
+
 type dummyMarshaler struct{}
 
 func (dummyMarshaler) ContentType() string { return "" }
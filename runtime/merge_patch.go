@@ -0,0 +1,117 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/protobuf/field_mask"
+)
+
+// MergePatchContentType is the media type of JSON Merge Patch (RFC 7396) request bodies.
+const MergePatchContentType = "application/merge-patch+json"
+
+type mergePatchKey struct{}
+
+// WithMergePatch returns a ServeMuxOption which makes the gateway decode the body of all PATCH requests
+// as a JSON Merge Patch. Without it, only bodies of MergePatchContentType are. See DecodeMergePatch.
+func WithMergePatch() ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.mergePatch = true
+	}
+}
+
+// IsMergePatch reports whether the body of "req" is to be decoded as a JSON Merge Patch,
+// either because of its Content-Type or because the ServeMux in "ctx" was configured with WithMergePatch.
+func IsMergePatch(ctx context.Context, req *http.Request) bool {
+	if enabled, _ := ctx.Value(mergePatchKey{}).(bool); enabled {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return err == nil && mediaType == MergePatchContentType
+}
+
+// DecodeMergePatch decodes the JSON Merge Patch read from "r" into "v" with "marshaler",
+// and returns the mask of the fields present in the patch, including those set to null, which are cleared.
+// Fields of nested messages are masked individually, whereas repeated fields, maps and well-known types
+// are replaced as a whole. Generated handlers of PATCH methods whose request message has an "update_mask"
+// field set it to the result when IsMergePatch reports true. It returns io.EOF if "r" is empty, as decoders do.
+func DecodeMergePatch(marshaler Marshaler, r io.Reader, v interface{}) (*field_mask.FieldMask, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, io.EOF
+	}
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %v", err)
+	}
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%T is not a message", v)
+	}
+
+	mask := new(field_mask.FieldMask)
+	mergePatchPaths(t, "", patch, mask)
+	sort.Strings(mask.Paths)
+
+	if err := marshaler.NewDecoder(bytes.NewReader(data)).Decode(v); err != nil {
+		return nil, err
+	}
+	return mask, nil
+}
+
+// mergePatchPaths appends to "mask" the paths of the fields of the message struct type "t" which are present in "patch".
+func mergePatchPaths(t reflect.Type, prefix string, patch map[string]json.RawMessage, mask *field_mask.FieldMask) {
+	for key, raw := range patch {
+		l := lookupField(t, key)
+		if l.props == nil {
+			// Unknown fields are ignored when decoding.
+			continue
+		}
+		path := prefix + l.props.OrigName
+
+		var ft reflect.Type
+		if l.oneof != nil {
+			ft = l.oneof.Type.Elem().Field(0).Type
+		} else {
+			ft = t.FieldByIndex(l.index).Type
+		}
+		if nested, ok := nestedMergePatch(ft, raw); ok {
+			mergePatchPaths(ft.Elem(), path+".", nested, mask)
+			continue
+		}
+		mask.Paths = append(mask.Paths, path)
+	}
+}
+
+// nestedMergePatch returns the patch of the message field of type "ft" if "raw" is a JSON object
+// whose fields are to be merged into the message rather than replacing it.
+func nestedMergePatch(ft reflect.Type, raw json.RawMessage) (map[string]json.RawMessage, bool) {
+	if ft.Kind() != reflect.Ptr || ft.Elem().Kind() != reflect.Struct {
+		return nil, false
+	}
+	msg, ok := reflect.Zero(ft).Interface().(proto.Message)
+	if !ok || strings.HasPrefix(proto.MessageName(msg), "google.protobuf.") {
+		return nil, false
+	}
+	var nested map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &nested); err != nil || nested == nil {
+		return nil, false
+	}
+	return nested, true
+}
@@ -0,0 +1,71 @@
+package runtime_test
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	pb "github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"golang.org/x/net/context"
+)
+
+func TestDecodeMergePatch(t *testing.T) {
+	body := `{
+		"string_value": "foo",
+		"singleNested": {"amount": 3, "ok": null},
+		"int64_value": null,
+		"repeated_string_value": ["a"],
+		"oneof_string": "bar",
+		"unknown_field": 1
+	}`
+	msg := &pb.ABitOfEverything{Int64Value: 10}
+	mask, err := runtime.DecodeMergePatch(&runtime.JSONPb{}, strings.NewReader(body), msg)
+	if err != nil {
+		t.Fatalf("runtime.DecodeMergePatch(marshaler, %q, msg) failed with %v; want success", body, err)
+	}
+
+	want := []string{"int64_value", "oneof_string", "repeated_string_value", "single_nested.amount", "single_nested.ok", "string_value"}
+	if got := mask.GetPaths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("mask.Paths = %q; want %q", got, want)
+	}
+	if got, want := msg.StringValue, "foo"; got != want {
+		t.Errorf("msg.StringValue = %q; want %q", got, want)
+	}
+	if got, want := msg.GetSingleNested().GetAmount(), uint32(3); got != want {
+		t.Errorf("msg.SingleNested.Amount = %d; want %d", got, want)
+	}
+	if got, want := msg.GetOneofString(), "bar"; got != want {
+		t.Errorf("msg.OneofString = %q; want %q", got, want)
+	}
+
+	if _, err := runtime.DecodeMergePatch(&runtime.JSONPb{}, strings.NewReader(`["a"]`), new(pb.ABitOfEverything)); err == nil {
+		t.Errorf("runtime.DecodeMergePatch(marshaler, %q, msg) succeeded; want failure", `["a"]`)
+	}
+}
+
+func TestIsMergePatch(t *testing.T) {
+	for _, spec := range []struct {
+		contentType string
+		opts        []runtime.ServeMuxOption
+		want        bool
+	}{
+		{contentType: "application/json"},
+		{contentType: "application/merge-patch+json; charset=utf-8", want: true},
+		{contentType: "application/json", opts: []runtime.ServeMuxOption{runtime.WithMergePatch()}, want: true},
+	} {
+		req, err := http.NewRequest("PATCH", "http://example.com/foo", nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest(%q, %q, nil) failed with %v; want success", "PATCH", "http://example.com/foo", err)
+		}
+		req.Header.Set("Content-Type", spec.contentType)
+		ctx, err := runtime.AnnotateContext(context.Background(), runtime.NewServeMux(spec.opts...), req)
+		if err != nil {
+			t.Fatalf("runtime.AnnotateContext(ctx, mux, req) failed with %v; want success", err)
+		}
+		if got := runtime.IsMergePatch(ctx, req); got != spec.want {
+			t.Errorf("runtime.IsMergePatch(ctx, req) = %t; want %t; Content-Type=%q", got, spec.want, spec.contentType)
+		}
+	}
+}
@@ -4,10 +4,14 @@ import (
 	"fmt"
 	"net/http"
 	"net/textproto"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/httprule"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
@@ -20,15 +24,84 @@ type HandlerFunc func(w http.ResponseWriter, r *http.Request, pathParams map[str
 // It matches http requests to patterns and invokes the corresponding handler.
 type ServeMux struct {
 	// handlers maps HTTP method to a list of handlers.
-	handlers               map[string][]handler
-	forwardResponseOptions []func(context.Context, http.ResponseWriter, proto.Message) error
-	marshalers             marshalerRegistry
-	incomingHeaderMatcher  HeaderMatcherFunc
-	outgoingHeaderMatcher  HeaderMatcherFunc
-	metadataAnnotator      func(context.Context, *http.Request) metadata.MD
-	protoErrorHandler      ProtoErrorHandlerFunc
+	handlers                map[string][]handler
+	forwardResponseOptions  []func(context.Context, http.ResponseWriter, proto.Message) error
+	responseBodyOptions     []func(context.Context, http.ResponseWriter, proto.Message, []byte) error
+	marshalers              marshalerRegistry
+	incomingHeaderMatcher   HeaderMatcherFunc
+	joinHeaderValues        bool
+	outgoingHeaderMatcher   HeaderMatcherFunc
+	metadataAnnotator       func(context.Context, *http.Request) metadata.MD
+	protoErrorHandler       ProtoErrorHandlerFunc
+	callOptions             []grpc.CallOption
+	callOptionsFunc         CallOptionsFunc
+	requestIDHeader         string
+	noContentForEmpty       bool
+	streamCoalesceBytes     int
+	streamCoalesceDelay     time.Duration
+	strictTransport         bool
+	linkHeaderMetadataKey   string
+	streamFraming           bool
+	queryDefaults           map[string]func() interface{}
+	validateRequests        bool
+	emptyOnNotFound         []emptyResponseRoute
+	outgoingTrailerMatcher  HeaderMatcherFunc
+	maxStreamMessages       int
+	maxClientStreamMessages int
+	streamHeartbeatInterval time.Duration
+	streamHeartbeatData     []byte
+	emptyStreamBody         []byte
+	headForGet              bool
+	validateOnly            bool
+	disallowedMethods       map[string]bool
+	auditLog                func(AuditEvent)
+	auditActorKey           string
+	pathPrefix              string
+	compression             *compressionConfig
+	idempotency             *idempotencyConfig
+	requestTransformers     []RequestTransformerFunc
+	allowEmptyPathParams    bool
+	mergePatch              bool
+	errorObserver           ErrorObserverFunc
+	passthroughRoutes       []passthroughRoute
+	returnPreference        bool
+	openAPIEndpoints        map[string][]byte
+	openAPIHostRewrite      bool
+	openAPIHosts            map[string]bool
+	tracing                 bool
+	tracer                  Tracer
+	strictQueryParams       map[string]bool
+	querySeparator          rune
+	queryBools              map[string]bool
+	acceptLanguageKey       string
+	serverTiming            bool
+	unwrapAny               bool
+	anyTypeHeader           string
+	authenticateChallenge   string
+	unaryInterceptors       []UnaryInterceptor
+	concurrencyLimit        chan struct{}
+	maxMetadataHeaders      int
+	maxMetadataSize         int
+	shutdownCtx             context.Context
+	echoedHeaders           []string
+	routeMetadata           bool
+	retry                   *retryConfig
+	routeHeaders            []routeHeaders
+	routeContentTypes       []routeContentType
+	clientCertFields        []ClientCertField
+	headerPrecedence        HeaderPrecedence
+	requiredBodies          []requiredBodyRoute
+	fallbackHandler         http.Handler
+	statusTrailers          bool
+	compressionLevel        func(*http.Request) int
+	errorMessageSanitizer   ErrorMessageSanitizerFunc
+	// routeIndexes maps HTTP method to the index of its handlers if WithRouteIndex is given.
+	routeIndexes map[string]*routeIndex
 }
 
+// retryAfterOverloaded is the Retry-After header of the requests rejected because of WithMaxConcurrentRequests.
+const retryAfterOverloaded = "1"
+
 // ServeMuxOption is an option that can be given to a ServeMux on construction.
 type ServeMuxOption func(*ServeMux)
 
@@ -38,12 +111,30 @@ type ServeMuxOption func(*ServeMux)
 // http.ResponseWriter, and proto.Message before every forwarded response.
 //
 // The message may be nil in the case where just a header is being sent.
+//
+// For unary responses, forwardResponseOption may inspect the response message and set the status of the
+// response with WriteHeader, e.g. http.StatusAccepted when the backend only started an operation, before
+// the body is written. If it returns an error, an error response is sent in place of the message.
 func WithForwardResponseOption(forwardResponseOption func(context.Context, http.ResponseWriter, proto.Message) error) ServeMuxOption {
 	return func(serveMux *ServeMux) {
 		serveMux.forwardResponseOptions = append(serveMux.forwardResponseOptions, forwardResponseOption)
 	}
 }
 
+// WithForwardResponseBodyOption returns a ServeMuxOption which calls "fn" with the marshaled body of
+// every unary response, after the options given to WithForwardResponseOption and before the status
+// and the body are written. This lets "fn" set headers which depend on the body, e.g. a digest or a
+// signature of it, and the status with WriteHeader as forward response options do. If "fn" returns
+// an error, an error response is sent in place of the message.
+//
+// "fn" is not called for responses without a body, i.e. google.api.HttpBody responses, responses to
+// requests preferring "return=minimal" and empty responses replaced with WithNoContentForEmpty.
+func WithForwardResponseBodyOption(fn func(ctx context.Context, w http.ResponseWriter, resp proto.Message, body []byte) error) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.responseBodyOptions = append(serveMux.responseBodyOptions, fn)
+	}
+}
+
 // HeaderMatcherFunc checks whether a header key should be forwarded to/from gRPC context.
 type HeaderMatcherFunc func(string) (string, bool)
 
@@ -70,6 +161,15 @@ func WithIncomingHeaderMatcher(fn HeaderMatcherFunc) ServeMuxOption {
 	}
 }
 
+// WithJoinedHeaderValues returns a ServeMuxOption which makes the gateway fold the values of a request
+// header which occurs several times into a single comma-separated metadata value, e.g. for backends which
+// only read the first value of a key. By default, each value is appended to the metadata of the key.
+func WithJoinedHeaderValues() ServeMuxOption {
+	return func(mux *ServeMux) {
+		mux.joinHeaderValues = true
+	}
+}
+
 // WithOutgoingHeaderMatcher returns a ServeMuxOption representing a headerMatcher for outgoing response from gateway.
 //
 // This matcher will be called with each header in response header metadata. If matcher returns true, that header will be
@@ -81,6 +181,19 @@ func WithOutgoingHeaderMatcher(fn HeaderMatcherFunc) ServeMuxOption {
 	}
 }
 
+// WithOutgoingTrailerMatcher returns a ServeMuxOption which makes unary responses forward the
+// trailer metadata of the gRPC call in their headers rather than in HTTP trailers, which some
+// clients and proxies drop.
+//
+// This matcher will be called with each key in the trailer metadata. If matcher returns true, the
+// entry will be passed in the response headers under the returned key, e.g. with the
+// MetadataTrailerPrefix to keep it distinct from header metadata.
+func WithOutgoingTrailerMatcher(fn HeaderMatcherFunc) ServeMuxOption {
+	return func(mux *ServeMux) {
+		mux.outgoingTrailerMatcher = fn
+	}
+}
+
 // WithMetadata returns a ServeMuxOption for passing metadata to a gRPC context.
 //
 // This can be used by services that need to read from http.Request and modify gRPC context. A common use case
@@ -102,6 +215,332 @@ func WithProtoErrorHandler(fn ProtoErrorHandlerFunc) ServeMuxOption {
 	}
 }
 
+// CallOptionsFunc computes grpc.CallOptions for the RPC dispatched on behalf of a request.
+type CallOptionsFunc func(context.Context, *http.Request) []grpc.CallOption
+
+// WithCallOptions returns a ServeMuxOption which applies "opts" to every RPC dispatched through the ServeMux.
+//
+// This can be used to raise limits such as grpc.MaxCallRecvMsgSize for services with large responses.
+func WithCallOptions(opts ...grpc.CallOption) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.callOptions = append(serveMux.callOptions, opts...)
+	}
+}
+
+// WithCallOptionsFunc returns a ServeMuxOption for computing grpc.CallOptions per request.
+//
+// The returned options are applied after the ones given to WithCallOptions.
+func WithCallOptionsFunc(fn CallOptionsFunc) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.callOptionsFunc = fn
+	}
+}
+
+// WithRequestIDHeader returns a ServeMuxOption which correlates requests with an ID carried in the "header" HTTP header.
+//
+// The ID is taken from the request or generated when the request has none. It is forwarded to the gRPC
+// context as metadata, echoed in the response header before any body is written, including on streams,
+// and made available to forward response options via RequestIDFromContext.
+func WithRequestIDHeader(header string) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.requestIDHeader = textproto.CanonicalMIMEHeaderKey(header)
+	}
+}
+
+// WithNoContentForEmpty returns a ServeMuxOption which makes unary responses reply with
+// http.StatusNoContent and no body when the response message is a google.protobuf.Empty
+// or marshals into a trivial body such as "{}".
+//
+// Streaming responses are not affected.
+func WithNoContentForEmpty() ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.noContentForEmpty = true
+	}
+}
+
+// WithStreamCoalescing returns a ServeMuxOption which makes streamed responses coalesce messages
+// into fewer writes. Buffered messages are written once they amount to "maxBytes" or once "maxDelay"
+// has passed since the first of them was buffered, whichever comes first. A zero "maxDelay" only
+// bounds the buffer by size.
+//
+// By default every message is written and flushed as soon as it is received. Note that with
+// coalescing, forward response options must not modify response headers once the stream started.
+func WithStreamCoalescing(maxBytes int, maxDelay time.Duration) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.streamCoalesceBytes = maxBytes
+		serveMux.streamCoalesceDelay = maxDelay
+	}
+}
+
+// WithMaxStreamMessages returns a ServeMuxOption which caps the number of messages forwarded from
+// a server stream to "n". When the backend sends more, ForwardResponseStream terminates the response
+// with a codes.ResourceExhausted error chunk, and returning from the handler cancels the backend call.
+func WithMaxStreamMessages(n int) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.maxStreamMessages = n
+	}
+}
+
+// WithMaxClientStreamMessages returns a ServeMuxOption which caps the number of messages accepted from
+// the body of a client-streaming or bidirectional streaming request to "n". When the client sends more,
+// the RPC is canceled and the request fails with codes.ResourceExhausted. See LimitClientStream.
+func WithMaxClientStreamMessages(n int) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.maxClientStreamMessages = n
+	}
+}
+
+// WithStreamHeartbeat returns a ServeMuxOption which makes streamed responses write "data" whenever
+// no message was received from the backend for "interval", e.g. to keep load balancers from closing
+// idle connections. The timer is reset on each message, and heartbeats stop when the stream ends.
+//
+// "data" is written verbatim between messages, so it must be distinguishable from them by clients,
+// e.g. an extra newline for newline-delimited JSON. Heartbeats are not sent with WithStreamFraming.
+func WithStreamHeartbeat(interval time.Duration, data []byte) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.streamHeartbeatInterval = interval
+		serveMux.streamHeartbeatData = data
+	}
+}
+
+// WithEmptyStreamBody returns a ServeMuxOption which makes streamed responses write "body" when the backend
+// ends the stream successfully without sending any message, e.g. "[]" or a sentinel agreed upon with clients,
+// so that they can tell an empty result from a broken connection. "body" is written verbatim in place of
+// the messages. It does not apply with WithStreamFraming, whose trailer frame already ends the stream,
+// nor with ProtoMarshaller.
+func WithEmptyStreamBody(body []byte) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.emptyStreamBody = body
+	}
+}
+
+// WithStreamFraming returns a ServeMuxOption which makes streamed responses use gRPC-style framing
+// instead of delimited chunks. Each response message is marshaled as is into a length-prefixed data frame,
+// and the stream is terminated by a trailer frame carrying the final "grpc-status" and "grpc-message",
+// so that clients, notably proto ones, can tell whether the stream completed successfully.
+// Use ReadStreamFrame to read the frames.
+func WithStreamFraming() ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.streamFraming = true
+	}
+}
+
+// WithQueryDefaultFunc returns a ServeMuxOption which makes "fn" supply the value of the field
+// at "fieldPath", e.g. "timestamp_value", when the corresponding query parameter is absent.
+// "fn" is called for each such request and must return a value of the Go type of the field,
+// e.g. a *timestamp.Timestamp for a google.protobuf.Timestamp field.
+//
+// Defaults are only applied to fields which may be given as query parameters,
+// and which were not otherwise set.
+func WithQueryDefaultFunc(fieldPath string, fn func() interface{}) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		if serveMux.queryDefaults == nil {
+			serveMux.queryDefaults = make(map[string]func() interface{})
+		}
+		serveMux.queryDefaults[fieldPath] = fn
+	}
+}
+
+// WithStrictQueryParameters returns a ServeMuxOption which makes the gateway reject requests with
+// codes.InvalidArgument if they have query parameters which do not refer to a field of the request message,
+// e.g. misspelled ones. "allowed" lists the query parameters which are accepted nevertheless, e.g. ones
+// handled by middlewares such as "pretty". See CheckQueryParameters.
+func WithStrictQueryParameters(allowed ...string) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.strictQueryParams = make(map[string]bool)
+		for _, key := range allowed {
+			serveMux.strictQueryParams[key] = true
+		}
+	}
+}
+
+// WithBoolQueryValues returns a ServeMuxOption which makes the query parameters of bool fields accept the values
+// in "truthy" for true and those in "falsy" for false, in any case, instead of the values accepted by default:
+// those of strconv.ParseBool as well as "yes", "on", "y", "no", "off" and "n". Other values are rejected with
+// codes.InvalidArgument. See PopulateQueryParametersContext.
+func WithBoolQueryValues(truthy, falsy []string) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.queryBools = make(map[string]bool)
+		for _, v := range truthy {
+			serveMux.queryBools[strings.ToLower(v)] = true
+		}
+		for _, v := range falsy {
+			serveMux.queryBools[strings.ToLower(v)] = false
+		}
+	}
+}
+
+// WithRepeatedQuerySeparator returns a ServeMuxOption which makes the gateway also split the values of
+// the query parameters of repeated fields on "sep", so that "?tag=a,b&tag=c" populates "a", "b" and "c"
+// when "sep" is ','. A separator preceded by a backslash is taken literally, as is an escaped backslash.
+// See PopulateQueryParametersContext.
+func WithRepeatedQuerySeparator(sep rune) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.querySeparator = sep
+	}
+}
+
+// WithRequestValidation returns a ServeMuxOption which makes the gateway call the Validate method
+// of request messages implementing it, e.g. those generated by protoc-gen-validate, once path and
+// query parameters are bound and before the RPC is dispatched. See ValidateRequest.
+func WithRequestValidation() ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.validateRequests = true
+	}
+}
+
+// WithValidateOnly returns a ServeMuxOption which lets clients ask for requests to be only decoded
+// and, with WithRequestValidation, validated by setting ValidateOnlyParam or ValidateOnlyHeader to true.
+// Such requests are answered with an empty response message instead of being dispatched to the
+// backend, or with the decoding or validation error. Streaming calls are always dispatched.
+func WithValidateOnly() ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.validateOnly = true
+	}
+}
+
+// WithRouteIndex returns a ServeMuxOption which makes the ServeMux index the registered patterns
+// by their leading literal segments instead of examining all of them for each request.
+// This speeds up the dispatch when many patterns are registered. Among the patterns which match
+// a request, the one registered first is still chosen, and patterns starting with a variable are
+// always examined.
+func WithRouteIndex() ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.routeIndexes = make(map[string]*routeIndex)
+	}
+}
+
+// WithDisallowedMethods returns a ServeMuxOption which makes the ServeMux reject requests using
+// any of "methods", e.g. "TRACE" and "CONNECT", with http.StatusMethodNotAllowed before routing them,
+// regardless of the registered patterns. Form POST requests overriding their method with one of "methods"
+// in the X-HTTP-Method-Override header are rejected as well.
+func WithDisallowedMethods(methods ...string) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		if serveMux.disallowedMethods == nil {
+			serveMux.disallowedMethods = make(map[string]bool)
+		}
+		for _, m := range methods {
+			serveMux.disallowedMethods[strings.ToUpper(m)] = true
+		}
+	}
+}
+
+// WithFallbackHandler returns a ServeMuxOption which makes the ServeMux serve the requests which match no
+// registered pattern with "h" instead of answering them with http.StatusNotFound, or with
+// http.StatusMethodNotAllowed if a pattern matches their path with another method, e.g. with an
+// httputil.ReverseProxy to the HTTP backend of the endpoints which are not migrated to gRPC yet.
+// Requests rejected before routing, e.g. because of WithDisallowedMethods, do not reach "h".
+func WithFallbackHandler(h http.Handler) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.fallbackHandler = h
+	}
+}
+
+// WithPathPrefix returns a ServeMuxOption which makes the ServeMux strip "prefix", e.g. "/api",
+// from request paths before matching them against the registered patterns, so that the gateway can be
+// mounted below a base path. Requests whose path is not below "prefix" are answered with http.StatusNotFound.
+// Unlike http.StripPrefix, the requests given to handlers keep their full path.
+func WithPathPrefix(prefix string) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.pathPrefix = strings.TrimSuffix(prefix, "/")
+	}
+}
+
+// WithEmptyPathParameters returns a ServeMuxOption which lets requests whose path binds an empty string
+// to a path parameter, e.g. "/v1/users//sub" for "/v1/users/{id}/sub", reach the backend.
+// By default they are rejected with codes.InvalidArgument, except for parameters capturing a deep wildcard
+// alone, e.g. "/v1/files" for "/v1/files/{path=**}".
+func WithEmptyPathParameters() ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.allowEmptyPathParams = true
+	}
+}
+
+// ErrorObserverFunc is notified of each error response with the error, its gRPC code and the HTTP status it is mapped to.
+type ErrorObserverFunc func(ctx context.Context, err error, code codes.Code, httpStatus int)
+
+// WithAuthenticateChallenge returns a ServeMuxOption which makes the error handlers set the WWW-Authenticate
+// header of the 401 responses to codes.Unauthenticated errors to "challenge", e.g. `Basic realm="example"`,
+// so that browsers prompt for credentials. A WWW-Authenticate header forwarded from the backend takes precedence.
+func WithAuthenticateChallenge(challenge string) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.authenticateChallenge = challenge
+	}
+}
+
+// WithMaxConcurrentRequests returns a ServeMuxOption which caps the number of requests served at the same
+// time to "n", e.g. to protect backends from overload. Excess requests are rejected with codes.Unavailable,
+// i.e. 503 Service Unavailable, and a Retry-After header. Note that streaming calls hold their slot until
+// the stream ends.
+func WithMaxConcurrentRequests(n int) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.concurrencyLimit = make(chan struct{}, n)
+	}
+}
+
+// WithMaxIncomingMetadata returns a ServeMuxOption which rejects requests forwarding more than "count"
+// headers to the backend metadata, or whose forwarded header names and values add up to more than "size"
+// bytes, with codes.InvalidArgument, i.e. 400 Bad Request. Only the headers accepted by the incoming header
+// matcher are accounted. A zero limit is not enforced.
+func WithMaxIncomingMetadata(count, size int) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.maxMetadataHeaders = count
+		serveMux.maxMetadataSize = size
+	}
+}
+
+// WithErrorObserver returns a ServeMuxOption which makes DefaultHTTPError and DefaultHTTPProtoErrorHandler
+// call "fn" before writing each error response, e.g. to count errors by code and status.
+func WithErrorObserver(fn ErrorObserverFunc) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.errorObserver = fn
+	}
+}
+
+// ErrorMessageSanitizerFunc returns the message sent to the client for an error of "code" whose message is "msg".
+type ErrorMessageSanitizerFunc func(ctx context.Context, code codes.Code, msg string) string
+
+// WithErrorMessageSanitizer returns a ServeMuxOption which makes the error handlers of this package, as well as
+// the errors terminating response streams, send the messages of errors as rewritten by "fn", e.g. to replace
+// the messages of codes.Internal errors, which may leak implementation details, with a generic one.
+// The function given to WithErrorObserver still receives the original error, so that the real message can be logged.
+func WithErrorMessageSanitizer(fn ErrorMessageSanitizerFunc) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.errorMessageSanitizer = fn
+	}
+}
+
+// WithEmptyResponseOnNotFound returns a ServeMuxOption which makes the gateway reply to requests
+// matching "meth" and "pat" with http.StatusOK and the empty message returned by "newResponse"
+// instead of an error when the backend returns codes.NotFound, e.g. for list methods whose
+// clients expect an empty collection. Use a Marshaler emitting default values, such as
+// JSONPb with EmitDefaults, to render empty repeated fields as empty arrays.
+//
+// It applies to the errors handled by DefaultHTTPError and DefaultHTTPProtoErrorHandler.
+func WithEmptyResponseOnNotFound(meth string, pat Pattern, newResponse func() proto.Message) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.emptyOnNotFound = append(serveMux.emptyOnNotFound, emptyResponseRoute{meth: meth, pat: pat, newResponse: newResponse})
+	}
+}
+
+// WithStrictTransport returns a ServeMuxOption which makes ServeMux reject requests with
+// http.StatusBadRequest if they declare a transfer encoding other than "chunked" or "identity".
+// This protects backends from request smuggling through ambiguous message framing.
+func WithStrictTransport() ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.strictTransport = true
+	}
+}
+
+// WithLinkHeaderMetadata returns a ServeMuxOption which forwards the values of the gRPC header
+// metadata "key" as "Link" response headers, e.g. "</style.css>; rel=preload" to let browsers
+// preload assets. Each value is added as a separate "Link" header.
+func WithLinkHeaderMetadata(key string) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.linkHeaderMetadataKey = strings.ToLower(key)
+	}
+}
+
 // NewServeMux returns a new ServeMux whose internal mapping is empty.
 func NewServeMux(opts ...ServeMuxOption) *ServeMux {
 	serveMux := &ServeMux{
@@ -142,13 +581,90 @@ func NewServeMux(opts ...ServeMuxOption) *ServeMux {
 // Handle associates "h" to the pair of HTTP method and path pattern.
 func (s *ServeMux) Handle(meth string, pat Pattern, h HandlerFunc) {
 	s.handlers[meth] = append(s.handlers[meth], handler{pat: pat, h: h})
+	if s.routeIndexes != nil {
+		idx, ok := s.routeIndexes[meth]
+		if !ok {
+			idx = new(routeIndex)
+			s.routeIndexes[meth] = idx
+		}
+		idx.add(handler{pat: pat, h: h})
+	}
+}
+
+// HandlePath associates "h" to the pair of HTTP method and the path template "pathPattern",
+// e.g. "/v1/{name=messages/*}:get", for routes which are not generated by protoc-gen-grpc-gateway.
+// "pathPattern" follows the syntax of the path templates of google.api.http annotations,
+// including variables, wildcards and verbs. It returns an error if "pathPattern" is malformed.
+func (s *ServeMux) HandlePath(meth string, pathPattern string, h HandlerFunc) error {
+	compiler, err := httprule.Parse(pathPattern)
+	if err != nil {
+		return fmt.Errorf("parsing path pattern: %v", err)
+	}
+	tp := compiler.Compile()
+	pat, err := NewPattern(tp.Version, tp.OpCodes, tp.Pool, tp.Verb)
+	if err != nil {
+		return fmt.Errorf("creating new pattern: %v", err)
+	}
+	s.Handle(meth, pat, h)
+	return nil
 }
 
 // ServeHTTP dispatches the request to the first handler whose pattern matches to r.Method and r.Path.
 func (s *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	path := r.URL.Path
+	if s.compression != nil && acceptsGzip(r) {
+		cw := newCompressWriter(w, s.compression, s.gzipLevel(r))
+		defer cw.finish()
+		w = cw
+	}
+
+	if s.isDisallowedMethod(r) {
+		if s.protoErrorHandler != nil {
+			_, outboundMarshaler := MarshalerForRequest(s, r)
+			sterr := status.Error(codes.Unimplemented, http.StatusText(http.StatusMethodNotAllowed))
+			s.protoErrorHandler(ctx, s, outboundMarshaler, w, r, sterr)
+		} else {
+			OtherErrorHandler(w, r, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	if s.concurrencyLimit != nil {
+		select {
+		case s.concurrencyLimit <- struct{}{}:
+			defer func() { <-s.concurrencyLimit }()
+		default:
+			w.Header().Set("Retry-After", retryAfterOverloaded)
+			if s.protoErrorHandler != nil {
+				_, outboundMarshaler := MarshalerForRequest(s, r)
+				sterr := status.Error(codes.Unavailable, "too many concurrent requests")
+				s.protoErrorHandler(ctx, s, outboundMarshaler, w, r, sterr)
+			} else {
+				OtherErrorHandler(w, r, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			}
+			return
+		}
+	}
+
+	path, ok := s.routePath(r.URL.Path)
+	if !ok {
+		if s.serveFallback(w, r) {
+			return
+		}
+		if s.protoErrorHandler != nil {
+			_, outboundMarshaler := MarshalerForRequest(s, r)
+			sterr := status.Error(codes.Unimplemented, http.StatusText(http.StatusNotImplemented))
+			s.protoErrorHandler(ctx, s, outboundMarshaler, w, r, sterr)
+		} else {
+			OtherErrorHandler(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		}
+		return
+	}
+	if spec, ok := s.openAPIEndpoints[path]; ok && (r.Method == "GET" || r.Method == "HEAD") {
+		s.serveSwaggerSpec(w, r, spec, s.pathPrefix)
+		return
+	}
 	if !strings.HasPrefix(path, "/") {
 		if s.protoErrorHandler != nil {
 			_, outboundMarshaler := MarshalerForRequest(s, r)
@@ -160,10 +676,24 @@ func (s *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if s.strictTransport && !isValidTransferEncoding(r) {
+		if s.protoErrorHandler != nil {
+			_, outboundMarshaler := MarshalerForRequest(s, r)
+			sterr := status.Error(codes.InvalidArgument, "unsupported transfer encoding")
+			s.protoErrorHandler(ctx, s, outboundMarshaler, w, r, sterr)
+		} else {
+			OtherErrorHandler(w, r, "unsupported transfer encoding", http.StatusBadRequest)
+		}
+		return
+	}
+
 	components := strings.Split(path[1:], "/")
 	l := len(components)
 	var verb string
 	if idx := strings.LastIndex(components[l-1], ":"); idx == 0 {
+		if s.serveFallback(w, r) {
+			return
+		}
 		if s.protoErrorHandler != nil {
 			_, outboundMarshaler := MarshalerForRequest(s, r)
 			sterr := status.Error(codes.Unimplemented, http.StatusText(http.StatusNotImplemented))
@@ -190,26 +720,26 @@ func (s *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-	for _, h := range s.handlers[r.Method] {
-		pathParams, err := h.pat.Match(components, verb)
-		if err != nil {
-			continue
-		}
-		h.h(w, r, pathParams)
+	if h, pathParams, ok := s.match(r.Method, components, verb); ok {
+		s.serve(h, r.Method, w, r, pathParams)
 		return
 	}
+	if r.Method == "HEAD" && s.headForGet {
+		if h, pathParams, ok := s.match("GET", components, verb); ok {
+			hw := &headResponseWriter{ResponseWriter: w}
+			s.serve(h, "GET", hw, r, pathParams)
+			hw.finish()
+			return
+		}
+	}
 
 	// lookup other methods to handle fallback from GET to POST and
 	// to determine if it is MethodNotAllowed or NotFound.
-	for m, handlers := range s.handlers {
+	for m := range s.handlers {
 		if m == r.Method {
 			continue
 		}
-		for _, h := range handlers {
-			pathParams, err := h.pat.Match(components, verb)
-			if err != nil {
-				continue
-			}
+		if h, pathParams, ok := s.match(m, components, verb); ok {
 			// X-HTTP-Method-Override is optional. Always allow fallback to POST.
 			if isPathLengthFallback(r) {
 				if err := r.ParseForm(); err != nil {
@@ -222,7 +752,10 @@ func (s *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 					}
 					return
 				}
-				h.h(w, r, pathParams)
+				s.serve(h, m, w, r, pathParams)
+				return
+			}
+			if s.serveFallback(w, r) {
 				return
 			}
 			if s.protoErrorHandler != nil {
@@ -236,6 +769,9 @@ func (s *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if s.serveFallback(w, r) {
+		return
+	}
 	if s.protoErrorHandler != nil {
 		_, outboundMarshaler := MarshalerForRequest(s, r)
 		sterr := status.Error(codes.Unimplemented, http.StatusText(http.StatusNotImplemented))
@@ -245,15 +781,224 @@ func (s *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// serveFallback serves "r" with the handler given to WithFallbackHandler, if any, and returns whether it did.
+func (s *ServeMux) serveFallback(w http.ResponseWriter, r *http.Request) bool {
+	if s.fallbackHandler == nil {
+		return false
+	}
+	s.fallbackHandler.ServeHTTP(w, r)
+	return true
+}
+
+// serve calls the handler "h" matched for "meth", starting a span if WithTracing is given,
+// recording an AuditEvent if WithAuditLog is given, preserving unknown fields if
+// WithUnknownFieldPassthrough applies to it and deduplicating the request if WithIdempotency does.
+// The route of the request is recorded to be forwarded as metadata if WithRouteMetadata is given,
+// and the headers and Content-Type of its responses if WithRouteHeaders and WithRouteContentType are.
+// Requests without a body are rejected if WithRequiredBody applies to them.
+// The sizes of the request and response bodies are recorded if the request context was derived from WithBodySizes.
+func (s *ServeMux) serve(h handler, meth string, w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	w, r = countBodySizes(w, r)
+	if s.tracing {
+		var span Span
+		r, span = s.startSpan(r)
+		if span != nil {
+			sw := &statusResponseWriter{ResponseWriter: w}
+			w = sw.responseWriter()
+			defer func() {
+				if sw.status == 0 {
+					sw.status = http.StatusOK
+				}
+				span.End(sw.status)
+			}()
+		}
+	}
+	if s.auditLog != nil {
+		aw := &statusResponseWriter{ResponseWriter: w}
+		w = aw.responseWriter()
+		defer func() {
+			if aw.status == 0 {
+				aw.status = http.StatusOK
+			}
+			s.auditLog(AuditEvent{
+				Actor:    s.auditActor(r),
+				Action:   fmt.Sprintf("%s %s", meth, h.pat),
+				Resource: pathParams,
+				Status:   aw.status,
+			})
+		}()
+	}
+	if !s.allowEmptyPathParams {
+		if name, ok := emptyPathParam(h.pat, pathParams); ok {
+			msg := fmt.Sprintf("missing parameter %s", name)
+			if s.protoErrorHandler != nil {
+				_, outboundMarshaler := MarshalerForRequest(s, r)
+				s.protoErrorHandler(r.Context(), s, outboundMarshaler, w, r, status.Error(codes.InvalidArgument, msg))
+			} else {
+				OtherErrorHandler(w, r, msg, http.StatusBadRequest)
+			}
+			return
+		}
+	}
+	if len(s.requiredBodies) > 0 && s.requiresBody(meth, h.pat) && hasEmptyBody(r) {
+		const msg = "missing request body"
+		if s.protoErrorHandler != nil {
+			_, outboundMarshaler := MarshalerForRequest(s, r)
+			s.protoErrorHandler(r.Context(), s, outboundMarshaler, w, r, status.Error(codes.InvalidArgument, msg))
+		} else {
+			OtherErrorHandler(w, r, msg, http.StatusBadRequest)
+		}
+		return
+	}
+	if s.routeMetadata {
+		r = withRouteMetadata(r, meth, h.pat)
+	}
+	if len(s.routeHeaders) > 0 {
+		r = s.withRouteHeaders(r, meth, h.pat)
+	}
+	if len(s.routeContentTypes) > 0 {
+		r = s.withRouteContentType(r, meth, h.pat)
+	}
+	if s.isPassthrough(meth, h.pat) {
+		r = withUnknownFields(r)
+	}
+	if key := r.Header.Get(IdempotencyKeyHeader); key != "" && s.idempotency != nil && s.idempotency.methods[meth] {
+		s.serveIdempotent(h, meth, key, w, r, pathParams)
+		return
+	}
+	h.h(w, r, pathParams)
+}
+
+// emptyPathParam returns the name of a parameter bound to an empty string in "pathParams", captured with
+// "pat", if any. Parameters capturing a deep wildcard alone, e.g. "path" in "/v1/files/{path=**}", are not
+// reported since they match no segment by design.
+func emptyPathParam(pat Pattern, pathParams map[string]string) (string, bool) {
+	var (
+		names []string
+		deep  map[string]bool
+	)
+	for name, val := range pathParams {
+		if val != "" {
+			continue
+		}
+		if deep == nil {
+			deep = pat.deepWildcardVars()
+		}
+		if !deep[name] {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "", false
+	}
+	sort.Strings(names)
+	return names[0], true
+}
+
+// match returns the first handler registered for "meth" whose pattern matches "components" and "verb",
+// along with the path parameters it captures.
+func (s *ServeMux) match(meth string, components []string, verb string) (handler, map[string]string, bool) {
+	if s.routeIndexes != nil {
+		if idx, ok := s.routeIndexes[meth]; ok {
+			return idx.match(components, verb)
+		}
+		return handler{}, nil, false
+	}
+	for _, h := range s.handlers[meth] {
+		pathParams, err := h.pat.Match(components, verb)
+		if err != nil {
+			continue
+		}
+		return h, pathParams, true
+	}
+	return handler{}, nil, false
+}
+
+// routePath returns the part of "path" to match against the registered patterns,
+// or false if "path" is not below the prefix given with WithPathPrefix.
+func (s *ServeMux) routePath(path string) (string, bool) {
+	if s.pathPrefix == "" {
+		return path, true
+	}
+	if path == s.pathPrefix {
+		return "/", true
+	}
+	if !strings.HasPrefix(path, s.pathPrefix+"/") {
+		return "", false
+	}
+	return path[len(s.pathPrefix):], true
+}
+
 // GetForwardResponseOptions returns the ForwardResponseOptions associated with this ServeMux.
 func (s *ServeMux) GetForwardResponseOptions() []func(context.Context, http.ResponseWriter, proto.Message) error {
 	return s.forwardResponseOptions
 }
 
+// isDisallowedMethod reports whether "r" uses a method given to WithDisallowedMethods, either directly
+// or through the X-HTTP-Method-Override header with which it is routed.
+func (s *ServeMux) isDisallowedMethod(r *http.Request) bool {
+	if s.disallowedMethods[strings.ToUpper(r.Method)] {
+		return true
+	}
+	override := r.Header.Get("X-HTTP-Method-Override")
+	return override != "" && isPathLengthFallback(r) && s.disallowedMethods[strings.ToUpper(override)]
+}
+
 func isPathLengthFallback(r *http.Request) bool {
 	return r.Method == "POST" && r.Header.Get("Content-Type") == "application/x-www-form-urlencoded"
 }
 
+// isValidTransferEncoding reports whether the transfer encodings of "r" are only "chunked" or "identity".
+// Both the encodings parsed by net/http and any Transfer-Encoding header left in the request are checked.
+func isValidTransferEncoding(r *http.Request) bool {
+	encodings := append([]string(nil), r.TransferEncoding...)
+	for _, v := range r.Header[textproto.CanonicalMIMEHeaderKey("Transfer-Encoding")] {
+		encodings = append(encodings, strings.Split(v, ",")...)
+	}
+	for _, e := range encodings {
+		switch strings.ToLower(strings.TrimSpace(e)) {
+		case "chunked", "identity":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+type emptyResponseRoute struct {
+	meth        string
+	pat         Pattern
+	newResponse func() proto.Message
+}
+
+// emptyResponseOnNotFound returns the response to reply with instead of a codes.NotFound error to "r",
+// as configured by WithEmptyResponseOnNotFound.
+func (s *ServeMux) emptyResponseOnNotFound(r *http.Request) (proto.Message, bool) {
+	if len(s.emptyOnNotFound) == 0 || r == nil {
+		return nil, false
+	}
+	path, ok := s.routePath(r.URL.Path)
+	if !ok || !strings.HasPrefix(path, "/") {
+		return nil, false
+	}
+	components := strings.Split(path[1:], "/")
+	l := len(components)
+	var verb string
+	if idx := strings.LastIndex(components[l-1], ":"); idx > 0 {
+		c := components[l-1]
+		components[l-1], verb = c[:idx], c[idx+1:]
+	}
+	for _, route := range s.emptyOnNotFound {
+		if route.meth != r.Method {
+			continue
+		}
+		if _, err := route.pat.Match(components, verb); err == nil {
+			return route.newResponse(), true
+		}
+	}
+	return nil, false
+}
+
 type handler struct {
 	pat Pattern
 	h   HandlerFunc
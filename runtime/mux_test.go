@@ -3,12 +3,23 @@ package runtime_test
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	pb "github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/examples/sub"
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
 	"github.com/grpc-ecosystem/grpc-gateway/utilities"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 func TestMuxServeHTTP(t *testing.T) {
@@ -211,3 +222,692 @@ func TestMuxServeHTTP(t *testing.T) {
 		}
 	}
 }
+
+func TestMuxServeHTTPStrictTransport(t *testing.T) {
+	for _, spec := range []struct {
+		transferEncoding string
+		respStatus       int
+	}{
+		{respStatus: http.StatusOK},
+		{transferEncoding: "chunked", respStatus: http.StatusOK},
+		{transferEncoding: "identity", respStatus: http.StatusOK},
+		{transferEncoding: "gzip, chunked", respStatus: http.StatusBadRequest},
+		{transferEncoding: "bogus", respStatus: http.StatusBadRequest},
+	} {
+		mux := runtime.NewServeMux(runtime.WithStrictTransport())
+		pat := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0}, []string{"foo"}, ""))
+		mux.Handle("POST", pat, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+			fmt.Fprintf(w, "POST %s", pat)
+		})
+
+		r := httptest.NewRequest("POST", "http://host.example/foo", bytes.NewReader(nil))
+		if spec.transferEncoding != "" {
+			r.Header.Set("Transfer-Encoding", spec.transferEncoding)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		if got, want := w.Code, spec.respStatus; got != want {
+			t.Errorf("w.Code = %d; want %d; Transfer-Encoding=%q", got, want, spec.transferEncoding)
+		}
+	}
+}
+
+func TestMuxServeHTTPRouteIndex(t *testing.T) {
+	lit, push, pushM := int(utilities.OpLitPush), int(utilities.OpPush), int(utilities.OpPushM)
+	concat, capture := int(utilities.OpConcatN), int(utilities.OpCapture)
+	patterns := []struct {
+		method string
+		ops    []int
+		pool   []string
+		verb   string
+	}{
+		// /v1/{name=shelves/*}
+		{method: "GET", ops: []int{lit, 0, lit, 1, push, 0, concat, 2, capture, 2}, pool: []string{"v1", "shelves", "name"}},
+		// /v1/shelves/special
+		{method: "GET", ops: []int{lit, 0, lit, 1, lit, 2}, pool: []string{"v1", "shelves", "special"}},
+		// /{version}/shelves/special
+		{method: "GET", ops: []int{push, 0, concat, 1, capture, 0, lit, 1, lit, 2}, pool: []string{"version", "shelves", "special"}},
+		// /v1/shelves/{shelf}:archive
+		{method: "POST", ops: []int{lit, 0, lit, 1, push, 0, concat, 1, capture, 2}, pool: []string{"v1", "shelves", "shelf"}, verb: "archive"},
+		// /v1/shelves/{shelf}
+		{method: "POST", ops: []int{lit, 0, lit, 1, push, 0, concat, 1, capture, 2}, pool: []string{"v1", "shelves", "shelf"}},
+		// /v1/**
+		{method: "GET", ops: []int{lit, 0, pushM, 0}, pool: []string{"v1"}},
+		// /v2/files/{path=**}
+		{method: "GET", ops: []int{lit, 0, lit, 1, pushM, 0, concat, 1, capture, 2}, pool: []string{"v2", "files", "path"}},
+	}
+	newMux := func(opts ...runtime.ServeMuxOption) *runtime.ServeMux {
+		mux := runtime.NewServeMux(opts...)
+		for i, p := range patterns {
+			i := i
+			pat, err := runtime.NewPattern(1, p.ops, p.pool, p.verb)
+			if err != nil {
+				t.Fatalf("runtime.NewPattern(1, %#v, %#v, %q) failed with %v; want success", p.ops, p.pool, p.verb, err)
+			}
+			mux.Handle(p.method, pat, func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+				fmt.Fprintf(w, "%d %v", i, pathParams)
+			})
+		}
+		return mux
+	}
+	linear, indexed := newMux(), newMux(runtime.WithRouteIndex())
+
+	for _, req := range []struct {
+		method, path string
+	}{
+		{"GET", "/v1/shelves/special"},
+		{"GET", "/v1/shelves/1"},
+		{"GET", "/v2/shelves/special"},
+		{"GET", "/v1/shelves"},
+		{"GET", "/v1"},
+		{"GET", "/v2/files/a/b/c"},
+		{"GET", "/v2/files"},
+		{"GET", "/v3/foo"},
+		{"POST", "/v1/shelves/1:archive"},
+		{"POST", "/v1/shelves/1"},
+		{"POST", "/v1/shelves/special"},
+		{"DELETE", "/v1/shelves/1"},
+	} {
+		want := httptest.NewRecorder()
+		linear.ServeHTTP(want, httptest.NewRequest(req.method, "http://host.example"+req.path, nil))
+		got := httptest.NewRecorder()
+		indexed.ServeHTTP(got, httptest.NewRequest(req.method, "http://host.example"+req.path, nil))
+
+		if got.Code != want.Code || got.Body.String() != want.Body.String() {
+			t.Errorf("%s %s with route index = %d %q; want %d %q", req.method, req.path, got.Code, got.Body.String(), want.Code, want.Body.String())
+		}
+	}
+}
+
+func TestMuxServeHTTPDisallowedMethods(t *testing.T) {
+	for _, spec := range []struct {
+		method     string
+		override   string
+		respStatus int
+	}{
+		{method: "TRACE", respStatus: http.StatusMethodNotAllowed},
+		{method: "CONNECT", respStatus: http.StatusMethodNotAllowed},
+		{method: "GET", respStatus: http.StatusOK},
+		{method: "POST", override: "trace", respStatus: http.StatusMethodNotAllowed},
+		{method: "POST", override: "GET", respStatus: http.StatusOK},
+	} {
+		mux := runtime.NewServeMux(runtime.WithDisallowedMethods("TRACE", "connect"))
+		pat := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0}, []string{"foo"}, ""))
+		for _, meth := range []string{"GET", "TRACE", "CONNECT"} {
+			meth := meth
+			mux.Handle(meth, pat, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+				fmt.Fprintf(w, "%s %s", meth, pat)
+			})
+		}
+
+		r := httptest.NewRequest(spec.method, "http://host.example/foo", nil)
+		if spec.override != "" {
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			r.Header.Set("X-HTTP-Method-Override", spec.override)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		if got, want := w.Code, spec.respStatus; got != want {
+			t.Errorf("w.Code = %d; want %d; method=%q, override=%q", got, want, spec.method, spec.override)
+		}
+	}
+}
+
+func TestMuxServeHTTPPathPrefix(t *testing.T) {
+	for _, spec := range []struct {
+		prefix     string
+		reqPath    string
+		respStatus int
+	}{
+		{prefix: "/api", reqPath: "/api/foo", respStatus: http.StatusOK},
+		{prefix: "/api/", reqPath: "/api/foo", respStatus: http.StatusOK},
+		{prefix: "/api", reqPath: "/foo", respStatus: http.StatusNotFound},
+		{prefix: "/api", reqPath: "/apifoo", respStatus: http.StatusNotFound},
+		{prefix: "/api", reqPath: "/api/api/foo", respStatus: http.StatusNotFound},
+	} {
+		mux := runtime.NewServeMux(runtime.WithPathPrefix(spec.prefix))
+		pat := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0}, []string{"foo"}, ""))
+		mux.Handle("GET", pat, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+			fmt.Fprint(w, r.URL.Path)
+		})
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "http://host.example"+spec.reqPath, nil))
+
+		if got, want := w.Code, spec.respStatus; got != want {
+			t.Errorf("w.Code = %d; want %d; prefix=%q, path=%q", got, want, spec.prefix, spec.reqPath)
+		}
+		if spec.respStatus != http.StatusOK {
+			continue
+		}
+		// Handlers see the full external path.
+		if got, want := w.Body.String(), spec.reqPath; got != want {
+			t.Errorf("w.Body = %q; want %q", got, want)
+		}
+	}
+}
+
+func TestMuxServeHTTPEmptyPathParameters(t *testing.T) {
+	// /v1/users/{id}/sub
+	pat := runtime.MustPattern(runtime.NewPattern(1, []int{
+		int(utilities.OpLitPush), 0, int(utilities.OpLitPush), 1, int(utilities.OpPush), 0,
+		int(utilities.OpConcatN), 1, int(utilities.OpCapture), 2, int(utilities.OpLitPush), 3,
+	}, []string{"v1", "users", "id", "sub"}, ""))
+	for _, spec := range []struct {
+		reqPath    string
+		opts       []runtime.ServeMuxOption
+		respStatus int
+	}{
+		{reqPath: "/v1/users/1/sub", respStatus: http.StatusOK},
+		{reqPath: "/v1/users//sub", respStatus: http.StatusBadRequest},
+		{reqPath: "/v1/users//sub", opts: []runtime.ServeMuxOption{runtime.WithEmptyPathParameters()}, respStatus: http.StatusOK},
+	} {
+		mux := runtime.NewServeMux(spec.opts...)
+		mux.Handle("GET", pat, func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+			fmt.Fprintf(w, "id=%s", pathParams["id"])
+		})
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "http://host.example"+spec.reqPath, nil))
+
+		if got, want := w.Code, spec.respStatus; got != want {
+			t.Errorf("w.Code = %d; want %d; path=%q", got, want, spec.reqPath)
+		}
+		if spec.respStatus == http.StatusBadRequest && !strings.Contains(w.Body.String(), "id") {
+			t.Errorf("w.Body = %q; want the name of the missing parameter %q", w.Body.String(), "id")
+		}
+	}
+}
+
+func TestMuxServeHTTPEmptyDeepWildcard(t *testing.T) {
+	// /v1/files/{path=**}
+	pat := runtime.MustPattern(runtime.NewPattern(1, []int{
+		int(utilities.OpLitPush), 0, int(utilities.OpLitPush), 1, int(utilities.OpPushM), 0,
+		int(utilities.OpConcatN), 1, int(utilities.OpCapture), 2,
+	}, []string{"v1", "files", "path"}, ""))
+	mux := runtime.NewServeMux()
+	mux.Handle("GET", pat, func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		fmt.Fprintf(w, "path=%s", pathParams["path"])
+	})
+	for _, spec := range []struct {
+		reqPath  string
+		respBody string
+	}{
+		{reqPath: "/v1/files/a/b", respBody: "path=a/b"},
+		{reqPath: "/v1/files", respBody: "path="},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "http://host.example"+spec.reqPath, nil))
+
+		if got, want := w.Code, http.StatusOK; got != want {
+			t.Errorf("w.Code = %d; want %d; path=%q", got, want, spec.reqPath)
+			continue
+		}
+		if got, want := w.Body.String(), spec.respBody; got != want {
+			t.Errorf("w.Body = %q; want %q; path=%q", got, want, spec.reqPath)
+		}
+	}
+}
+
+func TestMuxHandlePath(t *testing.T) {
+	for _, spec := range []struct {
+		pathPattern string
+		reqPath     string
+		respStatus  int
+		respBody    string
+	}{
+		{pathPattern: "/v1/users/{id}", reqPath: "/v1/users/1", respStatus: http.StatusOK, respBody: "id=1"},
+		{pathPattern: "/v1/{name=messages/*}", reqPath: "/v1/messages/abc", respStatus: http.StatusOK, respBody: "name=messages/abc"},
+		{pathPattern: "/v1/files/{path=**}", reqPath: "/v1/files/a/b/c", respStatus: http.StatusOK, respBody: "path=a/b/c"},
+		{pathPattern: "/v1/{parent=organizations/*/locations/*}/items", reqPath: "/v1/organizations/123/locations/456/items", respStatus: http.StatusOK, respBody: "parent=organizations/123/locations/456"},
+		{pathPattern: "/v1/{parent=organizations/*/locations/*}/items", reqPath: "/v1/organizations/123/regions/456/items", respStatus: http.StatusNotFound},
+		{pathPattern: "/v1/users/{id}:activate", reqPath: "/v1/users/1:activate", respStatus: http.StatusOK, respBody: "id=1"},
+		{pathPattern: "/v1/users/{id}:activate", reqPath: "/v1/users/1", respStatus: http.StatusNotFound},
+		{pathPattern: "/v1/users/*", reqPath: "/v1/users/1/2", respStatus: http.StatusNotFound},
+	} {
+		mux := runtime.NewServeMux()
+		err := mux.HandlePath("GET", spec.pathPattern, func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+			for k, v := range pathParams {
+				fmt.Fprintf(w, "%s=%s", k, v)
+			}
+		})
+		if err != nil {
+			t.Errorf("mux.HandlePath(%q, %q, h) failed with %v; want success", "GET", spec.pathPattern, err)
+			continue
+		}
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "http://host.example"+spec.reqPath, nil))
+
+		if got, want := w.Code, spec.respStatus; got != want {
+			t.Errorf("w.Code = %d; want %d; pattern=%q, path=%q", got, want, spec.pathPattern, spec.reqPath)
+		}
+		if spec.respStatus != http.StatusOK {
+			continue
+		}
+		if got, want := w.Body.String(), spec.respBody; got != want {
+			t.Errorf("w.Body = %q; want %q; pattern=%q, path=%q", got, want, spec.pathPattern, spec.reqPath)
+		}
+	}
+
+	mux := runtime.NewServeMux()
+	for _, pathPattern := range []string{"", "v1/users", "/v1/{id"} {
+		if err := mux.HandlePath("GET", pathPattern, func(http.ResponseWriter, *http.Request, map[string]string) {}); err == nil {
+			t.Errorf("mux.HandlePath(%q, %q, h) succeeded; want an error", "GET", pathPattern)
+		}
+	}
+}
+
+func TestMuxServeHTTPMaxConcurrentRequests(t *testing.T) {
+	// WithProtoErrorHandler replaces the global error handlers.
+	defer func(httpError runtime.ProtoErrorHandlerFunc, otherErrorHandler func(http.ResponseWriter, *http.Request, string, int)) {
+		runtime.HTTPError, runtime.OtherErrorHandler = httpError, otherErrorHandler
+	}(runtime.HTTPError, runtime.OtherErrorHandler)
+	pat := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0}, []string{"foo"}, ""))
+	for _, opts := range [][]runtime.ServeMuxOption{
+		{runtime.WithMaxConcurrentRequests(1)},
+		{runtime.WithMaxConcurrentRequests(1), runtime.WithProtoErrorHandler(runtime.DefaultHTTPProtoErrorHandler)},
+	} {
+		mux := runtime.NewServeMux(opts...)
+		started, release := make(chan struct{}), make(chan struct{})
+		mux.Handle("GET", pat, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+			started <- struct{}{}
+			<-release
+		})
+
+		done := make(chan *httptest.ResponseRecorder)
+		go func() {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", "http://host.example/foo", nil))
+			done <- w
+		}()
+		<-started
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "http://host.example/foo", nil))
+		if got, want := w.Code, http.StatusServiceUnavailable; got != want {
+			t.Errorf("w.Code = %d; want %d", got, want)
+		}
+		if got := w.Header().Get("Retry-After"); got == "" {
+			t.Errorf("Retry-After is missing; want a delay")
+		}
+
+		close(release)
+		if got, want := (<-done).Code, http.StatusOK; got != want {
+			t.Errorf("w.Code of the first request = %d; want %d", got, want)
+		}
+
+		// The slot is released once the first request completes.
+		w = httptest.NewRecorder()
+		go func() { <-started }()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "http://host.example/foo", nil))
+		if got, want := w.Code, http.StatusOK; got != want {
+			t.Errorf("w.Code after release = %d; want %d", got, want)
+		}
+	}
+}
+
+func TestMuxServeHTTPHeadForGet(t *testing.T) {
+	foo := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0}, []string{"foo"}, ""))
+	bar := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0}, []string{"bar"}, ""))
+	for _, spec := range []struct {
+		opts          []runtime.ServeMuxOption
+		path          string
+		status        int
+		contentLength string
+		header        string
+	}{
+		{
+			path:   "/foo",
+			status: http.StatusMethodNotAllowed,
+		},
+		{
+			opts:          []runtime.ServeMuxOption{runtime.WithHeadForGet()},
+			path:          "/foo",
+			status:        http.StatusCreated,
+			contentLength: "11",
+			header:        "GET",
+		},
+		{
+			opts:   []runtime.ServeMuxOption{runtime.WithHeadForGet()},
+			path:   "/bar",
+			status: http.StatusOK,
+			header: "HEAD",
+		},
+	} {
+		mux := runtime.NewServeMux(spec.opts...)
+		mux.Handle("GET", foo, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+			w.Header().Set("X-Handler", "GET")
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, "hello")
+			fmt.Fprint(w, " world")
+		})
+		mux.Handle("GET", bar, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+			w.Header().Set("X-Handler", "GET")
+		})
+		mux.Handle("HEAD", bar, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+			w.Header().Set("X-Handler", "HEAD")
+		})
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("HEAD", "http://host.example"+spec.path, nil))
+
+		if got, want := w.Code, spec.status; got != want {
+			t.Errorf("w.Code = %d; want %d; path=%q", got, want, spec.path)
+		}
+		if got, want := w.Header().Get("Content-Length"), spec.contentLength; got != want {
+			t.Errorf("Content-Length = %q; want %q; path=%q", got, want, spec.path)
+		}
+		if got, want := w.Header().Get("X-Handler"), spec.header; got != want {
+			t.Errorf("X-Handler = %q; want %q; path=%q", got, want, spec.path)
+		}
+		if spec.header != "" && w.Body.Len() != 0 {
+			t.Errorf("w.Body = %q; want no body; path=%q", w.Body, spec.path)
+		}
+	}
+}
+
+func TestMuxServeHTTPBodySizes(t *testing.T) {
+	pat := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0}, []string{"foo"}, ""))
+	mux := runtime.NewServeMux()
+	mux.Handle("POST", pat, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			t.Errorf("ioutil.ReadAll(r.Body) failed with %v; want success", err)
+		}
+		fmt.Fprint(w, `{"id":"foo"}`)
+	})
+
+	r := httptest.NewRequest("POST", "http://host.example/foo", strings.NewReader(`{"id":"foo","num":1}`))
+	r = r.WithContext(runtime.WithBodySizes(r.Context()))
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+
+	if got, want := runtime.RequestBodySize(r.Context()), int64(20); got != want {
+		t.Errorf("runtime.RequestBodySize(ctx) = %d; want %d", got, want)
+	}
+	if got, want := runtime.ResponseBodySize(r.Context()), int64(12); got != want {
+		t.Errorf("runtime.ResponseBodySize(ctx) = %d; want %d", got, want)
+	}
+
+	// Without WithBodySizes, nothing is recorded.
+	r = httptest.NewRequest("POST", "http://host.example/foo", strings.NewReader(`{"id":"foo","num":1}`))
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+	if got := runtime.RequestBodySize(r.Context()); got != 0 {
+		t.Errorf("runtime.RequestBodySize(ctx) = %d; want 0", got)
+	}
+}
+
+// closeNotifyRecorder is an httptest.ResponseRecorder which implements http.CloseNotifier.
+type closeNotifyRecorder struct {
+	*httptest.ResponseRecorder
+	closed chan bool
+}
+
+func (w closeNotifyRecorder) CloseNotify() <-chan bool {
+	return w.closed
+}
+
+// blockingEchoClient is a pb.EchoServiceClient whose Echo blocks until its call is canceled.
+type blockingEchoClient struct {
+	echoServiceClient
+	canceled chan struct{}
+}
+
+func (c blockingEchoClient) Echo(ctx context.Context, _ *pb.SimpleMessage, _ ...grpc.CallOption) (*pb.SimpleMessage, error) {
+	<-ctx.Done()
+	close(c.canceled)
+	return nil, ctx.Err()
+}
+
+func TestMuxServeHTTPBodySizesClientDisconnect(t *testing.T) {
+	mux := runtime.NewServeMux()
+	client := blockingEchoClient{canceled: make(chan struct{})}
+	if err := pb.RegisterEchoServiceHandlerClient(context.Background(), mux, client); err != nil {
+		t.Fatalf("pb.RegisterEchoServiceHandlerClient(ctx, mux, client) failed with %v; want success", err)
+	}
+
+	r := httptest.NewRequest("POST", "http://host.example/v1/example/echo/foo", nil)
+	r = r.WithContext(runtime.WithBodySizes(r.Context()))
+	w := closeNotifyRecorder{ResponseRecorder: httptest.NewRecorder(), closed: make(chan bool, 1)}
+	go mux.ServeHTTP(w, r)
+	w.closed <- true
+
+	select {
+	case <-client.canceled:
+	case <-time.After(5 * time.Second):
+		t.Errorf("call was not canceled when the client disconnected")
+	}
+}
+
+func TestMuxServeHTTPRouteMetadata(t *testing.T) {
+	for _, spec := range []struct {
+		opts []runtime.ServeMuxOption
+		want map[string][]string
+	}{
+		{
+			opts: []runtime.ServeMuxOption{runtime.WithRouteMetadata()},
+			want: map[string][]string{
+				runtime.MetadataHTTPMethod:  {"GET"},
+				runtime.MetadataHTTPPattern: {"/v1/{name=messages/*}"},
+				runtime.MetadataHTTPPath:    {"/v1/messages/abc"},
+			},
+		},
+		{
+			want: map[string][]string{},
+		},
+	} {
+		mux := runtime.NewServeMux(spec.opts...)
+		var md metadata.MD
+		err := mux.HandlePath("GET", "/v1/{name=messages/*}", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+			ctx, err := runtime.AnnotateContext(r.Context(), mux, r)
+			if err != nil {
+				t.Errorf("runtime.AnnotateContext(ctx, mux, r) failed with %v; want success", err)
+				return
+			}
+			md, _ = metadata.FromOutgoingContext(ctx)
+		})
+		if err != nil {
+			t.Fatalf("mux.HandlePath(%q, %q, h) failed with %v; want success", "GET", "/v1/{name=messages/*}", err)
+		}
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "http://host.example/v1/messages/abc", nil))
+
+		got := make(map[string][]string)
+		for _, key := range []string{runtime.MetadataHTTPMethod, runtime.MetadataHTTPPattern, runtime.MetadataHTTPPath} {
+			if vals, ok := md[key]; ok {
+				got[key] = vals
+			}
+		}
+		if !reflect.DeepEqual(got, spec.want) {
+			t.Errorf("route metadata = %q; want %q", got, spec.want)
+		}
+	}
+}
+
+func TestMuxServeHTTPRequiredBody(t *testing.T) {
+	strict := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0}, []string{"strict"}, ""))
+	lenient := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0}, []string{"lenient"}, ""))
+	mux := runtime.NewServeMux(runtime.WithRequiredBody("POST", strict))
+	handler := func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("ioutil.ReadAll(r.Body) failed with %v; want success", err)
+		}
+		fmt.Fprintf(w, "body=%s", body)
+	}
+	mux.Handle("POST", strict, handler)
+	mux.Handle("POST", lenient, handler)
+
+	for _, spec := range []struct {
+		path       string
+		body       string
+		chunked    bool
+		respStatus int
+		respBody   string
+	}{
+		{path: "/strict", body: `{"id":"foo"}`, respStatus: http.StatusOK, respBody: `body={"id":"foo"}`},
+		{path: "/strict", body: `{"id":"foo"}`, chunked: true, respStatus: http.StatusOK, respBody: `body={"id":"foo"}`},
+		{path: "/strict", respStatus: http.StatusBadRequest},
+		{path: "/strict", chunked: true, respStatus: http.StatusBadRequest},
+		{path: "/lenient", respStatus: http.StatusOK, respBody: "body="},
+		{path: "/lenient", chunked: true, respStatus: http.StatusOK, respBody: "body="},
+	} {
+		r := httptest.NewRequest("POST", "http://host.example"+spec.path, strings.NewReader(spec.body))
+		if spec.chunked {
+			r.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		if got, want := w.Code, spec.respStatus; got != want {
+			t.Errorf("w.Code = %d; want %d; path=%q, body=%q, chunked=%v", got, want, spec.path, spec.body, spec.chunked)
+		}
+		if spec.respStatus != http.StatusOK {
+			continue
+		}
+		if got, want := w.Body.String(), spec.respBody; got != want {
+			t.Errorf("w.Body = %q; want %q; path=%q, body=%q, chunked=%v", got, want, spec.path, spec.body, spec.chunked)
+		}
+	}
+}
+
+// echoServiceClient is a pb.EchoServiceClient returning the requests it receives.
+type echoServiceClient struct{}
+
+func (echoServiceClient) Echo(_ context.Context, in *pb.SimpleMessage, _ ...grpc.CallOption) (*pb.SimpleMessage, error) {
+	return in, nil
+}
+
+func (echoServiceClient) EchoBody(_ context.Context, in *pb.SimpleMessage, _ ...grpc.CallOption) (*pb.SimpleMessage, error) {
+	return in, nil
+}
+
+func TestMuxServeHTTPGeneratedHandlerBody(t *testing.T) {
+	pat := runtime.MustPattern(runtime.NewPattern(1, []int{
+		int(utilities.OpLitPush), 0, int(utilities.OpLitPush), 1, int(utilities.OpLitPush), 2,
+	}, []string{"v1", "example", "echo_body"}, ""))
+	for _, spec := range []struct {
+		body       string
+		chunked    bool
+		strict     bool
+		respStatus int
+		respBody   string
+	}{
+		{body: `{"id":"foo"}`, respStatus: http.StatusOK, respBody: `{"id":"foo"}`},
+		{body: `{"id":"foo"}`, chunked: true, respStatus: http.StatusOK, respBody: `{"id":"foo"}`},
+		// Empty bodies are empty messages, unless a body is required.
+		{respStatus: http.StatusOK, respBody: `{}`},
+		{chunked: true, respStatus: http.StatusOK, respBody: `{}`},
+		{chunked: true, strict: true, respStatus: http.StatusBadRequest},
+		{body: `{"id":"foo"}`, chunked: true, strict: true, respStatus: http.StatusOK, respBody: `{"id":"foo"}`},
+		{body: `{"id":`, chunked: true, respStatus: http.StatusBadRequest},
+	} {
+		var opts []runtime.ServeMuxOption
+		if spec.strict {
+			opts = append(opts, runtime.WithRequiredBody("POST", pat))
+		}
+		mux := runtime.NewServeMux(opts...)
+		if err := pb.RegisterEchoServiceHandlerClient(context.Background(), mux, echoServiceClient{}); err != nil {
+			t.Fatalf("pb.RegisterEchoServiceHandlerClient(ctx, mux, client) failed with %v; want success", err)
+		}
+
+		r := httptest.NewRequest("POST", "http://host.example/v1/example/echo_body", strings.NewReader(spec.body))
+		if spec.chunked {
+			r.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		if got, want := w.Code, spec.respStatus; got != want {
+			t.Errorf("w.Code = %d; want %d; body=%q, chunked=%v, strict=%v", got, want, spec.body, spec.chunked, spec.strict)
+		}
+		if spec.respStatus != http.StatusOK {
+			continue
+		}
+		if got, want := w.Body.String(), spec.respBody; got != want {
+			t.Errorf("w.Body = %q; want %q; body=%q, chunked=%v, strict=%v", got, want, spec.body, spec.chunked, spec.strict)
+		}
+	}
+}
+
+// stringEchoClient is a pb.ABitOfEverythingServiceClient whose Echo returns the requests it receives.
+type stringEchoClient struct {
+	pb.ABitOfEverythingServiceClient
+}
+
+func (stringEchoClient) Echo(_ context.Context, in *sub.StringMessage, _ ...grpc.CallOption) (*sub.StringMessage, error) {
+	return in, nil
+}
+
+func TestMuxServeHTTPScalarBody(t *testing.T) {
+	mux := runtime.NewServeMux()
+	if err := pb.RegisterABitOfEverythingServiceHandlerClient(context.Background(), mux, stringEchoClient{}); err != nil {
+		t.Fatalf("pb.RegisterABitOfEverythingServiceHandlerClient(ctx, mux, client) failed with %v; want success", err)
+	}
+	// POST /v2/example/echo binds the whole body to the string field "value".
+	for _, spec := range []struct {
+		body       string
+		respStatus int
+		respBody   string
+	}{
+		{body: `"foo"`, respStatus: http.StatusOK, respBody: `{"value":"foo"}`},
+		{body: `""`, respStatus: http.StatusOK, respBody: `{"value":""}`},
+		// Empty bodies are only empty messages when the body is the whole request message.
+		{body: "", respStatus: http.StatusBadRequest},
+		{body: `{"value":"foo"}`, respStatus: http.StatusBadRequest},
+	} {
+		r := httptest.NewRequest("POST", "http://host.example/v2/example/echo", strings.NewReader(spec.body))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		if got, want := w.Code, spec.respStatus; got != want {
+			t.Errorf("w.Code = %d; want %d; body=%q", got, want, spec.body)
+		}
+		if spec.respStatus != http.StatusOK {
+			continue
+		}
+		if got, want := w.Body.String(), spec.respBody; got != want {
+			t.Errorf("w.Body = %q; want %q; body=%q", got, want, spec.body)
+		}
+	}
+}
+
+func TestMuxServeHTTPFallbackHandler(t *testing.T) {
+	pat := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0}, []string{"foo"}, ""))
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "upstream %s %s", r.Method, r.URL.Path)
+	}))
+	defer upstream.Close()
+	target, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatalf("url.Parse(%q) failed with %v; want success", upstream.URL, err)
+	}
+	mux := runtime.NewServeMux(
+		runtime.WithFallbackHandler(httputil.NewSingleHostReverseProxy(target)),
+		runtime.WithPathPrefix("/api"),
+	)
+	mux.Handle("GET", pat, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		fmt.Fprint(w, "gateway")
+	})
+
+	for _, spec := range []struct {
+		method   string
+		path     string
+		respBody string
+	}{
+		{method: "GET", path: "/api/foo", respBody: "gateway"},
+		{method: "GET", path: "/api/bar", respBody: "upstream GET /api/bar"},
+		{method: "DELETE", path: "/api/foo", respBody: "upstream DELETE /api/foo"},
+		{method: "GET", path: "/legacy", respBody: "upstream GET /legacy"},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(spec.method, "http://host.example"+spec.path, nil))
+
+		if got, want := w.Code, http.StatusOK; got != want {
+			t.Errorf("w.Code = %d; want %d; method=%q, path=%q", got, want, spec.method, spec.path)
+		}
+		if got, want := w.Body.String(), spec.respBody; got != want {
+			t.Errorf("w.Body = %q; want %q; method=%q, path=%q", got, want, spec.method, spec.path)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+)
+
+// WithUnknownFieldPassthrough returns a ServeMuxOption which preserves the fields of the requests matching
+// "meth" and "pat" which are unknown to the request message compiled into the gateway, and re-emits them
+// in the response, e.g. when the backend defines newer fields than the gateway.
+//
+// Unknown fields of requests decoded with ProtoMarshaller are preserved as unknown field bytes, which are
+// also sent to the backend. Unknown JSON keys cannot be represented in the request message, so they only
+// reach the response. Either is re-emitted in a response marshaled in the same format, unless the response
+// message knows a field of the same name or number.
+//
+// It applies to the first message decoded from the request body and to responses forwarded with
+// ForwardResponseMessage.
+func WithUnknownFieldPassthrough(meth string, pat Pattern) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.passthroughRoutes = append(serveMux.passthroughRoutes, passthroughRoute{meth: meth, pat: pat})
+	}
+}
+
+type passthroughRoute struct {
+	meth string
+	pat  Pattern
+}
+
+// isPassthrough returns whether WithUnknownFieldPassthrough was given for "meth" and "pat".
+func (s *ServeMux) isPassthrough(meth string, pat Pattern) bool {
+	for _, route := range s.passthroughRoutes {
+		if route.meth == meth && route.pat.String() == pat.String() {
+			return true
+		}
+	}
+	return false
+}
+
+type unknownFieldsKey struct{}
+
+// unknownFields holds the fields of a request message which are unknown to the gateway.
+type unknownFields struct {
+	mu       sync.Mutex
+	recorded bool
+	json     []unknownJSONField
+	proto    []unknownProtoField
+}
+
+type unknownJSONField struct {
+	key   string
+	value json.RawMessage
+}
+
+type unknownProtoField struct {
+	num int32
+	// raw is the encoded field including its tag.
+	raw []byte
+}
+
+func withUnknownFields(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), unknownFieldsKey{}, new(unknownFields)))
+}
+
+func unknownFieldsFromRequest(r *http.Request) (*unknownFields, bool) {
+	if r == nil {
+		return nil, false
+	}
+	u, ok := r.Context().Value(unknownFieldsKey{}).(*unknownFields)
+	return u, ok
+}
+
+// record records the fields of "data", the encoding of "v" by "marshaler", which are unknown to "v".
+func (u *unknownFields) record(marshaler Marshaler, data []byte, v interface{}) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.recorded {
+		return
+	}
+	u.recorded = true
+
+	known := knownFieldsOf(msg)
+	if known == nil {
+		return
+	}
+	if _, ok := marshaler.(*ProtoMarshaller); ok {
+		u.proto = unknownProtoFields(data, known)
+		return
+	}
+	u.json = unknownJSONFields(data, known)
+}
+
+// appendTo re-emits the recorded fields unknown to "resp" into "buf", the encoding of "resp" by "marshaler".
+func (u *unknownFields) appendTo(marshaler Marshaler, resp proto.Message, buf []byte) []byte {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	known := knownFieldsOf(resp)
+	if known == nil {
+		return buf
+	}
+	if _, ok := marshaler.(*ProtoMarshaller); ok {
+		for _, f := range u.proto {
+			if !known.nums[f.num] {
+				buf = append(buf, f.raw...)
+			}
+		}
+		return buf
+	}
+	return appendJSONFields(buf, u.json, known)
+}
+
+// knownFields are the JSON names and field numbers of a message type.
+type knownFields struct {
+	names map[string]bool
+	nums  map[int32]bool
+}
+
+// knownFieldsOf returns the fields of the type of "msg", or nil if its unknown fields cannot be told apart,
+// e.g. for well known types which have their own JSON representation.
+func knownFieldsOf(msg proto.Message) *knownFields {
+	if msg == nil || strings.HasPrefix(proto.MessageName(msg), "google.protobuf.") {
+		return nil
+	}
+	t := reflect.TypeOf(msg)
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	known := &knownFields{names: make(map[string]bool), nums: make(map[int32]bool)}
+	add := func(p *proto.Properties) {
+		if p.Tag == 0 {
+			return
+		}
+		known.nums[int32(p.Tag)] = true
+		known.names[p.OrigName] = true
+		known.names[p.JSONName] = true
+		known.names[jsonCamelCase(p.OrigName)] = true
+	}
+	props := proto.GetProperties(t.Elem())
+	for _, p := range props.Prop {
+		add(p)
+	}
+	for _, op := range props.OneofTypes {
+		add(op.Prop)
+	}
+	return known
+}
+
+// unknownProtoFields returns the fields of the wire-format message "data" whose numbers are not known.
+func unknownProtoFields(data []byte, known *knownFields) []unknownProtoField {
+	var fields []unknownProtoField
+	for len(data) > 0 {
+		tag, n := proto.DecodeVarint(data)
+		if n == 0 {
+			return fields
+		}
+		l := fieldLength(data[n:], tag&7)
+		if l < 0 {
+			return fields
+		}
+		if num := int32(tag >> 3); !known.nums[num] {
+			fields = append(fields, unknownProtoField{num: num, raw: data[:n+l]})
+		}
+		data = data[n+l:]
+	}
+	return fields
+}
+
+// fieldLength returns the length of the value of wire type "wireType" at the beginning of "data", or -1 if it is malformed.
+func fieldLength(data []byte, wireType uint64) int {
+	switch wireType {
+	case proto.WireVarint:
+		if _, n := proto.DecodeVarint(data); n > 0 {
+			return n
+		}
+	case proto.WireFixed64:
+		if len(data) >= 8 {
+			return 8
+		}
+	case proto.WireFixed32:
+		if len(data) >= 4 {
+			return 4
+		}
+	case proto.WireBytes:
+		l, n := proto.DecodeVarint(data)
+		if n > 0 && uint64(len(data)-n) >= l {
+			return n + int(l)
+		}
+	}
+	// Groups are deprecated and not preserved.
+	return -1
+}
+
+// unknownJSONFields returns the members of the JSON object "data" whose keys are not known.
+func unknownJSONFields(data []byte, known *knownFields) []unknownJSONField {
+	var obj map[string]json.RawMessage
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&obj); err != nil {
+		return nil
+	}
+	var fields []unknownJSONField
+	for key, value := range obj {
+		if !known.names[key] {
+			fields = append(fields, unknownJSONField{key: key, value: value})
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].key < fields[j].key })
+	return fields
+}
+
+// appendJSONFields adds "fields" to the JSON object "buf" unless it has the same keys or they are known.
+func appendJSONFields(buf []byte, fields []unknownJSONField, known *knownFields) []byte {
+	if len(fields) == 0 {
+		return buf
+	}
+	trimmed := bytes.TrimRight(buf, " \t\r\n")
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &obj); err != nil || obj == nil {
+		return buf
+	}
+	out := append([]byte(nil), trimmed[:len(trimmed)-1]...)
+	for _, f := range fields {
+		if _, ok := obj[f.key]; ok || known.names[f.key] {
+			continue
+		}
+		if len(bytes.TrimSpace(out)) > 1 {
+			out = append(out, ',')
+		}
+		key, _ := json.Marshal(f.key)
+		out = append(out, key...)
+		out = append(out, ':')
+		out = append(out, f.value...)
+		obj[f.key] = f.value
+	}
+	out = append(out, '}')
+	return append(out, buf[len(trimmed):]...)
+}
+
+// unknownFieldsMarshaler is a Marshaler which records the unknown fields of the decoded messages.
+type unknownFieldsMarshaler struct {
+	Marshaler
+	fields *unknownFields
+}
+
+func (m *unknownFieldsMarshaler) Unmarshal(data []byte, v interface{}) error {
+	if err := m.Marshaler.Unmarshal(data, v); err != nil {
+		return err
+	}
+	m.fields.record(m.Marshaler, data, v)
+	return nil
+}
+
+func (m *unknownFieldsMarshaler) NewDecoder(r io.Reader) Decoder {
+	tee := &firstMessageReader{r: r, buf: new(bytes.Buffer)}
+	return &unknownFieldsDecoder{dec: m.Marshaler.NewDecoder(tee), tee: tee, marshaler: m.Marshaler, fields: m.fields}
+}
+
+// unknownFieldsDecoder records the unknown fields of the first message it decodes.
+type unknownFieldsDecoder struct {
+	dec       Decoder
+	tee       *firstMessageReader
+	marshaler Marshaler
+	fields    *unknownFields
+}
+
+func (d *unknownFieldsDecoder) Decode(v interface{}) error {
+	if err := d.dec.Decode(v); err != nil {
+		return err
+	}
+	if d.tee.buf != nil {
+		d.fields.record(d.marshaler, d.tee.buf.Bytes(), v)
+		// Only the first message is recorded, so the rest of the body, e.g. of a client stream, is not kept.
+		d.tee.buf = nil
+	}
+	return nil
+}
+
+// firstMessageReader copies the bytes read from r into buf as long as it is not nil.
+type firstMessageReader struct {
+	r   io.Reader
+	buf *bytes.Buffer
+}
+
+func (t *firstMessageReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if t.buf != nil {
+		t.buf.Write(p[:n])
+	}
+	return n, err
+}
@@ -0,0 +1,38 @@
+package runtime
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime/internal"
+)
+
+func TestUnknownFieldsDecoderStopsRecording(t *testing.T) {
+	fields := new(unknownFields)
+	m := &unknownFieldsMarshaler{Marshaler: &JSONPb{}, fields: fields}
+	body := `{"message":"first","extra":1}` + strings.Repeat(`{"message":"next"}`, 1000)
+	dec := m.NewDecoder(strings.NewReader(body)).(*unknownFieldsDecoder)
+
+	var count int
+	for {
+		var msg internal.StreamError
+		err := dec.Decode(&msg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("dec.Decode(&msg) failed with %v; want success", err)
+		}
+		count++
+		if dec.tee.buf != nil {
+			t.Fatalf("dec.tee.buf = %q after message %d; want nil once the first message is recorded", dec.tee.buf, count)
+		}
+	}
+	if got, want := count, 1001; got != want {
+		t.Errorf("count = %d; want %d", got, want)
+	}
+	if got, want := len(fields.json), 1; got != want || fields.json[0].key != "extra" {
+		t.Errorf("fields.json = %v; want the unknown field %q of the first message", fields.json, "extra")
+	}
+}
@@ -0,0 +1,98 @@
+package runtime_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/utilities"
+	"golang.org/x/net/context"
+)
+
+func TestUnknownFieldPassthroughJSON(t *testing.T) {
+	pat := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0}, []string{"echo"}, ""))
+	for _, spec := range []struct {
+		opts    []runtime.ServeMuxOption
+		newResp func(*pb.SimpleMessage) proto.Message
+		want    string
+	}{
+		{
+			newResp: func(req *pb.SimpleMessage) proto.Message { return req },
+			want:    `{"id":"foo","num":"1"}`,
+		},
+		{
+			opts:    []runtime.ServeMuxOption{runtime.WithUnknownFieldPassthrough("POST", pat)},
+			newResp: func(req *pb.SimpleMessage) proto.Message { return req },
+			want:    `{"id":"foo","num":"1","color":"red","uuid":"bar"}`,
+		},
+		{
+			// "uuid" is known to the response and thus not re-emitted.
+			opts:    []runtime.ServeMuxOption{runtime.WithUnknownFieldPassthrough("POST", pat)},
+			newResp: func(req *pb.SimpleMessage) proto.Message { return &pb.ABitOfEverything{} },
+			want:    `{"color":"red"}`,
+		},
+	} {
+		mux := runtime.NewServeMux(spec.opts...)
+		mux.Handle("POST", pat, func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+			inbound, outbound := runtime.MarshalerForRequest(mux, r)
+			var req pb.SimpleMessage
+			if err := inbound.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("inbound.NewDecoder(r.Body).Decode(&req) failed with %v; want success", err)
+			}
+			ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+			runtime.ForwardResponseMessage(ctx, mux, outbound, w, r, spec.newResp(&req))
+		})
+
+		body := `{"id": "foo", "color": "red", "num": 1, "uuid": "bar"}`
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", "http://host.example/echo", strings.NewReader(body)))
+
+		if got := w.Body.String(); got != spec.want {
+			t.Errorf("w.Body = %s; want %s", got, spec.want)
+		}
+	}
+}
+
+func TestUnknownFieldPassthroughProto(t *testing.T) {
+	pat := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0}, []string{"echo"}, ""))
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption("application/x-protobuf", &runtime.ProtoMarshaller{}),
+		runtime.WithUnknownFieldPassthrough("POST", pat),
+	)
+	mux.Handle("POST", pat, func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		inbound, outbound := runtime.MarshalerForRequest(mux, r)
+		var req pb.SimpleMessage
+		if err := inbound.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("inbound.NewDecoder(r.Body).Decode(&req) failed with %v; want success", err)
+		}
+		ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+		runtime.ForwardResponseMessage(ctx, mux, outbound, w, r, &pb.SimpleMessage{Id: req.Id + "!"})
+	})
+
+	known, err := proto.Marshal(&pb.SimpleMessage{Id: "foo"})
+	if err != nil {
+		t.Fatalf("proto.Marshal failed with %v; want success", err)
+	}
+	// Field 15 of type string, which SimpleMessage does not define.
+	unknown := []byte{15<<3 | proto.WireBytes, 3, 'b', 'a', 'r'}
+	r := httptest.NewRequest("POST", "http://host.example/echo", bytes.NewReader(append(known, unknown...)))
+	r.Header.Set("Content-Type", "application/x-protobuf")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	if !bytes.HasSuffix(w.Body.Bytes(), unknown) {
+		t.Errorf("w.Body = %q; want suffix %q", w.Body.Bytes(), unknown)
+	}
+	var resp pb.SimpleMessage
+	if err := proto.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("proto.Unmarshal(%q, &resp) failed with %v; want success", w.Body.Bytes(), err)
+	}
+	if got, want := resp.Id, "foo!"; got != want {
+		t.Errorf("resp.Id = %q; want %q", got, want)
+	}
+}
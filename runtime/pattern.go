@@ -195,6 +195,32 @@ func (p Pattern) Match(components []string, verb string) (map[string]string, err
 	return bindings, nil
 }
 
+// deepWildcardVars returns the names of the variables of the Pattern which capture a deep wildcard "**"
+// alone, and thus bind an empty string when it matches no segment.
+func (p Pattern) deepWildcardVars() map[string]bool {
+	vars := make(map[string]bool)
+	// deep tells for each value on the stack whether it is made of a deep wildcard alone.
+	deep := make([]bool, 0, p.stacksize)
+	for _, op := range p.ops {
+		switch op.code {
+		case utilities.OpPush, utilities.OpLitPush:
+			deep = append(deep, false)
+		case utilities.OpPushM:
+			deep = append(deep, true)
+		case utilities.OpConcatN:
+			l := len(deep) - op.operand
+			deep = append(deep[:l], op.operand == 1 && deep[l])
+		case utilities.OpCapture:
+			n := len(deep) - 1
+			if deep[n] {
+				vars[p.vars[op.operand]] = true
+			}
+			deep = deep[:n]
+		}
+	}
+	return vars
+}
+
 // Verb returns the verb part of the Pattern.
 func (p Pattern) Verb() string { return p.verb }
 
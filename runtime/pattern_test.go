@@ -477,6 +477,24 @@ func TestMatchWithBinding(t *testing.T) {
 				"oname": "obj",
 			},
 		},
+		{
+			// /v1/{parent=organizations/*/locations/*}/items
+			ops: []int{
+				int(utilities.OpLitPush), 0,
+				int(utilities.OpLitPush), 1,
+				int(utilities.OpPush), anything,
+				int(utilities.OpLitPush), 2,
+				int(utilities.OpPush), anything,
+				int(utilities.OpConcatN), 4,
+				int(utilities.OpCapture), 3,
+				int(utilities.OpLitPush), 4,
+			},
+			pool: []string{"v1", "organizations", "locations", "parent", "items"},
+			path: "v1/organizations/123/locations/456/items",
+			want: map[string]string{
+				"parent": "organizations/123/locations/456",
+			},
+		},
 	} {
 		pat, err := NewPattern(validVersion, spec.ops, spec.pool, spec.verb)
 		if err != nil {
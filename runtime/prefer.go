@@ -0,0 +1,46 @@
+package runtime
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Headers of the return preference of RFC 7240, honored with WithReturnPreference.
+const (
+	PreferHeader            = "Prefer"
+	PreferenceAppliedHeader = "Preference-Applied"
+)
+
+// WithReturnPreference returns a ServeMuxOption which makes ForwardResponseMessage honor the "return"
+// preference of the Prefer header (RFC 7240). With "return=minimal", the status code and headers are
+// written without a response body. With "return=representation", the response is written as usual.
+// Either is acknowledged with the Preference-Applied header.
+func WithReturnPreference() ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.returnPreference = true
+	}
+}
+
+// returnPreference returns the value of the "return" preference of "req" if WithReturnPreference is given.
+func returnPreference(mux *ServeMux, req *http.Request) (string, bool) {
+	if !mux.returnPreference || req == nil {
+		return "", false
+	}
+	for _, v := range req.Header[http.CanonicalHeaderKey(PreferHeader)] {
+		for _, pref := range strings.Split(v, ",") {
+			// Ignore the parameters of the preference, if any.
+			if i := strings.Index(pref, ";"); i >= 0 {
+				pref = pref[:i]
+			}
+			kv := strings.SplitN(strings.TrimSpace(pref), "=", 2)
+			if len(kv) != 2 || !strings.EqualFold(strings.TrimSpace(kv[0]), "return") {
+				continue
+			}
+			switch val := strings.ToLower(strings.Trim(strings.TrimSpace(kv[1]), `"`)); val {
+			case "minimal", "representation":
+				return val, true
+			}
+		}
+	}
+	return "", false
+}
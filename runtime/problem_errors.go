@@ -0,0 +1,97 @@
+package runtime
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/status"
+)
+
+// ProblemJSONContentType is the content type of the problem details of RFC 7807.
+const ProblemJSONContentType = "application/problem+json"
+
+type problemDetails struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Details  []json.RawMessage `json:"details,omitempty"`
+}
+
+// ProblemJSONErrorHandler returns a ProtoErrorHandlerFunc which replies with the problem details of RFC 7807
+// as ProblemJSONContentType. Register it with WithProtoErrorHandler. The "status" member is mapped by
+// HTTPStatusFromCode, "title" is the CodeName of the gRPC code, e.g. "NOT_FOUND", and "detail" is the message
+// of the status. The "type" is "about:blank". If "withInstance" is true, "instance" is the path of the request.
+//
+// The details of the status are given in the "details" extension member, marshaled as in JSONPb, e.g.
+// [{"@type":"type.googleapis.com/google.rpc.BadRequest", ...}]. Details of unknown types are left out.
+func ProblemJSONErrorHandler(withInstance bool) ProtoErrorHandlerFunc {
+	return func(ctx context.Context, mux *ServeMux, marshaler Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+		const fallback = `{"type": "about:blank", "title": "INTERNAL", "status": 500, "detail": "failed to marshal error message"}`
+
+		w.Header().Del("Trailer")
+		handleEchoedHeaders(w, mux, r)
+
+		s, ok := status.FromError(err)
+		if !ok {
+			s = status.New(codes.Unknown, err.Error())
+		}
+		if handleEmptyResponseOnNotFound(ctx, mux, marshaler, w, r, s) {
+			return
+		}
+		s = sanitizeErrorMessage(ctx, mux, s)
+
+		st := HTTPStatusFromCode(s.Code())
+		body := problemDetails{
+			Type:   "about:blank",
+			Title:  CodeName(s.Code()),
+			Status: st,
+			Detail: s.Message(),
+		}
+		if withInstance && r != nil && r.URL != nil {
+			body.Instance = r.URL.Path
+		}
+		detailMarshaler := &JSONPb{OrigName: true}
+		for _, detail := range s.Proto().GetDetails() {
+			buf, err := detailMarshaler.Marshal(detail)
+			if err != nil {
+				grpclog.Printf("Failed to marshal error detail of type %q: %v", detail.GetTypeUrl(), err)
+				continue
+			}
+			body.Details = append(body.Details, json.RawMessage(buf))
+		}
+
+		buf, merr := json.Marshal(body)
+		w.Header().Set("Content-Type", ProblemJSONContentType)
+		if merr != nil {
+			grpclog.Printf("Failed to marshal error message %v: %v", body, merr)
+			observeError(ctx, mux, err, s.Code(), http.StatusInternalServerError)
+			w.WriteHeader(http.StatusInternalServerError)
+			if _, err := io.WriteString(w, fallback); err != nil {
+				grpclog.Printf("Failed to write response: %v", err)
+			}
+			return
+		}
+
+		md, ok := ServerMetadataFromContext(ctx)
+		if !ok {
+			grpclog.Printf("Failed to extract ServerMetadata from context")
+		}
+
+		handleForwardResponseServerMetadata(w, mux, md)
+		handleForwardResponseTrailerHeader(w, mux, md)
+		handleAuthenticateChallenge(w, mux, s.Code())
+		observeError(ctx, mux, err, s.Code(), st)
+		w.WriteHeader(st)
+		if _, err := w.Write(buf); err != nil {
+			grpclog.Printf("Failed to write response: %v", err)
+		}
+
+		handleForwardResponseTrailer(w, mux, md)
+	}
+}
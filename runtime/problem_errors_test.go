@@ -0,0 +1,89 @@
+package runtime_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestProblemJSONErrorHandler(t *testing.T) {
+	ctx := context.Background()
+	req, _ := http.NewRequest("GET", "http://example.com/v1/users/42", nil)
+	s, err := status.New(codes.InvalidArgument, "bad name").WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "name", Description: "required"}},
+	})
+	if err != nil {
+		t.Fatalf("s.WithDetails(detail) failed with %v; want success", err)
+	}
+
+	for _, spec := range []struct {
+		err          error
+		withInstance bool
+		want         map[string]interface{}
+	}{
+		{
+			err:          s.Err(),
+			withInstance: true,
+			want: map[string]interface{}{
+				"type":     "about:blank",
+				"title":    "INVALID_ARGUMENT",
+				"status":   float64(http.StatusBadRequest),
+				"detail":   "bad name",
+				"instance": "/v1/users/42",
+				"details": []interface{}{
+					map[string]interface{}{
+						"@type": "type.googleapis.com/google.rpc.BadRequest",
+						"field_violations": []interface{}{
+							map[string]interface{}{"field": "name", "description": "required"},
+						},
+					},
+				},
+			},
+		},
+		{
+			err: status.Error(codes.NotFound, "no such user"),
+			want: map[string]interface{}{
+				"type":   "about:blank",
+				"title":  "NOT_FOUND",
+				"status": float64(http.StatusNotFound),
+				"detail": "no such user",
+			},
+		},
+		{
+			err: errors.New("example error"),
+			want: map[string]interface{}{
+				"type":   "about:blank",
+				"title":  "UNKNOWN",
+				"status": float64(http.StatusInternalServerError),
+				"detail": "example error",
+			},
+		},
+	} {
+		w := httptest.NewRecorder()
+		runtime.ProblemJSONErrorHandler(spec.withInstance)(ctx, runtime.NewServeMux(), &runtime.JSONPb{}, w, req, spec.err)
+
+		if got, want := w.Code, int(spec.want["status"].(float64)); got != want {
+			t.Errorf("w.Code = %d; want %d; on spec.err=%v", got, want, spec.err)
+		}
+		if got, want := w.Header().Get("Content-Type"), runtime.ProblemJSONContentType; got != want {
+			t.Errorf("Content-Type = %q; want %q; on spec.err=%v", got, want, spec.err)
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Errorf("json.Unmarshal(%q, &body) failed with %v; want success", w.Body.Bytes(), err)
+			continue
+		}
+		if !reflect.DeepEqual(body, spec.want) {
+			t.Errorf("body = %v; want %v; on spec.err=%v", body, spec.want, spec.err)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package runtime
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+)
+
+// EventStreamContentType is the Content-Type of the server-sent events written by ForwardResponseProgress.
+const EventStreamContentType = "text/event-stream"
+
+// ForwardResponseProgress forwards a server stream whose last message is the result of the call and whose
+// other messages report its progress, e.g. for long-running methods. It is opted into per method by assigning
+// it to the forwarder of a server-streaming method in the package of the generated gateway, e.g.
+//
+//	func init() {
+//		forward_ExportService_Export_0 = runtime.ForwardResponseProgress
+//	}
+//
+// Clients which accept text/event-stream receive each progress message as it arrives in a "progress" event,
+// and the result in a "result" event. An error received after the first event is sent in an "error" event.
+// The data of the events is encoded by "marshaler".
+//
+// Other clients wait for the result, which is forwarded with ForwardResponseMessage as if the method were unary.
+func ForwardResponseProgress(ctx context.Context, mux *ServeMux, marshaler Marshaler, w http.ResponseWriter, req *http.Request, recv func() (proto.Message, error), opts ...func(context.Context, http.ResponseWriter, proto.Message) error) {
+	ctx = newRequestIDContext(ctx, mux, req)
+	if m, ok := MarshalerFromContext(ctx); ok {
+		marshaler = m
+	}
+	f, ok := w.(http.Flusher)
+	if !ok || !acceptsEventStream(req) {
+		resp, err := lastMessage(recv)
+		if err != nil {
+			HTTPError(ctx, mux, marshaler, w, req, err)
+			return
+		}
+		ForwardResponseMessage(ctx, mux, marshaler, w, req, resp, opts...)
+		return
+	}
+
+	md, ok := ServerMetadataFromContext(ctx)
+	if !ok {
+		logf(ctx, "Failed to extract ServerMetadata from context")
+	}
+	// Receive one message ahead to tell the result from the progress messages.
+	resp, err := recv()
+	if err == io.EOF {
+		err = fmt.Errorf("empty response")
+	}
+	if err != nil {
+		HTTPError(ctx, mux, marshaler, w, req, err)
+		return
+	}
+
+	handleForwardResponseServerMetadata(w, mux, md)
+	handleForwardResponseRequestID(ctx, w, mux)
+	handleEchoedHeaders(w, mux, req)
+	w.Header().Set("Content-Type", EventStreamContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	f.Flush()
+
+	for {
+		next, recvErr := recv()
+		if err := handleForwardResponseOptions(ctx, w, resp, opts); err != nil {
+			writeProgressEvent(ctx, w, f, "error", marshaler, streamChunk(ctx, mux, nil, err))
+			return
+		}
+		if recvErr == io.EOF {
+			writeProgressEvent(ctx, w, f, "result", marshaler, resp)
+			return
+		}
+		if !writeProgressEvent(ctx, w, f, "progress", marshaler, resp) {
+			return
+		}
+		if recvErr != nil {
+			writeProgressEvent(ctx, w, f, "error", marshaler, streamChunk(ctx, mux, nil, recvErr))
+			return
+		}
+		resp = next
+	}
+}
+
+// lastMessage receives all the messages from "recv" and returns the last one.
+func lastMessage(recv func() (proto.Message, error)) (proto.Message, error) {
+	var last proto.Message
+	for {
+		resp, err := recv()
+		if err == io.EOF {
+			if last == nil {
+				return nil, fmt.Errorf("empty response")
+			}
+			return last, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		last = resp
+	}
+}
+
+// writeProgressEvent writes "v" encoded by "marshaler" in a server-sent event named "event" and flushes it.
+// It returns whether the event was written.
+func writeProgressEvent(ctx context.Context, w io.Writer, f http.Flusher, event string, marshaler Marshaler, v interface{}) bool {
+	buf, err := marshaler.Marshal(v)
+	if err != nil {
+		logf(ctx, "Failed to marshal %s event: %v", event, err)
+		return false
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "event: %s\n", event)
+	// Each line of the data needs its own field.
+	for _, line := range strings.Split(strings.TrimRight(string(buf), "\n"), "\n") {
+		fmt.Fprintf(&b, "data: %s\n", strings.TrimSuffix(line, "\r"))
+	}
+	b.WriteString("\n")
+	if _, err := w.Write(b.Bytes()); err != nil {
+		logf(ctx, "Failed to send %s event: %v", event, err)
+		return false
+	}
+	f.Flush()
+	return true
+}
+
+// acceptsEventStream reports whether the Accept header of "req" accepts text/event-stream.
+func acceptsEventStream(req *http.Request) bool {
+	for _, v := range req.Header[acceptHeader] {
+		for _, part := range strings.Split(v, ",") {
+			if mt, _, err := mime.ParseMediaType(strings.TrimSpace(part)); err == nil && mt == EventStreamContentType {
+				return true
+			}
+		}
+	}
+	return false
+}
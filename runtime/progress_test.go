@@ -0,0 +1,92 @@
+package runtime_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newProgressRecv(ids []string, err error) func() (proto.Message, error) {
+	return func() (proto.Message, error) {
+		if len(ids) == 0 {
+			if err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		id := ids[0]
+		ids = ids[1:]
+		return &pb.SimpleMessage{Id: id}, nil
+	}
+}
+
+func TestForwardResponseProgress(t *testing.T) {
+	for _, spec := range []struct {
+		accept     string
+		ids        []string
+		err        error
+		wantStatus int
+		wantType   string
+		wantBody   string
+	}{
+		{
+			accept:     "text/event-stream",
+			ids:        []string{"10%", "50%", "done"},
+			wantStatus: http.StatusOK,
+			wantType:   "text/event-stream",
+			wantBody: "event: progress\ndata: {\"id\":\"10%\"}\n\n" +
+				"event: progress\ndata: {\"id\":\"50%\"}\n\n" +
+				"event: result\ndata: {\"id\":\"done\"}\n\n",
+		},
+		{
+			accept:     "application/json, text/event-stream;q=0.5",
+			ids:        []string{"10%"},
+			err:        status.Error(codes.Aborted, "cancelled"),
+			wantStatus: http.StatusOK,
+			wantType:   "text/event-stream",
+			wantBody: "event: progress\ndata: {\"id\":\"10%\"}\n\n" +
+				"event: error\ndata: {\"error\":{\"grpcCode\":10,\"httpCode\":409,\"message\":\"rpc error: code = Aborted desc = cancelled\",\"httpStatus\":\"Conflict\"}}\n\n",
+		},
+		{
+			ids:        []string{"10%", "50%", "done"},
+			wantStatus: http.StatusOK,
+			wantType:   "application/json",
+			wantBody:   `{"id":"done"}`,
+		},
+		{
+			ids:        []string{"10%"},
+			err:        status.Error(codes.Aborted, "cancelled"),
+			wantStatus: http.StatusConflict,
+			wantType:   "application/json",
+		},
+	} {
+		req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+		if spec.accept != "" {
+			req.Header.Set("Accept", spec.accept)
+		}
+		ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+		w := httptest.NewRecorder()
+		runtime.ForwardResponseProgress(ctx, runtime.NewServeMux(), &runtime.JSONPb{}, w, req, newProgressRecv(spec.ids, spec.err))
+
+		if got, want := w.Code, spec.wantStatus; got != want {
+			t.Errorf("w.Code = %d; want %d; accept = %q", got, want, spec.accept)
+		}
+		if got, want := w.Header().Get("Content-Type"), spec.wantType; got != want {
+			t.Errorf("Content-Type = %q; want %q; accept = %q", got, want, spec.accept)
+		}
+		if spec.wantBody == "" {
+			continue
+		}
+		if got, want := w.Body.String(), spec.wantBody; got != want {
+			t.Errorf("w.Body = %q; want %q; accept = %q", got, want, spec.accept)
+		}
+	}
+}
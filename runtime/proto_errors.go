@@ -22,21 +22,27 @@ var _ ProtoErrorHandlerFunc = DefaultHTTPProtoErrorHandler
 // The response body returned by this function is a Status message marshaled by a Marshaler.
 //
 // Do not set this function to HTTPError variable directly, use WithProtoErrorHandler option instead.
-func DefaultHTTPProtoErrorHandler(ctx context.Context, mux *ServeMux, marshaler Marshaler, w http.ResponseWriter, _ *http.Request, err error) {
+func DefaultHTTPProtoErrorHandler(ctx context.Context, mux *ServeMux, marshaler Marshaler, w http.ResponseWriter, r *http.Request, err error) {
 	// return Internal when Marshal failed
 	const fallback = `{"code": 13, "message": "failed to marshal error message"}`
 
 	w.Header().Del("Trailer")
+	handleEchoedHeaders(w, mux, r)
 
 	s, ok := status.FromError(err)
 	if !ok {
 		s = status.New(codes.Unknown, err.Error())
 	}
+	if handleEmptyResponseOnNotFound(ctx, mux, marshaler, w, r, s) {
+		return
+	}
+	s = sanitizeErrorMessage(ctx, mux, s)
 
 	buf, merr := marshaler.Marshal(s.Proto())
 	w.Header().Set("Content-Type", marshaler.ContentType())
 	if merr != nil {
 		grpclog.Printf("Failed to marshal error message %q: %v", s.Proto(), merr)
+		observeError(ctx, mux, err, s.Code(), http.StatusInternalServerError)
 		w.WriteHeader(http.StatusInternalServerError)
 		if _, err := io.WriteString(w, fallback); err != nil {
 			grpclog.Printf("Failed to write response: %v", err)
@@ -50,12 +56,16 @@ func DefaultHTTPProtoErrorHandler(ctx context.Context, mux *ServeMux, marshaler
 	}
 
 	handleForwardResponseServerMetadata(w, mux, md)
-	handleForwardResponseTrailerHeader(w, md)
+	handleForwardResponseTrailerHeader(w, mux, md)
+	handleStatusTrailerHeader(w, mux)
+	handleAuthenticateChallenge(w, mux, s.Code())
 	st := HTTPStatusFromCode(s.Code())
+	observeError(ctx, mux, err, s.Code(), st)
 	w.WriteHeader(st)
 	if _, err := w.Write(buf); err != nil {
 		grpclog.Printf("Failed to write response: %v", err)
 	}
 
-	handleForwardResponseTrailer(w, md)
+	handleForwardResponseTrailer(w, mux, md)
+	handleStatusTrailer(w, mux, s.Code(), s.Message())
 }
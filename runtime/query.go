@@ -6,47 +6,329 @@ import (
 	"net/url"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/grpc-ecosystem/grpc-gateway/utilities"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/status"
 )
 
+// mapKeyPattern matches the query parameters of map fields such as "map_value[key]".
+var mapKeyPattern = regexp.MustCompile("^(.*)\\[(.*)\\]$")
+
 // PopulateQueryParameters populates "values" into "msg".
-// A value is ignored if its key starts with one of the elements in "filter".
+// A value is ignored if its key starts with one of the elements in "filter", whichever of the protobuf field
+// name or JSON name of each field the key uses.
+//
+// The generated handlers filter the fields bound to the path and to the body, so that each field of a request
+// is set from a single source with a fixed precedence: path parameters override the body, and query parameters
+// never override either of them. Several keys naming the same field, e.g. by its protobuf and JSON names,
+// are populated in a fixed order in which the protobuf field name comes last and wins.
 func PopulateQueryParameters(msg proto.Message, values url.Values, filter *utilities.DoubleArray) error {
+	return populateQueryParameters(msg, values, filter, queryOptions{})
+}
+
+type querySeparatorKey struct{}
+
+type queryBoolsKey struct{}
+
+// queryOptions configures how the values of query parameters are populated into fields.
+type queryOptions struct {
+	// sep splits the values of repeated fields with splitQueryValues if it is not 0.
+	sep rune
+	// bools maps the lower-cased values accepted for bool fields to the value they stand for.
+	// The values accepted by QueryBool are used if it is nil.
+	bools map[string]bool
+}
+
+// PopulateQueryParametersContext is PopulateQueryParameters for the request in "ctx". If its ServeMux was
+// configured with WithRepeatedQuerySeparator, the values of repeated fields are also split on the separator.
+// If it was configured with WithBoolQueryValues, bool fields accept the given values only.
+func PopulateQueryParametersContext(ctx context.Context, msg proto.Message, values url.Values, filter *utilities.DoubleArray) error {
+	var opts queryOptions
+	opts.sep, _ = ctx.Value(querySeparatorKey{}).(rune)
+	opts.bools, _ = ctx.Value(queryBoolsKey{}).(map[string]bool)
+	return populateQueryParameters(msg, values, filter, opts)
+}
+
+func populateQueryParameters(msg proto.Message, values url.Values, filter *utilities.DoubleArray, opts queryOptions) error {
+	var params []queryParam
 	for key, values := range values {
-		re, err := regexp.Compile("^(.*)\\[(.*)\\]$")
-		if err != nil {
-			return err
-		}
-		match := re.FindStringSubmatch(key)
+		match := mapKeyPattern.FindStringSubmatch(key)
 		if len(match) == 3 {
 			key = match[1]
 			values = append([]string{match[2]}, values...)
 		}
-		fieldPath := strings.Split(key, ".")
+		fieldPath := protoFieldPath(reflect.TypeOf(msg), strings.Split(key, "."))
 		if filter.HasCommonPrefix(fieldPath) {
 			continue
 		}
-		if err := populateFieldValueFromPath(msg, fieldPath, values); err != nil {
+		params = append(params, queryParam{key: key, fieldPath: fieldPath, values: values})
+	}
+	sort.Slice(params, func(i, j int) bool {
+		return params[i].less(params[j])
+	})
+	for _, p := range params {
+		if err := populateFieldValueFromPath(msg, p.fieldPath, p.values, opts); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// queryParam is a query parameter whose key is resolved to the protobuf field names of "fieldPath".
+type queryParam struct {
+	key       string
+	fieldPath []string
+	values    []string
+}
+
+// less orders the parameters by field, and the parameters of a field so that the one named by its protobuf
+// field names is populated last.
+func (p queryParam) less(q queryParam) bool {
+	path, qpath := strings.Join(p.fieldPath, "."), strings.Join(q.fieldPath, ".")
+	if path != qpath {
+		return path < qpath
+	}
+	if exact, qexact := p.key == path, q.key == qpath; exact != qexact {
+		return qexact
+	}
+	return p.key < q.key
+}
+
+// protoFieldPath returns "fieldPath" in the message type "t" with the JSON names of its fields replaced
+// by their protobuf field names, as used in the filters of the generated handlers. The names from the first one
+// which does not refer to a field are kept as is.
+func protoFieldPath(t reflect.Type, fieldPath []string) []string {
+	resolved := append([]string(nil), fieldPath...)
+	for i, name := range fieldPath {
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			break
+		}
+		l := lookupField(t, name)
+		switch {
+		case l.oneof != nil:
+			t = l.oneof.Type.Elem().Field(0).Type
+		case l.props != nil:
+			resolved[i] = l.props.OrigName
+			t = t.FieldByIndex(l.index).Type
+		default:
+			return resolved
+		}
+	}
+	return resolved
+}
+
+type queryDefaultsKey struct{}
+
+// PopulateQueryDefaults sets the values supplied by the functions given to WithQueryDefaultFunc
+// for the request in "ctx" into the fields of "msg" whose query parameters are absent from "values".
+// A default is ignored if its field path starts with one of the elements in "filter",
+// or if the field is already set.
+func PopulateQueryDefaults(ctx context.Context, msg proto.Message, values url.Values, filter *utilities.DoubleArray) error {
+	defaults, _ := ctx.Value(queryDefaultsKey{}).(map[string]func() interface{})
+	for fieldPathString, fn := range defaults {
+		fieldPath := strings.Split(fieldPathString, ".")
+		if filter.HasCommonPrefix(fieldPath) {
+			continue
+		}
+		if err := populateQueryDefault(msg, fieldPath, values, fn); err != nil {
+			return status.Errorf(codes.Internal, "invalid default of query parameter %s: %v", fieldPathString, err)
+		}
+	}
+	return nil
+}
+
+type strictQueryParamsKey struct{}
+
+// CheckQueryParameters returns a codes.InvalidArgument error listing the keys of "values" which do not
+// refer to a field of "msg" if the ServeMux of the request in "ctx" was configured with WithStrictQueryParameters.
+// The keys allowed by WithStrictQueryParameters are accepted. A nil "msg" means that the method binds no
+// query parameters, so that all other keys are rejected.
+func CheckQueryParameters(ctx context.Context, msg proto.Message, values url.Values) error {
+	allowed, ok := ctx.Value(strictQueryParamsKey{}).(map[string]bool)
+	if !ok {
+		return nil
+	}
+	var unknown []string
+	for key := range values {
+		if allowed[key] || (msg != nil && isQueryField(reflect.TypeOf(msg), key)) {
+			continue
+		}
+		unknown = append(unknown, key)
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return status.Errorf(codes.InvalidArgument, "unexpected query parameters: %s", strings.Join(unknown, ", "))
+}
+
+// isQueryField reports whether the query parameter "key" refers to a field of the message type "t".
+func isQueryField(t reflect.Type, key string) bool {
+	if match := mapKeyPattern.FindStringSubmatch(key); len(match) == 3 {
+		key = match[1]
+	}
+	for _, name := range strings.Split(key, ".") {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return false
+		}
+		l := lookupField(t, name)
+		switch {
+		case l.oneof != nil:
+			t = l.oneof.Type.Elem().Field(0).Type
+		case l.props != nil:
+			t = t.FieldByIndex(l.index).Type
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func populateQueryDefault(msg proto.Message, fieldPath []string, values url.Values, fn func() interface{}) error {
+	m := reflect.ValueOf(msg)
+	if m.Kind() != reflect.Ptr {
+		return fmt.Errorf("unexpected type %T: %v", msg, msg)
+	}
+
+	// Resolve the fields along the path without allocating any message yet.
+	var props []*proto.Properties
+	t := m.Type().Elem()
+	for _, fieldName := range fieldPath {
+		if t.Kind() != reflect.Struct {
+			return fmt.Errorf("non-aggregate type in the mid of path: %s", strings.Join(fieldPath, "."))
+		}
+		p := lookupProtoProperties(t, fieldName)
+		if p == nil {
+			return fmt.Errorf("field not found in %T: %s", msg, strings.Join(fieldPath, "."))
+		}
+		props = append(props, p)
+		f, _ := t.FieldByName(p.Name)
+		t = f.Type
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+	for key := range values {
+		if i := strings.Index(key, "["); i >= 0 {
+			// map parameters such as "map_value[key]"
+			key = key[:i]
+		}
+		if hasQueryFieldPrefix(strings.Split(key, "."), props) {
+			return nil
+		}
+	}
+
+	m = m.Elem()
+	for i, p := range props {
+		f := m.FieldByName(p.Name)
+		if i == len(props)-1 {
+			if !isZeroField(f) {
+				return nil
+			}
+			v := reflect.ValueOf(fn())
+			if !v.IsValid() || !v.Type().AssignableTo(f.Type()) {
+				return fmt.Errorf("%v is not a %s", v, f.Type())
+			}
+			f.Set(v)
+			return nil
+		}
+		if f.Kind() == reflect.Ptr {
+			if f.IsNil() {
+				f.Set(reflect.New(f.Type().Elem()))
+			}
+			f = f.Elem()
+		}
+		m = f
+	}
+	return nil
+}
+
+// lookupProtoProperties returns the properties of the field of the struct type "t"
+// whose protobuf field name or JSON name is "name", or nil if no such field is found.
+func lookupProtoProperties(t reflect.Type, name string) *proto.Properties {
+	for _, p := range proto.GetProperties(t).Prop {
+		if p.OrigName == name || p.JSONName == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// hasQueryFieldPrefix reports whether the query parameter "keyPath" refers to the fields "props" or to their subfields.
+func hasQueryFieldPrefix(keyPath []string, props []*proto.Properties) bool {
+	if len(keyPath) < len(props) {
+		return false
+	}
+	for i, p := range props {
+		if keyPath[i] != p.OrigName && keyPath[i] != p.JSONName {
+			return false
+		}
+	}
+	return true
+}
+
+func isZeroField(f reflect.Value) bool {
+	switch f.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		return f.IsNil()
+	}
+	return reflect.DeepEqual(f.Interface(), reflect.Zero(f.Type()).Interface())
+}
+
 // PopulateFieldFromPath sets a value in a nested Protobuf structure.
 // It instantiates missing protobuf fields as it goes.
 func PopulateFieldFromPath(msg proto.Message, fieldPathString string, value string) error {
 	fieldPath := strings.Split(fieldPathString, ".")
-	return populateFieldValueFromPath(msg, fieldPath, []string{value})
+	return populateFieldValueFromPath(msg, fieldPath, []string{value}, queryOptions{})
+}
+
+// AllocateFieldPath allocates the messages along the dot-separated "fieldPathString" in "msg", but not its last
+// field, so that the address of that field can be taken, e.g. to decode the body of a request into a nested field.
+// Messages which are already set are kept.
+func AllocateFieldPath(msg proto.Message, fieldPathString string) error {
+	m := reflect.ValueOf(msg)
+	if m.Kind() != reflect.Ptr {
+		return fmt.Errorf("unexpected type %T: %v", msg, msg)
+	}
+	m = m.Elem()
+	fieldPath := strings.Split(fieldPathString, ".")
+	for _, fieldName := range fieldPath[:len(fieldPath)-1] {
+		if m.Kind() != reflect.Struct {
+			return fmt.Errorf("non-aggregate type in the mid of path: %s", fieldPathString)
+		}
+		f, _, err := fieldByProtoName(m, fieldName)
+		if err != nil {
+			return err
+		}
+		if !f.IsValid() || f.Kind() != reflect.Ptr {
+			return fmt.Errorf("no message field %s in %s of %T", fieldName, fieldPathString, msg)
+		}
+		if f.IsNil() {
+			f.Set(reflect.New(f.Type().Elem()))
+		}
+		m = f.Elem()
+	}
+	return nil
 }
 
-func populateFieldValueFromPath(msg proto.Message, fieldPath []string, values []string) error {
+// populateFieldValueFromPath populates "values" into the field at "fieldPath" of "msg".
+// The values are converted as configured by "opts".
+func populateFieldValueFromPath(msg proto.Message, fieldPath []string, values []string, opts queryOptions) error {
 	m := reflect.ValueOf(msg)
 	if m.Kind() != reflect.Ptr {
 		return fmt.Errorf("unexpected type %T: %v", msg, msg)
@@ -83,8 +365,15 @@ func populateFieldValueFromPath(msg proto.Message, fieldPath []string, values []
 				m = f
 				break
 			}
-			return populateRepeatedField(f, values, props)
+			if opts.sep != 0 {
+				values = splitQueryValues(values, opts.sep)
+			}
+			return populateRepeatedField(f, values, props, opts)
 		case reflect.Ptr:
+			if isLast && f.Type().Elem().Kind() != reflect.Struct {
+				// A scalar field with explicit presence, e.g. a proto3 optional field.
+				return populateOptionalField(f, fieldPath, values, props, opts)
+			}
 			if f.IsNil() {
 				m = reflect.New(f.Type().Elem())
 				f.Set(m.Convert(f.Type()))
@@ -98,11 +387,15 @@ func populateFieldValueFromPath(msg proto.Message, fieldPath []string, values []
 			if !isLast {
 				return fmt.Errorf("unexpected nested field %s in %s", fieldPath[i+1], strings.Join(fieldPath[:i+1], "."))
 			}
-			return populateMapField(f, values, props)
+			return populateMapField(f, values, props, opts)
 		default:
 			return fmt.Errorf("unexpected type %s in %T", f.Type(), msg)
 		}
 	}
+	return populateFieldValue(m, fieldPath, values, props, opts)
+}
+
+func populateFieldValue(f reflect.Value, fieldPath []string, values []string, props *proto.Properties, opts queryOptions) error {
 	switch len(values) {
 	case 0:
 		return fmt.Errorf("no value of field: %s", strings.Join(fieldPath, "."))
@@ -110,37 +403,135 @@ func populateFieldValueFromPath(msg proto.Message, fieldPath []string, values []
 	default:
 		grpclog.Printf("too many field values: %s", strings.Join(fieldPath, "."))
 	}
-	return populateField(m, values[0], props)
+	return populateField(f, values[0], props, opts)
+}
+
+// populateOptionalField populates the scalar field with explicit presence "f", which must be a pointer.
+// The field is only set once its value is successfully parsed, so that it stays absent otherwise.
+func populateOptionalField(f reflect.Value, fieldPath []string, values []string, props *proto.Properties, opts queryOptions) error {
+	v := reflect.New(f.Type().Elem())
+	if err := populateFieldValue(v.Elem(), fieldPath, values, props, opts); err != nil {
+		return err
+	}
+	f.Set(v.Convert(f.Type()))
+	return nil
 }
 
 // fieldByProtoName looks up a field whose corresponding protobuf field name is "name".
 // "m" must be a struct value. It returns zero reflect.Value if no such field found.
 func fieldByProtoName(m reflect.Value, name string) (reflect.Value, *proto.Properties, error) {
-	props := proto.GetProperties(m.Type())
-
-	// look up field name in oneof map
-	if op, ok := props.OneofTypes[name]; ok {
-		v := reflect.New(op.Type.Elem())
-		field := m.Field(op.Field)
+	l := lookupField(m.Type(), name)
+	if l.oneof != nil {
+		field := m.Field(l.oneof.Field)
 		if !field.IsNil() {
-			return reflect.Value{}, nil, fmt.Errorf("field already set for %s oneof", props.Prop[op.Field].OrigName)
+			return reflect.Value{}, nil, fmt.Errorf("field already set for %s oneof", l.oneofName)
 		}
+		v := reflect.New(l.oneof.Type.Elem())
 		field.Set(v)
-		return v.Elem().Field(0), op.Prop, nil
+		return v.Elem().Field(0), l.props, nil
+	}
+	if l.props == nil {
+		return reflect.Value{}, nil, nil
 	}
+	return m.FieldByIndex(l.index), l.props, nil
+}
+
+// fieldIndexes caches the fieldIndex of each message struct type resolved by lookupField.
+// It is keyed by type only, so that its size is bounded by the message types of the binary
+// whatever the names requests look up.
+var fieldIndexes = struct {
+	sync.RWMutex
+	m map[reflect.Type]*fieldIndex
+}{m: make(map[reflect.Type]*fieldIndex)}
+
+// fieldIndex indexes the fields of a message struct type.
+type fieldIndex struct {
+	// name is the full name of the message, or "" if the type is not a message.
+	name string
+	// names maps the protobuf field names and JSON names of the fields to their fieldLookup.
+	names map[string]fieldLookup
+	// fields lists the fields in the order of the struct, followed by the members of oneofs in the order of their tags.
+	fields []fieldLookup
+}
+
+// fieldLookup is a protobuf field name resolved against a message struct type.
+// props is nil if no such field exists.
+type fieldLookup struct {
+	index []int
+	props *proto.Properties
+	// typ is the type of the values of the field.
+	typ reflect.Type
+	// oneof is set if the field is a member of a oneof, whose field is named oneofName.
+	oneof     *proto.OneofProperties
+	oneofName string
+}
 
+// value returns the value of the field in the message struct "v", or an invalid Value if the field
+// is a member of a oneof and another member is set.
+func (l fieldLookup) value(v reflect.Value) reflect.Value {
+	if l.oneof == nil {
+		return v.FieldByIndex(l.index)
+	}
+	f := v.Field(l.oneof.Field)
+	if f.IsNil() || f.Elem().Type() != l.oneof.Type {
+		return reflect.Value{}
+	}
+	return f.Elem().Elem().Field(0)
+}
+
+// lookupField resolves the field of the message struct type "t" whose protobuf field name or JSON name is "name".
+// The fields of each type are indexed once so that requests do not have to walk the properties of the message.
+func lookupField(t reflect.Type, name string) fieldLookup {
+	return messageFieldIndex(t).names[name]
+}
+
+// messageFieldIndex returns the fieldIndex of the message struct type "t", building it on first use.
+func messageFieldIndex(t reflect.Type) *fieldIndex {
+	fieldIndexes.RLock()
+	idx, ok := fieldIndexes.m[t]
+	fieldIndexes.RUnlock()
+	if ok {
+		return idx
+	}
+
+	props := proto.GetProperties(t)
+	idx = &fieldIndex{names: make(map[string]fieldLookup)}
+	if msg, ok := reflect.New(t).Interface().(proto.Message); ok {
+		idx.name = proto.MessageName(msg)
+	}
+	// Members of oneofs are looked up by their protobuf field name first, then the other fields by
+	// either of their names in the order of the struct.
+	var oneofs []fieldLookup
+	for name, op := range props.OneofTypes {
+		l := fieldLookup{props: op.Prop, typ: op.Type.Elem().Field(0).Type, oneof: op, oneofName: props.Prop[op.Field].OrigName}
+		idx.names[name] = l
+		oneofs = append(oneofs, l)
+	}
+	sort.Slice(oneofs, func(i, j int) bool { return oneofs[i].props.Tag < oneofs[j].props.Tag })
 	for _, p := range props.Prop {
-		if p.OrigName == name {
-			return m.FieldByName(p.Name), p, nil
+		f, ok := t.FieldByName(p.Name)
+		if !ok {
+			continue
 		}
-		if p.JSONName == name {
-			return m.FieldByName(p.Name), p, nil
+		l := fieldLookup{index: f.Index, props: p, typ: f.Type}
+		for _, name := range []string{p.OrigName, p.JSONName} {
+			if _, ok := idx.names[name]; !ok && name != "" {
+				idx.names[name] = l
+			}
+		}
+		if !strings.HasPrefix(p.Name, "XXX_") && f.Type.Kind() != reflect.Interface {
+			idx.fields = append(idx.fields, l)
 		}
 	}
-	return reflect.Value{}, nil, nil
+	idx.fields = append(idx.fields, oneofs...)
+
+	fieldIndexes.Lock()
+	fieldIndexes.m[t] = idx
+	fieldIndexes.Unlock()
+	return idx
 }
 
-func populateMapField(f reflect.Value, values []string, props *proto.Properties) error {
+func populateMapField(f reflect.Value, values []string, props *proto.Properties, opts queryOptions) error {
 	if len(values) != 2 {
 		return fmt.Errorf("more than one value provided for key %s in map %s", values[0], props.Name)
 	}
@@ -152,30 +543,60 @@ func populateMapField(f reflect.Value, values []string, props *proto.Properties)
 		f.Set(reflect.MakeMap(f.Type()))
 	}
 
-	keyConv, ok := convFromType[keyType.Kind()]
-	if !ok {
+	if _, ok := convFromType[keyType.Kind()]; !ok {
 		return fmt.Errorf("unsupported key type %s in map %s", keyType, props.Name)
 	}
-	valueConv, ok := convFromType[valueType.Kind()]
-	if !ok {
+	if _, ok := convFromType[valueType.Kind()]; !ok {
 		return fmt.Errorf("unsupported value type %s in map %s", valueType, props.Name)
 	}
 
-	keyV := keyConv.Call([]reflect.Value{reflect.ValueOf(key)})
-	if err := keyV[1].Interface(); err != nil {
-		return err.(error)
+	keyV, err := opts.convert(keyType.Kind(), key)
+	if err != nil {
+		return err
 	}
-	valueV := valueConv.Call([]reflect.Value{reflect.ValueOf(value)})
-	if err := valueV[1].Interface(); err != nil {
-		return err.(error)
+	valueV, err := opts.convert(valueType.Kind(), value)
+	if err != nil {
+		return err
 	}
 
-	f.SetMapIndex(keyV[0].Convert(keyType), valueV[0].Convert(valueType))
+	f.SetMapIndex(keyV.Convert(keyType), valueV.Convert(valueType))
 
 	return nil
 }
 
-func populateRepeatedField(f reflect.Value, values []string, props *proto.Properties) error {
+// splitQueryValues splits each of "values" on "sep". A backslash escapes a following separator or
+// backslash, so that `a\,b,c` is split into "a,b" and "c"; other backslashes are kept as is.
+func splitQueryValues(values []string, sep rune) []string {
+	var split []string
+	for _, v := range values {
+		var elem []rune
+		escaped := false
+		for _, r := range v {
+			switch {
+			case escaped:
+				if r != sep && r != '\\' {
+					elem = append(elem, '\\')
+				}
+				elem = append(elem, r)
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == sep:
+				split = append(split, string(elem))
+				elem = elem[:0]
+			default:
+				elem = append(elem, r)
+			}
+		}
+		if escaped {
+			elem = append(elem, '\\')
+		}
+		split = append(split, string(elem))
+	}
+	return split
+}
+
+func populateRepeatedField(f reflect.Value, values []string, props *proto.Properties, opts queryOptions) error {
 	elemType := f.Type().Elem()
 
 	// is the destination field a slice of an enumeration type?
@@ -183,22 +604,21 @@ func populateRepeatedField(f reflect.Value, values []string, props *proto.Proper
 		return populateFieldEnumRepeated(f, values, enumValMap)
 	}
 
-	conv, ok := convFromType[elemType.Kind()]
-	if !ok {
+	if _, ok := convFromType[elemType.Kind()]; !ok {
 		return fmt.Errorf("unsupported field type %s", elemType)
 	}
 	f.Set(reflect.MakeSlice(f.Type(), len(values), len(values)).Convert(f.Type()))
 	for i, v := range values {
-		result := conv.Call([]reflect.Value{reflect.ValueOf(v)})
-		if err := result[1].Interface(); err != nil {
-			return err.(error)
+		result, err := opts.convert(elemType.Kind(), v)
+		if err != nil {
+			return err
 		}
-		f.Index(i).Set(result[0].Convert(f.Index(i).Type()))
+		f.Index(i).Set(result.Convert(f.Index(i).Type()))
 	}
 	return nil
 }
 
-func populateField(f reflect.Value, value string, props *proto.Properties) error {
+func populateField(f reflect.Value, value string, props *proto.Properties, opts queryOptions) error {
 	i := f.Addr().Interface()
 
 	// Handle protobuf well known types
@@ -249,13 +669,11 @@ func populateField(f reflect.Value, value string, props *proto.Properties) error
 			f.Field(0).SetUint(uint64Val)
 			return nil
 		case "BoolValue":
-			if value == "true" {
-				f.Field(0).SetBool(true)
-			} else if value == "false" {
-				f.Field(0).SetBool(false)
-			} else {
+			boolVal, err := opts.parseBool(value)
+			if err != nil {
 				return fmt.Errorf("bad BoolValue: %s", value)
 			}
+			f.Field(0).SetBool(boolVal)
 			return nil
 		case "StringValue":
 			f.Field(0).SetString(value)
@@ -289,18 +707,57 @@ func populateField(f reflect.Value, value string, props *proto.Properties) error
 		return populateFieldEnum(f, value, enumValMap)
 	}
 
-	conv, ok := convFromType[f.Kind()]
-	if !ok {
+	if _, ok := convFromType[f.Kind()]; !ok {
 		return fmt.Errorf("unsupported field type %T", f)
 	}
-	result := conv.Call([]reflect.Value{reflect.ValueOf(value)})
-	if err := result[1].Interface(); err != nil {
-		return err.(error)
+	result, err := opts.convert(f.Kind(), value)
+	if err != nil {
+		return err
 	}
-	f.Set(result[0].Convert(f.Type()))
+	f.Set(result.Convert(f.Type()))
 	return nil
 }
 
+// convert converts "value" into a value of "kind" with convFromType, except for bools which are parsed with parseBool.
+func (o queryOptions) convert(kind reflect.Kind, value string) (reflect.Value, error) {
+	if kind == reflect.Bool {
+		b, err := o.parseBool(value)
+		return reflect.ValueOf(b), err
+	}
+	result := convFromType[kind].Call([]reflect.Value{reflect.ValueOf(value)})
+	if err := result[1].Interface(); err != nil {
+		return reflect.Value{}, err.(error)
+	}
+	return result[0], nil
+}
+
+// parseBool parses "value" with the values configured in "o", or with QueryBool if there are none.
+func (o queryOptions) parseBool(value string) (bool, error) {
+	if o.bools == nil {
+		return QueryBool(value)
+	}
+	if b, ok := o.bools[strings.ToLower(value)]; ok {
+		return b, nil
+	}
+	return false, fmt.Errorf("invalid bool value %q", value)
+}
+
+// QueryBool parses the values accepted by strconv.ParseBool, as well as "yes", "on" and "y"
+// for true and "no", "off" and "n" for false in any case, e.g. as sent for HTML form checkboxes.
+// It is how bool query parameters are parsed unless WithBoolQueryValues is given.
+func QueryBool(value string) (bool, error) {
+	if b, err := strconv.ParseBool(value); err == nil {
+		return b, nil
+	}
+	switch strings.ToLower(value) {
+	case "yes", "on", "y":
+		return true, nil
+	case "no", "off", "n":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid bool value %q", value)
+}
+
 func convertEnum(value string, t reflect.Type, enumValMap map[string]int32) (reflect.Value, error) {
 	// see if it's an enumeration string
 	if enumVal, ok := enumValMap[value]; ok {
@@ -0,0 +1,30 @@
+package runtime
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime/internal"
+)
+
+func TestLookupFieldUnknownNames(t *testing.T) {
+	typ := reflect.TypeOf(internal.StreamError{})
+	for _, name := range []string{"grpc_code", "grpcCode", "message"} {
+		if l := lookupField(typ, name); l.props == nil {
+			t.Errorf("lookupField(%v, %q).props = nil; want a field", typ, name)
+		}
+	}
+	size := len(messageFieldIndex(typ).names)
+
+	// Names from requests must not be retained.
+	for i := 0; i < 100; i++ {
+		name := fmt.Sprintf("unknown_%d", i)
+		if l := lookupField(typ, name); l.props != nil {
+			t.Errorf("lookupField(%v, %q).props = %v; want nil", typ, name, l.props)
+		}
+	}
+	if got := len(messageFieldIndex(typ).names); got != size {
+		t.Errorf("len(messageFieldIndex(%v)) = %d; want %d", typ, got, size)
+	}
+}
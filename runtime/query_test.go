@@ -3,8 +3,10 @@ package runtime_test
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,7 +16,10 @@ import (
 	"github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
 	"github.com/grpc-ecosystem/grpc-gateway/utilities"
+	"golang.org/x/net/context"
 	"google.golang.org/genproto/protobuf/field_mask"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestPopulateParameters(t *testing.T) {
@@ -422,6 +427,60 @@ func TestPopulateParametersWithFilters(t *testing.T) {
 	}
 }
 
+func TestPopulateQueryParametersPrecedence(t *testing.T) {
+	// As in a generated handler whose path binds string_value and whose body binds nested.
+	filter := utilities.NewDoubleArray([][]string{{"nested"}, {"string_value"}})
+	for _, spec := range []struct {
+		name   string
+		values url.Values
+		want   proto3Message
+	}{
+		{
+			name:   "path over query",
+			values: url.Values{"string_value": {"query"}},
+			want:   proto3Message{StringValue: "path", Nested: &proto2Message{StringValue: proto.String("body")}},
+		},
+		{
+			name:   "path over query by JSON name",
+			values: url.Values{"stringValue": {"query"}},
+			want:   proto3Message{StringValue: "path", Nested: &proto2Message{StringValue: proto.String("body")}},
+		},
+		{
+			name:   "body over query",
+			values: url.Values{"nested.string_value": {"query"}},
+			want:   proto3Message{StringValue: "path", Nested: &proto2Message{StringValue: proto.String("body")}},
+		},
+		{
+			name:   "body over query by JSON name",
+			values: url.Values{"nested.stringValue": {"query"}},
+			want:   proto3Message{StringValue: "path", Nested: &proto2Message{StringValue: proto.String("body")}},
+		},
+		{
+			name:   "protobuf name over JSON name",
+			values: url.Values{"float_value": {"1.5"}, "floatValue": {"2.5"}},
+			want:   proto3Message{StringValue: "path", Nested: &proto2Message{StringValue: proto.String("body")}, FloatValue: 1.5},
+		},
+		{
+			name:   "repeated protobuf name over JSON name",
+			values: url.Values{"repeatedValue": {"b"}, "repeated_value": {"a"}},
+			want:   proto3Message{StringValue: "path", Nested: &proto2Message{StringValue: proto.String("body")}, RepeatedValue: []string{"a"}},
+		},
+	} {
+		// The results of conflicting keys must not depend on the order of the map.
+		for i := 0; i < 10; i++ {
+			msg := &proto3Message{Nested: &proto2Message{StringValue: proto.String("body")}}
+			msg.StringValue = "path"
+			if err := runtime.PopulateQueryParameters(msg, spec.values, filter); err != nil {
+				t.Fatalf("%s: runtime.PopulateQueryParameters(msg, %v, filter) failed with %v; want success", spec.name, spec.values, err)
+			}
+			if got, want := msg, &spec.want; !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: runtime.PopulateQueryParameters(msg, %v, filter) = %+v; want %+v", spec.name, spec.values, *got, *want)
+				break
+			}
+		}
+	}
+}
+
 func TestPopulateQueryParametersWithInvalidNestedParameters(t *testing.T) {
 	for _, spec := range []struct {
 		msg    proto.Message
@@ -521,6 +580,271 @@ func TestPopulateQueryParametersWithInvalidNestedParameters(t *testing.T) {
 	}
 }
 
+func TestPopulateQueryParametersOptionalPresence(t *testing.T) {
+	for _, spec := range []struct {
+		values   url.Values
+		wantInt  *int32
+		wantStr  *string
+		wantBool *bool
+	}{
+		{
+			values: url.Values{},
+		},
+		{
+			values:   url.Values{"int32_value": {"0"}, "bool_value": {"false"}},
+			wantInt:  proto.Int32(0),
+			wantBool: proto.Bool(false),
+		},
+		{
+			values:  url.Values{"int32Value": {"42"}, "string_value": {""}},
+			wantInt: proto.Int32(42),
+			wantStr: proto.String(""),
+		},
+	} {
+		msg := new(proto3OptionalMessage)
+		if err := runtime.PopulateQueryParameters(msg, spec.values, utilities.NewDoubleArray(nil)); err != nil {
+			t.Errorf("runtime.PopulateQueryParameters(msg, %v, nil) failed with %v; want success", spec.values, err)
+			continue
+		}
+		if got, want := msg.Int32Value, spec.wantInt; (got == nil) != (want == nil) || (got != nil && *got != *want) {
+			t.Errorf("msg.Int32Value = %v; want %v; values=%v", got, want, spec.values)
+		}
+		if got, want := msg.StringValue, spec.wantStr; (got == nil) != (want == nil) || (got != nil && *got != *want) {
+			t.Errorf("msg.StringValue = %v; want %v; values=%v", got, want, spec.values)
+		}
+		if got, want := msg.BoolValue, spec.wantBool; (got == nil) != (want == nil) || (got != nil && *got != *want) {
+			t.Errorf("msg.BoolValue = %v; want %v; values=%v", got, want, spec.values)
+		}
+	}
+
+	msg := new(proto3OptionalMessage)
+	values := url.Values{"int32_value": {"not a number"}}
+	if err := runtime.PopulateQueryParameters(msg, values, utilities.NewDoubleArray(nil)); err == nil {
+		t.Errorf("runtime.PopulateQueryParameters(msg, %v, nil) did not fail; want error", values)
+	}
+	if msg.Int32Value != nil {
+		t.Errorf("msg.Int32Value = %v; want nil", *msg.Int32Value)
+	}
+}
+
+func TestCheckQueryParameters(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://example.com/foo", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(%q, %q, nil) failed with %v; want success", "GET", "http://example.com/foo", err)
+	}
+	strict, err := runtime.AnnotateContext(context.Background(), runtime.NewServeMux(runtime.WithStrictQueryParameters("pretty")), req)
+	if err != nil {
+		t.Fatalf("runtime.AnnotateContext(ctx, mux, req) failed with %v; want success", err)
+	}
+
+	for _, spec := range []struct {
+		ctx     context.Context
+		msg     proto.Message
+		values  url.Values
+		wantErr string
+	}{
+		{
+			ctx:    context.Background(),
+			msg:    new(proto3Message),
+			values: url.Values{"nosuchfield": {"foo"}},
+		},
+		{
+			ctx: strict,
+			msg: new(proto3Message),
+			values: url.Values{
+				"float_value":                  {"1.5"},
+				"timestampValue":               {"2016-12-15T05:35:00Z"},
+				"nested.nested.map_value[key]": {"value"},
+				"oneof_string_value":           {"foo"},
+				"pretty":                       {"true"},
+			},
+		},
+		{
+			ctx:     strict,
+			msg:     new(proto3Message),
+			values:  url.Values{"flaot_value": {"1.5"}, "nested.nosuchfield": {"foo"}, "pretty": {"true"}},
+			wantErr: "unexpected query parameters: flaot_value, nested.nosuchfield",
+		},
+		{
+			ctx:     strict,
+			values:  url.Values{"float_value": {"1.5"}, "pretty": {"true"}},
+			wantErr: "unexpected query parameters: float_value",
+		},
+	} {
+		err := runtime.CheckQueryParameters(spec.ctx, spec.msg, spec.values)
+		if spec.wantErr == "" {
+			if err != nil {
+				t.Errorf("runtime.CheckQueryParameters(ctx, msg, %v) failed with %v; want success", spec.values, err)
+			}
+			continue
+		}
+		if s, ok := status.FromError(err); !ok || s.Code() != codes.InvalidArgument || s.Message() != spec.wantErr {
+			t.Errorf("runtime.CheckQueryParameters(ctx, msg, %v) = %v; want codes.InvalidArgument with %q", spec.values, err, spec.wantErr)
+		}
+	}
+}
+
+func TestPopulateQueryParametersContextSeparator(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://example.com/foo", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(%q, %q, nil) failed with %v; want success", "GET", "http://example.com/foo", err)
+	}
+	split, err := runtime.AnnotateContext(context.Background(), runtime.NewServeMux(runtime.WithRepeatedQuerySeparator(',')), req)
+	if err != nil {
+		t.Fatalf("runtime.AnnotateContext(ctx, mux, req) failed with %v; want success", err)
+	}
+
+	for _, spec := range []struct {
+		ctx    context.Context
+		values url.Values
+		want   proto3Message
+	}{
+		{
+			ctx:    context.Background(),
+			values: url.Values{"repeated_value": {"a,b", "c"}},
+			want:   proto3Message{RepeatedValue: []string{"a,b", "c"}},
+		},
+		{
+			ctx:    split,
+			values: url.Values{"repeated_value": {"a,b", "c"}},
+			want:   proto3Message{RepeatedValue: []string{"a", "b", "c"}},
+		},
+		{
+			ctx:    split,
+			values: url.Values{"repeated_value": {`a\,b,c\\,d\e,`}},
+			want:   proto3Message{RepeatedValue: []string{"a,b", `c\`, `d\e`, ""}},
+		},
+		{
+			ctx:    split,
+			values: url.Values{"repeated_enum": {"1,2"}, "string_value": {"a,b"}},
+			want:   proto3Message{RepeatedEnum: []EnumValue{EnumValue_Y, EnumValue_Z}, StringValue: "a,b"},
+		},
+	} {
+		msg := new(proto3Message)
+		if err := runtime.PopulateQueryParametersContext(spec.ctx, msg, spec.values, utilities.NewDoubleArray(nil)); err != nil {
+			t.Errorf("runtime.PopulateQueryParametersContext(ctx, msg, %v, nil) failed with %v; want success", spec.values, err)
+			continue
+		}
+		if got, want := msg, &spec.want; !reflect.DeepEqual(got, want) {
+			t.Errorf("runtime.PopulateQueryParametersContext(ctx, msg, %v, nil) = %v; want %v", spec.values, got, want)
+		}
+	}
+}
+
+func TestAllocateFieldPath(t *testing.T) {
+	msg := &proto3Message{Nested: &proto2Message{FloatValue: proto.Float32(1.5)}}
+	if err := runtime.AllocateFieldPath(msg, "nested.nested.repeated_value"); err != nil {
+		t.Fatalf("runtime.AllocateFieldPath(msg, %q) failed with %v; want success", "nested.nested.repeated_value", err)
+	}
+	if msg.Nested.FloatValue == nil || msg.Nested.Nested == nil {
+		t.Fatalf("msg = %v; want msg.Nested kept and msg.Nested.Nested allocated", msg)
+	}
+	// The body of a request can then be decoded into the nested field.
+	body := `["a", "b"]`
+	if err := (&runtime.JSONPb{}).NewDecoder(strings.NewReader(body)).Decode(&msg.Nested.Nested.RepeatedValue); err != nil {
+		t.Fatalf("dec.Decode(&msg.Nested.Nested.RepeatedValue) failed with %v; want success; body = %q", err, body)
+	}
+	if got, want := msg.Nested.Nested.RepeatedValue, []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("msg.Nested.Nested.RepeatedValue = %q; want %q", got, want)
+	}
+
+	for _, path := range []string{"string_value.foo", "nosuchfield.foo"} {
+		if err := runtime.AllocateFieldPath(new(proto3Message), path); err == nil {
+			t.Errorf("runtime.AllocateFieldPath(msg, %q) succeeded; want an error", path)
+		}
+	}
+}
+
+func TestPopulateQueryDefaults(t *testing.T) {
+	now := time.Date(2017, 1, 2, 3, 4, 5, 6, time.UTC)
+	nowPb, err := ptypes.TimestampProto(now)
+	if err != nil {
+		t.Fatalf("ptypes.TimestampProto(%v) failed with %v; want success", now, err)
+	}
+	mux := runtime.NewServeMux(
+		runtime.WithQueryDefaultFunc("timestamp_value", func() interface{} { return nowPb }),
+		runtime.WithQueryDefaultFunc("nested.string_value", func() interface{} { return proto.String("default") }),
+	)
+	req, err := http.NewRequest("GET", "http://example.com/foo", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(%q, %q, nil) failed with %v; want success", "GET", "http://example.com/foo", err)
+	}
+	ctx, err := runtime.AnnotateContext(context.Background(), mux, req)
+	if err != nil {
+		t.Fatalf("runtime.AnnotateContext(ctx, mux, req) failed with %v; want success", err)
+	}
+
+	for _, spec := range []struct {
+		values     url.Values
+		filter     *utilities.DoubleArray
+		wantTime   *timestamp.Timestamp
+		wantNested *proto2Message
+	}{
+		{
+			values:     url.Values{},
+			filter:     utilities.NewDoubleArray(nil),
+			wantTime:   nowPb,
+			wantNested: &proto2Message{StringValue: proto.String("default")},
+		},
+		{
+			values: url.Values{
+				"timestampValue":      {"2016-12-15T05:35:00Z"},
+				"nested.string_value": {"foo"},
+			},
+			filter: utilities.NewDoubleArray(nil),
+		},
+		{
+			values:   url.Values{},
+			filter:   utilities.NewDoubleArray([][]string{{"nested"}}),
+			wantTime: nowPb,
+		},
+	} {
+		// Parameters given in the query are left to PopulateQueryParameters.
+		msg := new(proto3Message)
+		if err := runtime.PopulateQueryDefaults(ctx, msg, spec.values, spec.filter); err != nil {
+			t.Errorf("runtime.PopulateQueryDefaults(ctx, msg, %v, %v) failed with %v; want success", spec.values, spec.filter, err)
+			continue
+		}
+		if got, want := msg.TimestampValue, spec.wantTime; !proto.Equal(got, want) {
+			t.Errorf("msg.TimestampValue = %v; want %v; values=%v", got, want, spec.values)
+		}
+		if got, want := msg.Nested, spec.wantNested; !reflect.DeepEqual(got, want) {
+			t.Errorf("msg.Nested = %v; want %v; values=%v", got, want, spec.values)
+		}
+	}
+
+	mux = runtime.NewServeMux(runtime.WithQueryDefaultFunc("timestamp_value", func() interface{} { return now }))
+	ctx, err = runtime.AnnotateContext(context.Background(), mux, req)
+	if err != nil {
+		t.Fatalf("runtime.AnnotateContext(ctx, mux, req) failed with %v; want success", err)
+	}
+	if err := runtime.PopulateQueryDefaults(ctx, new(proto3Message), url.Values{}, utilities.NewDoubleArray(nil)); err == nil {
+		t.Errorf("runtime.PopulateQueryDefaults(ctx, msg, nil, nil) did not fail with a time.Time default; want error")
+	}
+}
+
+func BenchmarkPopulateQueryParameters(b *testing.B) {
+	values := url.Values{
+		"float_value":                {"1.5"},
+		"int64_value":                {"-1"},
+		"string_value":               {"str"},
+		"repeated_value":             {"a", "b", "c"},
+		"enum_value":                 {"EnumValue_Z"},
+		"nested.nested.string_value": {"foo"},
+		"nested.int32_value":         {"42"},
+		"oneof_string_value":         {"bar"},
+	}
+	filter := utilities.NewDoubleArray(nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		msg := new(proto3Message)
+		if err := runtime.PopulateQueryParameters(msg, values, filter); err != nil {
+			b.Fatalf("runtime.PopulateQueryParameters(msg, %v, filter) failed with %v; want success", values, err)
+		}
+	}
+}
+
 type proto3Message struct {
 	Nested             *proto2Message           `protobuf:"bytes,1,opt,name=nested,json=nested" json:"nested,omitempty"`
 	NestedNonNull      proto2Message            `protobuf:"bytes,15,opt,name=nested_non_null,json=nestedNonNull" json:"nested_non_null,omitempty"`
@@ -792,3 +1116,82 @@ var EnumValue_value = map[string]int32{
 func init() {
 	proto.RegisterEnum("runtime_test_api.EnumValue", EnumValue_name, EnumValue_value)
 }
+
+// proto3OptionalMessage has scalar fields with explicit presence, like proto3 optional fields.
+type proto3OptionalMessage struct {
+	Int32Value  *int32  `protobuf:"varint,1,opt,name=int32_value,json=int32Value,proto3" json:"int32_value,omitempty"`
+	StringValue *string `protobuf:"bytes,2,opt,name=string_value,json=stringValue,proto3" json:"string_value,omitempty"`
+	BoolValue   *bool   `protobuf:"varint,3,opt,name=bool_value,json=boolValue,proto3" json:"bool_value,omitempty"`
+}
+
+func (m *proto3OptionalMessage) Reset()         { *m = proto3OptionalMessage{} }
+func (m *proto3OptionalMessage) String() string { return proto.CompactTextString(m) }
+func (*proto3OptionalMessage) ProtoMessage()    {}
+
+func TestPopulateQueryParametersBoolValues(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://example.com/foo", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(%q, %q, nil) failed with %v; want success", "GET", "http://example.com/foo", err)
+	}
+	custom, err := runtime.AnnotateContext(context.Background(), runtime.NewServeMux(runtime.WithBoolQueryValues([]string{"Ja"}, []string{"Nein"})), req)
+	if err != nil {
+		t.Fatalf("runtime.AnnotateContext(ctx, mux, req) failed with %v; want success", err)
+	}
+
+	for _, spec := range []struct {
+		ctx     context.Context
+		values  url.Values
+		want    proto3Message
+		wantErr bool
+	}{
+		{
+			ctx:    context.Background(),
+			values: url.Values{"bool_value": {"true"}},
+			want:   proto3Message{BoolValue: true},
+		},
+		{
+			ctx:    context.Background(),
+			values: url.Values{"bool_value": {"on"}, "map_value14[a]": {"Yes"}},
+			want:   proto3Message{BoolValue: true, MapValue14: map[string]bool{"a": true}},
+		},
+		{
+			ctx:    context.Background(),
+			values: url.Values{"oneof_bool_value": {"OFF"}, "map_value14[a]": {"n"}},
+			want: proto3Message{
+				OneofValue: &proto3Message_OneofBoolValue{false},
+				MapValue14: map[string]bool{"a": false},
+			},
+		},
+		{
+			ctx:     context.Background(),
+			values:  url.Values{"bool_value": {"maybe"}},
+			wantErr: true,
+		},
+		{
+			ctx:    custom,
+			values: url.Values{"bool_value": {"ja"}, "map_value14[a]": {"NEIN"}},
+			want:   proto3Message{BoolValue: true, MapValue14: map[string]bool{"a": false}},
+		},
+		{
+			ctx:     custom,
+			values:  url.Values{"bool_value": {"true"}},
+			wantErr: true,
+		},
+	} {
+		msg := new(proto3Message)
+		err := runtime.PopulateQueryParametersContext(spec.ctx, msg, spec.values, utilities.NewDoubleArray(nil))
+		if spec.wantErr {
+			if err == nil {
+				t.Errorf("runtime.PopulateQueryParametersContext(ctx, msg, %v, nil) succeeded; want failure", spec.values)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("runtime.PopulateQueryParametersContext(ctx, msg, %v, nil) failed with %v; want success", spec.values, err)
+			continue
+		}
+		if got, want := msg, &spec.want; !reflect.DeepEqual(got, want) {
+			t.Errorf("runtime.PopulateQueryParametersContext(ctx, msg, %v, nil) = %v; want %v", spec.values, got, want)
+		}
+	}
+}
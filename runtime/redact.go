@@ -0,0 +1,183 @@
+package runtime
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RedactionPathsFunc returns the paths of the fields to redact from the responses of the call in "ctx",
+// e.g. depending on the scope of the caller.
+// A path is a dot-separated list of proto field names such as "owner.email".
+type RedactionPathsFunc func(ctx context.Context) []string
+
+// RedactFields returns a forward response option which clears the fields at the paths returned by "fn"
+// from each response message before it is marshaled. Register it with WithForwardResponseOption.
+//
+// Paths traverse singular, repeated and map message fields alike, and may name a member of a oneof.
+// Paths are validated against the type of each response message when they are first applied to it: a path
+// naming a field which does not exist, e.g. because it is misspelled, or going through a non-message field
+// fails the call with codes.Internal so that the response is not sent unredacted. "fn" must thus only return
+// paths of the response messages of the calls in "ctx". Paths are resolved once per message type and cached.
+func RedactFields(fn RedactionPathsFunc) func(context.Context, http.ResponseWriter, proto.Message) error {
+	r := &fieldRedactor{plans: make(map[redactionKey]*redactionPlan)}
+	return func(ctx context.Context, _ http.ResponseWriter, msg proto.Message) error {
+		if msg == nil {
+			return nil
+		}
+		v := reflect.ValueOf(msg)
+		if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+			return nil
+		}
+		for _, path := range fn(ctx) {
+			plan := r.plan(v.Type().Elem(), path)
+			if plan.err != nil {
+				return status.Errorf(codes.Internal, "invalid redaction path %q for %s: %v", path, proto.MessageName(msg), plan.err)
+			}
+			redact(v.Elem(), plan.steps)
+		}
+		return nil
+	}
+}
+
+type fieldRedactor struct {
+	mu    sync.RWMutex
+	plans map[redactionKey]*redactionPlan
+}
+
+type redactionKey struct {
+	t    reflect.Type
+	path string
+}
+
+// redactionPlan is a path resolved against a message type.
+type redactionPlan struct {
+	steps []redactionStep
+	err   error
+}
+
+// redactionStep selects a field of a message struct.
+type redactionStep struct {
+	// index is the index of the field in the struct.
+	index int
+	// oneof is the wrapper type the field is held in if it is a member of a oneof.
+	oneof reflect.Type
+}
+
+func (r *fieldRedactor) plan(t reflect.Type, path string) *redactionPlan {
+	key := redactionKey{t: t, path: path}
+	r.mu.RLock()
+	plan, ok := r.plans[key]
+	r.mu.RUnlock()
+	if ok {
+		return plan
+	}
+
+	steps, err := resolveRedactionPath(t, strings.Split(path, "."))
+	plan = &redactionPlan{steps: steps, err: err}
+	r.mu.Lock()
+	r.plans[key] = plan
+	r.mu.Unlock()
+	return plan
+}
+
+// resolveRedactionPath resolves "fieldPath" against the message struct type "t".
+func resolveRedactionPath(t reflect.Type, fieldPath []string) ([]redactionStep, error) {
+	var steps []redactionStep
+	for i, name := range fieldPath {
+		if name == "" {
+			return nil, fmt.Errorf("empty field name")
+		}
+		step, ft, ok := lookupRedactionField(t, name)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a field of %s", strings.Join(fieldPath[:i+1], "."), redactionTypeName(t))
+		}
+		steps = append(steps, step)
+		if i == len(fieldPath)-1 {
+			break
+		}
+
+		switch ft.Kind() {
+		case reflect.Slice, reflect.Map:
+			ft = ft.Elem()
+		}
+		if ft.Kind() != reflect.Ptr || ft.Elem().Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%s is not a message field", strings.Join(fieldPath[:i+1], "."))
+		}
+		t = ft.Elem()
+	}
+	return steps, nil
+}
+
+// redactionTypeName returns the full name of the message struct type "t".
+func redactionTypeName(t reflect.Type) string {
+	if msg, ok := reflect.New(t).Interface().(proto.Message); ok {
+		return proto.MessageName(msg)
+	}
+	return t.String()
+}
+
+// lookupRedactionField looks up the field with the proto name "name" in "t" and returns the type of its value.
+func lookupRedactionField(t reflect.Type, name string) (redactionStep, reflect.Type, bool) {
+	props := proto.GetProperties(t)
+	for _, p := range props.Prop {
+		if p.OrigName != name || p.OrigName == "" {
+			continue
+		}
+		f, ok := t.FieldByName(p.Name)
+		if !ok || f.Type.Kind() == reflect.Interface {
+			break
+		}
+		return redactionStep{index: f.Index[0]}, f.Type, true
+	}
+	if op, ok := props.OneofTypes[name]; ok {
+		return redactionStep{index: op.Field, oneof: op.Type}, op.Type.Elem().Field(0).Type, true
+	}
+	return redactionStep{}, nil, false
+}
+
+// redact clears the field at the end of "steps" in the message struct "v".
+func redact(v reflect.Value, steps []redactionStep) {
+	step := steps[0]
+	f := v.Field(step.index)
+	if step.oneof != nil {
+		if f.IsNil() || f.Elem().Type() != step.oneof {
+			return
+		}
+		if len(steps) == 1 {
+			f.Set(reflect.Zero(f.Type()))
+			return
+		}
+		f = f.Elem().Elem().Field(0)
+	}
+	if len(steps) == 1 {
+		f.Set(reflect.Zero(f.Type()))
+		return
+	}
+
+	switch f.Kind() {
+	case reflect.Ptr:
+		if !f.IsNil() {
+			redact(f.Elem(), steps[1:])
+		}
+	case reflect.Slice:
+		for i := 0; i < f.Len(); i++ {
+			if e := f.Index(i); !e.IsNil() {
+				redact(e.Elem(), steps[1:])
+			}
+		}
+	case reflect.Map:
+		for _, k := range f.MapKeys() {
+			if e := f.MapIndex(k); !e.IsNil() {
+				redact(e.Elem(), steps[1:])
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package runtime_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type scopeKey struct{}
+
+func redactionPathsForScope(ctx context.Context) []string {
+	if scope, _ := ctx.Value(scopeKey{}).(string); scope == "external" {
+		return []string{"uuid", "single_nested.name", "nested.amount", "mapped_nested_value.name", "oneof_string"}
+	}
+	return nil
+}
+
+func newRedactionTestMessage() *pb.ABitOfEverything {
+	return &pb.ABitOfEverything{
+		Uuid:         "6EC2446F-7E89-4127-B3E6-5C05E6BECBA7",
+		SingleNested: &pb.ABitOfEverything_Nested{Name: "foo", Amount: 10},
+		Nested: []*pb.ABitOfEverything_Nested{
+			{Name: "bar", Amount: 20},
+			{Name: "baz", Amount: 30},
+		},
+		MappedNestedValue: map[string]*pb.ABitOfEverything_Nested{
+			"a": {Name: "qux", Amount: 40},
+		},
+		OneofValue:  &pb.ABitOfEverything_OneofString{OneofString: "secret"},
+		StringValue: "public",
+	}
+}
+
+func TestRedactFields(t *testing.T) {
+	opt := runtime.RedactFields(redactionPathsForScope)
+
+	msg := newRedactionTestMessage()
+	if err := opt(context.Background(), httptest.NewRecorder(), msg); err != nil {
+		t.Fatalf("opt(ctx, w, %v) failed with %v; want success", msg, err)
+	}
+	if got, want := msg, newRedactionTestMessage(); !proto.Equal(got, want) {
+		t.Errorf("msg = %v; want %v", got, want)
+	}
+
+	ctx := context.WithValue(context.Background(), scopeKey{}, "external")
+	for i := 0; i < 2; i++ {
+		msg := newRedactionTestMessage()
+		if err := opt(ctx, httptest.NewRecorder(), msg); err != nil {
+			t.Fatalf("opt(ctx, w, %v) failed with %v; want success", msg, err)
+		}
+		want := &pb.ABitOfEverything{
+			SingleNested: &pb.ABitOfEverything_Nested{Amount: 10},
+			Nested: []*pb.ABitOfEverything_Nested{
+				{Name: "bar"},
+				{Name: "baz"},
+			},
+			MappedNestedValue: map[string]*pb.ABitOfEverything_Nested{
+				"a": {Amount: 40},
+			},
+			StringValue: "public",
+		}
+		if !proto.Equal(msg, want) {
+			t.Errorf("msg = %v; want %v", msg, want)
+		}
+	}
+
+	if err := opt(ctx, httptest.NewRecorder(), nil); err != nil {
+		t.Errorf("opt(ctx, w, nil) failed with %v; want success", err)
+	}
+}
+
+func TestRedactFieldsInvalidPath(t *testing.T) {
+	opt := runtime.RedactFields(func(context.Context) []string {
+		return []string{"uuid.foo"}
+	})
+	msg := newRedactionTestMessage()
+	err := opt(context.Background(), httptest.NewRecorder(), msg)
+	if err == nil {
+		t.Fatalf("opt(ctx, w, %v) succeeded; want failure", msg)
+	}
+	if s, _ := status.FromError(err); s.Code() != codes.Internal {
+		t.Errorf("opt(ctx, w, %v) failed with %v; want code %v", msg, err, codes.Internal)
+	}
+}
+
+func TestRedactFieldsUnknownField(t *testing.T) {
+	for _, spec := range []struct {
+		path string
+		msg  proto.Message
+	}{
+		{path: "single_nested.nmae", msg: newRedactionTestMessage()},
+		{path: "no_such_field", msg: newRedactionTestMessage()},
+		// The paths must exist in the type of the response.
+		{path: "uuid", msg: &pb.SimpleMessage{Id: "foo"}},
+	} {
+		opt := runtime.RedactFields(func(context.Context) []string {
+			return []string{spec.path}
+		})
+		err := opt(context.Background(), httptest.NewRecorder(), spec.msg)
+		if err == nil {
+			t.Errorf("opt(ctx, w, %v) with path %q succeeded; want failure", spec.msg, spec.path)
+			continue
+		}
+		if s, _ := status.FromError(err); s.Code() != codes.Internal || !strings.Contains(s.Message(), "is not a field of") {
+			t.Errorf("opt(ctx, w, %v) with path %q failed with %v; want code %v for an unknown field", spec.msg, spec.path, err, codes.Internal)
+		}
+	}
+}
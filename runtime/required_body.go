@@ -0,0 +1,61 @@
+package runtime
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// WithRequiredBody returns a ServeMuxOption which rejects the requests matching "meth" and "pat" whose body is
+// empty with codes.InvalidArgument, for methods which must not be called with a default request message.
+//
+// By default, the generated handlers of methods whose body is the whole request message, i.e. body: "*", decode
+// a request without a body, e.g. one whose fields are all bound from the path and the query, as an empty request
+// message whose fields are then populated from the path and query parameters: the io.EOF returned by the first
+// Decode of an empty body is not an error. Empty bodies mapped to a field of the request message are always
+// rejected. A body is empty if it has no bytes, whether the request declares a zero Content-Length or is chunked.
+func WithRequiredBody(meth string, pat Pattern) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.requiredBodies = append(serveMux.requiredBodies, requiredBodyRoute{meth: meth, pat: pat})
+	}
+}
+
+type requiredBodyRoute struct {
+	meth string
+	pat  Pattern
+}
+
+// requiresBody returns whether WithRequiredBody was given for "meth" and "pat".
+func (s *ServeMux) requiresBody(meth string, pat Pattern) bool {
+	for _, route := range s.requiredBodies {
+		if route.meth == meth && route.pat.String() == pat.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// hasEmptyBody reports whether the body of "r" has no bytes. The body of "r" is replaced so that
+// a byte read to tell is still read by the handler.
+func hasEmptyBody(r *http.Request) bool {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return true
+	}
+	if r.ContentLength > 0 {
+		return false
+	}
+	var b [1]byte
+	n, err := io.ReadFull(r.Body, b[:])
+	if n == 0 && err == io.EOF {
+		return true
+	}
+	body := r.Body
+	r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(b[:n]), body), Closer: body}
+	return false
+}
+
+// readCloser reads from Reader and closes Closer.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,113 @@
+package runtime
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryPolicy configures the retries of WithRetry.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of calls of a method, including the first one.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay before a retry. The delays are not capped if it is zero.
+	MaxBackoff time.Duration
+	// Multiplier grows the delay before each retry after the first one. It defaults to 2.
+	Multiplier float64
+	// RetriableCodes are the status codes of the errors which are retried. It defaults to codes.Unavailable.
+	RetriableCodes []codes.Code
+}
+
+// WithRetry returns a ServeMuxOption which retries the gRPC calls of unary methods made with InvokeUnary which
+// fail with one of the retriable codes of "policy", waiting for an exponential backoff between the attempts.
+// Only the calls made for requests using one of the HTTP methods in "methods", e.g. "GET", or of the gRPC
+// methods in "methods", e.g. "/foo.v1.Bars/GetBar", are retried, so only idempotent methods must be given.
+//
+// No attempt is made which would start after the deadline of the request context, in which case the last
+// error is returned. The interceptors given with WithUnaryInterceptor see a single call.
+func WithRetry(policy RetryPolicy, methods ...string) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		config := &retryConfig{policy: policy, methods: make(map[string]bool)}
+		if config.policy.Multiplier < 1 {
+			config.policy.Multiplier = 2
+		}
+		if len(config.policy.RetriableCodes) == 0 {
+			config.policy.RetriableCodes = []codes.Code{codes.Unavailable}
+		}
+		for _, m := range methods {
+			config.methods[m] = true
+		}
+		serveMux.retry = config
+	}
+}
+
+type retryConfig struct {
+	policy  RetryPolicy
+	methods map[string]bool
+}
+
+type retryKey struct{}
+
+// retryRequest is the retry configuration of a request, set by AnnotateContext.
+type retryRequest struct {
+	config     *retryConfig
+	httpMethod string
+}
+
+func withRetry(ctx context.Context, mux *ServeMux, req *http.Request) context.Context {
+	if mux.retry == nil || mux.retry.policy.MaxAttempts <= 1 {
+		return ctx
+	}
+	return context.WithValue(ctx, retryKey{}, retryRequest{config: mux.retry, httpMethod: req.Method})
+}
+
+// retryUnary returns "invoker" retrying the calls of "fullMethod" as configured by WithRetry for the request in "ctx".
+func retryUnary(ctx context.Context, fullMethod string, invoker UnaryInvoker) UnaryInvoker {
+	r, ok := ctx.Value(retryKey{}).(retryRequest)
+	if !ok || !(r.config.methods[r.httpMethod] || r.config.methods[fullMethod]) {
+		return invoker
+	}
+	policy := r.config.policy
+	return func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		backoff := policy.InitialBackoff
+		for attempt := 1; ; attempt++ {
+			resp, err := invoker(ctx, req)
+			if err == nil || attempt >= policy.MaxAttempts || !isRetriable(err, policy.RetriableCodes) {
+				return resp, err
+			}
+			if deadline, ok := ctx.Deadline(); ok && time.Now().Add(backoff).After(deadline) {
+				return nil, err
+			}
+			t := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return nil, err
+			case <-t.C:
+			}
+			backoff = time.Duration(float64(backoff) * policy.Multiplier)
+			if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+				backoff = policy.MaxBackoff
+			}
+		}
+	}
+}
+
+func isRetriable(err error, retriable []codes.Code) bool {
+	s, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	for _, c := range retriable {
+		if s.Code() == c {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package runtime_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestInvokeUnaryRetry(t *testing.T) {
+	const fullMethod = "/grpc.gateway.examples.examplepb.EchoService/Echo"
+	policy := runtime.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	for _, spec := range []struct {
+		name     string
+		opts     []runtime.ServeMuxOption
+		method   string
+		timeout  time.Duration
+		failures []codes.Code
+		attempts int
+		code     codes.Code
+	}{
+		{
+			name:     "retried GET",
+			opts:     []runtime.ServeMuxOption{runtime.WithRetry(policy, "GET")},
+			method:   "GET",
+			failures: []codes.Code{codes.Unavailable, codes.Unavailable},
+			attempts: 3,
+			code:     codes.OK,
+		},
+		{
+			name:     "retried gRPC method",
+			opts:     []runtime.ServeMuxOption{runtime.WithRetry(policy, fullMethod)},
+			method:   "POST",
+			failures: []codes.Code{codes.Unavailable},
+			attempts: 2,
+			code:     codes.OK,
+		},
+		{
+			name:     "max attempts",
+			opts:     []runtime.ServeMuxOption{runtime.WithRetry(policy, "GET")},
+			method:   "GET",
+			failures: []codes.Code{codes.Unavailable, codes.Unavailable, codes.Unavailable},
+			attempts: 3,
+			code:     codes.Unavailable,
+		},
+		{
+			name:     "non-retriable code",
+			opts:     []runtime.ServeMuxOption{runtime.WithRetry(policy, "GET")},
+			method:   "GET",
+			failures: []codes.Code{codes.Internal},
+			attempts: 1,
+			code:     codes.Internal,
+		},
+		{
+			name:     "non-idempotent method",
+			opts:     []runtime.ServeMuxOption{runtime.WithRetry(policy, "GET")},
+			method:   "POST",
+			failures: []codes.Code{codes.Unavailable},
+			attempts: 1,
+			code:     codes.Unavailable,
+		},
+		{
+			name:     "deadline",
+			opts:     []runtime.ServeMuxOption{runtime.WithRetry(runtime.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Minute}, "GET")},
+			method:   "GET",
+			timeout:  time.Second,
+			failures: []codes.Code{codes.Unavailable},
+			attempts: 1,
+			code:     codes.Unavailable,
+		},
+		{
+			name:     "without retry",
+			method:   "GET",
+			failures: []codes.Code{codes.Unavailable},
+			attempts: 1,
+			code:     codes.Unavailable,
+		},
+	} {
+		mux := runtime.NewServeMux(spec.opts...)
+		req, err := http.NewRequest(spec.method, "http://example.com/v1/example/echo", nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest failed with %v; want success", err)
+		}
+		ctx := context.Background()
+		if spec.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, spec.timeout)
+			defer cancel()
+		}
+		ctx, err = runtime.AnnotateContext(ctx, mux, req)
+		if err != nil {
+			t.Fatalf("runtime.AnnotateContext(ctx, mux, req) failed with %v; want success", err)
+		}
+
+		var attempts int
+		_, err = runtime.InvokeUnary(ctx, fullMethod, &pb.SimpleMessage{Id: "foo"}, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+			attempts++
+			if attempts <= len(spec.failures) {
+				return nil, status.Error(spec.failures[attempts-1], "failure")
+			}
+			return &pb.SimpleMessage{Id: "bar"}, nil
+		})
+		if got, want := attempts, spec.attempts; got != want {
+			t.Errorf("%s: attempts = %d; want %d", spec.name, got, want)
+		}
+		if got, want := status.Code(err), spec.code; got != want {
+			t.Errorf("%s: status.Code(err) = %v; want %v", spec.name, got, want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package runtime
+
+import (
+	"net/http"
+	"reflect"
+
+	"golang.org/x/net/context"
+)
+
+// WithRouteContentType returns a ServeMuxOption which advertises "contentType", e.g. a vendor media type such as
+// "application/vnd.myapi.v1+json", as the Content-Type of the responses of ForwardResponseMessage to the requests
+// matching "meth" and "pat", in place of the one of the marshaler.
+//
+// The body is still marshaled by the marshaler negotiated from the Accept header, for which "contentType" is
+// looked up as any other MIME type, falling back to the "*" marshaler. The Content-Type is only replaced if the
+// negotiated marshaler is the one "contentType" resolves to, so that clients accepting another registered
+// format keep receiving its own Content-Type.
+func WithRouteContentType(meth string, pat Pattern, contentType string) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.routeContentTypes = append(serveMux.routeContentTypes, routeContentType{meth: meth, pat: pat, contentType: contentType})
+	}
+}
+
+type routeContentType struct {
+	meth        string
+	pat         Pattern
+	contentType string
+}
+
+type routeContentTypeKey struct{}
+
+// withRouteContentType records the Content-Type given to WithRouteContentType for "meth" and "pat" into the context of "r".
+func (s *ServeMux) withRouteContentType(r *http.Request, meth string, pat Pattern) *http.Request {
+	for i := len(s.routeContentTypes) - 1; i >= 0; i-- {
+		route := s.routeContentTypes[i]
+		if route.meth == meth && route.pat.String() == pat.String() {
+			return r.WithContext(context.WithValue(r.Context(), routeContentTypeKey{}, route.contentType))
+		}
+	}
+	return r
+}
+
+// responseContentType returns the Content-Type of the response to "r" marshaled by "marshaler".
+func responseContentType(mux *ServeMux, r *http.Request, marshaler Marshaler) string {
+	if r == nil {
+		return marshaler.ContentType()
+	}
+	contentType, ok := r.Context().Value(routeContentTypeKey{}).(string)
+	if !ok {
+		return marshaler.ContentType()
+	}
+	advertised, ok := mux.marshalers.lookup([]string{contentType})
+	if !ok {
+		advertised = mux.marshalers.mimeMap[MIMEWildcard]
+	}
+	if !sameMarshaler(marshaler, advertised) {
+		return marshaler.ContentType()
+	}
+	return contentType
+}
+
+// sameMarshaler reports whether "a" and "b" are the same marshaler.
+func sameMarshaler(a, b Marshaler) bool {
+	if a == nil || b == nil || reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
@@ -0,0 +1,68 @@
+package runtime
+
+import (
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// WithRouteHeaders returns a ServeMuxOption which sets "header" in the responses of ForwardResponseMessage and
+// ForwardResponseStream to the requests matching "meth" and "pat", e.g. the pattern of a generated handler.
+// Headers given for the same route several times are all set, later values of a header replacing earlier ones.
+// They are set before the headers forwarded from metadata, see WithHeaderPrecedence.
+func WithRouteHeaders(meth string, pat Pattern, header http.Header) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.routeHeaders = append(serveMux.routeHeaders, routeHeaders{meth: meth, pat: pat, header: header})
+	}
+}
+
+// WithDeprecatedRoute returns a ServeMuxOption which marks the route matching "meth" and "pat" as deprecated
+// with the "Deprecation: true" header, and with a Sunset header of the date it will be removed, "sunset",
+// unless it is zero. See WithRouteHeaders.
+func WithDeprecatedRoute(meth string, pat Pattern, sunset time.Time) ServeMuxOption {
+	header := http.Header{"Deprecation": {"true"}}
+	if !sunset.IsZero() {
+		header.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+	}
+	return WithRouteHeaders(meth, pat, header)
+}
+
+type routeHeaders struct {
+	meth   string
+	pat    Pattern
+	header http.Header
+}
+
+type routeHeadersKey struct{}
+
+// withRouteHeaders records the headers given to WithRouteHeaders for "meth" and "pat" into the context of "r".
+func (s *ServeMux) withRouteHeaders(r *http.Request, meth string, pat Pattern) *http.Request {
+	var header http.Header
+	for _, route := range s.routeHeaders {
+		if route.meth != meth || route.pat.String() != pat.String() {
+			continue
+		}
+		if header == nil {
+			header = make(http.Header)
+		}
+		for k, vs := range route.header {
+			header[http.CanonicalHeaderKey(k)] = vs
+		}
+	}
+	if header == nil {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), routeHeadersKey{}, header))
+}
+
+// handleRouteHeaders sets the headers recorded by withRouteHeaders for "r" into the response.
+func handleRouteHeaders(w http.ResponseWriter, r *http.Request) {
+	if r == nil {
+		return
+	}
+	header, _ := r.Context().Value(routeHeadersKey{}).(http.Header)
+	for k, vs := range header {
+		w.Header()[k] = append([]string(nil), vs...)
+	}
+}
@@ -0,0 +1,89 @@
+package runtime
+
+import (
+	"sort"
+
+	"github.com/grpc-ecosystem/grpc-gateway/utilities"
+)
+
+// routeIndex indexes the handlers registered for an HTTP method by the literal segments their
+// patterns start with, so that only the handlers which may match a path have to be examined.
+// Patterns which do not start with a literal segment are kept at the root and always examined.
+type routeIndex struct {
+	root routeNode
+	// n is the number of indexed handlers, which gives their order of registration.
+	n int
+}
+
+type routeNode struct {
+	children map[string]*routeNode
+	handlers []indexedHandler
+}
+
+type indexedHandler struct {
+	handler
+	seq int
+}
+
+type byRegistration []indexedHandler
+
+func (hs byRegistration) Len() int           { return len(hs) }
+func (hs byRegistration) Less(i, j int) bool { return hs[i].seq < hs[j].seq }
+func (hs byRegistration) Swap(i, j int)      { hs[i], hs[j] = hs[j], hs[i] }
+
+func (idx *routeIndex) add(h handler) {
+	node := &idx.root
+	for _, lit := range literalPrefix(h.pat) {
+		child, ok := node.children[lit]
+		if !ok {
+			if node.children == nil {
+				node.children = make(map[string]*routeNode)
+			}
+			child = new(routeNode)
+			node.children[lit] = child
+		}
+		node = child
+	}
+	node.handlers = append(node.handlers, indexedHandler{handler: h, seq: idx.n})
+	idx.n++
+}
+
+// match returns the handler registered first whose pattern matches "components" and "verb",
+// along with the path parameters it captures.
+func (idx *routeIndex) match(components []string, verb string) (handler, map[string]string, bool) {
+	var candidates []indexedHandler
+	node := &idx.root
+	for i := 0; node != nil; i++ {
+		candidates = append(candidates, node.handlers...)
+		if i == len(components) {
+			break
+		}
+		node = node.children[components[i]]
+	}
+	sort.Sort(byRegistration(candidates))
+
+	for _, c := range candidates {
+		pathParams, err := c.pat.Match(components, verb)
+		if err != nil {
+			continue
+		}
+		return c.handler, pathParams, true
+	}
+	return handler{}, nil, false
+}
+
+// literalPrefix returns the literal segments which the paths matching "p" must start with.
+func literalPrefix(p Pattern) []string {
+	var lits []string
+	for _, op := range p.ops {
+		switch op.code {
+		case utilities.OpLitPush:
+			lits = append(lits, p.pool[op.operand])
+		case utilities.OpNop, utilities.OpConcatN, utilities.OpCapture:
+			// These do not consume path segments.
+		default:
+			return lits
+		}
+	}
+	return lits
+}
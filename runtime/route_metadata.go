@@ -0,0 +1,48 @@
+package runtime
+
+import (
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// MetadataHTTPMethod is the gRPC metadata key of the HTTP method of the request forwarded with WithRouteMetadata.
+	MetadataHTTPMethod = MetadataPrefix + "http-method"
+	// MetadataHTTPPattern is the gRPC metadata key of the path template matched by the request forwarded with
+	// WithRouteMetadata, e.g. "/v1/{name=messages/*}".
+	MetadataHTTPPattern = MetadataPrefix + "http-pattern"
+	// MetadataHTTPPath is the gRPC metadata key of the path of the request forwarded with WithRouteMetadata.
+	MetadataHTTPPath = MetadataPrefix + "http-path"
+)
+
+// WithRouteMetadata returns a ServeMuxOption which forwards the route of the requests to the backend in the
+// gRPC metadata keys MetadataHTTPMethod, MetadataHTTPPattern and MetadataHTTPPath, e.g. for authorization
+// scoped to HTTP methods.
+//
+// The method is the one the request was matched with, e.g. after X-HTTP-Method-Override, the pattern is the
+// one its handler was registered with, below the prefix given to WithPathPrefix if any, and the path is the
+// escaped path of the request.
+func WithRouteMetadata() ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.routeMetadata = true
+	}
+}
+
+type routeMetadataKey struct{}
+
+// withRouteMetadata records the route of "r" to be forwarded by AnnotateContext.
+func withRouteMetadata(r *http.Request, meth string, pat Pattern) *http.Request {
+	pairs := []string{
+		MetadataHTTPMethod, meth,
+		MetadataHTTPPattern, pat.String(),
+		MetadataHTTPPath, r.URL.EscapedPath(),
+	}
+	return r.WithContext(context.WithValue(r.Context(), routeMetadataKey{}, pairs))
+}
+
+// routeMetadata returns the metadata pairs of the route of "req" recorded by withRouteMetadata, if any.
+func routeMetadata(req *http.Request) []string {
+	pairs, _ := req.Context().Value(routeMetadataKey{}).([]string)
+	return pairs
+}
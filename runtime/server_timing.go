@@ -0,0 +1,62 @@
+package runtime
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+)
+
+// WithServerTiming returns a ServeMuxOption which adds a Server-Timing header to unary responses with the
+// duration of the gRPC call as "rpc" and the duration of the marshaling of the response message as "marshal",
+// both in milliseconds, e.g. "rpc;dur=12.345, marshal;dur=0.042". The call is measured by InvokeUnary
+// without the interceptors given to WithUnaryInterceptor. Responses without a marshaled body, e.g.
+// google.api.HttpBody responses and those to "return=minimal" preferences, only carry the "rpc" metric.
+func WithServerTiming() ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.serverTiming = true
+	}
+}
+
+type serverTimingKey struct{}
+
+// serverTiming holds the durations measured for the request being served.
+type serverTiming struct {
+	rpc time.Duration
+}
+
+// timeUnary returns "invoker" measuring its calls into the serverTiming in "ctx", if any.
+func timeUnary(ctx context.Context, invoker UnaryInvoker) UnaryInvoker {
+	t, ok := ctx.Value(serverTimingKey{}).(*serverTiming)
+	if !ok {
+		return invoker
+	}
+	return func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		start := time.Now()
+		defer func() {
+			t.rpc += time.Since(start)
+		}()
+		return invoker(ctx, req)
+	}
+}
+
+// handleServerTiming adds the Server-Timing header of the request in "ctx" if WithServerTiming is given.
+// "marshal" is the duration of the marshaling of the response, if it was marshaled.
+func handleServerTiming(ctx context.Context, w http.ResponseWriter, marshal time.Duration, marshaled bool) {
+	t, ok := ctx.Value(serverTimingKey{}).(*serverTiming)
+	if !ok {
+		return
+	}
+	v := fmt.Sprintf("rpc;dur=%s", formatDuration(t.rpc))
+	if marshaled {
+		v += fmt.Sprintf(", marshal;dur=%s", formatDuration(marshal))
+	}
+	w.Header().Add("Server-Timing", v)
+}
+
+// formatDuration formats "d" in milliseconds as in the Server-Timing header.
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%.3f", float64(d)/float64(time.Millisecond))
+}
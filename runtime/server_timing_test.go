@@ -0,0 +1,65 @@
+package runtime_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"golang.org/x/net/context"
+)
+
+func TestServerTiming(t *testing.T) {
+	serverTimingPattern := regexp.MustCompile(`^rpc;dur=([0-9.]+), marshal;dur=[0-9.]+$`)
+	for _, spec := range []struct {
+		opts []runtime.ServeMuxOption
+		want bool
+	}{
+		{},
+		{
+			opts: []runtime.ServeMuxOption{runtime.WithServerTiming()},
+			want: true,
+		},
+	} {
+		mux := runtime.NewServeMux(spec.opts...)
+		req := httptest.NewRequest("GET", "http://example.com/v1/example/echo/foo", nil)
+		ctx, err := runtime.AnnotateContext(context.Background(), mux, req)
+		if err != nil {
+			t.Fatalf("runtime.AnnotateContext(ctx, mux, req) failed with %v; want success", err)
+		}
+		resp, err := runtime.InvokeUnary(ctx, "/grpc.gateway.examples.examplepb.EchoService/Echo", &pb.SimpleMessage{Id: "foo"}, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+			time.Sleep(5 * time.Millisecond)
+			return req, nil
+		})
+		if err != nil {
+			t.Fatalf("runtime.InvokeUnary(ctx, method, req, invoker) failed with %v; want success", err)
+		}
+		w := httptest.NewRecorder()
+		ctx = runtime.NewServerMetadataContext(ctx, runtime.ServerMetadata{})
+		runtime.ForwardResponseMessage(ctx, mux, &runtime.JSONPb{}, w, req, resp)
+
+		got := w.Header().Get("Server-Timing")
+		if !spec.want {
+			if got != "" {
+				t.Errorf("Server-Timing = %q; want no header", got)
+			}
+			continue
+		}
+		match := serverTimingPattern.FindStringSubmatch(got)
+		if match == nil {
+			t.Errorf("Server-Timing = %q; want to match %q", got, serverTimingPattern)
+			continue
+		}
+		if rpc, err := strconv.ParseFloat(match[1], 64); err != nil || rpc < 5 {
+			t.Errorf("rpc duration = %q; want at least 5ms", match[1])
+		}
+		if w.Code != http.StatusOK {
+			t.Errorf("w.Code = %d; want %d", w.Code, http.StatusOK)
+		}
+	}
+}
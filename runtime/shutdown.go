@@ -0,0 +1,48 @@
+package runtime
+
+import (
+	"io"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WithShutdownContext returns a ServeMuxOption which cancels the contexts of the calls in flight once "ctx"
+// is done, e.g. when the server starts to shut down, so that long-running calls and streams wrap up instead
+// of holding their connections until they are cut. A stream forwarded with ForwardResponseStream then ends
+// with an error chunk of codes.Unavailable, which clients may retry against another server.
+//
+// The contexts of the calls are derived by AnnotateContext, so the cancellation applies to the calls made
+// by the generated handlers, but not to the handlers registered with Handle.
+func WithShutdownContext(ctx context.Context) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.shutdownCtx = ctx
+	}
+}
+
+// withShutdown returns a copy of "ctx" which is canceled once "shutdown" is done.
+func withShutdown(ctx, shutdown context.Context) context.Context {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-shutdown.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx
+}
+
+// shutdownStreamError returns a recv function which fails with codes.Unavailable instead of
+// the error returned by "recv" once "shutdown" is done.
+func shutdownStreamError(recv func() (proto.Message, error), shutdown context.Context) func() (proto.Message, error) {
+	return func() (proto.Message, error) {
+		resp, err := recv()
+		if err != nil && err != io.EOF && shutdown.Err() != nil {
+			return nil, status.Error(codes.Unavailable, "server is shutting down")
+		}
+		return resp, err
+	}
+}
@@ -0,0 +1,54 @@
+package runtime_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestShutdownContext(t *testing.T) {
+	shutdown, cancel := context.WithCancel(context.Background())
+	mux := runtime.NewServeMux(runtime.WithShutdownContext(shutdown))
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	ctx, err := runtime.AnnotateContext(context.Background(), mux, req)
+	if err != nil {
+		t.Fatalf("runtime.AnnotateContext(ctx, mux, req) failed with %v; want success", err)
+	}
+	ctx = runtime.NewServerMetadataContext(ctx, runtime.ServerMetadata{})
+
+	var count int
+	recv := func() (proto.Message, error) {
+		if count++; count == 1 {
+			return &pb.SimpleMessage{Id: "foo"}, nil
+		}
+		// The backend sees the cancellation once the server shuts down.
+		cancel()
+		select {
+		case <-ctx.Done():
+		case <-time.After(5 * time.Second):
+			t.Errorf("ctx.Done() is not closed after shutdown")
+		}
+		return nil, status.Error(codes.Canceled, "context canceled")
+	}
+	w := httptest.NewRecorder()
+	runtime.ForwardResponseStream(ctx, mux, &runtime.JSONPb{}, w, req, recv)
+
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if got, want := len(lines), 2; got != want {
+		t.Fatalf("len(lines) = %d; want %d; body = %q", got, want, w.Body)
+	}
+	if got, want := lines[0], `{"result":{"id":"foo"}}`; got != want {
+		t.Errorf("lines[0] = %q; want %q", got, want)
+	}
+	if got, want := lines[1], `"grpcCode":14`; !strings.Contains(got, want) {
+		t.Errorf("lines[1] = %q; want it to contain %q", got, want)
+	}
+}
@@ -0,0 +1,41 @@
+package runtime
+
+import (
+	"net/http"
+	"strconv"
+
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	grpcStatusTrailer  = "Grpc-Status"
+	grpcMessageTrailer = "Grpc-Message"
+)
+
+// WithStatusTrailers returns a ServeMuxOption which sends the gRPC status of unary calls in the Grpc-Status and
+// Grpc-Message HTTP trailers, as gRPC-Web does, in addition to the HTTP status. They are announced in the
+// Trailer header and written after the body by ForwardResponseMessage, DefaultHTTPError and
+// DefaultHTTPProtoErrorHandler. As in gRPC, Grpc-Message is percent-encoded and empty for successful calls.
+func WithStatusTrailers() ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.statusTrailers = true
+	}
+}
+
+// handleStatusTrailerHeader announces the trailers of WithStatusTrailers in the Trailer header.
+func handleStatusTrailerHeader(w http.ResponseWriter, mux *ServeMux) {
+	if mux == nil || !mux.statusTrailers {
+		return
+	}
+	w.Header().Add("Trailer", grpcStatusTrailer)
+	w.Header().Add("Trailer", grpcMessageTrailer)
+}
+
+// handleStatusTrailer writes the trailers of WithStatusTrailers for the status "code" and "msg".
+func handleStatusTrailer(w http.ResponseWriter, mux *ServeMux, code codes.Code, msg string) {
+	if mux == nil || !mux.statusTrailers {
+		return
+	}
+	w.Header().Set(grpcStatusTrailer, strconv.Itoa(int(code)))
+	w.Header().Set(grpcMessageTrailer, encodeGrpcMessage(msg))
+}
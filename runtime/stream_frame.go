@@ -0,0 +1,135 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Flags of the frames written by ForwardResponseStream with WithStreamFraming.
+const (
+	// StreamFrameData flags a frame which contains a marshaled response message.
+	StreamFrameData byte = 0x00
+	// StreamFrameTrailer flags the last frame of a stream, which contains the trailers of the call
+	// including "grpc-status" and "grpc-message".
+	StreamFrameTrailer byte = 0x80
+)
+
+// ReadStreamFrame reads a frame written by ForwardResponseStream with WithStreamFraming from "r".
+// A frame consists of a flag byte, the 4-byte big-endian length of its payload and the payload.
+func ReadStreamFrame(r io.Reader) (flags byte, payload []byte, err error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	payload = make([]byte, binary.BigEndian.Uint32(header[1:]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
+
+func writeStreamFrame(w io.Writer, flags byte, payload []byte) error {
+	var header [5]byte
+	header[0] = flags
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// forwardFramedResponseStream forwards each message received from "recv" in a data frame
+// and terminates the stream with a trailer frame carrying the final status.
+func forwardFramedResponseStream(ctx context.Context, mux *ServeMux, marshaler Marshaler, w http.ResponseWriter, sw *streamWriter, recv func() (proto.Message, error), opts []func(context.Context, http.ResponseWriter, proto.Message) error) {
+	for {
+		resp, err := recv()
+		if err == io.EOF {
+			writeStreamTrailer(ctx, mux, sw, nil)
+			return
+		}
+		if err == nil && resp == nil {
+			err = fmt.Errorf("empty response")
+		}
+		if err != nil {
+			writeStreamTrailer(ctx, mux, sw, err)
+			return
+		}
+		if err := handleForwardResponseOptions(ctx, w, resp, opts); err != nil {
+			writeStreamTrailer(ctx, mux, sw, err)
+			return
+		}
+
+		buf, err := marshaler.Marshal(resp)
+		if err != nil {
+			logf(ctx, "Failed to marshal response chunk: %v", err)
+			writeStreamTrailer(ctx, mux, sw, err)
+			return
+		}
+		sw.SetHeader("Content-Type", marshaler.ContentType())
+		if err := writeStreamFrame(sw, StreamFrameData, buf); err != nil {
+			logf(ctx, "Failed to send response chunk: %v", err)
+			return
+		}
+		sw.Flush()
+	}
+}
+
+func writeStreamTrailer(ctx context.Context, mux *ServeMux, w io.Writer, err error) {
+	if err != nil {
+		err = sanitizeError(ctx, mux, err)
+	}
+	if werr := writeStreamFrame(w, StreamFrameTrailer, streamTrailer(err)); werr != nil {
+		logf(ctx, "Failed to send trailer frame: %v", werr)
+	}
+}
+
+// streamTrailer returns the payload of the trailer frame for a stream terminated by "err",
+// which is nil if the stream completed successfully.
+func streamTrailer(err error) []byte {
+	if err == nil {
+		return []byte("grpc-status: 0\r\n")
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		s = status.New(codes.Unknown, err.Error())
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "grpc-status: %d\r\n", s.Code())
+	if msg := s.Message(); msg != "" {
+		fmt.Fprintf(&buf, "grpc-message: %s\r\n", encodeGrpcMessage(msg))
+	}
+	if len(s.Details()) > 0 {
+		if b, err := proto.Marshal(s.Proto()); err == nil {
+			fmt.Fprintf(&buf, "grpc-status-details-bin: %s\r\n", base64.RawStdEncoding.EncodeToString(b))
+		}
+	}
+	return buf.Bytes()
+}
+
+// encodeGrpcMessage percent-encodes "msg" as the value of a grpc-message trailer.
+func encodeGrpcMessage(msg string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c >= ' ' && c <= '~' && c != '%' {
+			buf.WriteByte(c)
+		} else {
+			fmt.Fprintf(&buf, "%%%02X", c)
+		}
+	}
+	return buf.String()
+}
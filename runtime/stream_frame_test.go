@@ -0,0 +1,88 @@
+package runtime_test
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestForwardResponseStreamFraming(t *testing.T) {
+	for _, spec := range []struct {
+		name    string
+		err     error
+		trailer string
+	}{
+		{
+			name:    "ok",
+			err:     io.EOF,
+			trailer: "grpc-status: 0\r\n",
+		},
+		{
+			name:    "error",
+			err:     grpc.Errorf(codes.OutOfRange, "out of 100%%"),
+			trailer: "grpc-status: 11\r\ngrpc-message: out of 100%25\r\n",
+		},
+	} {
+		t.Run(spec.name, func(t *testing.T) {
+			msgs := []proto.Message{&pb.SimpleMessage{Id: "One"}, &pb.SimpleMessage{Id: "Two"}}
+			var count int
+			recv := func() (proto.Message, error) {
+				if count == len(msgs) {
+					return nil, spec.err
+				}
+				count++
+				return msgs[count-1], nil
+			}
+			ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+			req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+			resp := httptest.NewRecorder()
+			mux := runtime.NewServeMux(runtime.WithStreamFraming())
+
+			runtime.ForwardResponseStream(ctx, mux, &runtime.ProtoMarshaller{}, resp, req, recv)
+
+			r := bytes.NewReader(resp.Body.Bytes())
+			for i, want := range msgs {
+				flags, payload, err := runtime.ReadStreamFrame(r)
+				if err != nil {
+					t.Fatalf("runtime.ReadStreamFrame(r) failed with %v; want success", err)
+				}
+				if flags != runtime.StreamFrameData {
+					t.Fatalf("flags of frame %d = %#x; want %#x", i, flags, runtime.StreamFrameData)
+				}
+				got := new(pb.SimpleMessage)
+				if err := proto.Unmarshal(payload, got); err != nil {
+					t.Fatalf("proto.Unmarshal(%q, got) failed with %v; want success", payload, err)
+				}
+				if !proto.Equal(got, want) {
+					t.Errorf("message %d = %v; want %v", i, got, want)
+				}
+			}
+
+			flags, payload, err := runtime.ReadStreamFrame(r)
+			if err != nil {
+				t.Fatalf("runtime.ReadStreamFrame(r) failed with %v; want success", err)
+			}
+			if flags != runtime.StreamFrameTrailer {
+				t.Errorf("flags of last frame = %#x; want %#x", flags, runtime.StreamFrameTrailer)
+			}
+			if got := string(payload); got != spec.trailer {
+				t.Errorf("trailer = %q; want %q", got, spec.trailer)
+			}
+			if !strings.HasPrefix(resp.Header().Get("Content-Type"), "application/octet-stream") {
+				t.Errorf("Content-Type = %q; want %q", resp.Header().Get("Content-Type"), "application/octet-stream")
+			}
+			if _, _, err := runtime.ReadStreamFrame(r); err != io.EOF {
+				t.Errorf("runtime.ReadStreamFrame(r) = _, _, %v; want io.EOF", err)
+			}
+		})
+	}
+}
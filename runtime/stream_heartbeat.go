@@ -0,0 +1,99 @@
+package runtime
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/grpclog"
+)
+
+// streamHeartbeat writes "data" to a streamed response whenever no message was written for "interval".
+//
+// Its mutex is held by ForwardResponseStream except while it waits for the next message,
+// so that heartbeats are never written in the middle of a message.
+type streamHeartbeat struct {
+	sw       *streamWriter
+	interval time.Duration
+	data     []byte
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	last    time.Time
+	sent    bool
+	stopped bool
+}
+
+// startStreamHeartbeat returns a streamHeartbeat writing to "sw", or nil if "interval" is not positive.
+// It has to be stopped with stop.
+func startStreamHeartbeat(sw *streamWriter, interval time.Duration, data []byte) *streamHeartbeat {
+	if interval <= 0 {
+		return nil
+	}
+	h := &streamHeartbeat{sw: sw, interval: interval, data: data}
+	h.mu.Lock()
+	h.timer = time.AfterFunc(interval, h.beat)
+	h.timer.Stop()
+	return h
+}
+
+// wrap returns a recv function which lets heartbeats be sent while "recv" waits for a message.
+func (h *streamHeartbeat) wrap(recv func() (proto.Message, error)) func() (proto.Message, error) {
+	if h == nil {
+		return recv
+	}
+	return func() (proto.Message, error) {
+		h.last = time.Now()
+		h.timer.Reset(h.interval)
+		h.mu.Unlock()
+
+		resp, err := recv()
+
+		h.mu.Lock()
+		h.timer.Stop()
+		if err != nil {
+			h.stopLocked()
+		}
+		return resp, err
+	}
+}
+
+func (h *streamHeartbeat) beat() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.stopped {
+		return
+	}
+	if d := h.interval - time.Since(h.last); d > 0 {
+		// A message was written since the timer fired.
+		h.timer.Reset(d)
+		return
+	}
+	if _, err := h.sw.Write(h.data); err != nil {
+		grpclog.Printf("Failed to send heartbeat: %v", err)
+		return
+	}
+	h.sw.Flush()
+	h.sent = true
+	h.last = time.Now()
+	h.timer.Reset(h.interval)
+}
+
+// wroteHeader reports whether a heartbeat started the response.
+func (h *streamHeartbeat) wroteHeader() bool {
+	return h != nil && h.sent
+}
+
+// stop stops sending heartbeats. It must be called by the goroutine forwarding the stream.
+func (h *streamHeartbeat) stop() {
+	if h == nil || h.stopped {
+		return
+	}
+	h.stopLocked()
+}
+
+func (h *streamHeartbeat) stopLocked() {
+	h.stopped = true
+	h.timer.Stop()
+	h.mu.Unlock()
+}
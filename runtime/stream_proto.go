@@ -0,0 +1,91 @@
+package runtime
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+)
+
+// NewProtoStreamDecoder returns a Decoder which reads the response messages streamed by
+// ForwardResponseStream with ProtoMarshaller from "r". Each message is preceded by its length
+// encoded as a varint. Decode returns io.EOF at the end of the stream, and io.ErrUnexpectedEOF
+// if the stream ends in the middle of a message.
+func NewProtoStreamDecoder(r io.Reader) Decoder {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		buffered := bufio.NewReader(r)
+		br, r = buffered, buffered
+	}
+	return DecoderFunc(func(v interface{}) error {
+		msg, ok := v.(proto.Message)
+		if !ok {
+			return fmt.Errorf("unable to unmarshal non proto field")
+		}
+		l, err := binary.ReadUvarint(br)
+		if err != nil {
+			return err
+		}
+		buf := make([]byte, l)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		return proto.Unmarshal(buf, msg)
+	})
+}
+
+// forwardProtoResponseStream forwards each message received from "recv" with its varint length prefix.
+// An error received before the first message is reported as in HTTPError. Later errors cannot be told
+// apart from messages, so the stream just ends; use WithStreamFraming to report them to clients.
+func forwardProtoResponseStream(ctx context.Context, mux *ServeMux, marshaler Marshaler, w http.ResponseWriter, req *http.Request, sw *streamWriter, recv func() (proto.Message, error), wroteHeader bool, opts []func(context.Context, http.ResponseWriter, proto.Message) error) {
+	fail := func(err error) {
+		sw.Close()
+		if wroteHeader {
+			logf(ctx, "Failed to forward response stream: %v", err)
+			return
+		}
+		HTTPError(ctx, mux, marshaler, w, req, err)
+	}
+	for {
+		resp, err := recv()
+		if err == io.EOF {
+			return
+		}
+		if err == nil && resp == nil {
+			err = fmt.Errorf("empty response")
+		}
+		if err != nil {
+			fail(err)
+			return
+		}
+		if err := handleForwardResponseOptions(ctx, w, resp, opts); err != nil {
+			fail(err)
+			return
+		}
+
+		buf, err := marshaler.Marshal(resp)
+		if err != nil {
+			logf(ctx, "Failed to marshal response chunk: %v", err)
+			fail(err)
+			return
+		}
+		sw.SetHeader("Content-Type", marshaler.ContentType())
+		if _, err := sw.Write(proto.EncodeVarint(uint64(len(buf)))); err != nil {
+			logf(ctx, "Failed to send response chunk: %v", err)
+			return
+		}
+		wroteHeader = true
+		if _, err := sw.Write(buf); err != nil {
+			logf(ctx, "Failed to send response chunk: %v", err)
+			return
+		}
+		sw.Flush()
+	}
+}
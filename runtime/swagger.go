@@ -0,0 +1,113 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+
+	"google.golang.org/grpc/grpclog"
+)
+
+// RebaseSwagger rewrites the swagger specification "spec" so that its API is served under "basePath",
+// e.g. "/api" when the gateway is mounted behind that prefix.
+// The "host" of the specification is removed so that clients such as Swagger UI send requests to the
+// host which serves the specification.
+func RebaseSwagger(spec []byte, basePath string) ([]byte, error) {
+	return rewriteSwagger(spec, "", basePath)
+}
+
+// rewriteSwagger sets the "basePath" of the swagger specification "spec" to "basePath", and its "host" to "host",
+// or removes it if "host" is empty.
+func rewriteSwagger(spec []byte, host, basePath string) ([]byte, error) {
+	var doc map[string]*json.RawMessage
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, err
+	}
+	if basePath == "" {
+		basePath = "/"
+	}
+	fields := map[string]string{"basePath": path.Clean("/" + basePath)}
+	if host != "" {
+		fields["host"] = host
+	} else {
+		delete(doc, "host")
+	}
+	for key, val := range fields {
+		buf, err := json.Marshal(val)
+		if err != nil {
+			return nil, err
+		}
+		doc[key] = (*json.RawMessage)(&buf)
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// ServeSwagger registers a handler which serves the swagger specification "spec" on GET requests matching "pat".
+// The specification is rebased on "basePath" with RebaseSwagger.
+func (s *ServeMux) ServeSwagger(pat Pattern, spec []byte, basePath string) error {
+	rebased, err := RebaseSwagger(spec, basePath)
+	if err != nil {
+		return err
+	}
+	s.Handle("GET", pat, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		s.serveSwaggerSpec(w, r, rebased, basePath)
+	})
+	return nil
+}
+
+// WithOpenAPIEndpoint returns a ServeMuxOption which makes ServeMux serve the OpenAPI (swagger) specification
+// "spec" as application/json on GET requests to "path", e.g. "/openapi.json". As the paths of routes, "path"
+// is matched against the request path without the prefix given to WithPathPrefix.
+// It panics if "spec" is not a JSON object.
+func WithOpenAPIEndpoint(path string, spec []byte) ServeMuxOption {
+	var doc map[string]*json.RawMessage
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		panic(fmt.Sprintf("invalid OpenAPI specification for %s: %v", path, err))
+	}
+	return func(serveMux *ServeMux) {
+		if serveMux.openAPIEndpoints == nil {
+			serveMux.openAPIEndpoints = make(map[string][]byte)
+		}
+		serveMux.openAPIEndpoints[path] = spec
+	}
+}
+
+// WithOpenAPIHostRewrite returns a ServeMuxOption which makes the specifications served by ServeSwagger and
+// WithOpenAPIEndpoint document the API serving them. Their "basePath" is set to the base path given to
+// ServeSwagger or to the prefix given with WithPathPrefix, and their "host" to the Host header of each request
+// if it is one of "hosts". The host is removed otherwise, so that clients use the host serving the
+// specification, and a Host header forged by a client never makes it into the specification.
+func WithOpenAPIHostRewrite(hosts ...string) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.openAPIHostRewrite = true
+		serveMux.openAPIHosts = make(map[string]bool)
+		for _, host := range hosts {
+			serveMux.openAPIHosts[host] = true
+		}
+	}
+}
+
+// serveSwaggerSpec writes the specification "spec", served under "basePath", in response to "r".
+func (s *ServeMux) serveSwaggerSpec(w http.ResponseWriter, r *http.Request, spec []byte, basePath string) {
+	if s.openAPIHostRewrite {
+		var host string
+		if s.openAPIHosts[r.Host] {
+			host = r.Host
+		}
+		rewritten, err := rewriteSwagger(spec, host, basePath)
+		if err != nil {
+			grpclog.Printf("Failed to rewrite OpenAPI specification: %v", err)
+			OtherErrorHandler(w, r, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		spec = rewritten
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == "HEAD" {
+		return
+	}
+	if _, err := w.Write(spec); err != nil {
+		grpclog.Printf("Failed to write OpenAPI specification: %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package runtime_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/utilities"
+)
+
+const testSwaggerSpec = `{
+  "swagger": "2.0",
+  "info": {"title": "echo_service.proto", "version": "version not set"},
+  "host": "localhost:8080",
+  "basePath": "/",
+  "paths": {"/v1/example/echo/{id}": {}}
+}`
+
+func TestRebaseSwagger(t *testing.T) {
+	for _, spec := range []struct {
+		basePath string
+		want     string
+	}{
+		{basePath: "/api", want: "/api"},
+		{basePath: "api/", want: "/api"},
+		{basePath: "", want: "/"},
+	} {
+		buf, err := runtime.RebaseSwagger([]byte(testSwaggerSpec), spec.basePath)
+		if err != nil {
+			t.Errorf("runtime.RebaseSwagger(spec, %q) failed with %v; want success", spec.basePath, err)
+			continue
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(buf, &doc); err != nil {
+			t.Errorf("json.Unmarshal(%q) failed with %v; want success", buf, err)
+			continue
+		}
+		if got := doc["basePath"]; got != spec.want {
+			t.Errorf("basePath = %v; want %q", got, spec.want)
+		}
+		if host, ok := doc["host"]; ok {
+			t.Errorf("host = %v; want no host", host)
+		}
+		if _, ok := doc["paths"].(map[string]interface{})["/v1/example/echo/{id}"]; !ok {
+			t.Errorf("paths = %v; want the original paths", doc["paths"])
+		}
+	}
+
+	if _, err := runtime.RebaseSwagger([]byte("not json"), "/api"); err == nil {
+		t.Errorf("runtime.RebaseSwagger(%q, %q) succeeded; want failure", "not json", "/api")
+	}
+}
+
+func TestServeSwagger(t *testing.T) {
+	mux := runtime.NewServeMux()
+	pat := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0}, []string{"swagger.json"}, ""))
+	if err := mux.ServeSwagger(pat, []byte(testSwaggerSpec), "/api"); err != nil {
+		t.Fatalf("mux.ServeSwagger(pat, spec, %q) failed with %v; want success", "/api", err)
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "http://host.example/swagger.json", nil))
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("json.Unmarshal(%q) failed with %v; want success", w.Body.Bytes(), err)
+	}
+	if got, want := doc["basePath"], "/api"; got != want {
+		t.Errorf("basePath = %v; want %q", got, want)
+	}
+}
+
+func TestWithOpenAPIEndpoint(t *testing.T) {
+	for _, spec := range []struct {
+		opts     []runtime.ServeMuxOption
+		url      string
+		host     interface{}
+		basePath interface{}
+	}{
+		{
+			url:      "http://gateway.example/openapi.json",
+			host:     "localhost:8080",
+			basePath: "/",
+		},
+		{
+			opts:     []runtime.ServeMuxOption{runtime.WithOpenAPIHostRewrite("gateway.example"), runtime.WithPathPrefix("/api")},
+			url:      "http://gateway.example/api/openapi.json",
+			host:     "gateway.example",
+			basePath: "/api",
+		},
+		// Hosts which are not allowed are not written into the specification.
+		{
+			opts:     []runtime.ServeMuxOption{runtime.WithOpenAPIHostRewrite("gateway.example"), runtime.WithPathPrefix("/api")},
+			url:      "http://evil.example/api/openapi.json",
+			basePath: "/api",
+		},
+	} {
+		opts := append([]runtime.ServeMuxOption{runtime.WithOpenAPIEndpoint("/openapi.json", []byte(testSwaggerSpec))}, spec.opts...)
+		mux := runtime.NewServeMux(opts...)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", spec.url, nil))
+
+		if got, want := w.Header().Get("Content-Type"), "application/json"; got != want {
+			t.Errorf("%s: Content-Type = %q; want %q", spec.url, got, want)
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+			t.Fatalf("%s: json.Unmarshal(%q) failed with %v; want success", spec.url, w.Body.Bytes(), err)
+		}
+		if got := doc["host"]; got != spec.host {
+			t.Errorf("%s: host = %v; want %v", spec.url, got, spec.host)
+		}
+		if got := doc["basePath"]; got != spec.basePath {
+			t.Errorf("%s: basePath = %v; want %v", spec.url, got, spec.basePath)
+		}
+	}
+}
+
+func TestServeSwaggerHostRewrite(t *testing.T) {
+	mux := runtime.NewServeMux(runtime.WithOpenAPIHostRewrite("gateway.example"))
+	pat := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0}, []string{"swagger.json"}, ""))
+	if err := mux.ServeSwagger(pat, []byte(testSwaggerSpec), "/api"); err != nil {
+		t.Fatalf("mux.ServeSwagger(pat, spec, %q) failed with %v; want success", "/api", err)
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "http://gateway.example/swagger.json", nil))
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("json.Unmarshal(%q) failed with %v; want success", w.Body.Bytes(), err)
+	}
+	if got, want := doc["host"], "gateway.example"; got != want {
+		t.Errorf("host = %v; want %q", got, want)
+	}
+	if got, want := doc["basePath"], "/api"; got != want {
+		t.Errorf("basePath = %v; want %q", got, want)
+	}
+}
@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// Headers and metadata keys of the W3C trace context (https://www.w3.org/TR/trace-context/).
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+)
+
+// TraceContext is the W3C trace context of a request.
+type TraceContext struct {
+	// TraceParent is the value of the traceparent header,
+	// e.g. "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+	TraceParent string
+	// TraceState is the value of the tracestate header, if any.
+	TraceState string
+}
+
+// Tracer starts a span for each request served by a ServeMux with WithTracing.
+// It can be implemented on top of any tracing library, e.g. OpenTelemetry.
+type Tracer interface {
+	// StartSpan starts the span of "r", continuing the trace context "parent" sent by the client,
+	// which is zero if the request had none or an invalid one. The returned context is used to serve "r".
+	// The returned Span may be nil, e.g. if the request is sampled out, in which case "parent" is propagated as is.
+	StartSpan(ctx context.Context, r *http.Request, parent TraceContext) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	// TraceContext returns the trace context propagated to the backend, which identifies the span as parent.
+	TraceContext() TraceContext
+	// End ends the span of a request answered with the HTTP status code "status".
+	End(status int)
+}
+
+// WithTracing returns a ServeMuxOption which propagates the W3C trace context of the requests to the backend
+// in the "traceparent" and "tracestate" metadata. If "tracer" is not nil, it starts a span for each request
+// and the trace context of the span is propagated instead.
+func WithTracing(tracer Tracer) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.tracing = true
+		serveMux.tracer = tracer
+	}
+}
+
+type traceContextKey struct{}
+
+// TraceContextFromContext returns the trace context propagated to the backend, if WithTracing is given.
+func TraceContextFromContext(ctx context.Context) (tc TraceContext, ok bool) {
+	tc, ok = ctx.Value(traceContextKey{}).(TraceContext)
+	return
+}
+
+// startSpan returns "r" with the trace context to propagate and the span started for it, if any.
+func (s *ServeMux) startSpan(r *http.Request) (*http.Request, Span) {
+	var tc TraceContext
+	if parent := strings.TrimSpace(r.Header.Get(TraceParentHeader)); isValidTraceParent(parent) {
+		tc = TraceContext{
+			TraceParent: parent,
+			TraceState:  strings.Join(r.Header[http.CanonicalHeaderKey(TraceStateHeader)], ","),
+		}
+	}
+	ctx := r.Context()
+	var span Span
+	if s.tracer != nil {
+		ctx, span = s.tracer.StartSpan(ctx, r, tc)
+		if span != nil {
+			tc = span.TraceContext()
+		}
+	}
+	if tc.TraceParent != "" {
+		ctx = context.WithValue(ctx, traceContextKey{}, tc)
+	}
+	return r.WithContext(ctx), span
+}
+
+// traceMetadata returns the metadata pairs propagating the trace context in "ctx", if any.
+func traceMetadata(ctx context.Context) []string {
+	tc, ok := TraceContextFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	pairs := []string{TraceParentHeader, tc.TraceParent}
+	if tc.TraceState != "" {
+		pairs = append(pairs, TraceStateHeader, tc.TraceState)
+	}
+	return pairs
+}
+
+// isValidTraceParent reports whether "s" is a traceparent header of the form version-traceid-parentid-flags
+// with a non-zero trace and parent ID.
+func isValidTraceParent(s string) bool {
+	parts := strings.Split(s, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return false
+	}
+	for i, l := range []int{2, 32, 16, 2} {
+		if len(parts[i]) != l || !isLowerHex(parts[i]) {
+			return false
+		}
+	}
+	return strings.Trim(parts[1], "0") != "" && strings.Trim(parts[2], "0") != ""
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package runtime_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/utilities"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+type fakeTracer struct {
+	// sampledOut makes the tracer start no span.
+	sampledOut bool
+	parent     runtime.TraceContext
+	status     int
+}
+
+func (t *fakeTracer) StartSpan(ctx context.Context, r *http.Request, parent runtime.TraceContext) (context.Context, runtime.Span) {
+	t.parent = parent
+	if t.sampledOut {
+		return ctx, nil
+	}
+	return ctx, fakeSpan{t}
+}
+
+type fakeSpan struct{ t *fakeTracer }
+
+func (s fakeSpan) TraceContext() runtime.TraceContext {
+	return runtime.TraceContext{TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-b7ad6b7169203331-01", TraceState: s.t.parent.TraceState}
+}
+
+func (s fakeSpan) End(status int) { s.t.status = status }
+
+func TestMuxServeHTTPTracing(t *testing.T) {
+	pat := runtime.MustPattern(runtime.NewPattern(1, []int{int(utilities.OpLitPush), 0}, []string{"foo"}, ""))
+	for _, spec := range []struct {
+		name        string
+		tracer      *fakeTracer
+		traceParent string
+		wantMD      metadata.MD
+	}{
+		{
+			name:        "propagate",
+			traceParent: testTraceParent,
+			wantMD:      metadata.Pairs("traceparent", testTraceParent, "tracestate", "congo=t61rcWkgMzE"),
+		},
+		{
+			name:        "invalid",
+			traceParent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			wantMD:      metadata.MD{},
+		},
+		{
+			name:        "tracer",
+			tracer:      new(fakeTracer),
+			traceParent: testTraceParent,
+			wantMD:      metadata.Pairs("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-b7ad6b7169203331-01", "tracestate", "congo=t61rcWkgMzE"),
+		},
+		{
+			name:        "sampled out",
+			tracer:      &fakeTracer{sampledOut: true},
+			traceParent: testTraceParent,
+			wantMD:      metadata.Pairs("traceparent", testTraceParent, "tracestate", "congo=t61rcWkgMzE"),
+		},
+	} {
+		t.Run(spec.name, func(t *testing.T) {
+			var tracer runtime.Tracer
+			if spec.tracer != nil {
+				tracer = spec.tracer
+			}
+			mux := runtime.NewServeMux(runtime.WithTracing(tracer))
+			var md metadata.MD
+			mux.Handle("GET", pat, func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+				ctx, err := runtime.AnnotateContext(r.Context(), mux, r)
+				if err != nil {
+					t.Fatalf("runtime.AnnotateContext(ctx, mux, r) failed with %v; want success", err)
+				}
+				md, _ = metadata.FromOutgoingContext(ctx)
+				w.WriteHeader(http.StatusAccepted)
+			})
+
+			r := httptest.NewRequest("GET", "http://host.example/foo", nil)
+			r.Header.Set("traceparent", spec.traceParent)
+			r.Header.Set("tracestate", "congo=t61rcWkgMzE")
+			mux.ServeHTTP(httptest.NewRecorder(), r)
+
+			got := metadata.MD{}
+			for _, key := range []string{"traceparent", "tracestate"} {
+				if vals, ok := md[key]; ok {
+					got[key] = vals
+				}
+			}
+			if !reflect.DeepEqual(got, spec.wantMD) {
+				t.Errorf("metadata = %v; want %v", got, spec.wantMD)
+			}
+			if spec.tracer != nil {
+				if got, want := spec.tracer.parent.TraceParent, spec.traceParent; got != want {
+					t.Errorf("parent.TraceParent = %q; want %q", got, want)
+				}
+				if got, want := spec.tracer.status, http.StatusAccepted; got != want && !spec.tracer.sampledOut {
+					t.Errorf("status = %d; want %d", got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package runtime
+
+import (
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+)
+
+// RequestTransformerFunc transforms a request message in place before it is sent to the backend.
+// "ctx" carries the metadata of the call.
+type RequestTransformerFunc func(ctx context.Context, msg proto.Message) error
+
+type requestTransformersKey struct{}
+
+// WithRequestTransformer returns a ServeMuxOption which makes the gateway call "fn" with each request message
+// once its body, path and query parameters are bound and before it is validated and sent to the backend,
+// e.g. to set server-side defaults or normalize fields. An error returned by "fn" rejects the request;
+// return a status error to choose its code. Transformers are called in the order of their options.
+func WithRequestTransformer(fn RequestTransformerFunc) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.requestTransformers = append(serveMux.requestTransformers, fn)
+	}
+}
+
+// TransformRequest calls the transformers given to WithRequestTransformer for the ServeMux of
+// the request in "ctx" with the request message "msg".
+func TransformRequest(ctx context.Context, msg proto.Message) error {
+	fns, _ := ctx.Value(requestTransformersKey{}).([]RequestTransformerFunc)
+	for _, fn := range fns {
+		if err := fn(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package runtime_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestTransformRequest(t *testing.T) {
+	lower := func(ctx context.Context, msg proto.Message) error {
+		m := msg.(*pb.SimpleMessage)
+		m.Id = strings.ToLower(m.Id)
+		return nil
+	}
+	withCaller := func(ctx context.Context, msg proto.Message) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		callers := md["caller"]
+		if len(callers) == 0 {
+			return status.Error(codes.PermissionDenied, "no caller")
+		}
+		msg.(*pb.SimpleMessage).Id += "@" + callers[0]
+		return nil
+	}
+	mux := runtime.NewServeMux(runtime.WithRequestTransformer(lower), runtime.WithRequestTransformer(withCaller))
+
+	req, err := http.NewRequest("POST", "http://example.com/foo", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(%q, %q, nil) failed with %v; want success", "POST", "http://example.com/foo", err)
+	}
+	req.Header.Set("Grpc-Metadata-Caller", "alice")
+	ctx, err := runtime.AnnotateContext(context.Background(), mux, req)
+	if err != nil {
+		t.Fatalf("runtime.AnnotateContext(ctx, mux, req) failed with %v; want success", err)
+	}
+	msg := &pb.SimpleMessage{Id: "FOO"}
+	if err := runtime.TransformRequest(ctx, msg); err != nil {
+		t.Fatalf("runtime.TransformRequest(ctx, msg) failed with %v; want success", err)
+	}
+	if got, want := msg.Id, "foo@alice"; got != want {
+		t.Errorf("msg.Id = %q; want %q", got, want)
+	}
+
+	req.Header.Del("Grpc-Metadata-Caller")
+	ctx, err = runtime.AnnotateContext(context.Background(), mux, req)
+	if err != nil {
+		t.Fatalf("runtime.AnnotateContext(ctx, mux, req) failed with %v; want success", err)
+	}
+	err = runtime.TransformRequest(ctx, &pb.SimpleMessage{Id: "FOO"})
+	if s, _ := status.FromError(err); s.Code() != codes.PermissionDenied {
+		t.Errorf("runtime.TransformRequest(ctx, msg) failed with %v; want code %v", err, codes.PermissionDenied)
+	}
+}
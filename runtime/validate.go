@@ -0,0 +1,86 @@
+package runtime
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/status"
+)
+
+// validator is implemented by messages which can validate themselves,
+// e.g. those generated by protoc-gen-validate.
+type validator interface {
+	Validate() error
+}
+
+// fieldError is implemented by the validation errors of protoc-gen-validate.
+type fieldError interface {
+	Field() string
+	Reason() string
+}
+
+// ValidateOnlyParam is the query parameter which asks for the validate-only mode enabled by WithValidateOnly.
+const ValidateOnlyParam = "validateOnly"
+
+// ValidateOnlyHeader is the HTTP header which asks for the validate-only mode enabled by WithValidateOnly.
+const ValidateOnlyHeader = "Grpc-Gateway-Validate-Only"
+
+type requestValidationKey struct{}
+
+type validateOnlyKey struct{}
+
+// ValidateRequest calls the Validate method of the request message "msg", if any,
+// when the ServeMux of the request in "ctx" was configured with WithRequestValidation.
+// A validation failure is returned as a codes.InvalidArgument error with a
+// google.rpc.BadRequest detail describing the offending field when it is known.
+func ValidateRequest(ctx context.Context, msg proto.Message) error {
+	if enabled, _ := ctx.Value(requestValidationKey{}).(bool); !enabled {
+		return nil
+	}
+	v, ok := msg.(validator)
+	if !ok {
+		return nil
+	}
+	err := v.Validate()
+	if err == nil {
+		return nil
+	}
+
+	s := status.New(codes.InvalidArgument, err.Error())
+	if ferr, ok := err.(fieldError); ok {
+		detail := &errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: ferr.Field(), Description: ferr.Reason()},
+			},
+		}
+		if ds, derr := s.WithDetails(detail); derr == nil {
+			s = ds
+		} else {
+			grpclog.Printf("Failed to attach validation details: %v", derr)
+		}
+	}
+	return s.Err()
+}
+
+// IsValidateOnly returns true if the request in "ctx" only asked for its decoding and validation,
+// in which case the gateway replies with an empty response message instead of dispatching the RPC.
+// See WithValidateOnly.
+func IsValidateOnly(ctx context.Context) bool {
+	validateOnly, _ := ctx.Value(validateOnlyKey{}).(bool)
+	return validateOnly
+}
+
+// isValidateOnlyRequest returns true if "req" sets ValidateOnlyParam or ValidateOnlyHeader to true.
+func isValidateOnlyRequest(req *http.Request) bool {
+	val := req.URL.Query().Get(ValidateOnlyParam)
+	if val == "" {
+		val = req.Header.Get(ValidateOnlyHeader)
+	}
+	validateOnly, _ := strconv.ParseBool(val)
+	return validateOnly
+}
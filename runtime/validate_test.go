@@ -0,0 +1,115 @@
+package runtime_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	pb "github.com/grpc-ecosystem/grpc-gateway/examples/examplepb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// validationError mimics the errors generated by protoc-gen-validate.
+type validationError struct {
+	field, reason string
+}
+
+func (e validationError) Field() string  { return e.field }
+func (e validationError) Reason() string { return e.reason }
+func (e validationError) Error() string  { return "invalid " + e.field + ": " + e.reason }
+
+type validatedMessage struct {
+	pb.SimpleMessage
+}
+
+func (m *validatedMessage) Validate() error {
+	if m.Id == "" {
+		return validationError{field: "id", reason: "value is required"}
+	}
+	if m.Id == "bad" {
+		return errors.New("bad id")
+	}
+	return nil
+}
+
+func TestValidateRequest(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://example.com/foo", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(%q, %q, nil) failed with %v; want success", "GET", "http://example.com/foo", err)
+	}
+	ctx, err := runtime.AnnotateContext(context.Background(), runtime.NewServeMux(runtime.WithRequestValidation()), req)
+	if err != nil {
+		t.Fatalf("runtime.AnnotateContext(ctx, mux, req) failed with %v; want success", err)
+	}
+
+	if err := runtime.ValidateRequest(ctx, &validatedMessage{pb.SimpleMessage{Id: "foo"}}); err != nil {
+		t.Errorf("runtime.ValidateRequest(ctx, valid) failed with %v; want success", err)
+	}
+	if err := runtime.ValidateRequest(ctx, &pb.SimpleMessage{}); err != nil {
+		t.Errorf("runtime.ValidateRequest(ctx, &pb.SimpleMessage{}) failed with %v; want success", err)
+	}
+
+	err = runtime.ValidateRequest(ctx, &validatedMessage{})
+	s, _ := status.FromError(err)
+	if s.Code() != codes.InvalidArgument {
+		t.Fatalf("runtime.ValidateRequest(ctx, invalid) failed with %v; want code %v", err, codes.InvalidArgument)
+	}
+	details := s.Details()
+	if len(details) != 1 {
+		t.Fatalf("s.Details() = %v; want 1 detail", details)
+	}
+	br, ok := details[0].(*errdetails.BadRequest)
+	if !ok || len(br.FieldViolations) != 1 {
+		t.Fatalf("s.Details()[0] = %v; want a BadRequest with 1 field violation", details[0])
+	}
+	if got, want := br.FieldViolations[0].Field, "id"; got != want {
+		t.Errorf("br.FieldViolations[0].Field = %q; want %q", got, want)
+	}
+
+	err = runtime.ValidateRequest(ctx, &validatedMessage{pb.SimpleMessage{Id: "bad"}})
+	if s, _ := status.FromError(err); s.Code() != codes.InvalidArgument || len(s.Details()) != 0 {
+		t.Errorf("runtime.ValidateRequest(ctx, bad) failed with %v; want code %v without details", err, codes.InvalidArgument)
+	}
+
+	ctx, err = runtime.AnnotateContext(context.Background(), runtime.NewServeMux(), req)
+	if err != nil {
+		t.Fatalf("runtime.AnnotateContext(ctx, mux, req) failed with %v; want success", err)
+	}
+	if err := runtime.ValidateRequest(ctx, &validatedMessage{}); err != nil {
+		t.Errorf("runtime.ValidateRequest(ctx, invalid) failed with %v without WithRequestValidation; want success", err)
+	}
+}
+
+func TestIsValidateOnly(t *testing.T) {
+	for _, spec := range []struct {
+		url    string
+		header string
+		opts   []runtime.ServeMuxOption
+		want   bool
+	}{
+		{url: "http://example.com/foo?validateOnly=true", opts: []runtime.ServeMuxOption{runtime.WithValidateOnly()}, want: true},
+		{url: "http://example.com/foo", header: "1", opts: []runtime.ServeMuxOption{runtime.WithValidateOnly()}, want: true},
+		{url: "http://example.com/foo?validateOnly=false", header: "true", opts: []runtime.ServeMuxOption{runtime.WithValidateOnly()}, want: false},
+		{url: "http://example.com/foo", opts: []runtime.ServeMuxOption{runtime.WithValidateOnly()}, want: false},
+		{url: "http://example.com/foo?validateOnly=true", want: false},
+	} {
+		req, err := http.NewRequest("POST", spec.url, nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest(%q, %q, nil) failed with %v; want success", "POST", spec.url, err)
+		}
+		if spec.header != "" {
+			req.Header.Set(runtime.ValidateOnlyHeader, spec.header)
+		}
+		ctx, err := runtime.AnnotateContext(context.Background(), runtime.NewServeMux(spec.opts...), req)
+		if err != nil {
+			t.Fatalf("runtime.AnnotateContext(ctx, mux, req) failed with %v; want success", err)
+		}
+		if got := runtime.IsValidateOnly(ctx); got != spec.want {
+			t.Errorf("runtime.IsValidateOnly(ctx) = %t; want %t; url = %q, header = %q", got, spec.want, spec.url, spec.header)
+		}
+	}
+}